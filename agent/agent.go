@@ -3,10 +3,13 @@ package agent
 import (
 	"context"
 	"fmt"
+	"io"
 	"log"
+	"math"
 	"os"
 	"runtime"
 	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -14,12 +17,17 @@ import (
 	"github.com/influxdata/telegraf/config"
 	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/models"
+	"github.com/influxdata/telegraf/plugins/serializers"
 	"github.com/influxdata/telegraf/plugins/serializers/influx"
 )
 
 // Agent runs a set of plugins.
 type Agent struct {
 	Config *config.Config
+
+	// gatherSlots limits the number of concurrent gathers, it is nil if
+	// there is no limit.
+	gatherSlots chan struct{}
 }
 
 // NewAgent returns an Agent for the given Config.
@@ -27,6 +35,9 @@ func NewAgent(config *config.Config) (*Agent, error) {
 	a := &Agent{
 		Config: config,
 	}
+	if config.Agent.MaxConcurrentGathers > 0 {
+		a.gatherSlots = make(chan struct{}, config.Agent.MaxConcurrentGathers)
+	}
 	return a, nil
 }
 
@@ -204,6 +215,10 @@ func (a *Agent) Run(ctx context.Context) error {
 // initPlugins runs the Init function on plugins.
 func (a *Agent) initPlugins() error {
 	for _, input := range a.Config.Inputs {
+		if err := a.Config.ResolveSecrets(input.Input); err != nil {
+			return fmt.Errorf("could not resolve secrets of input %s: %v",
+				input.LogName(), err)
+		}
 		err := input.Init()
 		if err != nil {
 			return fmt.Errorf("could not initialize input %s: %v",
@@ -211,6 +226,10 @@ func (a *Agent) initPlugins() error {
 		}
 	}
 	for _, processor := range a.Config.Processors {
+		if err := a.Config.ResolveSecrets(processor.Processor); err != nil {
+			return fmt.Errorf("could not resolve secrets of processor %s: %v",
+				processor.Config.Name, err)
+		}
 		err := processor.Init()
 		if err != nil {
 			return fmt.Errorf("could not initialize processor %s: %v",
@@ -218,6 +237,10 @@ func (a *Agent) initPlugins() error {
 		}
 	}
 	for _, aggregator := range a.Config.Aggregators {
+		if err := a.Config.ResolveSecrets(aggregator.Aggregator); err != nil {
+			return fmt.Errorf("could not resolve secrets of aggregator %s: %v",
+				aggregator.Config.Name, err)
+		}
 		err := aggregator.Init()
 		if err != nil {
 			return fmt.Errorf("could not initialize aggregator %s: %v",
@@ -225,6 +248,10 @@ func (a *Agent) initPlugins() error {
 		}
 	}
 	for _, processor := range a.Config.AggProcessors {
+		if err := a.Config.ResolveSecrets(processor.Processor); err != nil {
+			return fmt.Errorf("could not resolve secrets of processor %s: %v",
+				processor.Config.Name, err)
+		}
 		err := processor.Init()
 		if err != nil {
 			return fmt.Errorf("could not initialize processor %s: %v",
@@ -232,6 +259,10 @@ func (a *Agent) initPlugins() error {
 		}
 	}
 	for _, output := range a.Config.Outputs {
+		if err := a.Config.ResolveSecrets(output.Output); err != nil {
+			return fmt.Errorf("could not resolve secrets of output %s: %v",
+				output.Config.Name, err)
+		}
 		err := output.Init()
 		if err != nil {
 			return fmt.Errorf("could not initialize output %s: %v",
@@ -252,6 +283,12 @@ func (a *Agent) startInputs(
 	}
 
 	for _, input := range inputs {
+		if err := input.Open(); err != nil {
+			stopServiceInputs(unit.inputs)
+			closeInputs(unit.inputs)
+			return nil, fmt.Errorf("starting input %s: %w", input.LogName(), err)
+		}
+
 		if si, ok := input.Input.(telegraf.ServiceInput); ok {
 			// Service input plugins are not normally subject to timestamp
 			// rounding except for when precision is set on the input plugin.
@@ -271,6 +308,7 @@ func (a *Agent) startInputs(
 			err := si.Start(acc)
 			if err != nil {
 				stopServiceInputs(unit.inputs)
+				closeInputs(append(unit.inputs, input))
 				return nil, fmt.Errorf("starting input %s: %w", input.LogName(), err)
 			}
 		}
@@ -309,12 +347,23 @@ func (a *Agent) runInputs(
 			jitter = input.Config.CollectionJitter
 		}
 
+		// The interval is not used for scheduling if this plugin has a
+		// schedule, but still for the default precision.
+		if input.Config.Schedule != nil {
+			interval = scheduleInterval(input.Config.Schedule, startTime)
+		}
+
 		var ticker Ticker
-		if a.Config.Agent.RoundInterval {
+		if input.Config.Schedule != nil {
+			ticker = NewCronTicker(startTime, input.Config.Schedule, jitter)
+		} else if a.Config.Agent.RoundInterval {
 			ticker = NewAlignedTicker(startTime, interval, jitter)
 		} else {
 			ticker = NewUnalignedTicker(interval, jitter)
 		}
+		if len(input.Config.ActiveWindows) != 0 {
+			ticker = NewWindowTicker(ticker, input.Config.ActiveWindows, input.Config.Timezone)
+		}
 		defer ticker.Stop()
 
 		acc := NewAccumulator(input, unit.dst)
@@ -331,6 +380,7 @@ func (a *Agent) runInputs(
 
 	log.Printf("D! [agent] Stopping service inputs")
 	stopServiceInputs(unit.inputs)
+	closeInputs(unit.inputs)
 
 	close(unit.dst)
 	log.Printf("D! [agent] Input channel closed")
@@ -352,6 +402,10 @@ func (a *Agent) testStartInputs(
 	}
 
 	for _, input := range inputs {
+		if err := input.Open(); err != nil {
+			log.Printf("E! [agent] Starting input %s: %v", input.LogName(), err)
+		}
+
 		if si, ok := input.Input.(telegraf.ServiceInput); ok {
 			// Service input plugins are not subject to timestamp rounding.
 			// This only applies to the accumulator passed to Start(), the
@@ -405,6 +459,10 @@ func (a *Agent) testRunInputs(
 				precision = input.Config.Precision
 			}
 
+			if input.Config.Schedule != nil {
+				interval = scheduleInterval(input.Config.Schedule, time.Now())
+			}
+
 			// Run plugins that require multiple gathers to calculate rate
 			// and delta metrics twice.
 			switch input.Config.Name {
@@ -432,6 +490,7 @@ func (a *Agent) testRunInputs(
 
 	log.Printf("D! [agent] Stopping service inputs")
 	stopServiceInputs(unit.inputs)
+	closeInputs(unit.inputs)
 
 	close(unit.dst)
 	log.Printf("D! [agent] Input channel closed")
@@ -447,6 +506,13 @@ func stopServiceInputs(inputs []*models.RunningInput) {
 	}
 }
 
+// closeInputs stops the recording of the metrics of all inputs.
+func closeInputs(inputs []*models.RunningInput) {
+	for _, input := range inputs {
+		input.Close()
+	}
+}
+
 // gather runs an input's gather function periodically until the context is
 // done.
 func (a *Agent) gatherLoop(
@@ -461,7 +527,11 @@ func (a *Agent) gatherLoop(
 	for {
 		select {
 		case <-ticker.Elapsed():
-			err := a.gatherOnce(acc, input, ticker, interval)
+			if !a.acquireGatherSlot(ctx, input, ticker) {
+				return
+			}
+			err := a.gatherOnce(ctx, acc, input, ticker, interval)
+			a.releaseGatherSlot()
 			if err != nil {
 				acc.AddError(err)
 			}
@@ -471,39 +541,122 @@ func (a *Agent) gatherLoop(
 	}
 }
 
+// acquireGatherSlot waits for a free slot if the number of concurrent gathers
+// is limited.  A gather that cannot start before the next one is due is
+// skipped.  It returns false if the context is done before a slot is free.
+func (a *Agent) acquireGatherSlot(
+	ctx context.Context,
+	input *models.RunningInput,
+	ticker Ticker,
+) bool {
+	if a.gatherSlots == nil {
+		return true
+	}
+
+	for {
+		select {
+		case a.gatherSlots <- struct{}{}:
+			return true
+		case <-ticker.Elapsed():
+			input.GathersSkipped.Incr(1)
+			log.Printf("D! [%s] Maximum number of concurrent gathers reached; scheduled collection skipped",
+				input.LogName())
+		case <-ctx.Done():
+			return false
+		}
+	}
+}
+
+func (a *Agent) releaseGatherSlot() {
+	if a.gatherSlots != nil {
+		<-a.gatherSlots
+	}
+}
+
 // gatherOnce runs the input's Gather function once, logging a warning each
-// interval it fails to complete before.
+// interval, or each scheduled run if the input has a schedule, it fails to
+// complete before.  Once the gather timeout of the input expires the gather
+// is cancelled, inputs not supporting cancellation are still waited for so
+// gathers never pile up.
 func (a *Agent) gatherOnce(
+	ctx context.Context,
 	acc telegraf.Accumulator,
 	input *models.RunningInput,
 	ticker Ticker,
 	interval time.Duration,
 ) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
 	done := make(chan error)
 	go func() {
-		done <- input.Gather(acc)
+		done <- input.GatherContext(ctx, acc)
 	}()
 
+	var timeout <-chan time.Time
+	if input.Config.GatherTimeout > 0 {
+		timer := time.NewTimer(input.Config.GatherTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
 	// Only warn after interval seconds, even if the interval is started late.
 	// Intervals can start late if the previous interval went over or due to
-	// clock changes.
-	slowWarning := time.NewTicker(interval)
+	// clock changes.  With a schedule the warning is due at the next run.
+	slowWarning := time.NewTimer(slowWarningPeriod(input, interval))
 	defer slowWarning.Stop()
 
 	for {
 		select {
 		case err := <-done:
 			return err
+		case <-timeout:
+			cancel()
+			input.GatherTimeouts.Incr(1)
+			acc.AddError(fmt.Errorf("collection did not complete within gather_timeout of %s",
+				input.Config.GatherTimeout))
 		case <-slowWarning.C:
-			log.Printf("W! [%s] Collection took longer than expected; not complete after interval of %s",
-				input.LogName(), interval)
+			if input.Config.Schedule != nil {
+				log.Printf("W! [%s] Collection took longer than expected; not complete at next run of schedule %q",
+					input.LogName(), input.Config.Schedule)
+			} else {
+				log.Printf("W! [%s] Collection took longer than expected; not complete after interval of %s",
+					input.LogName(), interval)
+			}
+			slowWarning.Reset(slowWarningPeriod(input, interval))
 		case <-ticker.Elapsed():
+			input.GathersSkipped.Incr(1)
 			log.Printf("D! [%s] Previous collection has not completed; scheduled collection skipped",
 				input.LogName())
 		}
 	}
 }
 
+// slowWarningPeriod returns the time after which a gather of the input is
+// considered slow, which is the time until the next run if the input has a
+// schedule.
+func slowWarningPeriod(input *models.RunningInput, interval time.Duration) time.Duration {
+	if input.Config.Schedule != nil {
+		// Schedules without any further run never warn
+		if next := input.Config.Schedule.Next(time.Now()); !next.IsZero() {
+			return time.Until(next)
+		}
+		return math.MaxInt64
+	}
+	return interval
+}
+
+// scheduleInterval returns the time between the next two runs of the
+// schedule after t, or zero if the schedule has no further runs.
+func scheduleInterval(schedule *internal.Schedule, t time.Time) time.Duration {
+	next := schedule.Next(t)
+	after := schedule.Next(next)
+	if next.IsZero() || after.IsZero() {
+		return 0
+	}
+	return after.Sub(next)
+}
+
 // startProcessors sets up the processor chain and calls Start on all
 // processors.  If an error occurs any started processors are Stopped.
 func (a *Agent) startProcessors(
@@ -571,12 +724,23 @@ func (a *Agent) runProcessors(
 	return nil
 }
 
-// startAggregators sets up the aggregator unit and returns the source channel.
+// startAggregators starts the service aggregators, sets up the aggregator unit
+// and returns the source channel.
 func (a *Agent) startAggregators(
 	aggC chan<- telegraf.Metric,
 	outputC chan<- telegraf.Metric,
 	aggregators []*models.RunningAggregator,
 ) (chan<- telegraf.Metric, *aggregatorUnit, error) {
+	for i, agg := range aggregators {
+		err := agg.Start()
+		if err != nil {
+			for _, started := range aggregators[:i] {
+				started.Stop()
+			}
+			return nil, nil, fmt.Errorf("starting aggregator %s: %w", agg.LogName(), err)
+		}
+	}
+
 	src := make(chan telegraf.Metric, 100)
 	unit := &aggregatorUnit{
 		src:         src,
@@ -599,6 +763,9 @@ func (a *Agent) runAggregators(
 	// that any metric created after start time will be aggregated.
 	for _, agg := range a.Config.Aggregators {
 		since, until := updateWindow(startTime, a.Config.Agent.RoundInterval, agg.Period())
+		if agg.Config.Schedule != nil {
+			since, until = startTime, agg.ScheduledPeriodEnd(startTime)
+		}
 		agg.UpdateWindow(since, until)
 	}
 
@@ -639,6 +806,10 @@ func (a *Agent) runAggregators(
 
 	wg.Wait()
 
+	for _, agg := range a.Config.Aggregators {
+		agg.Stop()
+	}
+
 	// In the case that there are no processors, both aggC and outputC are the
 	// same channel.  If there are processors, we close the aggC and the
 	// processor chain will close the outputC when it finishes processing.
@@ -875,7 +1046,7 @@ func (a *Agent) Test(ctx context.Context, wait time.Duration) error {
 		for metric := range src {
 			octets, err := s.Serialize(metric)
 			if err == nil {
-				fmt.Print("> ", string(octets))
+				fmt.Print("> ", internal.Redact(string(octets)))
 			}
 			metric.Reject()
 		}
@@ -894,6 +1065,116 @@ func (a *Agent) Test(ctx context.Context, wait time.Duration) error {
 	return nil
 }
 
+// TestOutputs runs the inputs, processors and aggregators for a single gather
+// like Test, and writes the data each output would write to stdout instead of
+// the metrics.  The data is serialized with the data format of the output,
+// or as line protocol if the output has none, outputs are not connected.
+func (a *Agent) TestOutputs(ctx context.Context, wait time.Duration) error {
+	return a.testOutputs(ctx, wait, os.Stdout)
+}
+
+func (a *Agent) testOutputs(ctx context.Context, wait time.Duration, w io.Writer) error {
+	src := make(chan telegraf.Metric, 100)
+
+	var metrics []telegraf.Metric
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for metric := range src {
+			metrics = append(metrics, metric)
+		}
+	}()
+
+	err := a.test(ctx, wait, src)
+	if err != nil {
+		return err
+	}
+
+	wg.Wait()
+
+	for _, output := range a.Config.Outputs {
+		err := testOutput(w, output, metrics)
+		if err != nil {
+			log.Printf("E! [agent] Error serializing metrics for output %s: %v",
+				output.LogName(), err)
+		}
+	}
+
+	for _, metric := range metrics {
+		metric.Reject()
+	}
+
+	if models.GlobalGatherErrors.Get() != 0 {
+		return fmt.Errorf("input plugins recorded %d errors", models.GlobalGatherErrors.Get())
+	}
+	return nil
+}
+
+// testOutput writes the batches the output would write for the metrics,
+// after applying its filters and modifiers, to w.
+func testOutput(w io.Writer, output *models.RunningOutput, metrics []telegraf.Metric) error {
+	serializer := output.Config.Serializer
+	if serializer == nil {
+		// Outputs without a data format are shown as line protocol
+		s := influx.NewSerializer()
+		s.SetFieldSortOrder(influx.SortFields)
+		serializer = s
+	}
+	printer := &printOutput{
+		name:       output.LogName(),
+		serializer: serializer,
+		w:          w,
+	}
+
+	if agg, ok := output.Output.(telegraf.AggregatingOutput); ok {
+		// Aggregating outputs only write their aggregates
+		for _, metric := range metrics {
+			output.AddMetric(metric.Copy())
+		}
+		aggregates := agg.Push()
+		agg.Reset()
+		if len(aggregates) == 0 {
+			return nil
+		}
+		return printer.Write(aggregates)
+	}
+
+	ro := models.NewRunningOutput(output.Config.Name, printer, output.Config,
+		output.MetricBatchSize, output.MetricBufferLimit)
+	for _, metric := range metrics {
+		ro.AddMetric(metric.Copy())
+	}
+	return ro.Write()
+}
+
+// printOutput is an output writing each batch to a writer instead of sending
+// it, used in test mode.
+type printOutput struct {
+	name       string
+	serializer serializers.Serializer
+	w          io.Writer
+}
+
+func (p *printOutput) Connect() error       { return nil }
+func (p *printOutput) Close() error         { return nil }
+func (p *printOutput) Description() string  { return "" }
+func (p *printOutput) SampleConfig() string { return "" }
+
+func (p *printOutput) Write(metrics []telegraf.Metric) error {
+	octets, err := p.serializer.SerializeBatch(metrics)
+	if err != nil {
+		return err
+	}
+
+	payload := internal.Redact(string(octets))
+	if !strings.HasSuffix(payload, "\n") {
+		payload += "\n"
+	}
+	_, err = fmt.Fprintf(p.w, "> %s: batch of %d metrics\n%s", p.name, len(metrics), payload)
+	return err
+}
+
 // Test runs the agent and performs a single gather sending output to the
 // outputF.  After gathering pauses for the wait duration to allow service
 // inputs to run.
@@ -1,12 +1,19 @@
 package agent
 
 import (
+	"bytes"
+	"context"
 	"testing"
 	"time"
 
+	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/models"
 	_ "github.com/influxdata/telegraf/plugins/inputs/all"
 	_ "github.com/influxdata/telegraf/plugins/outputs/all"
+	"github.com/influxdata/telegraf/plugins/serializers/json"
+	"github.com/influxdata/telegraf/testutil"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -166,3 +173,227 @@ func TestWindow(t *testing.T) {
 		})
 	}
 }
+
+// manualTicker ticks when a time is sent to its channel.
+type manualTicker struct {
+	ch chan time.Time
+}
+
+func (t *manualTicker) Elapsed() <-chan time.Time {
+	return t.ch
+}
+
+func (t *manualTicker) Stop() {
+}
+
+// contextInput gathers until its context is cancelled.
+type contextInput struct{}
+
+func (i *contextInput) SampleConfig() string { return "" }
+func (i *contextInput) Description() string  { return "" }
+
+func (i *contextInput) Gather(acc telegraf.Accumulator) error {
+	return i.GatherContext(context.Background(), acc)
+}
+
+func (i *contextInput) GatherContext(ctx context.Context, acc telegraf.Accumulator) error {
+	<-ctx.Done()
+	return nil
+}
+
+// blockingInput gathers until it is released, ignoring any timeout.
+type blockingInput struct {
+	release chan struct{}
+}
+
+func (i *blockingInput) SampleConfig() string { return "" }
+func (i *blockingInput) Description() string  { return "" }
+
+func (i *blockingInput) Gather(acc telegraf.Accumulator) error {
+	<-i.release
+	return nil
+}
+
+func TestGatherTimeoutCancelsContextInput(t *testing.T) {
+	a, err := NewAgent(config.NewConfig())
+	require.NoError(t, err)
+
+	input := models.NewRunningInput(&contextInput{}, &models.InputConfig{
+		Name:          "test_timeout_context",
+		GatherTimeout: 50 * time.Millisecond,
+	})
+	ticker := &manualTicker{ch: make(chan time.Time)}
+	timeouts := input.GatherTimeouts.Get()
+
+	var acc testutil.Accumulator
+	err = a.gatherOnce(context.Background(), &acc, input, ticker, time.Minute)
+	require.NoError(t, err)
+	require.Len(t, acc.Errors, 1)
+	require.Equal(t, timeouts+1, input.GatherTimeouts.Get())
+}
+
+func TestGatherTimeoutWaitsForInput(t *testing.T) {
+	a, err := NewAgent(config.NewConfig())
+	require.NoError(t, err)
+
+	plugin := &blockingInput{release: make(chan struct{})}
+	input := models.NewRunningInput(plugin, &models.InputConfig{
+		Name:          "test_timeout_blocking",
+		GatherTimeout: 50 * time.Millisecond,
+	})
+	ticker := &manualTicker{ch: make(chan time.Time)}
+	timeouts := input.GatherTimeouts.Get()
+	skipped := input.GathersSkipped.Get()
+
+	var acc testutil.Accumulator
+	done := make(chan error)
+	go func() {
+		done <- a.gatherOnce(context.Background(), &acc, input, ticker, time.Minute)
+	}()
+
+	require.Eventually(t, func() bool {
+		return input.GatherTimeouts.Get() == timeouts+1
+	}, time.Second, 10*time.Millisecond)
+
+	// The gather is not abandoned after the timeout, so the next scheduled
+	// gather is skipped instead of running concurrently.
+	ticker.ch <- time.Now()
+	require.Eventually(t, func() bool {
+		return input.GathersSkipped.Get() == skipped+1
+	}, time.Second, 10*time.Millisecond)
+
+	close(plugin.release)
+	require.NoError(t, <-done)
+	require.Len(t, acc.Errors, 1)
+}
+
+func TestMaxConcurrentGathers(t *testing.T) {
+	c := config.NewConfig()
+	c.Agent.MaxConcurrentGathers = 1
+	a, err := NewAgent(c)
+	require.NoError(t, err)
+
+	input := models.NewRunningInput(&contextInput{}, &models.InputConfig{
+		Name: "test_max_concurrent",
+	})
+	ticker := &manualTicker{ch: make(chan time.Time)}
+	skipped := input.GathersSkipped.Get()
+
+	require.True(t, a.acquireGatherSlot(context.Background(), input, ticker))
+
+	acquired := make(chan bool)
+	go func() {
+		acquired <- a.acquireGatherSlot(context.Background(), input, ticker)
+	}()
+
+	// The gather due while waiting for a slot is skipped
+	ticker.ch <- time.Now()
+	require.Eventually(t, func() bool {
+		return input.GathersSkipped.Get() == skipped+1
+	}, time.Second, 10*time.Millisecond)
+
+	a.releaseGatherSlot()
+	require.True(t, <-acquired)
+	a.releaseGatherSlot()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	require.True(t, a.acquireGatherSlot(ctx, input, ticker))
+	cancel()
+	require.False(t, a.acquireGatherSlot(ctx, input, ticker))
+}
+
+// metricInput adds a cpu and a mem metric.
+type metricInput struct{}
+
+func (i *metricInput) SampleConfig() string { return "" }
+func (i *metricInput) Description() string  { return "" }
+
+func (i *metricInput) Gather(acc telegraf.Accumulator) error {
+	tm := time.Unix(42, 0)
+	acc.AddFields("cpu", map[string]interface{}{"usage": 42.0}, nil, tm)
+	acc.AddFields("mem", map[string]interface{}{"used": 42}, nil, tm)
+	return nil
+}
+
+// discardOutput fails the test if it is connected or written to.
+type discardOutput struct {
+	t *testing.T
+}
+
+func (o *discardOutput) SampleConfig() string { return "" }
+func (o *discardOutput) Description() string  { return "" }
+func (o *discardOutput) Close() error         { return nil }
+
+func (o *discardOutput) Connect() error {
+	o.t.Error("output connected in test mode")
+	return nil
+}
+
+func (o *discardOutput) Write(metrics []telegraf.Metric) error {
+	o.t.Error("output written in test mode")
+	return nil
+}
+
+func TestTestOutputs(t *testing.T) {
+	c := config.NewConfig()
+	c.Agent.OmitHostname = true
+	c.Inputs = append(c.Inputs, models.NewRunningInput(&metricInput{}, &models.InputConfig{
+		Name: "test_outputs",
+	}))
+
+	serializer, err := json.NewSerializer(time.Second)
+	require.NoError(t, err)
+	jsonConfig := &models.OutputConfig{
+		Name:       "json",
+		NamePrefix: "test_",
+		Filter:     models.Filter{NamePass: []string{"cpu"}},
+		Serializer: serializer,
+	}
+	require.NoError(t, jsonConfig.Filter.Compile())
+	c.Outputs = append(c.Outputs,
+		models.NewRunningOutput("json", &discardOutput{t: t}, jsonConfig, 0, 0),
+		models.NewRunningOutput("lines", &discardOutput{t: t}, &models.OutputConfig{
+			Name:            "lines",
+			MetricBatchSize: 1,
+		}, 0, 0),
+	)
+
+	a, err := NewAgent(c)
+	require.NoError(t, err)
+
+	var buf bytes.Buffer
+	require.NoError(t, a.testOutputs(context.Background(), 0, &buf))
+
+	expected := `> outputs.json: batch of 1 metrics
+{"metrics":[{"fields":{"usage":42},"name":"test_cpu","tags":{},"timestamp":42}]}
+> outputs.lines: batch of 1 metrics
+cpu usage=42 42000000000
+> outputs.lines: batch of 1 metrics
+mem used=42i 42000000000
+`
+	require.Equal(t, expected, buf.String())
+}
+
+func TestSlowWarningPeriodSchedule(t *testing.T) {
+	schedule, err := internal.ParseSchedule("0 3 * * *", time.UTC)
+	require.NoError(t, err)
+
+	input := models.NewRunningInput(&contextInput{}, &models.InputConfig{
+		Name:     "test_schedule",
+		Schedule: schedule,
+	})
+	next := schedule.Next(time.Now())
+	period := slowWarningPeriod(input, 10*time.Second)
+	require.InDelta(t, float64(time.Until(next)), float64(period), float64(time.Second))
+
+	input = models.NewRunningInput(&contextInput{}, &models.InputConfig{Name: "test_interval"})
+	require.Equal(t, 10*time.Second, slowWarningPeriod(input, 10*time.Second))
+}
+
+func TestScheduleInterval(t *testing.T) {
+	schedule, err := internal.ParseSchedule("*/15 * * * * *", time.UTC)
+	require.NoError(t, err)
+	start := time.Date(2021, 1, 1, 0, 0, 7, 0, time.UTC)
+	require.Equal(t, 15*time.Second, scheduleInterval(schedule, start))
+	require.Equal(t, time.Second, getPrecision(0, scheduleInterval(schedule, start)))
+}
@@ -273,3 +273,172 @@ func (t *RollingTicker) Stop() {
 	t.cancel()
 	t.wg.Wait()
 }
+
+// CronTicker delivers ticks at the times of a cron schedule plus an optional
+// jitter.  The times of the schedule are computed from the clock, so changes
+// to the system clock are followed after the next tick.
+//
+// The first tick is emitted at the next scheduled time.
+//
+// Ticks are dropped for slow consumers.
+type CronTicker struct {
+	schedule  *internal.Schedule
+	jitter    time.Duration
+	scheduled time.Time
+	ch        chan time.Time
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+}
+
+func NewCronTicker(now time.Time, schedule *internal.Schedule, jitter time.Duration) *CronTicker {
+	return newCronTicker(now, schedule, jitter, clock.New())
+}
+
+func newCronTicker(now time.Time, schedule *internal.Schedule, jitter time.Duration, clock clock.Clock) *CronTicker {
+	ctx, cancel := context.WithCancel(context.Background())
+	t := &CronTicker{
+		schedule:  schedule,
+		jitter:    jitter,
+		scheduled: now,
+		ch:        make(chan time.Time, 1),
+		cancel:    cancel,
+	}
+
+	d, ok := t.next(now)
+	if !ok {
+		// The schedule has no further runs, so the ticker never ticks
+		return t
+	}
+	timer := clock.Timer(d)
+
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+		t.run(ctx, timer)
+	}()
+
+	return t
+}
+
+// next returns the time until the next tick, it returns false if the
+// schedule has no further runs.
+func (t *CronTicker) next(now time.Time) (time.Duration, bool) {
+	// Follow the schedule from the last scheduled time, so that a jitter
+	// delaying the tick does not skip the next time of the schedule.  Times
+	// already elapsed, for example after the clock jumped, are skipped.
+	next := t.schedule.Next(t.scheduled)
+	if !next.After(now) {
+		next = t.schedule.Next(now)
+	}
+	if next.IsZero() {
+		return 0, false
+	}
+	t.scheduled = next
+
+	return next.Sub(now) + internal.RandomDuration(t.jitter), true
+}
+
+func (t *CronTicker) run(ctx context.Context, timer *clock.Timer) {
+	for {
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case now := <-timer.C:
+			select {
+			case t.ch <- now:
+			default:
+			}
+
+			d, ok := t.next(now)
+			if !ok {
+				return
+			}
+			timer.Reset(d)
+		}
+	}
+}
+
+func (t *CronTicker) Elapsed() <-chan time.Time {
+	return t.ch
+}
+
+func (t *CronTicker) Stop() {
+	t.cancel()
+	t.wg.Wait()
+}
+
+// WindowTicker delivers the ticks of another ticker that are within one of
+// its active time windows, all other ticks are dropped.  The windows are
+// evaluated in the given location.
+//
+// Ticks are dropped for slow consumers.
+type WindowTicker struct {
+	ticker  Ticker
+	windows []internal.TimeWindow
+	loc     *time.Location
+	ch      chan time.Time
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+func NewWindowTicker(ticker Ticker, windows []internal.TimeWindow, loc *time.Location) *WindowTicker {
+	if loc == nil {
+		loc = time.Local
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	t := &WindowTicker{
+		ticker:  ticker,
+		windows: windows,
+		loc:     loc,
+		ch:      make(chan time.Time, 1),
+		cancel:  cancel,
+	}
+
+	t.wg.Add(1)
+	go func() {
+		defer t.wg.Done()
+		t.run(ctx)
+	}()
+
+	return t
+}
+
+func (t *WindowTicker) run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-t.ticker.Elapsed():
+			if !t.active(now) {
+				continue
+			}
+
+			select {
+			case t.ch <- now:
+			default:
+			}
+		}
+	}
+}
+
+func (t *WindowTicker) active(now time.Time) bool {
+	now = now.In(t.loc)
+	for _, w := range t.windows {
+		if w.Contains(now) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *WindowTicker) Elapsed() <-chan time.Time {
+	return t.ch
+}
+
+func (t *WindowTicker) Stop() {
+	t.cancel()
+	t.wg.Wait()
+	t.ticker.Stop()
+}
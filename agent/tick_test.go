@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/benbjohnson/clock"
+	"github.com/influxdata/telegraf/internal"
 	"github.com/stretchr/testify/require"
 )
 
@@ -158,6 +159,147 @@ func TestRollingTicker(t *testing.T) {
 	require.Equal(t, expected, actual)
 }
 
+func TestCronTicker(t *testing.T) {
+	schedule, err := internal.ParseSchedule("*/15 * * * * *", time.UTC)
+	require.NoError(t, err)
+
+	clock := clock.NewMock()
+	since := clock.Now()
+	until := since.Add(60 * time.Second)
+
+	ticker := newCronTicker(since, schedule, 0, clock)
+	defer ticker.Stop()
+
+	expected := []time.Time{
+		time.Unix(15, 0).UTC(),
+		time.Unix(30, 0).UTC(),
+		time.Unix(45, 0).UTC(),
+		time.Unix(60, 0).UTC(),
+	}
+
+	actual := []time.Time{}
+	for !clock.Now().After(until) {
+		select {
+		case tm := <-ticker.Elapsed():
+			actual = append(actual, tm.UTC())
+		default:
+		}
+		clock.Add(5 * time.Second)
+	}
+
+	require.Equal(t, expected, actual)
+}
+
+func TestCronTickerDaily(t *testing.T) {
+	schedule, err := internal.ParseSchedule("0 2 * * *", time.FixedZone("UTC+1", 3600))
+	require.NoError(t, err)
+
+	clock := clock.NewMock()
+	since := clock.Now()
+	until := since.Add(72 * time.Hour)
+
+	ticker := newCronTicker(since, schedule, 0, clock)
+	defer ticker.Stop()
+
+	expected := []time.Time{
+		time.Date(1970, 1, 1, 1, 0, 0, 0, time.UTC),
+		time.Date(1970, 1, 2, 1, 0, 0, 0, time.UTC),
+		time.Date(1970, 1, 3, 1, 0, 0, 0, time.UTC),
+	}
+
+	actual := []time.Time{}
+	for !clock.Now().After(until) {
+		select {
+		case tm := <-ticker.Elapsed():
+			actual = append(actual, tm.UTC())
+		default:
+		}
+		clock.Add(30 * time.Minute)
+	}
+
+	require.Equal(t, expected, actual)
+}
+
+func TestCronTickerNoFurtherRuns(t *testing.T) {
+	// February 30th never comes
+	schedule, err := internal.ParseSchedule("0 0 30 2 *", time.UTC)
+	require.NoError(t, err)
+
+	clock := clock.NewMock()
+	since := clock.Now()
+	until := since.Add(24 * time.Hour)
+
+	ticker := newCronTicker(since, schedule, 0, clock)
+	defer ticker.Stop()
+
+	for !clock.Now().After(until) {
+		select {
+		case tm := <-ticker.Elapsed():
+			require.Fail(t, "unexpected tick", "at %v", tm)
+		default:
+		}
+		clock.Add(time.Hour)
+	}
+}
+
+func TestCronTickerJitter(t *testing.T) {
+	schedule, err := internal.ParseSchedule("*/10 * * * * *", time.UTC)
+	require.NoError(t, err)
+	jitter := 5 * time.Second
+
+	clock := clock.NewMock()
+	since := clock.Now()
+	until := since.Add(60 * time.Second)
+
+	ticker := newCronTicker(since, schedule, jitter, clock)
+	defer ticker.Stop()
+
+	last := since
+	for !clock.Now().After(until) {
+		select {
+		case tm := <-ticker.Elapsed():
+			require.True(t, tm.Sub(last) <= 15*time.Second)
+			require.True(t, tm.Sub(last) >= 5*time.Second)
+			last = last.Add(10 * time.Second)
+		default:
+		}
+		clock.Add(time.Second)
+	}
+}
+
+func TestWindowTicker(t *testing.T) {
+	window, err := internal.ParseTimeWindow("22:00-02:00")
+	require.NoError(t, err)
+
+	clock := clock.NewMock()
+	since := clock.Now()
+	until := since.Add(24 * time.Hour)
+
+	// The window is evaluated in UTC+2, so it is open from 20:00 to 24:00 UTC
+	loc := time.FixedZone("UTC+2", 2*3600)
+	ticker := NewWindowTicker(newAlignedTicker(since, time.Hour, 0, clock), []internal.TimeWindow{window}, loc)
+	defer ticker.Stop()
+
+	expected := []time.Time{
+		time.Unix(20*3600, 0).UTC(),
+		time.Unix(21*3600, 0).UTC(),
+		time.Unix(22*3600, 0).UTC(),
+		time.Unix(23*3600, 0).UTC(),
+	}
+
+	actual := []time.Time{}
+	for !clock.Now().After(until) {
+		clock.Add(time.Hour)
+		select {
+		case tm := <-ticker.Elapsed():
+			actual = append(actual, tm.UTC())
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+
+	require.Equal(t, expected, actual)
+}
+
 // Simulates running the Ticker for an hour and displays stats about the
 // operation.
 func TestAlignedTickerDistribution(t *testing.T) {
@@ -16,3 +16,15 @@ type Aggregator interface {
 	// Reset resets the aggregators caches and aggregates.
 	Reset()
 }
+
+// ServiceAggregator is an Aggregator that runs a background service, for
+// example an external process, between Start and Stop.
+type ServiceAggregator interface {
+	Aggregator
+
+	// Start the ServiceAggregator before the first metric is added.
+	Start() error
+
+	// Stop the ServiceAggregator after the final push.
+	Stop()
+}
@@ -26,6 +26,7 @@ import (
 	"github.com/influxdata/telegraf/plugins/outputs"
 	_ "github.com/influxdata/telegraf/plugins/outputs/all"
 	_ "github.com/influxdata/telegraf/plugins/processors/all"
+	_ "github.com/influxdata/telegraf/plugins/secretstores/all"
 )
 
 // If you update these, update usage.go and usage_windows.go
@@ -35,11 +36,17 @@ var pprofAddr = flag.String("pprof-addr", "",
 	"pprof address to listen on, not activate pprof if empty")
 var fQuiet = flag.Bool("quiet", false,
 	"run in quiet mode")
-var fTest = flag.Bool("test", false, "enable test mode: gather metrics, print them out, and exit. Note: Test mode only runs inputs, not processors, aggregators, or outputs")
-var fTestWait = flag.Int("test-wait", 0, "wait up to this many seconds for service inputs to complete in test mode")
+var fTest = flag.Bool("test", false, "enable test mode: gather metrics, run them through the processors and aggregators, print them out, and exit. Note: Test mode does not run outputs")
+var fTestOutputs = flag.Bool("test-outputs", false,
+	"enable test mode, printing the data each output would write instead of the metrics, without connecting to any output. Note: Outputs without a data_format are shown as line protocol")
+var fTestWait = flag.Int("test-wait", 0, "wait up to this many seconds for service inputs and aggregator periods to complete in test mode")
 var fConfig = flag.String("config", "", "configuration file to load")
 var fConfigDirectory = flag.String("config-directory", "",
 	"directory containing additional *.conf files")
+var fCheckConfig = flag.Bool("check-config", false,
+	"check the configuration files, report all problems found and exit")
+var fPrintConfig = flag.Bool("print-config", false,
+	"print the effective configuration after merging all configuration files and exit")
 var fVersion = flag.Bool("version", false, "display the version and exit")
 var fSampleConfig = flag.Bool("sample-config", false,
 	"print out full sample configuration")
@@ -175,6 +182,11 @@ func runAgent(ctx context.Context,
 		return ag.Once(ctx, wait)
 	}
 
+	if *fTestOutputs {
+		wait := time.Duration(*fTestWait) * time.Second
+		return ag.TestOutputs(ctx, wait)
+	}
+
 	if *fTest || *fTestWait != 0 {
 		wait := time.Duration(*fTestWait) * time.Second
 		return ag.Test(ctx, wait)
@@ -207,6 +219,52 @@ func runAgent(ctx context.Context,
 	return ag.Run(ctx)
 }
 
+// checkConfig checks the configuration files without running any plugin and
+// prints the problems found, it returns the exit code.
+func checkConfig(inputFilters, outputFilters []string) int {
+	c := config.NewConfig()
+	c.OutputFilters = outputFilters
+	c.InputFilters = inputFilters
+
+	problems := c.CheckConfig(*fConfig)
+	if *fConfigDirectory != "" {
+		problems = append(problems, c.CheckDirectory(*fConfigDirectory)...)
+	}
+	if len(c.Outputs) == 0 {
+		problems = append(problems, config.Problem{Message: "no outputs found"})
+	}
+	if *fPlugins == "" && len(c.Inputs) == 0 {
+		problems = append(problems, config.Problem{Message: "no inputs found"})
+	}
+
+	for _, p := range problems {
+		fmt.Println(p)
+	}
+	if len(problems) > 0 {
+		fmt.Printf("Found %d problem(s) in the configuration\n", len(problems))
+		return 1
+	}
+	fmt.Println("Configuration is valid")
+	return 0
+}
+
+// printConfig loads the configuration files and prints the effective
+// configuration.
+func printConfig(inputFilters, outputFilters []string) error {
+	c := config.NewConfig()
+	c.OutputFilters = outputFilters
+	c.InputFilters = inputFilters
+	if err := c.LoadConfig(*fConfig); err != nil {
+		return err
+	}
+	if *fConfigDirectory != "" {
+		if err := c.LoadDirectory(*fConfigDirectory); err != nil {
+			return err
+		}
+	}
+	return c.PrintConfig(os.Stdout)
+}
+
 func usageExit(rc int) {
 	fmt.Println(internal.Usage)
 	os.Exit(rc)
@@ -346,6 +404,13 @@ func main() {
 			log.Fatalf("E! %s and %s", err, err2)
 		}
 		return
+	case *fCheckConfig:
+		os.Exit(checkConfig(inputFilters, outputFilters))
+	case *fPrintConfig:
+		if err := printConfig(inputFilters, outputFilters); err != nil {
+			log.Fatalf("E! %s", err)
+		}
+		return
 	}
 
 	shortVersion := version
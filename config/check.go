@@ -0,0 +1,765 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/aggregators"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	"github.com/influxdata/telegraf/plugins/parsers"
+	"github.com/influxdata/telegraf/plugins/processors"
+	"github.com/influxdata/telegraf/plugins/secretstores"
+	"github.com/influxdata/telegraf/plugins/serializers"
+	"github.com/influxdata/toml"
+	"github.com/influxdata/toml/ast"
+)
+
+// Problem is an issue found when checking the configuration.
+type Problem struct {
+	File string
+	Line int
+	// Section is the plugin, eg. "inputs.cpu", or the table the problem was
+	// found in.
+	Section string
+	Message string
+}
+
+func (p Problem) String() string {
+	var b strings.Builder
+	if p.File != "" {
+		b.WriteString(p.File)
+		if p.Line > 0 {
+			fmt.Fprintf(&b, ":%d", p.Line)
+		}
+		b.WriteString(": ")
+	} else if p.Line > 0 {
+		fmt.Fprintf(&b, "line %d: ", p.Line)
+	}
+	if p.Section != "" {
+		b.WriteString(p.Section)
+		b.WriteString(": ")
+	}
+	b.WriteString(p.Message)
+	return b.String()
+}
+
+// settingKind is the expected type of a setting common to all plugins of a
+// type.
+type settingKind int
+
+const (
+	kindString settingKind = iota
+	kindDuration
+	kindInt
+	kindBool
+	kindStrings
+	// table of strings
+	kindTags
+	// table of string arrays
+	kindTagFilter
+)
+
+var filterSettings = map[string]settingKind{
+	"namepass":   kindStrings,
+	"namedrop":   kindStrings,
+	"fieldpass":  kindStrings,
+	"fielddrop":  kindStrings,
+	"pass":       kindStrings,
+	"drop":       kindStrings,
+	"tagpass":    kindTagFilter,
+	"tagdrop":    kindTagFilter,
+	"taginclude": kindStrings,
+	"tagexclude": kindStrings,
+}
+
+var inputSettings = map[string]settingKind{
+	"interval":          kindDuration,
+	"precision":         kindDuration,
+	"collection_jitter": kindDuration,
+	"gather_timeout":    kindDuration,
+	"schedule":          kindString,
+	"active_windows":    kindStrings,
+	"timezone":          kindString,
+	"record_file":       kindString,
+	"name_prefix":       kindString,
+	"name_suffix":       kindString,
+	"name_override":     kindString,
+	"alias":             kindString,
+	"tags":              kindTags,
+}
+
+var outputSettings = map[string]settingKind{
+	"flush_interval":      kindDuration,
+	"flush_jitter":        kindDuration,
+	"metric_buffer_limit": kindInt,
+	"metric_batch_size":   kindInt,
+	"name_prefix":         kindString,
+	"name_suffix":         kindString,
+	"name_override":       kindString,
+	"alias":               kindString,
+}
+
+var processorSettings = map[string]settingKind{
+	"alias": kindString,
+	"order": kindInt,
+}
+
+var aggregatorSettings = map[string]settingKind{
+	"period":        kindDuration,
+	"delay":         kindDuration,
+	"grace":         kindDuration,
+	"drop_original": kindBool,
+	"schedule":      kindString,
+	"timezone":      kindString,
+	"name_prefix":   kindString,
+	"name_suffix":   kindString,
+	"name_override": kindString,
+	"alias":         kindString,
+	"tags":          kindTags,
+}
+
+var logTargets = []string{"file", "stderr", "eventlog"}
+
+// CheckConfig checks the given config file, see CheckConfigData.
+func (c *Config) CheckConfig(path string) []Problem {
+	var err error
+	if path == "" {
+		if path, err = getDefaultConfigPath(); err != nil {
+			return []Problem{{Message: err.Error()}}
+		}
+	}
+	data, err := loadConfig(path)
+	if err != nil {
+		return []Problem{{File: path, Message: err.Error()}}
+	}
+	return c.CheckConfigData(path, data)
+}
+
+// CheckDirectory checks the config files of the given directory, see
+// CheckConfigData.
+func (c *Config) CheckDirectory(path string) []Problem {
+	var problems []Problem
+	err := walkDirectory(path, func(thispath string) error {
+		problems = append(problems, c.CheckConfig(thispath)...)
+		return nil
+	})
+	if err != nil {
+		problems = append(problems, Problem{File: path, Message: err.Error()})
+	}
+	return problems
+}
+
+// CheckConfigData loads TOML-formatted config data like LoadConfigData, but
+// instead of stopping at the first error it reports all problems found:
+// unknown settings, settings of the wrong type, unknown plugins and data
+// formats, and plugins failing to initialize.  The plugins are initialized but
+// not started.  The file is only used to report the problems.
+func (c *Config) CheckConfigData(file string, data []byte) []Problem {
+	ck := &checker{c: c, file: file}
+	ck.check(data)
+
+	sort.SliceStable(ck.problems, func(i, j int) bool {
+		return ck.problems[i].Line < ck.problems[j].Line
+	})
+	return ck.problems
+}
+
+type checker struct {
+	c        *Config
+	file     string
+	problems []Problem
+}
+
+func (ck *checker) add(line int, section string, format string, args ...interface{}) {
+	ck.problems = append(ck.problems, Problem{
+		File:    ck.file,
+		Line:    line,
+		Section: section,
+		Message: fmt.Sprintf(format, args...),
+	})
+}
+
+// addError adds the error, using the line of TOML errors when available.
+func (ck *checker) addError(line int, section string, err error) {
+	var lerr *toml.LineError
+	if errors.As(err, &lerr) {
+		line = lerr.Line
+		err = lerr.Err
+	}
+	ck.add(line, section, "%v", err)
+}
+
+func (ck *checker) check(data []byte) {
+	tbl, err := parseConfig(data)
+	if err != nil {
+		ck.addError(0, "", err)
+		return
+	}
+
+	// The tags and agent tables are used by the plugins, check them first
+	for _, name := range []string{"tags", "global_tags", "agent"} {
+		val, ok := tbl.Fields[name]
+		if !ok {
+			continue
+		}
+		subTable, ok := val.(*ast.Table)
+		if !ok {
+			ck.add(nodeLine(val, 0), name, "expected a table")
+			continue
+		}
+
+		if name == "agent" {
+			ck.checkAgent(subTable)
+		} else if err := toml.UnmarshalTable(subTable, ck.c.Tags); err != nil {
+			ck.addError(subTable.Line, name, err)
+		}
+	}
+
+	for _, name := range sortedKeys(tbl.Fields) {
+		switch name {
+		case "agent", "global_tags", "tags":
+			continue
+		}
+
+		val := tbl.Fields[name]
+		subTable, ok := val.(*ast.Table)
+		if !ok {
+			ck.add(nodeLine(val, 0), name, "expected a table")
+			continue
+		}
+
+		switch name {
+		case "outputs":
+			for _, pluginName := range sortedKeys(subTable.Fields) {
+				for _, t := range ck.pluginTables("outputs."+pluginName, subTable.Fields[pluginName], true) {
+					ck.checkOutput(pluginName, t)
+				}
+			}
+		case "inputs", "plugins":
+			for _, pluginName := range sortedKeys(subTable.Fields) {
+				for _, t := range ck.pluginTables("inputs."+pluginName, subTable.Fields[pluginName], true) {
+					ck.checkInput(pluginName, t)
+				}
+			}
+		case "processors":
+			for _, pluginName := range sortedKeys(subTable.Fields) {
+				for _, t := range ck.pluginTables("processors."+pluginName, subTable.Fields[pluginName], false) {
+					ck.checkProcessor(pluginName, t)
+				}
+			}
+		case "aggregators":
+			for _, pluginName := range sortedKeys(subTable.Fields) {
+				for _, t := range ck.pluginTables("aggregators."+pluginName, subTable.Fields[pluginName], false) {
+					ck.checkAggregator(pluginName, t)
+				}
+			}
+		case "secretstores":
+			for _, pluginName := range sortedKeys(subTable.Fields) {
+				for _, t := range ck.pluginTables("secretstores."+pluginName, subTable.Fields[pluginName], false) {
+					ck.checkSecretStore(pluginName, t)
+				}
+			}
+		// Assume it's an input for legacy config file support if no other
+		// identifiers are present
+		default:
+			ck.checkInput(name, subTable)
+		}
+	}
+}
+
+// pluginTables returns the tables of a plugin, legacy plugin tables are only
+// allowed for inputs and outputs.
+func (ck *checker) pluginTables(section string, val interface{}, legacy bool) []*ast.Table {
+	switch t := val.(type) {
+	case *ast.Table:
+		if legacy {
+			return []*ast.Table{t}
+		}
+		ck.add(t.Line, section, "expected an array of tables, use [[%s]]", section)
+	case []*ast.Table:
+		return t
+	default:
+		ck.add(nodeLine(val, 0), section, "unsupported config format")
+	}
+	return nil
+}
+
+func (ck *checker) checkAgent(tbl *ast.Table) {
+	ck.checkUnknown(tbl, "agent", ck.c.Agent, nil)
+	if err := toml.UnmarshalTable(tbl, ck.c.Agent); err != nil {
+		ck.addError(tbl.Line, "agent", err)
+		return
+	}
+
+	if ck.c.Agent.Interval.Duration <= 0 {
+		ck.add(nodeLine(tbl.Fields["interval"], tbl.Line), "agent",
+			"interval must be positive, found %s", ck.c.Agent.Interval.Duration)
+	}
+	if ck.c.Agent.FlushInterval.Duration <= 0 {
+		ck.add(nodeLine(tbl.Fields["flush_interval"], tbl.Line), "agent",
+			"flush_interval must be positive, found %s", ck.c.Agent.FlushInterval.Duration)
+	}
+	if ck.c.Agent.MaxConcurrentGathers < 0 {
+		ck.add(nodeLine(tbl.Fields["max_concurrent_gathers"], tbl.Line), "agent",
+			"max_concurrent_gathers must not be negative, found %d", ck.c.Agent.MaxConcurrentGathers)
+	}
+	if ck.c.Agent.LogTarget != "" && !sliceContains(ck.c.Agent.LogTarget, logTargets) {
+		ck.add(nodeLine(tbl.Fields["logtarget"], tbl.Line), "agent",
+			"invalid logtarget %q, must be one of %s", ck.c.Agent.LogTarget, strings.Join(logTargets, ", "))
+	}
+}
+
+func (ck *checker) checkInput(name string, tbl *ast.Table) {
+	if len(ck.c.InputFilters) > 0 && !sliceContains(name, ck.c.InputFilters) {
+		return
+	}
+	section := "inputs." + name
+	// Legacy support renaming io input to diskio
+	if name == "io" {
+		name = "diskio"
+	}
+
+	creator, ok := inputs.Inputs[name]
+	if !ok {
+		ck.unknownPlugin(tbl.Line, section, name, inputs.Inputs)
+		return
+	}
+	input := creator()
+
+	known := ck.checkSettings(tbl, section, inputSettings, filterSettings)
+	switch input.(type) {
+	case parsers.ParserInput, parsers.ParserFuncInput:
+		if !ck.checkParser(name, tbl, section, known) {
+			return
+		}
+	}
+	ck.checkUnknown(tbl, section, input, known)
+
+	n := len(ck.c.Inputs)
+	if err := ck.c.addInput(name, tbl); err != nil {
+		ck.addError(tbl.Line, section, err)
+		return
+	}
+	for _, ri := range ck.c.Inputs[n:] {
+		ck.initPlugin(tbl.Line, section, ri.Input, ri.Init)
+	}
+}
+
+func (ck *checker) checkOutput(name string, tbl *ast.Table) {
+	if len(ck.c.OutputFilters) > 0 && !sliceContains(name, ck.c.OutputFilters) {
+		return
+	}
+	section := "outputs." + name
+
+	creator, ok := outputs.Outputs[name]
+	if !ok {
+		ck.unknownPlugin(tbl.Line, section, name, outputs.Outputs)
+		return
+	}
+	output := creator()
+
+	known := ck.checkSettings(tbl, section, outputSettings, filterSettings)
+	if _, ok := output.(serializers.SerializerOutput); ok {
+		if !ck.checkSerializer(name, tbl, section, known) {
+			return
+		}
+	}
+	ck.checkUnknown(tbl, section, output, known)
+
+	n := len(ck.c.Outputs)
+	if err := ck.c.addOutput(name, tbl); err != nil {
+		ck.addError(tbl.Line, section, err)
+		return
+	}
+	for _, ro := range ck.c.Outputs[n:] {
+		ck.initPlugin(tbl.Line, section, ro.Output, ro.Init)
+	}
+}
+
+func (ck *checker) checkProcessor(name string, tbl *ast.Table) {
+	section := "processors." + name
+
+	creator, ok := processors.Processors[name]
+	if !ok {
+		ck.unknownPlugin(tbl.Line, section, name, processors.Processors)
+		return
+	}
+	var processor interface{} = creator()
+	if p, ok := processor.(unwrappable); ok {
+		processor = p.Unwrap()
+	}
+
+	known := ck.checkSettings(tbl, section, processorSettings, filterSettings)
+	ck.checkUnknown(tbl, section, processor, known)
+
+	n := len(ck.c.Processors)
+	if err := ck.c.addProcessor(name, tbl); err != nil {
+		ck.addError(tbl.Line, section, err)
+		return
+	}
+	for _, rp := range ck.c.Processors[n:] {
+		ck.initPlugin(tbl.Line, section, rp.Processor, rp.Init)
+	}
+}
+
+func (ck *checker) checkAggregator(name string, tbl *ast.Table) {
+	section := "aggregators." + name
+
+	creator, ok := aggregators.Aggregators[name]
+	if !ok {
+		ck.unknownPlugin(tbl.Line, section, name, aggregators.Aggregators)
+		return
+	}
+	aggregator := creator()
+
+	known := ck.checkSettings(tbl, section, aggregatorSettings, filterSettings)
+	ck.checkUnknown(tbl, section, aggregator, known)
+
+	n := len(ck.c.Aggregators)
+	if err := ck.c.addAggregator(name, tbl); err != nil {
+		ck.addError(tbl.Line, section, err)
+		return
+	}
+	for _, ra := range ck.c.Aggregators[n:] {
+		ck.initPlugin(tbl.Line, section, ra.Aggregator, ra.Init)
+	}
+}
+
+// initPlugin initializes the plugin to run its own checks of the settings.
+// Plugins referencing secrets are skipped, as secret stores are not
+// initialized during checks and the plugin would see the references instead
+// of the values.
+func (ck *checker) initPlugin(line int, section string, plugin interface{}, init func() error) {
+	if hasSecretRefs(plugin) {
+		return
+	}
+	if err := init(); err != nil {
+		ck.add(line, section, "could not initialize: %v", err)
+	}
+}
+
+// checkSecretStore checks the settings of a secret store.  Stores are not
+// initialized as they might contact external services.
+func (ck *checker) checkSecretStore(name string, tbl *ast.Table) {
+	section := "secretstores." + name
+
+	creator, ok := secretstores.SecretStores[name]
+	if !ok {
+		ck.unknownPlugin(tbl.Line, section, name, secretstores.SecretStores)
+		return
+	}
+
+	ck.checkUnknown(tbl, section, creator(), map[string]bool{"id": true})
+	if err := ck.c.addSecretStore(name, tbl); err != nil {
+		ck.addError(tbl.Line, section, err)
+	}
+}
+
+func (ck *checker) unknownPlugin(line int, section, name string, registry interface{}) {
+	var names []string
+	for _, key := range reflect.ValueOf(registry).MapKeys() {
+		names = append(names, key.String())
+	}
+
+	msg := fmt.Sprintf("unknown plugin %q", name)
+	if s := suggest(name, names); s != "" {
+		msg += fmt.Sprintf(", did you mean %q?", s)
+	}
+	ck.add(line, section, "%s", msg)
+}
+
+// checkSettings checks the type of the settings common to all plugins of a
+// type.  Settings of the wrong type are removed from the table, the names of
+// the common settings are returned.
+func (ck *checker) checkSettings(tbl *ast.Table, section string, schemas ...map[string]settingKind) map[string]bool {
+	known := make(map[string]bool)
+	for _, schema := range schemas {
+		for key, kind := range schema {
+			known[key] = true
+
+			node, ok := tbl.Fields[key]
+			if !ok {
+				continue
+			}
+			if err := checkKind(node, kind); err != nil {
+				ck.add(nodeLine(node, tbl.Line), section, "invalid %s: %v", key, err)
+				delete(tbl.Fields, key)
+			}
+		}
+	}
+	return known
+}
+
+// checkParser checks the data format settings of parsers, and adds them to
+// the known settings.
+func (ck *checker) checkParser(name string, tbl *ast.Table, section string, known map[string]bool) bool {
+	tmp := copyTable(tbl)
+	config, err := getParserConfig(name, tmp)
+	if err == nil {
+		_, err = parsers.NewParser(config)
+	}
+	if err != nil {
+		ck.addError(nodeLine(tbl.Fields["data_format"], tbl.Line), section, err)
+		return false
+	}
+
+	known["data_format"] = true
+	for key := range tbl.Fields {
+		if _, ok := tmp.Fields[key]; !ok {
+			known[key] = true
+		}
+	}
+	return true
+}
+
+// checkSerializer checks the data format settings of serializers, and adds
+// them to the known settings.
+func (ck *checker) checkSerializer(name string, tbl *ast.Table, section string, known map[string]bool) bool {
+	tmp := copyTable(tbl)
+	if _, err := buildSerializer(name, tmp); err != nil {
+		ck.addError(nodeLine(tbl.Fields["data_format"], tbl.Line), section, err)
+		return false
+	}
+
+	known["data_format"] = true
+	for key := range tbl.Fields {
+		if _, ok := tmp.Fields[key]; !ok {
+			known[key] = true
+		}
+	}
+	return true
+}
+
+// checkUnknown reports the settings which are neither known nor a field of
+// the plugin, and removes them from the table.
+func (ck *checker) checkUnknown(tbl *ast.Table, section string, plugin interface{}, known map[string]bool) {
+	fields := newFieldKeys(reflect.TypeOf(plugin))
+
+	candidates := fields.names
+	for key := range known {
+		candidates = append(candidates, key)
+	}
+
+	for _, key := range sortedKeys(tbl.Fields) {
+		if known[key] || fields.has(key) {
+			continue
+		}
+
+		msg := fmt.Sprintf("unknown setting %q", key)
+		if s := suggest(key, candidates); s != "" {
+			msg += fmt.Sprintf(", did you mean %q?", s)
+		}
+		ck.add(nodeLine(tbl.Fields[key], tbl.Line), section, "%s", msg)
+		delete(tbl.Fields, key)
+	}
+}
+
+// fieldKeys are the keys of the fields of a struct, matched like the TOML
+// decoder does.
+type fieldKeys struct {
+	named map[string]bool
+	auto  map[string]bool
+	names []string
+}
+
+func newFieldKeys(t reflect.Type) *fieldKeys {
+	fk := &fieldKeys{
+		named: make(map[string]bool),
+		auto:  make(map[string]bool),
+	}
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t != nil && t.Kind() == reflect.Struct {
+		fk.descend(t)
+	}
+	return fk
+}
+
+func (fk *fieldKeys) descend(t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		ft := t.Field(i)
+		if ft.PkgPath != "" && !ft.Anonymous {
+			continue
+		}
+
+		col := strings.TrimSpace(strings.SplitN(ft.Tag.Get("toml"), ",", 2)[0])
+		if ft.Anonymous && ft.Type.Kind() == reflect.Struct && col == "" {
+			fk.descend(ft.Type)
+			continue
+		}
+
+		switch col {
+		case "-":
+		case "":
+			fk.auto[normalizeKey(ft.Name)] = true
+			fk.names = append(fk.names, internal.SnakeCase(ft.Name))
+		default:
+			fk.named[col] = true
+			fk.names = append(fk.names, col)
+		}
+	}
+}
+
+func (fk *fieldKeys) has(key string) bool {
+	return fk.named[key] || fk.auto[normalizeKey(key)]
+}
+
+func normalizeKey(s string) string {
+	return strings.Replace(strings.ToLower(s), "_", "", -1)
+}
+
+func checkKind(node interface{}, kind settingKind) error {
+	switch kind {
+	case kindTags, kindTagFilter:
+		tbl, ok := node.(*ast.Table)
+		if !ok {
+			return errors.New("expected a table")
+		}
+		for key, val := range tbl.Fields {
+			kv, ok := val.(*ast.KeyValue)
+			if !ok {
+				return fmt.Errorf("%s: expected a value", key)
+			}
+			want := kindString
+			if kind == kindTagFilter {
+				want = kindStrings
+			}
+			if err := checkValue(kv.Value, want); err != nil {
+				return fmt.Errorf("%s: %v", key, err)
+			}
+		}
+		return nil
+	}
+
+	kv, ok := node.(*ast.KeyValue)
+	if !ok {
+		return errors.New("expected a value, not a table")
+	}
+	return checkValue(kv.Value, kind)
+}
+
+func checkValue(val ast.Value, kind settingKind) error {
+	switch kind {
+	case kindString:
+		if _, ok := val.(*ast.String); !ok {
+			return errors.New("expected a string")
+		}
+	case kindDuration:
+		str, ok := val.(*ast.String)
+		if !ok {
+			return errors.New("expected a duration string like \"10s\"")
+		}
+		if _, err := time.ParseDuration(str.Value); err != nil {
+			return err
+		}
+	case kindInt:
+		if _, ok := val.(*ast.Integer); !ok {
+			return errors.New("expected an integer")
+		}
+	case kindBool:
+		if _, ok := val.(*ast.Boolean); !ok {
+			return errors.New("expected a boolean")
+		}
+	case kindStrings:
+		ary, ok := val.(*ast.Array)
+		if !ok {
+			return errors.New("expected an array of strings")
+		}
+		for _, elem := range ary.Value {
+			if _, ok := elem.(*ast.String); !ok {
+				return errors.New("expected an array of strings")
+			}
+		}
+	}
+	return nil
+}
+
+// suggest returns the candidate closest to the given key, if it is close
+// enough to be a typo.
+func suggest(key string, candidates []string) string {
+	best, bestDist := "", -1
+	for _, c := range candidates {
+		d := levenshtein(key, c)
+		if bestDist < 0 || d < bestDist || (d == bestDist && c < best) {
+			best, bestDist = c, d
+		}
+	}
+
+	limit := len(key) / 3
+	if limit < 2 {
+		limit = 2
+	}
+	if bestDist < 0 || bestDist > limit || bestDist >= len(key) {
+		return ""
+	}
+	return best
+}
+
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = minInt(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func minInt(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
+
+func copyTable(tbl *ast.Table) *ast.Table {
+	tmp := *tbl
+	tmp.Fields = make(map[string]interface{}, len(tbl.Fields))
+	for k, v := range tbl.Fields {
+		tmp.Fields[k] = v
+	}
+	return &tmp
+}
+
+func nodeLine(node interface{}, def int) int {
+	switch n := node.(type) {
+	case *ast.KeyValue:
+		return n.Line
+	case *ast.Table:
+		return n.Line
+	case []*ast.Table:
+		if len(n) > 0 {
+			return n[0].Line
+		}
+	}
+	return def
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,112 @@
+package config
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/stretchr/testify/require"
+)
+
+type checkInitInput struct {
+	Fail  bool   `toml:"fail"`
+	Token string `toml:"token"`
+}
+
+func (i *checkInitInput) Init() error {
+	if i.Fail {
+		return errors.New("failed as configured")
+	}
+	return nil
+}
+
+func (i *checkInitInput) SampleConfig() string                  { return "" }
+func (i *checkInitInput) Description() string                   { return "" }
+func (i *checkInitInput) Gather(acc telegraf.Accumulator) error { return nil }
+
+func init() {
+	inputs.Add("check_init", func() telegraf.Input { return &checkInitInput{} })
+}
+
+func TestCheckConfig(t *testing.T) {
+	c := NewConfig()
+	problems := c.CheckConfig("./testdata/check.toml")
+
+	var actual []string
+	for _, p := range problems {
+		actual = append(actual, p.String())
+	}
+	require.Equal(t, []string{
+		`./testdata/check.toml:3: agent: flush_interval must be positive, found 0s`,
+		`./testdata/check.toml:4: agent: invalid logtarget "syslog", must be one of file, stderr, eventlog`,
+		`./testdata/check.toml:5: agent: unknown setting "round_intervall", did you mean "round_interval"?`,
+		`./testdata/check.toml:9: inputs.memcached: unknown setting "unix_socket", did you mean "unix_sockets"?`,
+		`./testdata/check.toml:10: inputs.memcached: invalid namepass: expected an array of strings`,
+		`./testdata/check.toml:11: inputs.memcached: invalid interval: time: missing unit in duration "10"`,
+		`./testdata/check.toml:13: inputs.memcache: unknown plugin "memcache", did you mean "memcached"?`,
+		`./testdata/check.toml:18: inputs.exec: Invalid data format: influxx`,
+		`./testdata/check.toml:21: inputs.exec: cannot unmarshal TOML string into []string`,
+		`./testdata/check.toml:25: inputs.check_init: could not initialize: failed as configured`,
+		`./testdata/check.toml:33: outputs.http: invalid metric_batch_size: expected an integer`,
+	}, actual)
+
+	// Valid plugins are loaded
+	require.Len(t, c.Inputs, 2)
+	require.Len(t, c.Outputs, 1)
+}
+
+func TestCheckConfigValid(t *testing.T) {
+	c := NewConfig()
+	require.Empty(t, c.CheckConfig("./testdata/single_plugin.toml"))
+	require.Len(t, c.Inputs, 1)
+}
+
+func TestCheckConfigParseError(t *testing.T) {
+	c := NewConfig()
+	problems := c.CheckConfigData("telegraf.conf", []byte("[agent\ninterval = 10s"))
+	require.Len(t, problems, 1)
+	require.Equal(t, "telegraf.conf", problems[0].File)
+}
+
+func TestCheckConfigSecretStores(t *testing.T) {
+	c := NewConfig()
+	problems := c.CheckConfigData("telegraf.conf", []byte(`
+[[secretstores.mock]]
+  id = "my-store"
+  pth = "/run/secrets"
+[[secretstores.mocks]]
+  id = "other"
+`))
+
+	var actual []string
+	for _, p := range problems {
+		actual = append(actual, p.String())
+	}
+	require.Equal(t, []string{
+		`telegraf.conf:2: secretstores.mock: invalid id "my-store", it may only contain letters, digits and underscores`,
+		`telegraf.conf:4: secretstores.mock: unknown setting "pth"`,
+		`telegraf.conf:5: secretstores.mocks: unknown plugin "mocks", did you mean "mock"?`,
+	}, actual)
+}
+
+func TestCheckConfigSkipsInitWithSecrets(t *testing.T) {
+	c := NewConfig()
+	problems := c.CheckConfigData("telegraf.conf", []byte(`
+[[secretstores.mock]]
+  id = "mock"
+[[inputs.check_init]]
+  fail = true
+  token = "@{mock:token}"
+`))
+	require.Empty(t, problems)
+	require.False(t, c.SecretStores["mock"].(*mockSecretStore).initialized)
+}
+
+func TestSuggest(t *testing.T) {
+	candidates := []string{"servers", "unix_sockets", "interval"}
+	require.Equal(t, "servers", suggest("server", candidates))
+	require.Equal(t, "interval", suggest("intervall", candidates))
+	require.Equal(t, "", suggest("timeout", candidates))
+	require.Equal(t, "", suggest("x", candidates))
+}
@@ -26,6 +26,7 @@ import (
 	"github.com/influxdata/telegraf/plugins/outputs"
 	"github.com/influxdata/telegraf/plugins/parsers"
 	"github.com/influxdata/telegraf/plugins/processors"
+	"github.com/influxdata/telegraf/plugins/secretstores"
 	"github.com/influxdata/telegraf/plugins/serializers"
 	"github.com/influxdata/toml"
 	"github.com/influxdata/toml/ast"
@@ -67,6 +68,17 @@ type Config struct {
 	// Processors have a slice wrapper type because they need to be sorted
 	Processors    models.RunningProcessors
 	AggProcessors models.RunningProcessors
+
+	// SecretStores maps the store ids used in secret references to the
+	// configured secret-store plugins
+	SecretStores      map[string]telegraf.SecretStore
+	secretStoreNames  map[string]string
+	initializedStores map[string]bool
+
+	// data format options of the plugins, kept for printing the config
+	parserConfigs      map[*models.RunningInput]*parsers.Config
+	serializerConfigs  map[*models.RunningOutput]*serializers.Config
+	destinationConfigs map[*models.RunningOutput][]*serializers.Config
 }
 
 func NewConfig() *Config {
@@ -87,6 +99,14 @@ func NewConfig() *Config {
 		AggProcessors: make([]*models.RunningProcessor, 0),
 		InputFilters:  make([]string, 0),
 		OutputFilters: make([]string, 0),
+
+		SecretStores:      make(map[string]telegraf.SecretStore),
+		secretStoreNames:  make(map[string]string),
+		initializedStores: make(map[string]bool),
+
+		parserConfigs:      make(map[*models.RunningInput]*parsers.Config),
+		serializerConfigs:  make(map[*models.RunningOutput]*serializers.Config),
+		destinationConfigs: make(map[*models.RunningOutput][]*serializers.Config),
 	}
 	return c
 }
@@ -113,6 +133,11 @@ type AgentConfig struct {
 	// same time, which can have a measurable effect on the system.
 	CollectionJitter internal.Duration
 
+	// MaxConcurrentGathers limits the number of inputs gathering at the same
+	// time, a gather waits for a free slot until its next gather is due.  When
+	// set to 0 there is no limit.
+	MaxConcurrentGathers int `toml:"max_concurrent_gathers"`
+
 	// FlushInterval is the Interval at which to flush data
 	FlushInterval internal.Duration
 
@@ -274,6 +299,10 @@ var agentConfig = `
   ## same time, which can have a measurable effect on the system.
   collection_jitter = "0s"
 
+  ## Maximum number of inputs gathering at the same time, 0 for no limit.
+  ## Gathers that cannot start before their next gather is due are skipped.
+  # max_concurrent_gathers = 0
+
   ## Default flushing interval for all outputs. Maximum flush_interval will be
   ## flush_interval + flush_jitter
   flush_interval = "10s"
@@ -619,6 +648,11 @@ func PrintOutputConfig(name string) error {
 }
 
 func (c *Config) LoadDirectory(path string) error {
+	return walkDirectory(path, c.LoadConfig)
+}
+
+// walkDirectory calls fn for each config file in the directory.
+func walkDirectory(path string, fn func(path string) error) error {
 	walkfn := func(thispath string, info os.FileInfo, _ error) error {
 		if info == nil {
 			log.Printf("W! Telegraf is not permitted to read %s", thispath)
@@ -637,11 +671,7 @@ func (c *Config) LoadDirectory(path string) error {
 		if len(name) < 6 || name[len(name)-5:] != ".conf" {
 			return nil
 		}
-		err := c.LoadConfig(thispath)
-		if err != nil {
-			return err
-		}
-		return nil
+		return fn(thispath)
 	}
 	return filepath.Walk(path, walkfn)
 }
@@ -812,6 +842,20 @@ func (c *Config) LoadConfigData(data []byte) error {
 						pluginName)
 				}
 			}
+		case "secretstores":
+			for pluginName, pluginVal := range subTable.Fields {
+				switch pluginSubTable := pluginVal.(type) {
+				case []*ast.Table:
+					for _, t := range pluginSubTable {
+						if err = c.addSecretStore(pluginName, t); err != nil {
+							return fmt.Errorf("Error parsing %s, %s", pluginName, err)
+						}
+					}
+				default:
+					return fmt.Errorf("Unsupported config format: %s",
+						pluginName)
+				}
+			}
 		// Assume it's an input input for legacy config file support if no other
 		// identifiers are present
 		default:
@@ -911,6 +955,39 @@ func parseConfig(contents []byte) (*ast.Table, error) {
 	return toml.Parse(contents)
 }
 
+func (c *Config) addSecretStore(name string, table *ast.Table) error {
+	creator, ok := secretstores.SecretStores[name]
+	if !ok {
+		return fmt.Errorf("Undefined but requested secretstore: %s", name)
+	}
+	store := creator()
+
+	var id string
+	if node, ok := table.Fields["id"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				id = str.Value
+			}
+		}
+	}
+	delete(table.Fields, "id")
+
+	if !secretStoreIDRe.MatchString(id) {
+		return fmt.Errorf("invalid id %q, it may only contain letters, digits and underscores", id)
+	}
+	if _, ok := c.SecretStores[id]; ok {
+		return fmt.Errorf("duplicate secret-store id %q", id)
+	}
+
+	if err := toml.UnmarshalTable(table, store); err != nil {
+		return err
+	}
+
+	c.SecretStores[id] = store
+	c.secretStoreNames[id] = name
+	return nil
+}
+
 func (c *Config) addAggregator(name string, table *ast.Table) error {
 	creator, ok := aggregators.Aggregators[name]
 	if !ok {
@@ -992,19 +1069,56 @@ func (c *Config) addOutput(name string, table *ast.Table) error {
 
 	// If the output has a SetSerializer function, then this means it can write
 	// arbitrary types of output, so build the serializer and set it.
+	var serializer serializers.Serializer
+	var serializerConfig *serializers.Config
 	switch t := output.(type) {
 	case serializers.SerializerOutput:
-		serializer, err := buildSerializer(name, table)
+		config, err := getSerializerConfig(name, table)
+		if err != nil {
+			return err
+		}
+		serializer, err = serializers.NewSerializer(config)
 		if err != nil {
 			return err
 		}
 		t.SetSerializer(serializer)
+		serializerConfig = config
+
+		if t, ok := output.(serializers.SerializerFuncOutput); ok {
+			t.SetSerializerFunc(func() (serializers.Serializer, error) {
+				return serializers.NewSerializer(config)
+			})
+		}
+	}
+
+	var destinationConfigs []*serializers.Config
+	switch t := output.(type) {
+	case serializers.DestinationSerializerOutput:
+		var fns []serializers.SerializerFunc
+		if node, ok := table.Fields["destination"]; ok {
+			if subtables, ok := node.([]*ast.Table); ok {
+				for _, subtable := range subtables {
+					config, err := getSerializerConfig(name, subtable)
+					if err != nil {
+						return err
+					}
+					fn, err := serializerFunc(config)
+					if err != nil {
+						return err
+					}
+					fns = append(fns, fn)
+					destinationConfigs = append(destinationConfigs, config)
+				}
+			}
+		}
+		t.SetDestinationSerializerFuncs(fns)
 	}
 
 	outputConfig, err := buildOutput(name, table)
 	if err != nil {
 		return err
 	}
+	outputConfig.Serializer = serializer
 
 	if err := toml.UnmarshalTable(table, output); err != nil {
 		return err
@@ -1013,6 +1127,12 @@ func (c *Config) addOutput(name string, table *ast.Table) error {
 	ro := models.NewRunningOutput(name, output, outputConfig,
 		c.Agent.MetricBatchSize, c.Agent.MetricBufferLimit)
 	c.Outputs = append(c.Outputs, ro)
+	if serializerConfig != nil {
+		c.serializerConfigs[ro] = serializerConfig
+	}
+	if len(destinationConfigs) > 0 {
+		c.destinationConfigs[ro] = destinationConfigs
+	}
 	return nil
 }
 
@@ -1033,20 +1153,33 @@ func (c *Config) addInput(name string, table *ast.Table) error {
 
 	// If the input has a SetParser function, then this means it can accept
 	// arbitrary types of input, so build the parser and set it.
+	var parserConfig *parsers.Config
 	switch t := input.(type) {
 	case parsers.ParserInput:
-		parser, err := buildParser(name, table)
+		config, err := getParserConfig(name, table)
+		if err != nil {
+			return err
+		}
+		parser, err := parsers.NewParser(config)
 		if err != nil {
 			return err
 		}
 		t.SetParser(parser)
+		parserConfig = config
 	}
 
 	switch t := input.(type) {
 	case parsers.ParserFuncInput:
-		config, err := getParserConfig(name, table)
-		if err != nil {
-			return err
+		// The options were removed from the table if the parser was
+		// already built above.
+		config := parserConfig
+		if config == nil {
+			var err error
+			config, err = getParserConfig(name, table)
+			if err != nil {
+				return err
+			}
+			parserConfig = config
 		}
 		t.SetParserFunc(func() (parsers.Parser, error) {
 			return parsers.NewParser(config)
@@ -1065,6 +1198,9 @@ func (c *Config) addInput(name string, table *ast.Table) error {
 	rp := models.NewRunningInput(input, pluginConfig)
 	rp.SetDefaultTags(c.Tags)
 	c.Inputs = append(c.Inputs, rp)
+	if parserConfig != nil {
+		c.parserConfigs[rp] = parserConfig
+	}
 	return nil
 }
 
@@ -1091,6 +1227,14 @@ func buildAggregator(name string, tbl *ast.Table) (*models.AggregatorConfig, err
 		return nil, err
 	}
 
+	if err := getConfigLocation(tbl, "timezone", &conf.Timezone); err != nil {
+		return nil, err
+	}
+
+	if err := getConfigSchedule(tbl, "schedule", conf.Timezone, &conf.Schedule); err != nil {
+		return nil, err
+	}
+
 	if node, ok := tbl.Fields["drop_original"]; ok {
 		if kv, ok := node.(*ast.KeyValue); ok {
 			if b, ok := kv.Value.(*ast.Boolean); ok {
@@ -1349,6 +1493,22 @@ func buildInput(name string, tbl *ast.Table) (*models.InputConfig, error) {
 		return nil, err
 	}
 
+	if err := getConfigDuration(tbl, "gather_timeout", &cp.GatherTimeout); err != nil {
+		return nil, err
+	}
+
+	if err := getConfigLocation(tbl, "timezone", &cp.Timezone); err != nil {
+		return nil, err
+	}
+
+	if err := getConfigSchedule(tbl, "schedule", cp.Timezone, &cp.Schedule); err != nil {
+		return nil, err
+	}
+
+	if err := getConfigTimeWindows(tbl, "active_windows", &cp.ActiveWindows); err != nil {
+		return nil, err
+	}
+
 	if node, ok := tbl.Fields["name_prefix"]; ok {
 		if kv, ok := node.(*ast.KeyValue); ok {
 			if str, ok := kv.Value.(*ast.String); ok {
@@ -1381,6 +1541,14 @@ func buildInput(name string, tbl *ast.Table) (*models.InputConfig, error) {
 		}
 	}
 
+	if node, ok := tbl.Fields["record_file"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				cp.RecordFile = str.Value
+			}
+		}
+	}
+
 	cp.Tags = make(map[string]string)
 	if node, ok := tbl.Fields["tags"]; ok {
 		if subtbl, ok := node.(*ast.Table); ok {
@@ -1395,6 +1563,7 @@ func buildInput(name string, tbl *ast.Table) (*models.InputConfig, error) {
 	delete(tbl.Fields, "name_override")
 	delete(tbl.Fields, "alias")
 	delete(tbl.Fields, "tags")
+	delete(tbl.Fields, "record_file")
 	var err error
 	cp.Filter, err = buildFilter(tbl)
 	if err != nil {
@@ -1403,17 +1572,8 @@ func buildInput(name string, tbl *ast.Table) (*models.InputConfig, error) {
 	return cp, nil
 }
 
-// buildParser grabs the necessary entries from the ast.Table for creating
-// a parsers.Parser object, and creates it, which can then be added onto
-// an Input object.
-func buildParser(name string, tbl *ast.Table) (parsers.Parser, error) {
-	config, err := getParserConfig(name, tbl)
-	if err != nil {
-		return nil, err
-	}
-	return parsers.NewParser(config)
-}
-
+// getParserConfig grabs the necessary entries from the ast.Table for creating
+// a parsers.Parser object.
 func getParserConfig(name string, tbl *ast.Table) (*parsers.Config, error) {
 	c := &parsers.Config{
 		JSONStrict: true,
@@ -1822,6 +1982,78 @@ func getParserConfig(name string, tbl *ast.Table) (*parsers.Config, error) {
 		}
 	}
 
+	if node, ok := tbl.Fields["avro_schema_registry"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.AvroSchemaRegistry = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["avro_schema_file"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.AvroSchemaFile = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["avro_measurement_field"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.AvroMeasurementField = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["avro_tags"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if ary, ok := kv.Value.(*ast.Array); ok {
+				for _, elem := range ary.Value {
+					if str, ok := elem.(*ast.String); ok {
+						c.AvroTags = append(c.AvroTags, str.Value)
+					}
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["avro_fields"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if ary, ok := kv.Value.(*ast.Array); ok {
+				for _, elem := range ary.Value {
+					if str, ok := elem.(*ast.String); ok {
+						c.AvroFields = append(c.AvroFields, str.Value)
+					}
+				}
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["avro_timestamp"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.AvroTimestamp = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["avro_timestamp_format"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.AvroTimestampFormat = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["avro_field_separator"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.AvroFieldSeparator = str.Value
+			}
+		}
+	}
+
 	c.MetricName = name
 
 	delete(tbl.Fields, "data_format")
@@ -1866,6 +2098,14 @@ func getParserConfig(name string, tbl *ast.Table) (*parsers.Config, error) {
 	delete(tbl.Fields, "csv_timezone")
 	delete(tbl.Fields, "csv_trim_space")
 	delete(tbl.Fields, "form_urlencoded_tag_keys")
+	delete(tbl.Fields, "avro_schema_registry")
+	delete(tbl.Fields, "avro_schema_file")
+	delete(tbl.Fields, "avro_measurement_field")
+	delete(tbl.Fields, "avro_tags")
+	delete(tbl.Fields, "avro_fields")
+	delete(tbl.Fields, "avro_timestamp")
+	delete(tbl.Fields, "avro_timestamp_format")
+	delete(tbl.Fields, "avro_field_separator")
 
 	return c, nil
 }
@@ -1874,6 +2114,25 @@ func getParserConfig(name string, tbl *ast.Table) (*parsers.Config, error) {
 // a serializers.Serializer object, and creates it, which can then be added onto
 // an Output object.
 func buildSerializer(name string, tbl *ast.Table) (serializers.Serializer, error) {
+	config, err := getSerializerConfig(name, tbl)
+	if err != nil {
+		return nil, err
+	}
+	return serializers.NewSerializer(config)
+}
+
+// serializerFunc returns a function creating a new serializer from the
+// config on every call.
+func serializerFunc(config *serializers.Config) (serializers.SerializerFunc, error) {
+	if _, err := serializers.NewSerializer(config); err != nil {
+		return nil, err
+	}
+	return func() (serializers.Serializer, error) {
+		return serializers.NewSerializer(config)
+	}, nil
+}
+
+func getSerializerConfig(name string, tbl *ast.Table) (*serializers.Config, error) {
 	c := &serializers.Config{TimestampUnits: time.Duration(1 * time.Second)}
 
 	if node, ok := tbl.Fields["data_format"]; ok {
@@ -2072,6 +2331,42 @@ func buildSerializer(name string, tbl *ast.Table) (serializers.Serializer, error
 		}
 	}
 
+	if node, ok := tbl.Fields["csv_separator"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.CSVSeparator = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["csv_header"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.CSVHeader = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["csv_timestamp_format"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				c.CSVTimestampFormat = str.Value
+			}
+		}
+	}
+
+	if node, ok := tbl.Fields["csv_columns"]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if ary, ok := kv.Value.(*ast.Array); ok {
+				for _, elem := range ary.Value {
+					if str, ok := elem.(*ast.String); ok {
+						c.CSVColumns = append(c.CSVColumns, str.Value)
+					}
+				}
+			}
+		}
+	}
+
 	delete(tbl.Fields, "influx_max_line_bytes")
 	delete(tbl.Fields, "influx_sort_fields")
 	delete(tbl.Fields, "influx_uint_support")
@@ -2089,7 +2384,11 @@ func buildSerializer(name string, tbl *ast.Table) (serializers.Serializer, error
 	delete(tbl.Fields, "prometheus_export_timestamp")
 	delete(tbl.Fields, "prometheus_sort_metrics")
 	delete(tbl.Fields, "prometheus_string_as_label")
-	return serializers.NewSerializer(c)
+	delete(tbl.Fields, "csv_separator")
+	delete(tbl.Fields, "csv_header")
+	delete(tbl.Fields, "csv_timestamp_format")
+	delete(tbl.Fields, "csv_columns")
+	return c, nil
 }
 
 // buildOutput parses output specific items from the ast.Table,
@@ -2211,3 +2510,55 @@ func getConfigDuration(tbl *ast.Table, key string, target *time.Duration) error
 	}
 	return nil
 }
+
+func getConfigLocation(tbl *ast.Table, key string, target **time.Location) error {
+	if node, ok := tbl.Fields[key]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				loc, err := time.LoadLocation(str.Value)
+				if err != nil {
+					return err
+				}
+				delete(tbl.Fields, key)
+				*target = loc
+			}
+		}
+	}
+	return nil
+}
+
+func getConfigSchedule(tbl *ast.Table, key string, loc *time.Location, target **internal.Schedule) error {
+	if node, ok := tbl.Fields[key]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if str, ok := kv.Value.(*ast.String); ok {
+				schedule, err := internal.ParseSchedule(str.Value, loc)
+				if err != nil {
+					return err
+				}
+				delete(tbl.Fields, key)
+				*target = schedule
+			}
+		}
+	}
+	return nil
+}
+
+func getConfigTimeWindows(tbl *ast.Table, key string, target *[]internal.TimeWindow) error {
+	if node, ok := tbl.Fields[key]; ok {
+		if kv, ok := node.(*ast.KeyValue); ok {
+			if ary, ok := kv.Value.(*ast.Array); ok {
+				for _, elem := range ary.Value {
+					if str, ok := elem.(*ast.String); ok {
+						w, err := internal.ParseTimeWindow(str.Value)
+						if err != nil {
+							return err
+						}
+						*target = append(*target, w)
+					}
+				}
+				delete(tbl.Fields, key)
+			}
+		}
+	}
+	return nil
+}
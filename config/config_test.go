@@ -12,6 +12,7 @@ import (
 	"github.com/influxdata/telegraf/plugins/inputs/http_listener_v2"
 	"github.com/influxdata/telegraf/plugins/inputs/memcached"
 	"github.com/influxdata/telegraf/plugins/inputs/procstat"
+	fileOut "github.com/influxdata/telegraf/plugins/outputs/file"
 	httpOut "github.com/influxdata/telegraf/plugins/outputs/http"
 	"github.com/influxdata/telegraf/plugins/parsers"
 	"github.com/stretchr/testify/assert"
@@ -257,3 +258,63 @@ func TestConfig_BadOrdering(t *testing.T) {
 	require.Error(t, err, "bad ordering")
 	assert.Equal(t, "Error loading config file ./testdata/non_slice_slice.toml: Error parsing http array, line 4: cannot unmarshal TOML array into string (need slice)", err.Error())
 }
+
+func TestConfig_Schedule(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfig("./testdata/schedule.toml")
+	require.NoError(t, err)
+	require.Equal(t, 1, len(c.Inputs))
+
+	cfg := c.Inputs[0].Config
+	require.Equal(t, time.UTC, cfg.Timezone)
+	require.NotNil(t, cfg.Schedule)
+	require.Equal(t, "0 2 * * *", cfg.Schedule.String())
+
+	now := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	require.Equal(t, time.Date(2020, 6, 2, 2, 0, 0, 0, time.UTC), cfg.Schedule.Next(now))
+
+	require.Equal(t, 2, len(cfg.ActiveWindows))
+	require.Equal(t, "Mon-Fri 09:00-17:00", cfg.ActiveWindows[0].String())
+	require.Equal(t, "Sat 10:00-12:00", cfg.ActiveWindows[1].String())
+}
+
+func TestConfig_InvalidSchedule(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfigData([]byte(`
+[[inputs.memcached]]
+  schedule = "0 2 * *"
+`))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), `invalid schedule "0 2 * *"`)
+}
+
+func TestConfig_OutputDestinations(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfigData([]byte(`
+[[outputs.file]]
+  files = ["stdout"]
+
+  [[outputs.file.destination]]
+    files = ["/tmp/metrics.json"]
+    data_format = "json"
+    json_timestamp_units = "1ms"
+    namepass = ["cpu"]
+`))
+	require.NoError(t, err)
+	require.Equal(t, 1, len(c.Outputs))
+
+	output, ok := c.Outputs[0].Output.(*fileOut.File)
+	require.True(t, ok)
+	require.Equal(t, 1, len(output.Destinations))
+	require.Equal(t, []string{"/tmp/metrics.json"}, output.Destinations[0].Files)
+	require.Equal(t, []string{"cpu"}, output.Destinations[0].NamePass)
+
+	c = NewConfig()
+	err = c.LoadConfigData([]byte(`
+[[outputs.file]]
+  [[outputs.file.destination]]
+    files = ["/tmp/metrics.out"]
+    data_format = "unknown"
+`))
+	require.Error(t, err)
+}
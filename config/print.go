@@ -0,0 +1,420 @@
+package config
+
+import (
+	"bytes"
+	"io"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/models"
+)
+
+const redacted = "<redacted>"
+
+var (
+	// sensitiveKeyRe matches the names of settings holding credentials
+	sensitiveKeyRe = regexp.MustCompile(`(?i)(password|passwd|passphrase|secret|token|api_?key|credential|authorization)`)
+
+	// referenceKeyRe matches the names of settings pointing to credentials,
+	// like token_url or shared_credential_file, instead of holding them
+	referenceKeyRe = regexp.MustCompile(`(?i)_(url|uri|file|path)$`)
+)
+
+// maxPrintDepth limits how deep nested structures of plugins are printed
+const maxPrintDepth = 8
+
+// PrintConfig writes the effective configuration as TOML to w.  Every plugin
+// instance is printed with the settings it inherits from the agent, such as
+// its interval, resolved.  Secret references are printed as is, while the
+// values of credential settings and the passwords of URLs are redacted.
+//
+// The data format options of parsers and serializers are printed with the
+// plugin if they are set or have a default.
+func (c *Config) PrintConfig(w io.Writer) error {
+	sections := []map[string]interface{}{
+		{"global_tags": c.Tags},
+		{"agent": structTable(reflect.ValueOf(c.Agent), 0)},
+	}
+
+	if len(c.SecretStores) > 0 {
+		stores := make(map[string]interface{})
+		for id, store := range c.SecretStores {
+			t := structTable(reflect.ValueOf(store), 0)
+			t["id"] = id
+			addInstance(stores, c.secretStoreNames[id], t)
+		}
+		sections = append(sections, map[string]interface{}{"secretstores": stores})
+	}
+
+	if len(c.Inputs) > 0 {
+		inputs := make(map[string]interface{})
+		for _, ri := range c.Inputs {
+			addInstance(inputs, ri.Config.Name, c.inputTable(ri))
+		}
+		sections = append(sections, map[string]interface{}{"inputs": inputs})
+	}
+
+	if len(c.Processors) > 0 {
+		processors := make(map[string]interface{})
+		for _, rp := range c.Processors {
+			addInstance(processors, rp.Config.Name, processorTable(rp))
+		}
+		sections = append(sections, map[string]interface{}{"processors": processors})
+	}
+
+	if len(c.Aggregators) > 0 {
+		aggregators := make(map[string]interface{})
+		for _, ra := range c.Aggregators {
+			addInstance(aggregators, ra.Config.Name, aggregatorTable(ra))
+		}
+		sections = append(sections, map[string]interface{}{"aggregators": aggregators})
+	}
+
+	if len(c.Outputs) > 0 {
+		outputs := make(map[string]interface{})
+		for _, ro := range c.Outputs {
+			addInstance(outputs, ro.Config.Name, c.outputTable(ro))
+		}
+		sections = append(sections, map[string]interface{}{"outputs": outputs})
+	}
+
+	for i, section := range sections {
+		var buf bytes.Buffer
+		if err := toml.NewEncoder(&buf).Encode(section); err != nil {
+			return err
+		}
+		if i > 0 {
+			if _, err := io.WriteString(w, "\n"); err != nil {
+				return err
+			}
+		}
+		if _, err := w.Write(buf.Bytes()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Config) inputTable(ri *models.RunningInput) map[string]interface{} {
+	cfg := ri.Config
+	t := structTable(reflect.ValueOf(ri.Input), 0)
+
+	t["interval"] = durationOr(cfg.Interval, c.Agent.Interval.Duration)
+	t["precision"] = durationOr(cfg.Precision, c.Agent.Precision.Duration)
+	t["collection_jitter"] = durationOr(cfg.CollectionJitter, c.Agent.CollectionJitter.Duration)
+	if cfg.GatherTimeout != 0 {
+		t["gather_timeout"] = cfg.GatherTimeout.String()
+	}
+	if cfg.Schedule != nil {
+		t["schedule"] = cfg.Schedule.String()
+	}
+	if len(cfg.ActiveWindows) > 0 {
+		windows := make([]string, 0, len(cfg.ActiveWindows))
+		for _, w := range cfg.ActiveWindows {
+			windows = append(windows, w.String())
+		}
+		t["active_windows"] = windows
+	}
+	if cfg.Timezone != nil {
+		t["timezone"] = cfg.Timezone.String()
+	}
+	if cfg.RecordFile != "" {
+		t["record_file"] = cfg.RecordFile
+	}
+	addModifiers(t, cfg.Alias, cfg.NameOverride, cfg.MeasurementPrefix, cfg.MeasurementSuffix)
+	if len(cfg.Tags) > 0 {
+		t["tags"] = cfg.Tags
+	}
+	addFilter(t, cfg.Filter)
+	if config, ok := c.parserConfigs[ri]; ok {
+		addDataFormat(t, config)
+	}
+	return t
+}
+
+func processorTable(rp *models.RunningProcessor) map[string]interface{} {
+	var processor interface{} = rp.Processor
+	if p, ok := processor.(unwrappable); ok {
+		processor = p.Unwrap()
+	}
+
+	cfg := rp.Config
+	t := structTable(reflect.ValueOf(processor), 0)
+
+	t["order"] = cfg.Order
+	addModifiers(t, cfg.Alias, "", "", "")
+	addFilter(t, cfg.Filter)
+	return t
+}
+
+func aggregatorTable(ra *models.RunningAggregator) map[string]interface{} {
+	cfg := ra.Config
+	t := structTable(reflect.ValueOf(ra.Aggregator), 0)
+
+	t["period"] = cfg.Period.String()
+	t["delay"] = cfg.Delay.String()
+	t["grace"] = cfg.Grace.String()
+	t["drop_original"] = cfg.DropOriginal
+	if cfg.Schedule != nil {
+		t["schedule"] = cfg.Schedule.String()
+	}
+	if cfg.Timezone != nil {
+		t["timezone"] = cfg.Timezone.String()
+	}
+	addModifiers(t, cfg.Alias, cfg.NameOverride, cfg.MeasurementPrefix, cfg.MeasurementSuffix)
+	if len(cfg.Tags) > 0 {
+		t["tags"] = cfg.Tags
+	}
+	addFilter(t, cfg.Filter)
+	return t
+}
+
+func (c *Config) outputTable(ro *models.RunningOutput) map[string]interface{} {
+	cfg := ro.Config
+	t := structTable(reflect.ValueOf(ro.Output), 0)
+
+	t["flush_interval"] = durationOr(cfg.FlushInterval, c.Agent.FlushInterval.Duration)
+	t["flush_jitter"] = durationOr(cfg.FlushJitter, c.Agent.FlushJitter.Duration)
+	t["metric_batch_size"] = ro.MetricBatchSize
+	t["metric_buffer_limit"] = ro.MetricBufferLimit
+	addModifiers(t, cfg.Alias, cfg.NameOverride, cfg.NamePrefix, cfg.NameSuffix)
+	addFilter(t, cfg.Filter)
+	if config, ok := c.serializerConfigs[ro]; ok {
+		addDataFormat(t, config)
+	}
+
+	// Destinations are only printed if they are not empty, so their data
+	// formats can only be matched if all of them are.
+	destinations, _ := t["destination"].([]map[string]interface{})
+	if configs := c.destinationConfigs[ro]; len(configs) == len(destinations) {
+		for i, config := range configs {
+			addDataFormat(destinations[i], config)
+		}
+	}
+	return t
+}
+
+// addDataFormat adds the data format options that are not zero to the table
+// of a plugin, unless the plugin has a setting of the same name.
+func addDataFormat(t map[string]interface{}, config interface{}) {
+	v := reflect.Indirect(reflect.ValueOf(config))
+	for i := 0; i < v.NumField(); i++ {
+		key := strings.SplitN(v.Type().Field(i).Tag.Get("toml"), ",", 2)[0]
+		if key == "" || key == "-" || v.Field(i).IsZero() {
+			continue
+		}
+		if _, ok := t[key]; ok {
+			continue
+		}
+		if value, ok := tomlValue(v.Field(i), isSensitive(key), 0); ok {
+			t[key] = value
+		}
+	}
+}
+
+// addInstance adds the table of a plugin instance to the array of tables of
+// the plugin.
+func addInstance(section map[string]interface{}, name string, t map[string]interface{}) {
+	instances, _ := section[name].([]map[string]interface{})
+	section[name] = append(instances, t)
+}
+
+func durationOr(d, fallback time.Duration) string {
+	if d == 0 {
+		d = fallback
+	}
+	return d.String()
+}
+
+func addModifiers(t map[string]interface{}, alias, override, prefix, suffix string) {
+	for key, value := range map[string]string{
+		"alias":         alias,
+		"name_override": override,
+		"name_prefix":   prefix,
+		"name_suffix":   suffix,
+	} {
+		if value != "" {
+			t[key] = value
+		}
+	}
+}
+
+func addFilter(t map[string]interface{}, f models.Filter) {
+	for key, value := range map[string][]string{
+		"namepass":   f.NamePass,
+		"namedrop":   f.NameDrop,
+		"fieldpass":  f.FieldPass,
+		"fielddrop":  f.FieldDrop,
+		"taginclude": f.TagInclude,
+		"tagexclude": f.TagExclude,
+	} {
+		if len(value) > 0 {
+			t[key] = value
+		}
+	}
+
+	for key, value := range map[string][]models.TagFilter{
+		"tagpass": f.TagPass,
+		"tagdrop": f.TagDrop,
+	} {
+		if len(value) > 0 {
+			tags := make(map[string]interface{}, len(value))
+			for _, tf := range value {
+				tags[tf.Name] = tf.Filter
+			}
+			t[key] = tags
+		}
+	}
+}
+
+// structTable returns the settings of a plugin struct, keyed like the TOML
+// decoder matches them.
+func structTable(v reflect.Value, depth int) map[string]interface{} {
+	t := make(map[string]interface{})
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return t
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return t
+	}
+
+	for i := 0; i < v.NumField(); i++ {
+		ft := v.Type().Field(i)
+		if ft.PkgPath != "" && !ft.Anonymous {
+			continue
+		}
+
+		key := strings.TrimSpace(strings.SplitN(ft.Tag.Get("toml"), ",", 2)[0])
+		if key == "-" {
+			continue
+		}
+		if ft.Anonymous && key == "" {
+			fv := v.Field(i)
+			if fv.Kind() == reflect.Ptr && !fv.IsNil() {
+				fv = fv.Elem()
+			}
+			if fv.Kind() == reflect.Struct {
+				for k, value := range structTable(fv, depth) {
+					t[k] = value
+				}
+			}
+			continue
+		}
+		if ft.PkgPath != "" {
+			continue
+		}
+		if key == "" {
+			key = internal.SnakeCase(ft.Name)
+		}
+
+		if value, ok := tomlValue(v.Field(i), isSensitive(key), depth); ok {
+			t[key] = value
+		}
+	}
+	return t
+}
+
+// tomlValue converts a setting to a value the TOML encoder supports, it
+// returns false for values that are unset or cannot be printed.
+func tomlValue(v reflect.Value, sensitive bool, depth int) (interface{}, bool) {
+	if depth > maxPrintDepth || !v.IsValid() || !v.CanInterface() {
+		return nil, false
+	}
+
+	switch x := v.Interface().(type) {
+	case internal.Duration:
+		return x.Duration.String(), true
+	case Duration:
+		return time.Duration(x).String(), true
+	case time.Duration:
+		return x.String(), true
+	case internal.Size:
+		return x.Size, true
+	case internal.Number:
+		return x.Value, true
+	case time.Time:
+		return nil, false
+	}
+
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return nil, false
+		}
+		return tomlValue(v.Elem(), sensitive, depth+1)
+	case reflect.String:
+		return redactValue(v.String(), sensitive), true
+	case reflect.Bool:
+		return v.Bool(), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	case reflect.Slice, reflect.Array:
+		var values []interface{}
+		var tables []map[string]interface{}
+		for i := 0; i < v.Len(); i++ {
+			value, ok := tomlValue(v.Index(i), sensitive, depth+1)
+			if !ok {
+				continue
+			}
+			if table, ok := value.(map[string]interface{}); ok {
+				tables = append(tables, table)
+			} else {
+				values = append(values, value)
+			}
+		}
+		if len(tables) > 0 {
+			return tables, true
+		}
+		return values, len(values) > 0
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil, false
+		}
+		m := make(map[string]interface{}, v.Len())
+		for _, key := range v.MapKeys() {
+			s := sensitive || isSensitive(key.String())
+			if value, ok := tomlValue(v.MapIndex(key), s, depth+1); ok {
+				m[key.String()] = value
+			}
+		}
+		return m, len(m) > 0
+	case reflect.Struct:
+		t := structTable(v, depth+1)
+		return t, len(t) > 0
+	}
+	return nil, false
+}
+
+func isSensitive(key string) bool {
+	return sensitiveKeyRe.MatchString(key) && !referenceKeyRe.MatchString(key)
+}
+
+// redactValue hides the value of credential settings and the passwords of
+// URLs.  Secret references are kept as they do not reveal the secret.
+func redactValue(s string, sensitive bool) string {
+	if s == "" || secretRefRe.MatchString(s) {
+		return s
+	}
+	if sensitive {
+		return redacted
+	}
+
+	if u, err := url.Parse(s); err == nil && u.User != nil {
+		if password, ok := u.User.Password(); ok && password != "" {
+			return strings.Replace(s, ":"+password+"@", ":"+redacted+"@", 1)
+		}
+	}
+	return internal.Redact(s)
+}
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/BurntSushi/toml"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrintConfig(t *testing.T) {
+	require.NoError(t, os.Setenv("PRINT_TEST_PASSWORD", "monkey123"))
+	defer os.Unsetenv("PRINT_TEST_PASSWORD")
+
+	c := NewConfig()
+	require.NoError(t, c.LoadConfig("./testdata/print.toml"))
+
+	var buf bytes.Buffer
+	require.NoError(t, c.PrintConfig(&buf))
+	require.NotContains(t, buf.String(), "monkey123")
+	require.NotContains(t, buf.String(), "pa55word")
+	require.NotContains(t, buf.String(), "0123456789")
+
+	var actual struct {
+		GlobalTags map[string]string `toml:"global_tags"`
+		Agent      struct {
+			Interval        string `toml:"interval"`
+			MetricBatchSize int    `toml:"metric_batch_size"`
+		} `toml:"agent"`
+		SecretStores map[string][]map[string]interface{} `toml:"secretstores"`
+		Inputs       map[string][]map[string]interface{} `toml:"inputs"`
+		Outputs      map[string][]map[string]interface{} `toml:"outputs"`
+	}
+	_, err := toml.Decode(buf.String(), &actual)
+	require.NoError(t, err, buf.String())
+
+	require.Equal(t, "us-east-1", actual.GlobalTags["dc"])
+	require.Equal(t, "20s", actual.Agent.Interval)
+	require.Equal(t, 500, actual.Agent.MetricBatchSize)
+
+	require.Equal(t, "mock", actual.SecretStores["mock"][0]["id"])
+
+	inputs := actual.Inputs["memcached"]
+	require.Len(t, inputs, 2)
+	require.Equal(t, []interface{}{"localhost"}, inputs[0]["servers"])
+	require.Equal(t, "20s", inputs[0]["interval"])
+	require.Equal(t, "remote", inputs[1]["alias"])
+	require.Equal(t, "1m0s", inputs[1]["interval"])
+	require.Equal(t, []interface{}{"memcached"}, inputs[1]["namepass"])
+	require.Equal(t, map[string]interface{}{"env": "prod"}, inputs[1]["tags"])
+	require.Equal(t, map[string]interface{}{"server": []interface{}{"remote*"}}, inputs[1]["tagpass"])
+
+	exec := actual.Inputs["exec"]
+	require.Len(t, exec, 1)
+	require.Equal(t, "json", exec[0]["data_format"])
+	require.Equal(t, []interface{}{"chip"}, exec[0]["tag_keys"])
+	require.Equal(t, true, exec[0]["json_strict"])
+
+	outputs := actual.Outputs["http"]
+	require.Len(t, outputs, 1)
+	output := outputs[0]
+	require.Equal(t, "5s", output["flush_interval"])
+	require.Equal(t, int64(500), output["metric_batch_size"])
+	require.Equal(t, "https://telegraf:<redacted>@example.com/metrics", output["url"])
+	require.Equal(t, "telegraf", output["username"])
+	require.Equal(t, "<redacted>", output["password"])
+	require.Equal(t, "https://example.com/token", output["token_url"])
+	require.Equal(t, "@{mock:client_secret}", output["client_secret"])
+	require.Equal(t, map[string]interface{}{"Authorization": "<redacted>"}, output["headers"])
+	require.Equal(t, "csv", output["data_format"])
+	require.Equal(t, ";", output["csv_separator"])
+	require.Equal(t, "1s", output["timestamp_units"])
+
+	files := actual.Outputs["file"]
+	require.Len(t, files, 1)
+	require.Equal(t, "influx", files[0]["data_format"])
+	destinations := files[0]["destination"].([]map[string]interface{})
+	require.Len(t, destinations, 1)
+	require.Equal(t, []interface{}{"/tmp/metrics.json"}, destinations[0]["files"])
+	require.Equal(t, "json", destinations[0]["data_format"])
+}
@@ -0,0 +1,147 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+)
+
+var (
+	// secretStoreIDRe matches the valid ids of secret stores
+	secretStoreIDRe = regexp.MustCompile(`^\w+$`)
+
+	// secretRefRe is a regex to find secret references like @{store:key} in
+	// string settings
+	secretRefRe = regexp.MustCompile(`@\{(\w+):([^{}]+)\}`)
+)
+
+// ResolveSecrets replaces the secret references in all string settings of
+// the plugin with the values from the referenced secret stores.  Stores are
+// only initialized once they are first referenced, and every resolved value
+// is redacted from the log and test output.
+func (c *Config) ResolveSecrets(plugin interface{}) error {
+	if p, ok := plugin.(unwrappable); ok {
+		plugin = p.Unwrap()
+	}
+	return walkStrings(reflect.ValueOf(plugin), make(map[uintptr]bool), c.resolveString)
+}
+
+// hasSecretRefs returns true if any string setting of the plugin references
+// a secret.
+func hasSecretRefs(plugin interface{}) bool {
+	if p, ok := plugin.(unwrappable); ok {
+		plugin = p.Unwrap()
+	}
+
+	var found bool
+	walkStrings(reflect.ValueOf(plugin), make(map[uintptr]bool), func(s string) (string, error) {
+		found = found || secretRefRe.MatchString(s)
+		return s, nil
+	})
+	return found
+}
+
+// walkStrings replaces every settable string reachable from v, including
+// those in interfaces, maps and slices, with the result of fn.
+func walkStrings(v reflect.Value, seen map[uintptr]bool, fn func(string) (string, error)) error {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() || seen[v.Pointer()] {
+			return nil
+		}
+		seen[v.Pointer()] = true
+		return walkStrings(v.Elem(), seen, fn)
+	case reflect.Interface:
+		if v.IsNil() || !v.CanSet() {
+			return nil
+		}
+		// The value of an interface cannot be set in place, so a copy is
+		// walked and stored back.
+		elem := reflect.New(v.Elem().Type()).Elem()
+		elem.Set(v.Elem())
+		if err := walkStrings(elem, seen, fn); err != nil {
+			return err
+		}
+		v.Set(elem)
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).PkgPath != "" {
+				// unexported fields are never set from the config
+				continue
+			}
+			if err := walkStrings(v.Field(i), seen, fn); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := walkStrings(v.Index(i), seen, fn); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		// Map elements are not addressable either
+		for _, key := range v.MapKeys() {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			elem.Set(v.MapIndex(key))
+			if err := walkStrings(elem, seen, fn); err != nil {
+				return err
+			}
+			v.SetMapIndex(key, elem)
+		}
+	case reflect.String:
+		if !v.CanSet() {
+			return nil
+		}
+		value, err := fn(v.String())
+		if err != nil {
+			return err
+		}
+		v.SetString(value)
+	}
+	return nil
+}
+
+func (c *Config) resolveString(s string) (string, error) {
+	var err error
+	resolved := secretRefRe.ReplaceAllStringFunc(s, func(ref string) string {
+		if err != nil {
+			return ref
+		}
+		match := secretRefRe.FindStringSubmatch(ref)
+
+		var secret []byte
+		secret, err = c.getSecret(match[1], match[2])
+		if err != nil {
+			return ref
+		}
+		internal.AddSecret(string(secret))
+		return string(secret)
+	})
+	return resolved, err
+}
+
+func (c *Config) getSecret(id, key string) ([]byte, error) {
+	store, ok := c.SecretStores[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown secret store %q", id)
+	}
+
+	if !c.initializedStores[id] {
+		if si, ok := store.(telegraf.Initializer); ok {
+			if err := si.Init(); err != nil {
+				return nil, fmt.Errorf("could not initialize secret store %q: %v", id, err)
+			}
+		}
+		c.initializedStores[id] = true
+	}
+
+	secret, err := store.Get(key)
+	if err != nil {
+		return nil, fmt.Errorf("could not get secret %q from store %q: %v", key, id, err)
+	}
+	return secret, nil
+}
@@ -0,0 +1,138 @@
+package config
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs/memcached"
+	httpOut "github.com/influxdata/telegraf/plugins/outputs/http"
+	"github.com/influxdata/telegraf/plugins/secretstores"
+	"github.com/stretchr/testify/require"
+)
+
+type mockSecretStore struct {
+	initialized bool
+	secrets     map[string]string
+}
+
+func (s *mockSecretStore) Init() error {
+	s.initialized = true
+	s.secrets = map[string]string{
+		"server": "db.example.com",
+		"key":    "client",
+		"token":  "s3cr3t-t0k3n",
+	}
+	return nil
+}
+
+func (s *mockSecretStore) Get(key string) ([]byte, error) {
+	secret, ok := s.secrets[key]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return []byte(secret), nil
+}
+
+func (s *mockSecretStore) SampleConfig() string { return "" }
+func (s *mockSecretStore) Description() string  { return "" }
+
+func init() {
+	secretstores.Add("mock", func() telegraf.SecretStore { return &mockSecretStore{} })
+}
+
+func TestResolveSecrets(t *testing.T) {
+	c := NewConfig()
+	require.NoError(t, c.LoadConfig("./testdata/secrets.toml"))
+	require.Len(t, c.SecretStores, 2)
+
+	// References are kept until the plugins are initialized
+	input := c.Inputs[0].Input.(*memcached.Memcached)
+	require.Equal(t, []string{"@{mock:server}:11211", "localhost"}, input.Servers)
+
+	require.NoError(t, c.ResolveSecrets(input))
+	require.Equal(t, []string{"db.example.com:11211", "localhost"}, input.Servers)
+
+	output := c.Outputs[0].Output.(*httpOut.HTTP)
+	require.NoError(t, c.ResolveSecrets(output))
+	require.Equal(t, "/etc/telegraf/client.pem", output.TLSKey)
+	require.Equal(t, "Bearer s3cr3t-t0k3n", output.Headers["Authorization"])
+
+	require.True(t, c.SecretStores["mock"].(*mockSecretStore).initialized)
+	require.False(t, c.SecretStores["unused"].(*mockSecretStore).initialized)
+
+	require.Equal(t, "Authorization: Bearer <redacted>",
+		internal.Redact("Authorization: Bearer s3cr3t-t0k3n"))
+}
+
+func TestResolveSecretsNested(t *testing.T) {
+	c := NewConfig()
+	require.NoError(t, c.LoadConfigData([]byte(`
+[[secretstores.mock]]
+  id = "mock"
+`)))
+
+	type settings struct {
+		Value   interface{}
+		Options map[string]interface{}
+		Hosts   map[string][]string
+		Nested  [][]string
+	}
+	plugin := &settings{
+		Value: "@{mock:token}",
+		Options: map[string]interface{}{
+			"server": "@{mock:server}",
+			"list":   []interface{}{"@{mock:key}", 42},
+			"port":   11211,
+		},
+		Hosts:  map[string][]string{"db": {"@{mock:server}"}},
+		Nested: [][]string{{"@{mock:key}"}},
+	}
+	require.True(t, hasSecretRefs(plugin))
+	require.NoError(t, c.ResolveSecrets(plugin))
+	require.False(t, hasSecretRefs(plugin))
+
+	require.Equal(t, &settings{
+		Value: "s3cr3t-t0k3n",
+		Options: map[string]interface{}{
+			"server": "db.example.com",
+			"list":   []interface{}{"client", 42},
+			"port":   11211,
+		},
+		Hosts:  map[string][]string{"db": {"db.example.com"}},
+		Nested: [][]string{{"client"}},
+	}, plugin)
+}
+
+func TestResolveSecretsErrors(t *testing.T) {
+	c := NewConfig()
+	require.NoError(t, c.LoadConfigData([]byte(`
+[[secretstores.mock]]
+  id = "mock"
+`)))
+
+	_, err := c.resolveString("@{other:server}")
+	require.EqualError(t, err, `unknown secret store "other"`)
+
+	_, err = c.resolveString("@{mock:missing}")
+	require.EqualError(t, err, `could not get secret "missing" from store "mock": not found`)
+}
+
+func TestSecretStoreID(t *testing.T) {
+	c := NewConfig()
+	err := c.LoadConfigData([]byte(`
+[[secretstores.mock]]
+  id = "my-store"
+`))
+	require.Error(t, err)
+
+	c = NewConfig()
+	err = c.LoadConfigData([]byte(`
+[[secretstores.mock]]
+  id = "mock"
+[[secretstores.mock]]
+  id = "mock"
+`))
+	require.Error(t, err)
+}
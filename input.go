@@ -1,5 +1,7 @@
 package telegraf
 
+import "context"
+
 type Input interface {
 	PluginDescriber
 
@@ -8,6 +10,16 @@ type Input interface {
 	Gather(Accumulator) error
 }
 
+// ContextInput is an Input whose gather can be cancelled.  Telegraf calls
+// GatherContext instead of Gather, the context is cancelled once the
+// gather_timeout of the input expires or Telegraf shuts down.
+type ContextInput interface {
+	Input
+
+	// GatherContext is like Gather, but returns early when ctx is done.
+	GatherContext(ctx context.Context, acc Accumulator) error
+}
+
 type ServiceInput interface {
 	Input
 
@@ -0,0 +1,72 @@
+package process
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// The framed protocol is an opt-in protocol between Telegraf and an execd
+// process.  Instead of raw metrics both sides exchange frames, JSON documents
+// on a single line each, so that every batch of metrics can be acknowledged
+// by the receiving side.  Telegraf announces the protocol to the process by
+// setting ProtocolEnv to ProtocolFramed in its environment.
+const (
+	ProtocolEnv    = "TELEGRAF_EXECD_PROTOCOL"
+	ProtocolFramed = "framed"
+)
+
+// Frame types
+const (
+	// FrameMetrics carries a batch of serialized metrics.
+	FrameMetrics = "metrics"
+	// FrameAck acknowledges that the batch with the same ID was delivered.
+	FrameAck = "ack"
+	// FrameNack reports that the batch with the same ID was rejected.
+	FrameNack = "nack"
+	// FrameGather asks an input to gather metrics.
+	FrameGather = "gather"
+)
+
+// maxFrameSize is the size of the largest frame that can be read.
+const maxFrameSize = 64 * 1024 * 1024
+
+// Frame is the envelope of the framed protocol.  IDs are chosen by the
+// sender of the metrics and are only used to match the acknowledgements.
+type Frame struct {
+	Type    string `json:"type"`
+	ID      uint64 `json:"id,omitempty"`
+	Metrics string `json:"metrics,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// WriteFrame writes the frame as a single line to w.
+func WriteFrame(w io.Writer, f *Frame) error {
+	b, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
+
+// ParseFrame parses a line read from a framed stream.
+func ParseFrame(line []byte) (*Frame, error) {
+	var f Frame
+	if err := json.Unmarshal(line, &f); err != nil {
+		return nil, fmt.Errorf("invalid frame: %w", err)
+	}
+	if f.Type == "" {
+		return nil, fmt.Errorf("invalid frame: missing type")
+	}
+	return &f, nil
+}
+
+// NewFrameScanner returns a scanner splitting a framed stream into lines,
+// with a buffer large enough for big batches of metrics.
+func NewFrameScanner(r io.Reader) *bufio.Scanner {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxFrameSize)
+	return scanner
+}
@@ -0,0 +1,51 @@
+package process
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFrameRoundTrip(t *testing.T) {
+	metrics := "cpu,host=a usage=1 1\ncpu,host=b usage=2 1\n"
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteFrame(&buf, &Frame{Type: FrameMetrics, ID: 1, Metrics: metrics}))
+	require.NoError(t, WriteFrame(&buf, &Frame{Type: FrameNack, ID: 1, Error: "rejected"}))
+	require.Equal(t, 2, strings.Count(buf.String(), "\n"))
+
+	scanner := NewFrameScanner(&buf)
+	var frames []*Frame
+	for scanner.Scan() {
+		f, err := ParseFrame(scanner.Bytes())
+		require.NoError(t, err)
+		frames = append(frames, f)
+	}
+	require.NoError(t, scanner.Err())
+	require.Equal(t, []*Frame{
+		{Type: FrameMetrics, ID: 1, Metrics: metrics},
+		{Type: FrameNack, ID: 1, Error: "rejected"},
+	}, frames)
+}
+
+func TestLargeFrame(t *testing.T) {
+	metrics := strings.Repeat("cpu,host=a usage=1 1\n", 100000)
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteFrame(&buf, &Frame{Type: FrameMetrics, ID: 1, Metrics: metrics}))
+
+	scanner := NewFrameScanner(&buf)
+	require.True(t, scanner.Scan())
+	f, err := ParseFrame(scanner.Bytes())
+	require.NoError(t, err)
+	require.Equal(t, metrics, f.Metrics)
+}
+
+func TestParseFrameErrors(t *testing.T) {
+	_, err := ParseFrame([]byte("cpu usage=1 1"))
+	require.Error(t, err)
+	_, err = ParseFrame([]byte(`{"id": 1}`))
+	require.Error(t, err)
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"os"
 	"os/exec"
 	"sync"
 	"sync/atomic"
@@ -24,12 +25,15 @@ type Process struct {
 	ReadStderrFn func(io.Reader)
 	RestartDelay time.Duration
 	Log          telegraf.Logger
+	// Env holds additional environment variables of the process.
+	Env []string
 
 	name       string
 	args       []string
 	pid        int32
 	cancel     context.CancelFunc
 	mainLoopWg sync.WaitGroup
+	stdinLock  sync.Mutex
 }
 
 // New creates a new process wrapper
@@ -79,18 +83,38 @@ func (p *Process) Stop() {
 		p.cancel()
 	}
 	// close stdin so the app can shut down gracefully.
+	p.stdinLock.Lock()
 	p.Stdin.Close()
+	p.stdinLock.Unlock()
 	p.mainLoopWg.Wait()
 }
 
+// WriteFrame writes a frame of the framed protocol to the stdin of the
+// process.  It is safe to call concurrently, also while the process is
+// restarted.  If stdin supports deadlines the write times out after a second.
+func (p *Process) WriteFrame(f *Frame) error {
+	p.stdinLock.Lock()
+	defer p.stdinLock.Unlock()
+	if osStdin, ok := p.Stdin.(*os.File); ok {
+		osStdin.SetWriteDeadline(time.Now().Add(1 * time.Second))
+	}
+	return WriteFrame(p.Stdin, f)
+}
+
 func (p *Process) cmdStart() error {
 	p.Cmd = exec.Command(p.name, p.args...)
+	if len(p.Env) > 0 {
+		p.Cmd.Env = append(os.Environ(), p.Env...)
+	}
 
-	var err error
-	p.Stdin, err = p.Cmd.StdinPipe()
+	stdin, err := p.Cmd.StdinPipe()
 	if err != nil {
 		return fmt.Errorf("error opening stdin pipe: %w", err)
 	}
+	// Frames may be written concurrently with a restart
+	p.stdinLock.Lock()
+	p.Stdin = stdin
+	p.stdinLock.Unlock()
 
 	p.Stdout, err = p.Cmd.StdoutPipe()
 	if err != nil {
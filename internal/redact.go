@@ -0,0 +1,75 @@
+package internal
+
+import (
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const redacted = "<redacted>"
+
+// minSecretLength is the minimum length of secrets to redact, replacing
+// shorter values like "1" or "on" would garble unrelated output.
+const minSecretLength = 4
+
+var (
+	secretsMu sync.RWMutex
+	secrets   = make(map[string]bool)
+	redactor  = strings.NewReplacer()
+)
+
+// AddSecret registers a secret value which is replaced by Redact from then
+// on.  Secrets shorter than four characters are not redacted.
+func AddSecret(secret string) {
+	if len(secret) < minSecretLength {
+		return
+	}
+
+	secretsMu.Lock()
+	defer secretsMu.Unlock()
+	if secrets[secret] {
+		return
+	}
+	secrets[secret] = true
+
+	// Try longer secrets first so a secret containing another one is
+	// replaced as a whole.
+	values := make([]string, 0, len(secrets))
+	for s := range secrets {
+		values = append(values, s)
+	}
+	sort.Slice(values, func(i, j int) bool {
+		return len(values[i]) > len(values[j])
+	})
+
+	oldnew := make([]string, 0, 2*len(values))
+	for _, s := range values {
+		oldnew = append(oldnew, s, redacted)
+	}
+	redactor = strings.NewReplacer(oldnew...)
+}
+
+// Redact replaces all registered secrets in s.
+func Redact(s string) string {
+	secretsMu.RLock()
+	defer secretsMu.RUnlock()
+	return redactor.Replace(s)
+}
+
+// RedactWriter wraps w so all registered secrets are replaced in the data
+// written.
+func RedactWriter(w io.Writer) io.Writer {
+	return &redactWriter{w: w}
+}
+
+type redactWriter struct {
+	w io.Writer
+}
+
+func (r *redactWriter) Write(b []byte) (int, error) {
+	if _, err := io.WriteString(r.w, Redact(string(b))); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
@@ -0,0 +1,27 @@
+package internal
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedact(t *testing.T) {
+	AddSecret("")
+	AddSecret("on")
+	AddSecret("hunter2")
+	AddSecret("hunter2hunter2")
+
+	require.Equal(t, "no secrets here", Redact("no secrets here"))
+	require.Equal(t, "logging on", Redact("logging on"))
+	require.Equal(t, "password=<redacted> token=<redacted>",
+		Redact("password=hunter2 token=hunter2hunter2"))
+
+	var buf bytes.Buffer
+	w := RedactWriter(&buf)
+	n, err := w.Write([]byte("login failed for hunter2\n"))
+	require.NoError(t, err)
+	require.Equal(t, 25, n)
+	require.Equal(t, "login failed for <redacted>\n", buf.String())
+}
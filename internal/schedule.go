@@ -0,0 +1,175 @@
+package internal
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// cronParser accepts standard five field cron expressions with an optional
+// leading seconds field, and descriptors such as "@daily" or "@every 1h".
+var cronParser = cron.NewParser(cron.SecondOptional | cron.Minute | cron.Hour |
+	cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// Schedule is a parsed cron expression.
+type Schedule struct {
+	spec     string
+	schedule cron.Schedule
+}
+
+// ParseSchedule parses a cron expression, which is evaluated in the given
+// location unless it sets its own with a "CRON_TZ=" prefix.
+func ParseSchedule(spec string, loc *time.Location) (*Schedule, error) {
+	schedule, err := cronParser.Parse(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: %v", spec, err)
+	}
+
+	if s, ok := schedule.(*cron.SpecSchedule); ok && loc != nil &&
+		!strings.HasPrefix(spec, "CRON_TZ=") && !strings.HasPrefix(spec, "TZ=") {
+		s.Location = loc
+	}
+	return &Schedule{spec: spec, schedule: schedule}, nil
+}
+
+// Next returns the first time of the schedule after t.
+func (s *Schedule) Next(t time.Time) time.Time {
+	return s.schedule.Next(t)
+}
+
+func (s *Schedule) String() string {
+	return s.spec
+}
+
+// TimeWindow is a daily period of time, optionally limited to some days of
+// the week, such as "Mon-Fri 09:00-17:00".  Windows ending before they start
+// span midnight and belong to the day they start on.
+type TimeWindow struct {
+	spec  string
+	days  [7]bool
+	start time.Duration
+	end   time.Duration
+}
+
+// ParseTimeWindow parses a time window of the form "[days] HH:MM-HH:MM",
+// where days is a comma-separated list of weekdays or ranges of them.
+func ParseTimeWindow(spec string) (TimeWindow, error) {
+	w := TimeWindow{spec: spec}
+
+	fields := strings.Fields(spec)
+	switch len(fields) {
+	case 1:
+		for i := range w.days {
+			w.days[i] = true
+		}
+	case 2:
+		if err := w.parseDays(fields[0]); err != nil {
+			return w, fmt.Errorf("invalid time window %q: %v", spec, err)
+		}
+		fields = fields[1:]
+	default:
+		return w, fmt.Errorf("invalid time window %q", spec)
+	}
+
+	times := strings.Split(fields[0], "-")
+	if len(times) != 2 {
+		return w, fmt.Errorf("invalid time window %q: expected start and end time", spec)
+	}
+
+	var err error
+	if w.start, err = parseClock(times[0]); err != nil {
+		return w, fmt.Errorf("invalid time window %q: %v", spec, err)
+	}
+	if w.end, err = parseClock(times[1]); err != nil {
+		return w, fmt.Errorf("invalid time window %q: %v", spec, err)
+	}
+	if w.start == w.end {
+		return w, fmt.Errorf("invalid time window %q: start and end time are equal", spec)
+	}
+	return w, nil
+}
+
+func (w *TimeWindow) parseDays(s string) error {
+	for _, part := range strings.Split(s, ",") {
+		bounds := strings.Split(part, "-")
+		if len(bounds) > 2 {
+			return fmt.Errorf("invalid days %q", part)
+		}
+
+		first, ok := weekdays[strings.ToLower(bounds[0])]
+		if !ok {
+			return fmt.Errorf("unknown day %q", bounds[0])
+		}
+		last := first
+		if len(bounds) == 2 {
+			if last, ok = weekdays[strings.ToLower(bounds[1])]; !ok {
+				return fmt.Errorf("unknown day %q", bounds[1])
+			}
+		}
+
+		// Ranges may wrap around the end of the week, like "Sat-Mon"
+		for day := first; ; day = (day + 1) % 7 {
+			w.days[day] = true
+			if day == last {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// parseClock parses a time of day as "HH:MM", "24:00" is the end of the day.
+func parseClock(s string) (time.Duration, error) {
+	parts := strings.Split(s, ":")
+	if len(parts) != 2 || len(parts[0]) == 0 || len(parts[1]) != 2 {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", s)
+	}
+
+	hour, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", s)
+	}
+	minute, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", s)
+	}
+	if hour < 0 || minute < 0 || minute > 59 || hour > 24 || (hour == 24 && minute != 0) {
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+	return time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute, nil
+}
+
+// Contains returns true if the window includes t, in the location of t.
+func (w TimeWindow) Contains(t time.Time) bool {
+	hour, minute, second := t.Clock()
+	since := time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute +
+		time.Duration(second)*time.Second + time.Duration(t.Nanosecond())
+	day := t.Weekday()
+
+	if w.start < w.end {
+		return w.days[day] && since >= w.start && since < w.end
+	}
+
+	// The window spans midnight
+	if since >= w.start {
+		return w.days[day]
+	}
+	return since < w.end && w.days[(day+6)%7]
+}
+
+func (w TimeWindow) String() string {
+	return w.spec
+}
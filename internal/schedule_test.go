@@ -0,0 +1,121 @@
+package internal
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseSchedule(t *testing.T) {
+	loc := time.FixedZone("UTC+2", 2*3600)
+	now := time.Date(2020, 6, 1, 12, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		spec     string
+		expected time.Time
+	}{
+		{
+			spec:     "0 2 * * *",
+			expected: time.Date(2020, 6, 2, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			spec:     "*/15 * * * *",
+			expected: time.Date(2020, 6, 1, 12, 45, 0, 0, time.UTC),
+		},
+		{
+			spec:     "30 0 15 * * *",
+			expected: time.Date(2020, 6, 1, 13, 0, 30, 0, time.UTC),
+		},
+		{
+			spec:     "@daily",
+			expected: time.Date(2020, 6, 1, 22, 0, 0, 0, time.UTC),
+		},
+		{
+			spec:     "CRON_TZ=UTC 0 2 * * *",
+			expected: time.Date(2020, 6, 2, 2, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			s, err := ParseSchedule(tt.spec, loc)
+			require.NoError(t, err)
+			require.Equal(t, tt.spec, s.String())
+			require.True(t, tt.expected.Equal(s.Next(now)), "next: %s", s.Next(now).UTC())
+		})
+	}
+
+	_, err := ParseSchedule("0 25 * * *", loc)
+	require.Error(t, err)
+}
+
+func TestTimeWindow(t *testing.T) {
+	// 2020-06-01 is a Monday
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2020, 6, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		spec    string
+		inside  []time.Time
+		outside []time.Time
+	}{
+		{
+			spec:    "09:00-17:00",
+			inside:  []time.Time{at(1, 9, 0), at(6, 16, 59)},
+			outside: []time.Time{at(1, 8, 59), at(1, 17, 0)},
+		},
+		{
+			spec:    "Mon-Fri 09:00-17:00",
+			inside:  []time.Time{at(1, 12, 0), at(5, 12, 0)},
+			outside: []time.Time{at(6, 12, 0), at(7, 12, 0), at(1, 18, 0)},
+		},
+		{
+			spec:    "sat,sun 00:00-24:00",
+			inside:  []time.Time{at(6, 0, 0), at(7, 23, 59)},
+			outside: []time.Time{at(5, 23, 59), at(8, 0, 0)},
+		},
+		{
+			spec:    "Fri 22:00-02:00",
+			inside:  []time.Time{at(5, 22, 0), at(6, 1, 59)},
+			outside: []time.Time{at(5, 2, 0), at(6, 2, 0), at(6, 22, 0)},
+		},
+		{
+			spec:    "Sat-Mon 08:00-10:00",
+			inside:  []time.Time{at(6, 9, 0), at(7, 9, 0), at(8, 9, 0)},
+			outside: []time.Time{at(9, 9, 0), at(5, 9, 0)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			w, err := ParseTimeWindow(tt.spec)
+			require.NoError(t, err)
+			require.Equal(t, tt.spec, w.String())
+			for _, tm := range tt.inside {
+				require.True(t, w.Contains(tm), "%s should be inside", tm)
+			}
+			for _, tm := range tt.outside {
+				require.False(t, w.Contains(tm), "%s should be outside", tm)
+			}
+		})
+	}
+}
+
+func TestParseTimeWindowErrors(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"09:00",
+		"9-17",
+		"09:00-09:00",
+		"25:00-26:00",
+		"09:60-10:00",
+		"Foo 09:00-17:00",
+		"Mon-Tue-Wed 09:00-17:00",
+		"Mon 09:00-17:00 extra",
+	} {
+		_, err := ParseTimeWindow(spec)
+		require.Error(t, err, spec)
+	}
+}
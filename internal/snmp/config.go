@@ -1,6 +1,12 @@
 package snmp
 
 import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
 	"github.com/influxdata/telegraf/internal"
 )
 
@@ -32,3 +38,28 @@ type ClientConfig struct {
 	EngineBoots  uint32 `toml:"-"`
 	EngineTime   uint32 `toml:"-"`
 }
+
+// ParseEngineID returns the SNMPv3 engine ID from its hex representation.  If
+// empty, a text format engine ID with the hostname is returned as described in
+// RFC 3411.
+func ParseEngineID(s string) (string, error) {
+	if s != "" {
+		id, err := hex.DecodeString(strings.TrimPrefix(s, "0x"))
+		if err != nil {
+			return "", fmt.Errorf("invalid engine_id: %v", err)
+		}
+		if len(id) < 5 || len(id) > 32 {
+			return "", errors.New("invalid engine_id: must be 5 to 32 bytes")
+		}
+		return string(id), nil
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		return "", err
+	}
+	if len(hostname) > 27 {
+		hostname = hostname[:27]
+	}
+	return "\x80\x00\x1f\x88\x04" + hostname, nil
+}
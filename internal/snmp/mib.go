@@ -0,0 +1,542 @@
+package snmp
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/influxdata/telegraf"
+)
+
+// builtinMib provides the top of the OID tree so that MIBs can be resolved
+// even when SNMPv2-SMI itself is not found in the configured paths.
+const builtinMib = `
+SNMPv2-SMI DEFINITIONS ::= BEGIN
+org            OBJECT IDENTIFIER ::= { iso 3 }
+dod            OBJECT IDENTIFIER ::= { org 6 }
+internet       OBJECT IDENTIFIER ::= { dod 1 }
+directory      OBJECT IDENTIFIER ::= { internet 1 }
+mgmt           OBJECT IDENTIFIER ::= { internet 2 }
+mib-2          OBJECT IDENTIFIER ::= { mgmt 1 }
+transmission   OBJECT IDENTIFIER ::= { mib-2 10 }
+experimental   OBJECT IDENTIFIER ::= { internet 3 }
+private        OBJECT IDENTIFIER ::= { internet 4 }
+enterprises    OBJECT IDENTIFIER ::= { private 1 }
+security       OBJECT IDENTIFIER ::= { internet 5 }
+snmpV2         OBJECT IDENTIFIER ::= { internet 6 }
+snmpDomains    OBJECT IDENTIFIER ::= { snmpV2 1 }
+snmpProxys     OBJECT IDENTIFIER ::= { snmpV2 2 }
+snmpModules    OBJECT IDENTIFIER ::= { snmpV2 3 }
+zeroDotZero    OBJECT IDENTIFIER ::= { 0 0 }
+END
+`
+
+var mibRoots = map[string]string{
+	"ccitt":           ".0",
+	"iso":             ".1",
+	"joint-iso-ccitt": ".2",
+}
+
+// MibNode is an object defined in a MIB module.
+type MibNode struct {
+	// Module is the name of the MIB module defining the object.
+	Module string
+	// Name is the descriptor of the object.
+	Name string
+	// Oid is the numeric OID of the object, with a leading dot.
+	Oid string
+	// Macro is the kind of definition, e.g. OBJECT-TYPE.
+	Macro string
+	// Access is the MAX-ACCESS of an OBJECT-TYPE.
+	Access string
+	// Type is the name of the SYNTAX of an OBJECT-TYPE as written in the
+	// MIB, BaseType the type it is ultimately derived from.
+	Type     string
+	BaseType string
+	// TextualConventions lists the textual conventions the type is derived
+	// from, starting with the most specific one.
+	TextualConventions []string
+	DisplayHint        string
+	// Enums maps the values of an enumerated INTEGER to their labels.
+	Enums map[int64]string
+	// Index lists the INDEX objects of a table entry.
+	Index []string
+
+	augments *MibNode
+	smiv1    bool
+}
+
+// MibTable is a conceptual table along with its columns.
+type MibTable struct {
+	Table   *MibNode
+	Entry   *MibNode
+	Index   []string
+	Columns []*MibNode
+}
+
+// Conversion returns the telegraf conversion matching the textual convention
+// of the object, if any.
+func (n *MibNode) Conversion() string {
+	for _, tc := range n.TextualConventions {
+		switch tc {
+		case "MacAddress", "PhysAddress":
+			return "hwaddr"
+		case "InetAddressIPv4", "InetAddressIPv6", "InetAddress", "IPSIpAddress":
+			return "ipaddr"
+		}
+	}
+	return ""
+}
+
+// FormatValue decodes a value of the object according to its textual
+// convention and enumeration.  Values which can not be decoded are returned
+// unchanged.
+func (n *MibNode) FormatValue(v interface{}) interface{} {
+	if len(n.Enums) != 0 {
+		var i int64
+		switch v := v.(type) {
+		case int:
+			i = int64(v)
+		case int32:
+			i = int64(v)
+		case int64:
+			i = v
+		case uint:
+			i = int64(v)
+		case uint32:
+			i = int64(v)
+		default:
+			return v
+		}
+		if label, ok := n.Enums[i]; ok {
+			return label
+		}
+		return strconv.FormatInt(i, 10)
+	}
+
+	b, ok := v.([]byte)
+	if !ok {
+		return v
+	}
+	switch n.Conversion() {
+	case "hwaddr":
+		return net.HardwareAddr(b).String()
+	case "ipaddr":
+		if len(b) == 4 || len(b) == 16 {
+			return net.IP(b).String()
+		}
+	}
+	return v
+}
+
+type mibStore struct {
+	sync.RWMutex
+
+	builtin *mibModule
+	loaded  bool
+	modules map[string]*mibModule
+	names   []string
+	files   map[string]bool
+
+	byOid    map[string]*MibNode
+	byName   map[string][]*MibNode
+	children map[string][]*MibNode
+}
+
+var mibs = newMibStore()
+
+func newMibStore() *mibStore {
+	builtin, err := parseMibFile([]byte(builtinMib))
+	if err != nil {
+		panic(err)
+	}
+
+	s := &mibStore{
+		builtin: builtin[0],
+		modules: make(map[string]*mibModule),
+		files:   make(map[string]bool),
+	}
+	s.rebuild()
+	return s
+}
+
+// LoadMibsFromPath parses all MIB files found in the given directories and
+// their subdirectories.  MIBs are loaded once and shared by all plugins; files
+// already loaded are skipped, as are modules with the name of one already
+// loaded.
+func LoadMibsFromPath(paths []string, log telegraf.Logger) error {
+	return mibs.load(paths, log)
+}
+
+// MibsLoaded reports whether any MIB files have been loaded.
+func MibsLoaded() bool {
+	mibs.RLock()
+	defer mibs.RUnlock()
+	return mibs.loaded
+}
+
+// TranslateOid resolves the given OID, in either numeric or textual form, using
+// the loaded MIBs.  The returned values match those of net-snmp's snmptranslate.
+func TranslateOid(oid string) (mibName string, oidNum string, oidText string, conversion string, err error) {
+	node, suffix, err := LookupOid(oid)
+	if err != nil {
+		return "", "", "", "", err
+	}
+	return node.Module, node.Oid + suffix, node.Name + suffix, node.Conversion(), nil
+}
+
+// LookupOid returns the object of the loaded MIBs closest to the given OID,
+// along with the remaining numeric suffix of the OID.
+func LookupOid(oid string) (*MibNode, string, error) {
+	mibs.RLock()
+	defer mibs.RUnlock()
+	return mibs.lookup(oid)
+}
+
+// LookupTable returns the table, or table entry, with the given OID along with
+// its columns.  Columns which are not accessible are omitted.
+func LookupTable(oid string) (*MibTable, error) {
+	mibs.RLock()
+	defer mibs.RUnlock()
+
+	node, suffix, err := mibs.lookup(oid)
+	if err != nil {
+		return nil, err
+	}
+	if suffix != "" {
+		return nil, fmt.Errorf("%s is not a table", oid)
+	}
+
+	entry := node
+	if node.BaseType == "SEQUENCE OF" {
+		entry = nil
+		for _, child := range mibs.children[node.Oid] {
+			if child.Macro == "OBJECT-TYPE" {
+				entry = child
+				break
+			}
+		}
+	}
+	if entry == nil || (len(entry.Index) == 0 && entry.augments == nil) {
+		return nil, fmt.Errorf("%s is not a table", oid)
+	}
+
+	table := &MibTable{Table: node, Entry: entry, Index: entry.Index}
+	if entry.augments != nil {
+		table.Index = entry.augments.Index
+	}
+	for _, child := range mibs.children[entry.Oid] {
+		if child.Macro == "OBJECT-TYPE" && child.Access != "not-accessible" {
+			table.Columns = append(table.Columns, child)
+		}
+	}
+	return table, nil
+}
+
+func (s *mibStore) load(paths []string, log telegraf.Logger) error {
+	s.Lock()
+	defer s.Unlock()
+
+	loaded := false
+	for _, path := range paths {
+		err := filepath.Walk(path, func(filename string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				if filename != path && strings.HasPrefix(info.Name(), ".") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if strings.HasPrefix(info.Name(), ".") {
+				return nil
+			}
+			if info.Mode()&os.ModeSymlink != 0 {
+				if info, err = os.Stat(filename); err != nil || !info.Mode().IsRegular() {
+					return nil
+				}
+			}
+
+			if s.files[filename] {
+				return nil
+			}
+			s.files[filename] = true
+
+			data, err := ioutil.ReadFile(filename)
+			if err != nil {
+				log.Warnf("Reading MIB file %s: %v", filename, err)
+				return nil
+			}
+			if !strings.Contains(string(data), "DEFINITIONS") {
+				return nil
+			}
+
+			modules, err := parseMibFile(data)
+			if err != nil {
+				log.Warnf("Parsing MIB file %s: %v", filename, err)
+				return nil
+			}
+			for _, m := range modules {
+				if existing, ok := s.modules[m.name]; ok && existing != s.builtin {
+					log.Debugf("Skipping module %s in %s, already loaded", m.name, filename)
+					continue
+				}
+				s.modules[m.name] = m
+				loaded = true
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("loading MIBs from %s: %w", path, err)
+		}
+	}
+
+	if loaded {
+		s.loaded = true
+		s.rebuild()
+	}
+	return nil
+}
+
+// rebuild resolves all definitions of the loaded modules into the lookup
+// tables.  It must be called with the lock held.
+func (s *mibStore) rebuild() {
+	if _, ok := s.modules[s.builtin.name]; !ok {
+		s.modules[s.builtin.name] = s.builtin
+	}
+
+	s.names = s.names[:0]
+	for name := range s.modules {
+		s.names = append(s.names, name)
+	}
+	sort.Strings(s.names)
+
+	s.byOid = make(map[string]*MibNode)
+	s.byName = make(map[string][]*MibNode)
+	s.children = make(map[string][]*MibNode)
+
+	r := &mibResolver{store: s, oids: make(map[string]string)}
+	type augmented struct {
+		node   *MibNode
+		module *mibModule
+		name   string
+	}
+	var augments []augmented
+
+	for _, name := range s.names {
+		m := s.modules[name]
+		for _, def := range m.order {
+			oid, ok := r.resolve(m, def.name)
+			if !ok {
+				continue
+			}
+
+			node := &MibNode{
+				Module: m.name,
+				Name:   def.name,
+				Oid:    oid,
+				Macro:  def.macro,
+				Access: def.access,
+				Index:  def.index,
+				smiv1:  def.smiv1,
+			}
+			if def.syntax != nil {
+				r.resolveSyntax(m, def.syntax, node)
+			}
+			if def.augments != "" {
+				augments = append(augments, augmented{node, m, def.augments})
+			}
+
+			s.byName[node.Name] = append(s.byName[node.Name], node)
+			// Prefer SMIv2 definitions over SMIv1 ones of the same object.
+			if existing, ok := s.byOid[oid]; !ok || (existing.smiv1 && !node.smiv1) {
+				s.byOid[oid] = node
+			}
+		}
+	}
+
+	for _, a := range augments {
+		if oid, ok := r.resolve(a.module, a.name); ok {
+			a.node.augments = s.byOid[oid]
+		}
+	}
+
+	for oid, node := range s.byOid {
+		i := strings.LastIndexByte(oid, '.')
+		if i > 0 {
+			s.children[oid[:i]] = append(s.children[oid[:i]], node)
+		}
+	}
+	for _, children := range s.children {
+		sort.Slice(children, func(i, j int) bool {
+			return lastSubid(children[i].Oid) < lastSubid(children[j].Oid)
+		})
+	}
+}
+
+func lastSubid(oid string) uint64 {
+	v, _ := strconv.ParseUint(oid[strings.LastIndexByte(oid, '.')+1:], 10, 32)
+	return v
+}
+
+// lookupDef finds the definition of a name as seen from module m: its own
+// definitions, its imports and finally all other modules.
+func (s *mibStore) lookupDef(m *mibModule, name string) (*mibDef, *mibModule) {
+	if def, ok := m.defs[name]; ok {
+		return def, m
+	}
+	if from, ok := s.modules[m.imports[name]]; ok {
+		if def, ok := from.defs[name]; ok {
+			return def, from
+		}
+	}
+	for _, n := range s.names {
+		if def, ok := s.modules[n].defs[name]; ok {
+			return def, s.modules[n]
+		}
+	}
+	return nil, nil
+}
+
+func (s *mibStore) lookupType(m *mibModule, name string) (*mibType, *mibModule) {
+	if t, ok := m.types[name]; ok {
+		return t, m
+	}
+	if from, ok := s.modules[m.imports[name]]; ok {
+		if t, ok := from.types[name]; ok {
+			return t, from
+		}
+	}
+	for _, n := range s.names {
+		if t, ok := s.modules[n].types[name]; ok {
+			return t, s.modules[n]
+		}
+	}
+	return nil, nil
+}
+
+// lookup finds the node for an OID given as numeric OID, as descriptor or as
+// MODULE::descriptor, each optionally followed by a numeric suffix.  Numeric
+// OIDs are matched to the closest known parent.
+func (s *mibStore) lookup(oid string) (*MibNode, string, error) {
+	module := ""
+	name := oid
+	if i := strings.Index(oid, "::"); i != -1 {
+		module, name = oid[:i], oid[i+2:]
+	}
+
+	if name == "" {
+		return nil, "", fmt.Errorf("invalid OID %q", oid)
+	}
+
+	if name[0] == '.' || (name[0] >= '0' && name[0] <= '9') {
+		parts := strings.Split(strings.TrimPrefix(name, "."), ".")
+		for _, part := range parts {
+			if _, err := strconv.ParseUint(part, 10, 32); err != nil {
+				return nil, "", fmt.Errorf("invalid OID %q", oid)
+			}
+		}
+		for i := len(parts); i > 0; i-- {
+			if node, ok := s.byOid["."+strings.Join(parts[:i], ".")]; ok {
+				var suffix string
+				if i < len(parts) {
+					suffix = "." + strings.Join(parts[i:], ".")
+				}
+				return node, suffix, nil
+			}
+		}
+		return nil, "", fmt.Errorf("OID %q not found in loaded MIBs", oid)
+	}
+
+	var suffix string
+	if i := strings.IndexByte(name, '.'); i != -1 {
+		name, suffix = name[:i], name[i:]
+		for _, part := range strings.Split(suffix[1:], ".") {
+			if _, err := strconv.ParseUint(part, 10, 32); err != nil {
+				return nil, "", fmt.Errorf("invalid OID %q", oid)
+			}
+		}
+	}
+
+	var found *MibNode
+	for _, node := range s.byName[name] {
+		if module != "" && node.Module != module {
+			continue
+		}
+		if found == nil || (found.smiv1 && !node.smiv1) {
+			found = node
+		}
+	}
+	if found == nil {
+		return nil, "", fmt.Errorf("OID %q not found in loaded MIBs", oid)
+	}
+	return found, suffix, nil
+}
+
+type mibResolver struct {
+	store *mibStore
+	oids  map[string]string
+}
+
+// resolve returns the numeric OID of the name as seen from module m.
+func (r *mibResolver) resolve(m *mibModule, name string) (string, bool) {
+	def, owner := r.store.lookupDef(m, name)
+	if def == nil {
+		oid, ok := mibRoots[name]
+		return oid, ok
+	}
+
+	key := owner.name + "::" + name
+	if oid, ok := r.oids[key]; ok {
+		return oid, oid != ""
+	}
+	// Guard against reference cycles while resolving the parent.
+	r.oids[key] = ""
+
+	var oid string
+	if def.parent != "" {
+		parent, ok := r.resolve(owner, def.parent)
+		if !ok {
+			return "", false
+		}
+		oid = parent
+	}
+	for _, subid := range def.subids {
+		oid += "." + subid
+	}
+	r.oids[key] = oid
+	return oid, true
+}
+
+// resolveSyntax follows the type of an object through type assignments and
+// textual conventions down to its base type.
+func (r *mibResolver) resolveSyntax(m *mibModule, t *mibType, node *MibNode) {
+	node.Type = t.name
+	node.Enums = t.enums
+
+	name := t.name
+	for depth := 0; depth < 16; depth++ {
+		def, owner := r.store.lookupType(m, name)
+		if def == nil {
+			break
+		}
+		if def.tc {
+			node.TextualConventions = append(node.TextualConventions, name)
+			if node.DisplayHint == "" {
+				node.DisplayHint = def.hint
+			}
+		}
+		if node.Enums == nil {
+			node.Enums = def.enums
+		}
+		name, m = def.name, owner
+	}
+	node.BaseType = name
+}
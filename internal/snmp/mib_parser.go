@@ -0,0 +1,640 @@
+package snmp
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// mibModule is a parsed MIB module.  Only the parts needed for OID
+// translation, table discovery and value decoding are kept.
+type mibModule struct {
+	name    string
+	imports map[string]string
+	defs    map[string]*mibDef
+	order   []*mibDef
+	types   map[string]*mibType
+}
+
+// mibDef is an OID valued definition, such as an OBJECT-TYPE or an OBJECT
+// IDENTIFIER assignment.
+type mibDef struct {
+	name   string
+	macro  string
+	parent string
+	subids []string
+
+	syntax   *mibType
+	access   string
+	index    []string
+	augments string
+	smiv1    bool
+}
+
+// mibType is a type reference together with its refinements.  For type
+// assignments and textual conventions it is the definition of the type.
+type mibType struct {
+	name  string
+	entry string
+	enums map[int64]string
+
+	tc   bool
+	hint string
+}
+
+type mibToken struct {
+	text string
+	line int
+	str  bool
+}
+
+func isMibIdentChar(c byte) bool {
+	return c == '-' || c == '_' ||
+		(c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+// lexMib splits the MIB source into tokens, dropping whitespace and comments.
+func lexMib(data []byte) ([]mibToken, error) {
+	var tokens []mibToken
+	line := 1
+	for i := 0; i < len(data); {
+		c := data[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+		case c == ' ' || c == '\t' || c == '\r' || c == '\f':
+			i++
+		case c == '-' && i+1 < len(data) && data[i+1] == '-':
+			// Comments run to the end of the line or the next "--".
+			i += 2
+			for i < len(data) && data[i] != '\n' {
+				if data[i] == '-' && i+1 < len(data) && data[i+1] == '-' {
+					i += 2
+					break
+				}
+				i++
+			}
+		case c == '"':
+			start := line
+			j := i + 1
+			for j < len(data) && data[j] != '"' {
+				if data[j] == '\n' {
+					line++
+				}
+				j++
+			}
+			if j >= len(data) {
+				return nil, fmt.Errorf("line %d: unterminated string", start)
+			}
+			tokens = append(tokens, mibToken{text: string(data[i+1 : j]), line: start, str: true})
+			i = j + 1
+		case c == '\'':
+			// Binary and hexadecimal strings, such as '00'H.
+			j := i + 1
+			for j < len(data) && data[j] != '\'' {
+				j++
+			}
+			if j >= len(data) {
+				return nil, fmt.Errorf("line %d: unterminated quoted string", line)
+			}
+			j++
+			for j < len(data) && isMibIdentChar(data[j]) {
+				j++
+			}
+			tokens = append(tokens, mibToken{text: string(data[i:j]), line: line})
+			i = j
+		case c == ':' && i+2 < len(data) && data[i+1] == ':' && data[i+2] == '=':
+			tokens = append(tokens, mibToken{text: "::=", line: line})
+			i += 3
+		case c == '.' && i+1 < len(data) && data[i+1] == '.':
+			tokens = append(tokens, mibToken{text: "..", line: line})
+			i += 2
+		case strings.IndexByte("{}()[],;|.:", c) != -1:
+			tokens = append(tokens, mibToken{text: string(c), line: line})
+			i++
+		case isMibIdentChar(c):
+			j := i + 1
+			for j < len(data) && isMibIdentChar(data[j]) {
+				if data[j] == '-' && j+1 < len(data) && data[j+1] == '-' {
+					break
+				}
+				j++
+			}
+			tokens = append(tokens, mibToken{text: string(data[i:j]), line: line})
+			i = j
+		default:
+			return nil, fmt.Errorf("line %d: unexpected character %q", line, c)
+		}
+	}
+	return tokens, nil
+}
+
+var errMibEOF = errors.New("unexpected end of file")
+
+type mibParser struct {
+	tokens []mibToken
+	pos    int
+}
+
+func (p *mibParser) eof() bool {
+	return p.pos >= len(p.tokens)
+}
+
+func (p *mibParser) peek() string {
+	if p.eof() {
+		return ""
+	}
+	return p.tokens[p.pos].text
+}
+
+func (p *mibParser) next() (mibToken, error) {
+	if p.eof() {
+		return mibToken{}, errMibEOF
+	}
+	tok := p.tokens[p.pos]
+	p.pos++
+	return tok, nil
+}
+
+func (p *mibParser) expect(text string) error {
+	tok, err := p.next()
+	if err != nil {
+		return err
+	}
+	if tok.str || tok.text != text {
+		return fmt.Errorf("line %d: expected %q, got %q", tok.line, text, tok.text)
+	}
+	return nil
+}
+
+// skipUntil consumes tokens up to and including the given token.
+func (p *mibParser) skipUntil(text string) error {
+	for {
+		tok, err := p.next()
+		if err != nil {
+			return err
+		}
+		if !tok.str && tok.text == text {
+			return nil
+		}
+	}
+}
+
+// skipBalanced consumes a parenthesized or braced group, the opening token
+// being the next token.
+func (p *mibParser) skipBalanced() error {
+	depth := 0
+	for {
+		tok, err := p.next()
+		if err != nil {
+			return err
+		}
+		if tok.str {
+			continue
+		}
+		switch tok.text {
+		case "{", "(", "[":
+			depth++
+		case "}", ")", "]":
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+	}
+}
+
+// parseMibFile parses all modules contained in the MIB source.
+func parseMibFile(data []byte) ([]*mibModule, error) {
+	tokens, err := lexMib(data)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &mibParser{tokens: tokens}
+	var modules []*mibModule
+	for !p.eof() {
+		m, err := p.parseModule()
+		if err != nil {
+			return nil, err
+		}
+		modules = append(modules, m)
+	}
+	return modules, nil
+}
+
+func (p *mibParser) parseModule() (*mibModule, error) {
+	tok, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+	m := &mibModule{
+		name:    tok.text,
+		imports: make(map[string]string),
+		defs:    make(map[string]*mibDef),
+		types:   make(map[string]*mibType),
+	}
+
+	if p.peek() == "{" {
+		if err := p.skipBalanced(); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.expect("DEFINITIONS"); err != nil {
+		return nil, err
+	}
+	if err := p.skipUntil("::="); err != nil {
+		return nil, err
+	}
+	if err := p.expect("BEGIN"); err != nil {
+		return nil, err
+	}
+
+	for {
+		tok, err := p.next()
+		if err != nil {
+			return nil, fmt.Errorf("module %s: %w", m.name, err)
+		}
+
+		switch tok.text {
+		case "END":
+			return m, nil
+		case "IMPORTS":
+			if err := p.parseImports(m); err != nil {
+				return nil, fmt.Errorf("module %s: %w", m.name, err)
+			}
+		case "EXPORTS":
+			if err := p.skipUntil(";"); err != nil {
+				return nil, fmt.Errorf("module %s: %w", m.name, err)
+			}
+		default:
+			if err := p.parseAssignment(m, tok); err != nil {
+				return nil, fmt.Errorf("module %s: %w", m.name, err)
+			}
+		}
+	}
+}
+
+func (p *mibParser) parseImports(m *mibModule) error {
+	var symbols []string
+	for {
+		tok, err := p.next()
+		if err != nil {
+			return err
+		}
+		switch tok.text {
+		case ";":
+			return nil
+		case ",":
+		case "FROM":
+			from, err := p.next()
+			if err != nil {
+				return err
+			}
+			for _, s := range symbols {
+				m.imports[s] = from.text
+			}
+			symbols = symbols[:0]
+		default:
+			symbols = append(symbols, tok.text)
+		}
+	}
+}
+
+func (p *mibParser) parseAssignment(m *mibModule, name mibToken) error {
+	switch p.peek() {
+	case "MACRO":
+		return p.skipUntil("END")
+	case "::=":
+		p.pos++
+		return p.parseTypeAssignment(m, name)
+	}
+
+	// Value assignment using a macro, such as OBJECT-TYPE, or a type,
+	// such as OBJECT IDENTIFIER.  The clauses run up to the "::=".
+	macro, err := p.next()
+	if err != nil {
+		return err
+	}
+	def := &mibDef{name: name.text, macro: macro.text}
+	if macro.text == "OBJECT" && p.peek() == "IDENTIFIER" {
+		p.pos++
+		def.macro = "OBJECT IDENTIFIER"
+	}
+
+	start := p.pos
+	depth := 0
+	for {
+		tok, err := p.next()
+		if err != nil {
+			return err
+		}
+		if tok.str {
+			continue
+		}
+		switch tok.text {
+		case "{", "(":
+			depth++
+		case "}", ")":
+			depth--
+		}
+		if depth == 0 && tok.text == "::=" {
+			break
+		}
+	}
+	clauses := &mibParser{tokens: p.tokens[start : p.pos-1]}
+
+	if p.peek() == "{" {
+		if err := p.parseOidValue(def); err != nil {
+			return fmt.Errorf("line %d: %s: %w", name.line, name.text, err)
+		}
+	} else {
+		value, err := p.next()
+		if err != nil {
+			return err
+		}
+		if def.macro != "TRAP-TYPE" {
+			// Not an OID value, e.g. an INTEGER constant.
+			return nil
+		}
+		def.subids = []string{"0", value.text}
+	}
+
+	if err := clauses.parseClauses(def); err != nil {
+		return fmt.Errorf("line %d: %s: %w", name.line, name.text, err)
+	}
+
+	if _, ok := m.defs[def.name]; !ok {
+		m.order = append(m.order, def)
+	}
+	m.defs[def.name] = def
+	return nil
+}
+
+func (p *mibParser) parseTypeAssignment(m *mibModule, name mibToken) error {
+	switch p.peek() {
+	case "TEXTUAL-CONVENTION":
+		p.pos++
+		var hint string
+		for {
+			tok, err := p.next()
+			if err != nil {
+				return err
+			}
+			if tok.str {
+				continue
+			}
+			if tok.text == "DISPLAY-HINT" {
+				h, err := p.next()
+				if err != nil {
+					return err
+				}
+				hint = h.text
+			}
+			if tok.text == "SYNTAX" {
+				break
+			}
+		}
+		t, err := p.parseType()
+		if err != nil {
+			return fmt.Errorf("line %d: %s: %w", name.line, name.text, err)
+		}
+		t.tc = true
+		t.hint = hint
+		m.types[name.text] = t
+		return nil
+	case "{":
+		// An OID value assignment without the OBJECT IDENTIFIER type.
+		def := &mibDef{name: name.text, macro: "OBJECT IDENTIFIER"}
+		if err := p.parseOidValue(def); err != nil {
+			return fmt.Errorf("line %d: %s: %w", name.line, name.text, err)
+		}
+		if _, ok := m.defs[def.name]; !ok {
+			m.order = append(m.order, def)
+		}
+		m.defs[def.name] = def
+		return nil
+	}
+
+	t, err := p.parseType()
+	if err != nil {
+		return fmt.Errorf("line %d: %s: %w", name.line, name.text, err)
+	}
+	m.types[name.text] = t
+	return nil
+}
+
+// parseType parses a type reference with its optional named numbers and
+// constraints.
+func (p *mibParser) parseType() (*mibType, error) {
+	tok, err := p.next()
+	if err != nil {
+		return nil, err
+	}
+
+	t := &mibType{name: tok.text}
+	switch tok.text {
+	case "[":
+		if err := p.skipUntil("]"); err != nil {
+			return nil, err
+		}
+		if p.peek() == "IMPLICIT" || p.peek() == "EXPLICIT" {
+			p.pos++
+		}
+		return p.parseType()
+	case "SEQUENCE":
+		if p.peek() == "OF" {
+			p.pos++
+			entry, err := p.parseType()
+			if err != nil {
+				return nil, err
+			}
+			return &mibType{name: "SEQUENCE OF", entry: entry.name}, nil
+		}
+		return t, p.skipBalanced()
+	case "CHOICE":
+		return t, p.skipBalanced()
+	case "OBJECT":
+		if err := p.expect("IDENTIFIER"); err != nil {
+			return nil, err
+		}
+		t.name = "OBJECT IDENTIFIER"
+	case "OCTET":
+		if err := p.expect("STRING"); err != nil {
+			return nil, err
+		}
+		t.name = "OCTET STRING"
+	}
+
+	if p.peek() == "{" {
+		if t.enums, err = p.parseNamedNumbers(); err != nil {
+			return nil, err
+		}
+	}
+	if p.peek() == "(" {
+		if err := p.skipBalanced(); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// parseNamedNumbers parses an enumeration such as { up(1), down(2) }.
+func (p *mibParser) parseNamedNumbers() (map[int64]string, error) {
+	if err := p.expect("{"); err != nil {
+		return nil, err
+	}
+
+	enums := make(map[int64]string)
+	for {
+		tok, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		switch tok.text {
+		case "}":
+			return enums, nil
+		case ",":
+			continue
+		}
+
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		num, err := p.next()
+		if err != nil {
+			return nil, err
+		}
+		v, err := strconv.ParseInt(num.text, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid value %q for %s", num.line, num.text, tok.text)
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		enums[v] = tok.text
+	}
+}
+
+// parseOidValue parses an OID value such as { iso org(3) 6 } into the parent
+// reference and sub-identifiers of the definition.
+func (p *mibParser) parseOidValue(def *mibDef) error {
+	if err := p.expect("{"); err != nil {
+		return err
+	}
+
+	for first := true; ; first = false {
+		tok, err := p.next()
+		if err != nil {
+			return err
+		}
+		if tok.text == "}" {
+			break
+		}
+
+		if _, err := strconv.ParseUint(tok.text, 10, 32); err == nil {
+			def.subids = append(def.subids, tok.text)
+			continue
+		}
+
+		if p.peek() == "(" {
+			p.pos++
+			num, err := p.next()
+			if err != nil {
+				return err
+			}
+			if _, err := strconv.ParseUint(num.text, 10, 32); err != nil {
+				return fmt.Errorf("line %d: invalid sub-identifier %q", num.line, num.text)
+			}
+			if err := p.expect(")"); err != nil {
+				return err
+			}
+			def.subids = append(def.subids, num.text)
+			continue
+		}
+
+		if !first {
+			return fmt.Errorf("line %d: unexpected reference %q in OID value", tok.line, tok.text)
+		}
+		def.parent = tok.text
+	}
+
+	if def.parent == "" && len(def.subids) == 0 {
+		return errors.New("empty OID value")
+	}
+	return nil
+}
+
+// parseClauses extracts the clauses of interest from an OBJECT-TYPE or
+// TRAP-TYPE definition.
+func (p *mibParser) parseClauses(def *mibDef) error {
+	if def.macro != "OBJECT-TYPE" && def.macro != "TRAP-TYPE" {
+		return nil
+	}
+	def.smiv1 = def.macro == "TRAP-TYPE"
+
+	for !p.eof() {
+		tok, _ := p.next()
+		if tok.str {
+			continue
+		}
+
+		switch tok.text {
+		case "SYNTAX":
+			t, err := p.parseType()
+			if err != nil {
+				return err
+			}
+			def.syntax = t
+		case "ACCESS", "MAX-ACCESS":
+			access, err := p.next()
+			if err != nil {
+				return err
+			}
+			def.access = access.text
+			def.smiv1 = tok.text == "ACCESS"
+		case "INDEX":
+			if err := p.expect("{"); err != nil {
+				return err
+			}
+			for {
+				col, err := p.next()
+				if err != nil {
+					return err
+				}
+				if col.text == "}" {
+					break
+				}
+				if col.text != "," && col.text != "IMPLIED" {
+					def.index = append(def.index, col.text)
+				}
+			}
+		case "AUGMENTS":
+			if err := p.expect("{"); err != nil {
+				return err
+			}
+			augments, err := p.next()
+			if err != nil {
+				return err
+			}
+			def.augments = augments.text
+			if err := p.expect("}"); err != nil {
+				return err
+			}
+		case "ENTERPRISE":
+			enterprise, err := p.next()
+			if err != nil {
+				return err
+			}
+			def.parent = enterprise.text
+		case "{", "(":
+			p.pos--
+			if err := p.skipBalanced(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
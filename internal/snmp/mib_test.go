@@ -0,0 +1,124 @@
+package snmp
+
+import (
+	"testing"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+const testEnterprise = ".1.3.6.1.4.1.65535"
+
+func loadTestMibs(t *testing.T) {
+	require.NoError(t, LoadMibsFromPath([]string{"testdata/mibs"}, testutil.Logger{}))
+	require.True(t, MibsLoaded())
+}
+
+func TestTranslateOid(t *testing.T) {
+	loadTestMibs(t)
+
+	tests := []struct {
+		oid        string
+		mibName    string
+		oidNum     string
+		oidText    string
+		conversion string
+	}{
+		{"TELEGRAF-TEST-MIB::testName.0", "TELEGRAF-TEST-MIB", testEnterprise + ".2.1.1.0", "testName.0", ""},
+		{"testName", "TELEGRAF-TEST-MIB", testEnterprise + ".2.1.1", "testName", ""},
+		{testEnterprise + ".2.1.1.0", "TELEGRAF-TEST-MIB", testEnterprise + ".2.1.1.0", "testName.0", ""},
+		{"1.3.6.1.4.1.65535.2.1.2.1.3.42", "TELEGRAF-TEST-MIB", testEnterprise + ".2.1.2.1.3.42", "testPortAddress.42", "hwaddr"},
+		{testEnterprise + ".99.1", "SNMPv2-SMI", testEnterprise + ".99.1", "enterprises.65535.99.1", ""},
+		{"testV1Name", "TELEGRAF-TEST-V1-MIB", testEnterprise + ".3.1", "testV1Name", ""},
+		{testEnterprise + ".3.0.7", "TELEGRAF-TEST-V1-MIB", testEnterprise + ".3.0.7", "testV1Trap", ""},
+		{"testPortDown", "TELEGRAF-TEST-MIB", testEnterprise + ".2.2.1", "testPortDown", ""},
+		{"mib-2", "SNMPv2-SMI", ".1.3.6.1.2.1", "mib-2", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.oid, func(t *testing.T) {
+			mibName, oidNum, oidText, conversion, err := TranslateOid(tt.oid)
+			require.NoError(t, err)
+			require.Equal(t, tt.mibName, mibName)
+			require.Equal(t, tt.oidNum, oidNum)
+			require.Equal(t, tt.oidText, oidText)
+			require.Equal(t, tt.conversion, conversion)
+		})
+	}
+}
+
+func TestTranslateOidNotFound(t *testing.T) {
+	loadTestMibs(t)
+
+	for _, oid := range []string{"unknownObject", "TELEGRAF-TEST-V1-MIB::testPortName", "testName.x", ".3.1", ""} {
+		_, _, _, _, err := TranslateOid(oid)
+		require.Error(t, err, oid)
+	}
+}
+
+func TestLookupTable(t *testing.T) {
+	loadTestMibs(t)
+
+	table, err := LookupTable("TELEGRAF-TEST-MIB::testPortTable")
+	require.NoError(t, err)
+	require.Equal(t, "testPortTable", table.Table.Name)
+	require.Equal(t, "testPortEntry", table.Entry.Name)
+	require.Equal(t, []string{"testPortIndex"}, table.Index)
+
+	var columns []string
+	for _, c := range table.Columns {
+		columns = append(columns, c.Name)
+	}
+	require.Equal(t, []string{"testPortName", "testPortAddress", "testPortStatus", "testPortAdmin", "testPortOctets"}, columns)
+
+	table, err = LookupTable("testPortXEntry")
+	require.NoError(t, err)
+	require.Equal(t, []string{"testPortIndex"}, table.Index)
+	require.Len(t, table.Columns, 1)
+	require.Equal(t, "testPortAlias", table.Columns[0].Name)
+
+	_, err = LookupTable("testName")
+	require.Error(t, err)
+}
+
+func TestMibNodeTypes(t *testing.T) {
+	loadTestMibs(t)
+
+	node, _, err := LookupOid("testPortStatus")
+	require.NoError(t, err)
+	require.Equal(t, "TestStatus", node.Type)
+	require.Equal(t, "INTEGER", node.BaseType)
+	require.Equal(t, []string{"TestStatus"}, node.TextualConventions)
+	require.Equal(t, map[int64]string{1: "up", 2: "down", 3: "testing"}, node.Enums)
+	require.Equal(t, "down", node.FormatValue(2))
+	require.Equal(t, "9", node.FormatValue(9))
+
+	node, _, err = LookupOid("testPortAdmin")
+	require.NoError(t, err)
+	require.Equal(t, "disabled", node.FormatValue(int64(2)))
+
+	node, _, err = LookupOid("testPortAddress")
+	require.NoError(t, err)
+	require.Equal(t, []string{"TestMacAddress", "MacAddress"}, node.TextualConventions)
+	require.Equal(t, "OCTET STRING", node.BaseType)
+	require.Equal(t, "1x:", node.DisplayHint)
+	require.Equal(t, "00:11:22:33:44:55", node.FormatValue([]byte{0x00, 0x11, 0x22, 0x33, 0x44, 0x55}))
+
+	node, _, err = LookupOid("testName")
+	require.NoError(t, err)
+	require.Equal(t, "read-only", node.Access)
+	require.Equal(t, "OBJECT-TYPE", node.Macro)
+	require.Equal(t, 42, node.FormatValue(42))
+}
+
+func TestParseMibErrors(t *testing.T) {
+	tests := []string{
+		`FOO DEFINITIONS ::= BEGIN foo OBJECT IDENTIFIER ::= { bar 1 }`,
+		`FOO DEFINITIONS ::= BEGIN foo OBJECT IDENTIFIER ::= { } END`,
+		`FOO DEFINITIONS ::= BEGIN Foo ::= INTEGER { a(x) } END`,
+		`FOO DEFINITIONS ::= BEGIN foo OBJECT-TYPE DESCRIPTION "unterminated ::= { bar 1 } END`,
+	}
+	for _, tt := range tests {
+		_, err := parseMibFile([]byte(tt))
+		require.Error(t, err, tt)
+	}
+}
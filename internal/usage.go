@@ -14,6 +14,8 @@ The commands & flags are:
   version             print the version to stdout
 
   --aggregator-filter <filter>   filter the aggregators to enable, separator is :
+  --check-config                 check the configuration files, report all problems
+                                 found and exit with an error if there are any
   --config <file>                configuration file to load
   --config-directory <directory> directory containing additional *.conf files
   --plugin-directory             directory containing *.so files, this directory will be
@@ -26,6 +28,8 @@ The commands & flags are:
   --output-list                  print available output plugins.
   --pidfile <file>               file to write our pid to
   --pprof-addr <address>         pprof address to listen on, don't activate pprof if empty
+  --print-config                 print the effective configuration after merging all
+                                 configuration files and exit
   --processor-filter <filter>    filter the processors to enable, separator is :
   --quiet                        run in quiet mode
   --section-filter               filter config sections to output, separator is :
@@ -34,8 +38,11 @@ The commands & flags are:
   --sample-config                print out full sample configuration
   --once                         enable once mode: gather metrics once, write them, and exit
   --test                         enable test mode: gather metrics once and print them
-  --test-wait                    wait up to this many seconds for service
-                                 inputs to complete in test or once mode
+  --test-outputs                 enable test mode, printing the data each output would
+                                 write instead of the metrics; outputs without a
+                                 data_format are shown as line protocol
+  --test-wait                    wait up to this many seconds for service inputs and
+                                 aggregator periods to complete in test or once mode
   --usage <plugin>               print usage for a plugin, ie, 'telegraf --usage mysql'
   --version                      display the version and exit
 
@@ -50,6 +57,15 @@ Examples:
   # run a single telegraf collection, outputting metrics to stdout
   telegraf --config telegraf.conf --test
 
+  # show what each output would write after a minute of aggregation
+  telegraf --config telegraf.conf --test-outputs --test-wait 60
+
+  # check a config file for problems, eg. in CI
+  telegraf --config telegraf.conf --check-config
+
+  # show the configuration telegraf runs with
+  telegraf --config telegraf.conf --config-directory telegraf.d --print-config
+
   # run telegraf with all plugins defined in config file
   telegraf --config telegraf.conf
 
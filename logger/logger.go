@@ -149,7 +149,8 @@ func newLogWriter(config LogConfig) io.Writer {
 	if closer, isCloser := actualLogger.(io.Closer); isCloser {
 		closer.Close()
 	}
-	log.SetOutput(logWriter)
+	// Never write resolved secrets to the log
+	log.SetOutput(internal.RedactWriter(logWriter))
 	actualLogger = logWriter
 
 	return logWriter
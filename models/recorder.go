@@ -0,0 +1,146 @@
+package models
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/serializers/influx"
+)
+
+// recordFlushInterval is the maximum time recorded metrics are buffered
+// before they are written to the file.
+const recordFlushInterval = time.Second
+
+// Recorder appends metrics to a file as line protocol, the file is gzip
+// compressed if its name ends with ".gz".  The recording can be played back
+// with the replay input.
+type Recorder struct {
+	sync.Mutex
+	file       *os.File
+	gzip       *gzip.Writer
+	buf        *bufio.Writer
+	serializer *influx.Serializer
+
+	// pending is true if metrics were recorded since the last flush
+	pending  bool
+	flushErr error
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+// NewRecorder opens the file for appending recorded metrics, it is created if
+// it does not exist.  Recorded metrics are written to the file every
+// recordFlushInterval until the recorder is closed.
+func NewRecorder(path string) (*Recorder, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0640)
+	if err != nil {
+		return nil, err
+	}
+
+	serializer := influx.NewSerializer()
+	serializer.SetFieldTypeSupport(influx.UintSupport)
+
+	r := &Recorder{
+		file:       file,
+		serializer: serializer,
+		done:       make(chan struct{}),
+	}
+
+	var w io.Writer = file
+	if strings.HasSuffix(path, ".gz") {
+		// Appending starts a new gzip member, which readers decode as one
+		// stream.
+		r.gzip = gzip.NewWriter(file)
+		w = r.gzip
+	}
+	r.buf = bufio.NewWriter(w)
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		r.flushLoop()
+	}()
+	return r, nil
+}
+
+// Record appends the metric to the recording.  An error of a previous
+// flush of the recording is returned once.
+func (r *Recorder) Record(metric telegraf.Metric) error {
+	r.Lock()
+	defer r.Unlock()
+
+	if err := r.flushErr; err != nil {
+		r.flushErr = nil
+		return err
+	}
+
+	// The serializer reuses its buffer, so it is only used under the lock
+	octets, err := r.serializer.Serialize(metric)
+	if err != nil {
+		return err
+	}
+	if _, err := r.buf.Write(octets); err != nil {
+		return err
+	}
+	r.pending = true
+	return nil
+}
+
+func (r *Recorder) flushLoop() {
+	ticker := time.NewTicker(recordFlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			r.Lock()
+			if r.pending {
+				if err := r.flush(); err != nil {
+					r.flushErr = err
+				}
+			}
+			r.Unlock()
+		case <-r.done:
+			return
+		}
+	}
+}
+
+func (r *Recorder) flush() error {
+	r.pending = false
+	if err := r.buf.Flush(); err != nil {
+		return err
+	}
+	if r.gzip != nil {
+		return r.gzip.Flush()
+	}
+	return nil
+}
+
+// Close writes all buffered metrics and closes the file.
+func (r *Recorder) Close() error {
+	close(r.done)
+	r.wg.Wait()
+
+	r.Lock()
+	defer r.Unlock()
+
+	if err := r.buf.Flush(); err != nil {
+		r.file.Close()
+		return err
+	}
+	if r.gzip != nil {
+		if err := r.gzip.Close(); err != nil {
+			r.file.Close()
+			return err
+		}
+	}
+	return r.file.Close()
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecorderFlushesPeriodically(t *testing.T) {
+	dir, err := ioutil.TempDir("", "record")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "test.rec")
+	recorder, err := NewRecorder(path)
+	require.NoError(t, err)
+	defer recorder.Close()
+
+	require.NoError(t, recorder.Record(testutil.TestMetric(42)))
+
+	// The metric is written without further metrics being recorded
+	require.Eventually(t, func() bool {
+		recording, err := ioutil.ReadFile(path)
+		return err == nil && len(recording) > 0
+	}, 3*recordFlushInterval, 50*time.Millisecond)
+}
+
+func TestRecorderConcurrent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "record")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "test.rec")
+	recorder, err := NewRecorder(path)
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				assert.NoError(t, recorder.Record(testutil.TestMetric(i*100+j)))
+			}
+		}(i)
+	}
+	wg.Wait()
+	require.NoError(t, recorder.Close())
+
+	recording, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSuffix(string(recording), "\n"), "\n")
+	require.Len(t, lines, 800)
+	for _, line := range lines {
+		require.True(t, strings.HasPrefix(line, "test1,tag1=value1 value="), line)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/metric"
 	"github.com/influxdata/telegraf/selfstat"
 )
@@ -72,6 +73,8 @@ type AggregatorConfig struct {
 	Period       time.Duration
 	Delay        time.Duration
 	Grace        time.Duration
+	Schedule     *internal.Schedule
+	Timezone     *time.Location
 
 	NameOverride      string
 	MeasurementPrefix string
@@ -94,6 +97,21 @@ func (r *RunningAggregator) Init() error {
 	return nil
 }
 
+// Start starts the aggregator if it is a ServiceAggregator.
+func (r *RunningAggregator) Start() error {
+	if p, ok := r.Aggregator.(telegraf.ServiceAggregator); ok {
+		return p.Start()
+	}
+	return nil
+}
+
+// Stop stops the aggregator if it is a ServiceAggregator.
+func (r *RunningAggregator) Stop() {
+	if p, ok := r.Aggregator.(telegraf.ServiceAggregator); ok {
+		p.Stop()
+	}
+}
+
 func (r *RunningAggregator) Period() time.Duration {
 	return r.Config.Period
 }
@@ -102,6 +120,16 @@ func (r *RunningAggregator) EndPeriod() time.Time {
 	return r.periodEnd
 }
 
+// ScheduledPeriodEnd returns the end of the period starting at since for an
+// aggregator with a schedule.  If the schedule has no further runs the period
+// is not ended before the aggregator is stopped.
+func (r *RunningAggregator) ScheduledPeriodEnd(since time.Time) time.Time {
+	if next := r.Config.Schedule.Next(since); !next.IsZero() {
+		return next
+	}
+	return since.AddDate(100, 0, 0)
+}
+
 func (r *RunningAggregator) UpdateWindow(start, until time.Time) {
 	r.periodStart = start
 	r.periodEnd = until
@@ -165,6 +193,9 @@ func (r *RunningAggregator) Push(acc telegraf.Accumulator) {
 
 	since := r.periodEnd
 	until := r.periodEnd.Add(r.Config.Period)
+	if r.Config.Schedule != nil {
+		until = r.ScheduledPeriodEnd(since)
+	}
 	r.UpdateWindow(since, until)
 
 	r.push(acc)
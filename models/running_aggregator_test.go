@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/testutil"
 	"github.com/stretchr/testify/require"
 )
@@ -179,6 +180,48 @@ func TestAddAndPushOnePeriod(t *testing.T) {
 	acc.AssertContainsFields(t, "TestMetric", map[string]interface{}{"sum": int64(101)})
 }
 
+func TestPushUpdatesWindowFromSchedule(t *testing.T) {
+	schedule, err := internal.ParseSchedule("0 2 * * *", time.UTC)
+	require.NoError(t, err)
+
+	a := &TestAggregator{}
+	ra := NewRunningAggregator(a, &AggregatorConfig{
+		Name: "TestRunningAggregator",
+		Filter: Filter{
+			NamePass: []string{"*"},
+		},
+		Schedule: schedule,
+	})
+	require.NoError(t, ra.Config.Filter.Compile())
+	acc := testutil.Accumulator{}
+
+	start := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	ra.UpdateWindow(start, schedule.Next(start))
+	require.Equal(t, time.Date(2020, 6, 2, 2, 0, 0, 0, time.UTC), ra.EndPeriod())
+
+	ra.Push(&acc)
+	require.Equal(t, time.Date(2020, 6, 3, 2, 0, 0, 0, time.UTC), ra.EndPeriod())
+}
+
+func TestPushScheduleWithoutFurtherRuns(t *testing.T) {
+	schedule, err := internal.ParseSchedule("0 0 30 2 *", time.UTC)
+	require.NoError(t, err)
+
+	a := &TestAggregator{}
+	ra := NewRunningAggregator(a, &AggregatorConfig{
+		Name:     "TestRunningAggregator",
+		Schedule: schedule,
+	})
+	acc := testutil.Accumulator{}
+
+	start := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	ra.UpdateWindow(start, ra.ScheduledPeriodEnd(start))
+	ra.Push(&acc)
+
+	// The period does not end while the aggregator runs
+	require.True(t, ra.EndPeriod().After(start.AddDate(50, 0, 0)))
+}
+
 func TestAddDropOriginal(t *testing.T) {
 	ra := NewRunningAggregator(&TestAggregator{}, &AggregatorConfig{
 		Name: "TestRunningAggregator",
@@ -1,9 +1,12 @@
 package models
 
 import (
+	"context"
+	"fmt"
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/selfstat"
 )
 
@@ -18,9 +21,12 @@ type RunningInput struct {
 
 	log         telegraf.Logger
 	defaultTags map[string]string
+	recorder    *Recorder
 
 	MetricsGathered selfstat.Stat
 	GatherTime      selfstat.Stat
+	GatherTimeouts  selfstat.Stat
+	GathersSkipped  selfstat.Stat
 }
 
 func NewRunningInput(input telegraf.Input, config *InputConfig) *RunningInput {
@@ -50,6 +56,16 @@ func NewRunningInput(input telegraf.Input, config *InputConfig) *RunningInput {
 			"gather_time_ns",
 			tags,
 		),
+		GatherTimeouts: selfstat.Register(
+			"gather",
+			"gather_timeouts",
+			tags,
+		),
+		GathersSkipped: selfstat.Register(
+			"gather",
+			"gathers_skipped",
+			tags,
+		),
 		log: logger,
 	}
 }
@@ -61,6 +77,11 @@ type InputConfig struct {
 	Interval         time.Duration
 	CollectionJitter time.Duration
 	Precision        time.Duration
+	GatherTimeout    time.Duration
+	Schedule         *internal.Schedule
+	ActiveWindows    []internal.TimeWindow
+	Timezone         *time.Location
+	RecordFile       string
 
 	NameOverride      string
 	MeasurementPrefix string
@@ -87,6 +108,33 @@ func (r *RunningInput) Init() error {
 	return nil
 }
 
+// Open starts the recording of the metrics of the input if a record file is
+// set.  It is called when the input is started, not by Init, so checking the
+// config does not create the file.
+func (r *RunningInput) Open() error {
+	if r.Config.RecordFile == "" || r.recorder != nil {
+		return nil
+	}
+
+	recorder, err := NewRecorder(r.Config.RecordFile)
+	if err != nil {
+		return fmt.Errorf("could not open record file: %v", err)
+	}
+	r.recorder = recorder
+	return nil
+}
+
+// Close stops the recording of the metrics of the input.
+func (r *RunningInput) Close() {
+	if r.recorder == nil {
+		return
+	}
+	if err := r.recorder.Close(); err != nil {
+		r.log.Errorf("Error closing record file: %v", err)
+	}
+	r.recorder = nil
+}
+
 func (r *RunningInput) MakeMetric(metric telegraf.Metric) telegraf.Metric {
 	if ok := r.Config.Filter.Select(metric); !ok {
 		r.metricFiltered(metric)
@@ -107,14 +155,31 @@ func (r *RunningInput) MakeMetric(metric telegraf.Metric) telegraf.Metric {
 		return nil
 	}
 
+	if r.recorder != nil {
+		if err := r.recorder.Record(m); err != nil {
+			r.log.Errorf("Error recording metric: %v", err)
+		}
+	}
+
 	r.MetricsGathered.Incr(1)
 	GlobalMetricsGathered.Incr(1)
 	return m
 }
 
 func (r *RunningInput) Gather(acc telegraf.Accumulator) error {
+	return r.GatherContext(context.Background(), acc)
+}
+
+// GatherContext gathers the input, the gather is only cancelled when ctx is
+// done if the input implements telegraf.ContextInput.
+func (r *RunningInput) GatherContext(ctx context.Context, acc telegraf.Accumulator) error {
 	start := time.Now()
-	err := r.Input.Gather(acc)
+	var err error
+	if ci, ok := r.Input.(telegraf.ContextInput); ok {
+		err = ci.GatherContext(ctx, acc)
+	} else {
+		err = r.Input.Gather(acc)
+	}
 	elapsed := time.Since(start)
 	r.GatherTime.Incr(elapsed.Nanoseconds())
 	return err
@@ -1,6 +1,9 @@
 package models
 
 import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -292,3 +295,45 @@ type testInput struct{}
 func (t *testInput) Description() string                   { return "" }
 func (t *testInput) SampleConfig() string                  { return "" }
 func (t *testInput) Gather(acc telegraf.Accumulator) error { return nil }
+
+func TestMakeMetricRecorded(t *testing.T) {
+	dir, err := ioutil.TempDir("", "record")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "test.rec")
+	ri := NewRunningInput(&testInput{}, &InputConfig{
+		Name:              "TestRunningInput",
+		MeasurementPrefix: "foo_",
+		RecordFile:        path,
+		Filter: Filter{
+			FieldDrop: []string{"dropped"},
+		},
+	})
+	require.NoError(t, ri.Config.Filter.Compile())
+	require.NoError(t, ri.Init())
+	_, err = os.Stat(path)
+	require.True(t, os.IsNotExist(err))
+	require.NoError(t, ri.Open())
+
+	for _, value := range []int64{101, 102} {
+		m, err := metric.New("RITest",
+			map[string]string{"tag": "value"},
+			map[string]interface{}{
+				"value":   value,
+				"dropped": true,
+			},
+			time.Unix(value, 0))
+		require.NoError(t, err)
+		require.NotNil(t, ri.MakeMetric(m))
+	}
+	ri.Close()
+
+	// Metrics are recorded as emitted by the input, after its modifiers
+	recording, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t,
+		"foo_RITest,tag=value value=101i 101000000000\n"+
+			"foo_RITest,tag=value value=102i 102000000000\n",
+		string(recording))
+}
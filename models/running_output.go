@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/serializers"
 	"github.com/influxdata/telegraf/selfstat"
 )
 
@@ -31,6 +32,9 @@ type OutputConfig struct {
 	NameOverride string
 	NamePrefix   string
 	NameSuffix   string
+
+	// Serializer is the data format of outputs supporting them
+	Serializer serializers.Serializer
 }
 
 // RunningOutput contains the output configuration
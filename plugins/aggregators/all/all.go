@@ -2,7 +2,9 @@ package all
 
 import (
 	_ "github.com/influxdata/telegraf/plugins/aggregators/basicstats"
+	_ "github.com/influxdata/telegraf/plugins/aggregators/execd"
 	_ "github.com/influxdata/telegraf/plugins/aggregators/final"
+	_ "github.com/influxdata/telegraf/plugins/aggregators/grpc_plugin"
 	_ "github.com/influxdata/telegraf/plugins/aggregators/histogram"
 	_ "github.com/influxdata/telegraf/plugins/aggregators/merge"
 	_ "github.com/influxdata/telegraf/plugins/aggregators/minmax"
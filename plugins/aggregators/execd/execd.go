@@ -0,0 +1,222 @@
+package execd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/internal/process"
+	"github.com/influxdata/telegraf/plugins/aggregators"
+	"github.com/influxdata/telegraf/plugins/parsers"
+	"github.com/influxdata/telegraf/plugins/serializers"
+)
+
+// Commands written to the process at the end of each period, and the marker
+// the process writes after the aggregates of a push.  These are the same as
+// in the shim of plugins/common/shim.
+const (
+	pushCommand  = "#telegraf:push"
+	resetCommand = "#telegraf:reset"
+	pushedMarker = "#telegraf:pushed"
+)
+
+const sampleConfig = `
+  ## Program to run as daemon
+  ## eg: command = ["/path/to/your_program", "arg1", "arg2"]
+  command = ["/path/to/your_program"]
+
+  ## Delay before the process is restarted after an unexpected termination
+  # restart_delay = "10s"
+
+  ## Maximum time to wait for the aggregates of the process after asking it
+  ## to push.
+  # push_timeout = "10s"
+
+  ## General Aggregator Arguments:
+  ## The period on which to flush & clear the aggregator.
+  period = "30s"
+  ## If true, the original metric will be dropped by the
+  ## aggregator and will not get sent to the output plugins.
+  drop_original = false
+`
+
+type Execd struct {
+	Command      []string        `toml:"command"`
+	RestartDelay config.Duration `toml:"restart_delay"`
+	PushTimeout  config.Duration `toml:"push_timeout"`
+	Log          telegraf.Logger
+
+	parser     parsers.Parser
+	serializer serializers.Serializer
+	process    *process.Process
+
+	// metrics read from the process since the last push, and a signal for
+	// each pushed marker
+	sync.Mutex
+	pending []telegraf.Metric
+	pushed  chan struct{}
+}
+
+func New() *Execd {
+	return &Execd{
+		RestartDelay: config.Duration(10 * time.Second),
+		PushTimeout:  config.Duration(10 * time.Second),
+	}
+}
+
+func (e *Execd) SampleConfig() string {
+	return sampleConfig
+}
+
+func (e *Execd) Description() string {
+	return "Run executable as long-running aggregator plugin"
+}
+
+func (e *Execd) Init() error {
+	if len(e.Command) == 0 {
+		return errors.New("no command specified")
+	}
+
+	var err error
+	e.parser, err = parsers.NewInfluxParser()
+	if err != nil {
+		return fmt.Errorf("error creating parser: %w", err)
+	}
+	e.serializer, err = serializers.NewInfluxSerializer()
+	if err != nil {
+		return fmt.Errorf("error creating serializer: %w", err)
+	}
+	e.pushed = make(chan struct{}, 1)
+	return nil
+}
+
+func (e *Execd) Start() error {
+	var err error
+	e.process, err = process.New(e.Command)
+	if err != nil {
+		return fmt.Errorf("error creating new process: %w", err)
+	}
+	e.process.Log = e.Log
+	e.process.RestartDelay = time.Duration(e.RestartDelay)
+	e.process.ReadStdoutFn = e.cmdReadOut
+	e.process.ReadStderrFn = e.cmdReadErr
+
+	if err = e.process.Start(); err != nil {
+		// if there was only one argument, and it contained spaces, warn the user
+		// that they may have configured it wrong.
+		if len(e.Command) == 1 && strings.Contains(e.Command[0], " ") {
+			e.Log.Warn("The aggregators.execd Command contained spaces but no arguments. " +
+				"This setting expects the program and arguments as an array of strings, " +
+				"not as a space-delimited string. See the plugin readme for an example.")
+		}
+		return fmt.Errorf("failed to start process %s: %w", e.Command, err)
+	}
+
+	return nil
+}
+
+func (e *Execd) Stop() {
+	e.process.Stop()
+}
+
+func (e *Execd) Add(m telegraf.Metric) {
+	b, err := e.serializer.Serialize(m)
+	if err != nil {
+		e.Log.Errorf("Metric serializing error: %v", err)
+		return
+	}
+
+	if _, err = e.process.Stdin.Write(b); err != nil {
+		e.Log.Errorf("Error writing to process stdin: %v", err)
+	}
+}
+
+func (e *Execd) Push(acc telegraf.Accumulator) {
+	// Discard a marker left over from a push that timed out
+	select {
+	case <-e.pushed:
+	default:
+	}
+
+	if err := e.writeCommand(pushCommand); err != nil {
+		e.Log.Errorf("Error writing to process stdin: %v", err)
+		return
+	}
+
+	select {
+	case <-e.pushed:
+	case <-time.After(time.Duration(e.PushTimeout)):
+		e.Log.Errorf("Timeout after %s waiting for the process to push", time.Duration(e.PushTimeout))
+	}
+
+	e.Lock()
+	metrics := e.pending
+	e.pending = nil
+	e.Unlock()
+
+	for _, m := range metrics {
+		acc.AddMetric(m)
+	}
+}
+
+func (e *Execd) Reset() {
+	if err := e.writeCommand(resetCommand); err != nil {
+		e.Log.Errorf("Error writing to process stdin: %v", err)
+	}
+}
+
+func (e *Execd) writeCommand(command string) error {
+	_, err := io.WriteString(e.process.Stdin, command+"\n")
+	return err
+}
+
+func (e *Execd) cmdReadOut(out io.Reader) {
+	scanner := bufio.NewScanner(out)
+
+	for scanner.Scan() {
+		if scanner.Text() == pushedMarker {
+			select {
+			case e.pushed <- struct{}{}:
+			default:
+			}
+			continue
+		}
+
+		metrics, err := e.parser.Parse(scanner.Bytes())
+		if err != nil {
+			e.Log.Errorf("Parse error: %s", err)
+		}
+
+		e.Lock()
+		e.pending = append(e.pending, metrics...)
+		e.Unlock()
+	}
+
+	if err := scanner.Err(); err != nil {
+		e.Log.Errorf("Error reading stdout: %s", err)
+	}
+}
+
+func (e *Execd) cmdReadErr(out io.Reader) {
+	scanner := bufio.NewScanner(out)
+
+	for scanner.Scan() {
+		e.Log.Errorf("stderr: %q", scanner.Text())
+	}
+
+	if err := scanner.Err(); err != nil {
+		e.Log.Errorf("Error reading stderr: %s", err)
+	}
+}
+
+func init() {
+	aggregators.Add("execd", func() telegraf.Aggregator {
+		return New()
+	})
+}
@@ -0,0 +1,145 @@
+package execd
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/common/shim"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProtocolMatchesShim(t *testing.T) {
+	require.Equal(t, shim.PushCommand, pushCommand)
+	require.Equal(t, shim.ResetCommand, resetCommand)
+	require.Equal(t, shim.PushedMarker, pushedMarker)
+}
+
+func TestExternalAggregatorWorks(t *testing.T) {
+	exe, err := os.Executable()
+	require.NoError(t, err)
+
+	e := New()
+	e.Log = testutil.Logger{}
+	e.Command = []string{exe, "-counter"}
+	e.RestartDelay = config.Duration(5 * time.Second)
+	require.NoError(t, e.Init())
+	require.NoError(t, e.Start())
+	defer e.Stop()
+
+	now := time.Now()
+	add := func(n int) {
+		for i := 0; i < n; i++ {
+			m, err := metric.New("test",
+				map[string]string{"city": "Toronto"},
+				map[string]interface{}{"population": 6000000},
+				now)
+			require.NoError(t, err)
+			e.Add(m)
+		}
+	}
+
+	acc := &testutil.Accumulator{}
+	add(3)
+	e.Push(acc)
+	e.Reset()
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{
+		testutil.MustMetric("test_count",
+			map[string]string{"city": "Toronto"},
+			map[string]interface{}{"count": int64(3)},
+			now),
+	}, acc.GetTelegrafMetrics())
+
+	acc.ClearMetrics()
+	add(1)
+	e.Push(acc)
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{
+		testutil.MustMetric("test_count",
+			map[string]string{"city": "Toronto"},
+			map[string]interface{}{"count": int64(1)},
+			now),
+	}, acc.GetTelegrafMetrics())
+}
+
+func TestPushTimeout(t *testing.T) {
+	e := New()
+	e.Log = testutil.Logger{}
+	e.Command = []string{"cat"}
+	e.PushTimeout = config.Duration(100 * time.Millisecond)
+	require.NoError(t, e.Init())
+	require.NoError(t, e.Start())
+	defer e.Stop()
+
+	// cat echoes the push command but never the pushed marker
+	acc := &testutil.Accumulator{}
+	e.Push(acc)
+	require.Empty(t, acc.GetTelegrafMetrics())
+}
+
+var counter = flag.Bool("counter", false,
+	"if true, act like an aggregator counting the metrics of each series")
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+	if *counter {
+		runCounterProgram()
+		os.Exit(0)
+	}
+	code := m.Run()
+	os.Exit(code)
+}
+
+func runCounterProgram() {
+	s := shim.New()
+	if err := s.AddAggregator(&countAggregator{}); err != nil {
+		fmt.Fprintf(os.Stderr, "ERR %v\n", err)
+		os.Exit(1)
+	}
+	if err := s.Run(shim.PollIntervalDisabled); err != nil {
+		fmt.Fprintf(os.Stderr, "ERR %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// countAggregator counts the metrics of each series.
+type countAggregator struct {
+	series map[uint64]telegraf.Metric
+	counts map[uint64]int64
+}
+
+func (a *countAggregator) Add(in telegraf.Metric) {
+	if a.series == nil {
+		a.Reset()
+	}
+	id := in.HashID()
+	if _, ok := a.series[id]; !ok {
+		a.series[id] = in
+	}
+	a.counts[id]++
+}
+
+func (a *countAggregator) Push(acc telegraf.Accumulator) {
+	for id, m := range a.series {
+		acc.AddFields(m.Name()+"_count",
+			map[string]interface{}{"count": a.counts[id]}, m.Tags(), m.Time())
+	}
+}
+
+func (a *countAggregator) Reset() {
+	a.series = make(map[uint64]telegraf.Metric)
+	a.counts = make(map[uint64]int64)
+}
+
+func (a *countAggregator) SampleConfig() string {
+	return ""
+}
+
+func (a *countAggregator) Description() string {
+	return ""
+}
@@ -0,0 +1,166 @@
+package grpc_plugin
+
+import (
+	"context"
+	"sync"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/aggregators"
+	"github.com/influxdata/telegraf/plugins/common/grpcplugin"
+	"github.com/influxdata/telegraf/plugins/common/grpcplugin/pluginv1"
+)
+
+// batchSize is the number of metrics buffered before adding them to the
+// plugin.
+const batchSize = 100
+
+const sampleConfig = `
+  ## Program to run as plugin, it must serve the plugin on the socket passed
+  ## in the TELEGRAF_PLUGIN_SOCKET environment variable.
+  command = ["telegraf-quantile-plugin"]
+
+  ## Delay before the process is restarted after an unexpected termination
+  # restart_delay = "10s"
+
+  ## Maximum time to wait for the plugin to serve after the process started
+  # start_timeout = "10s"
+
+  ## Interval of the health checks, the process is restarted when a check
+  ## fails.  Set to "0s" to disable the health checks.
+  # health_check_interval = "30s"
+
+  ## General Aggregator Arguments:
+  ## The period on which to flush & clear the aggregator.
+  period = "30s"
+  ## If true, the original metric will be dropped by the
+  ## aggregator and will not get sent to the output plugins.
+  drop_original = false
+
+  ## Settings of the plugin
+  # [aggregators.grpc_plugin.config]
+  #   quantiles = [0.5, 0.99]
+`
+
+type GRPCPlugin struct {
+	grpcplugin.Config
+	Log telegraf.Logger `toml:"-"`
+
+	client *grpcplugin.Client
+
+	sync.Mutex
+	pending []*pluginv1.Metric
+}
+
+func (g *GRPCPlugin) SampleConfig() string {
+	return sampleConfig
+}
+
+func (g *GRPCPlugin) Description() string {
+	return "Run an external aggregator plugin over gRPC"
+}
+
+func (g *GRPCPlugin) Init() error {
+	client, err := g.NewClient(pluginv1.PluginType_PLUGIN_TYPE_AGGREGATOR, g.Log)
+	if err != nil {
+		return err
+	}
+	client.OnReady = func(ctx context.Context, plugin pluginv1.PluginClient, info *pluginv1.InitResponse) error {
+		_, err := plugin.Start(ctx, &pluginv1.StartRequest{})
+		return err
+	}
+	g.client = client
+	return nil
+}
+
+func (g *GRPCPlugin) Start() error {
+	return g.client.Start()
+}
+
+func (g *GRPCPlugin) Stop() {
+	if ctx, plugin, err := g.client.Plugin(); err == nil {
+		if _, err := plugin.Stop(ctx, &pluginv1.StopRequest{}); err != nil {
+			g.Log.Errorf("Stopping plugin: %v", err)
+		}
+	}
+	g.client.Stop()
+}
+
+func (g *GRPCPlugin) Add(m telegraf.Metric) {
+	pm, err := grpcplugin.ToProto(m)
+	if err != nil {
+		g.Log.Error(err)
+		return
+	}
+
+	g.Lock()
+	defer g.Unlock()
+
+	g.pending = append(g.pending, pm)
+	if len(g.pending) >= batchSize {
+		g.flush()
+	}
+}
+
+// flush adds the buffered metrics to the plugin.
+func (g *GRPCPlugin) flush() {
+	if len(g.pending) == 0 {
+		return
+	}
+
+	batch := &pluginv1.Metrics{Metrics: g.pending}
+	g.pending = nil
+
+	ctx, plugin, err := g.client.Plugin()
+	if err != nil {
+		g.Log.Errorf("Dropped %d metrics: %v", len(batch.Metrics), err)
+		return
+	}
+	if _, err := plugin.Add(ctx, batch); err != nil {
+		g.Log.Errorf("Dropped %d metrics: %v", len(batch.Metrics), err)
+	}
+}
+
+func (g *GRPCPlugin) Push(acc telegraf.Accumulator) {
+	g.Lock()
+	g.flush()
+	g.Unlock()
+
+	ctx, plugin, err := g.client.Plugin()
+	if err != nil {
+		acc.AddError(err)
+		return
+	}
+
+	batch, err := plugin.Push(ctx, &pluginv1.PushRequest{})
+	if err != nil {
+		acc.AddError(err)
+		return
+	}
+	for _, pm := range batch.Metrics {
+		m, err := grpcplugin.FromProto(pm)
+		if err != nil {
+			acc.AddError(err)
+			continue
+		}
+		acc.AddMetric(m)
+	}
+}
+
+func (g *GRPCPlugin) Reset() {
+	ctx, plugin, err := g.client.Plugin()
+	if err != nil {
+		g.Log.Error(err)
+		return
+	}
+	if _, err := plugin.Reset(ctx, &pluginv1.ResetRequest{}); err != nil {
+		g.Log.Error(err)
+	}
+}
+
+func init() {
+	aggregators.Add("grpc_plugin", func() telegraf.Aggregator {
+		return &GRPCPlugin{
+			Config: grpcplugin.DefaultConfig(),
+		}
+	})
+}
@@ -0,0 +1,112 @@
+package grpc_plugin
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/common/grpcplugin"
+	"github.com/influxdata/telegraf/plugins/common/grpcplugin/server"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAggregate(t *testing.T) {
+	exe, err := os.Executable()
+	require.NoError(t, err)
+
+	g := &GRPCPlugin{Config: grpcplugin.DefaultConfig()}
+	g.Log = testutil.Logger{}
+	g.Command = []string{exe, "-counter"}
+	require.NoError(t, g.Init())
+	require.NoError(t, g.Start())
+	defer g.Stop()
+
+	now := time.Now()
+	add := func(n int) {
+		for i := 0; i < n; i++ {
+			g.Add(testutil.MustMetric("test",
+				map[string]string{"city": "Toronto"},
+				map[string]interface{}{"population": 6000000},
+				now))
+		}
+	}
+
+	// more than a batch to add some of the metrics before the push
+	acc := &testutil.Accumulator{}
+	add(batchSize + 3)
+	g.Push(acc)
+	g.Reset()
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{
+		testutil.MustMetric("test_count",
+			map[string]string{"city": "Toronto"},
+			map[string]interface{}{"count": int64(batchSize + 3)},
+			now),
+	}, acc.GetTelegrafMetrics())
+
+	acc.ClearMetrics()
+	add(1)
+	g.Push(acc)
+	testutil.RequireMetricsEqual(t, []telegraf.Metric{
+		testutil.MustMetric("test_count",
+			map[string]string{"city": "Toronto"},
+			map[string]interface{}{"count": int64(1)},
+			now),
+	}, acc.GetTelegrafMetrics())
+}
+
+var counter = flag.Bool("counter", false,
+	"if true, act like an aggregator counting the metrics of each series")
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+	if *counter {
+		if err := server.Serve(&countAggregator{}); err != nil {
+			fmt.Fprintf(os.Stderr, "ERR %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	code := m.Run()
+	os.Exit(code)
+}
+
+// countAggregator counts the metrics of each series.
+type countAggregator struct {
+	series map[uint64]telegraf.Metric
+	counts map[uint64]int64
+}
+
+func (a *countAggregator) Add(in telegraf.Metric) {
+	if a.series == nil {
+		a.Reset()
+	}
+	id := in.HashID()
+	if _, ok := a.series[id]; !ok {
+		a.series[id] = in
+	}
+	a.counts[id]++
+}
+
+func (a *countAggregator) Push(acc telegraf.Accumulator) {
+	for id, m := range a.series {
+		acc.AddFields(m.Name()+"_count",
+			map[string]interface{}{"count": a.counts[id]}, m.Tags(), m.Time())
+	}
+}
+
+func (a *countAggregator) Reset() {
+	a.series = make(map[uint64]telegraf.Metric)
+	a.counts = make(map[uint64]int64)
+}
+
+func (a *countAggregator) SampleConfig() string {
+	return ""
+}
+
+func (a *countAggregator) Description() string {
+	return ""
+}
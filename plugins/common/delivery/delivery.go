@@ -0,0 +1,73 @@
+// Package delivery keeps track of the metrics of a batch that were already
+// delivered by an output.
+//
+// RunningOutput retries a failed batch as a whole.  Outputs sending a batch
+// in several parts, or to several targets, use a Tracker to remember the
+// metrics delivered before the failure, so that only the remaining metrics
+// are sent again when the batch is retried.
+package delivery
+
+import (
+	"github.com/influxdata/telegraf"
+)
+
+// Tracker holds the delivered metrics of the batch being written.  The zero
+// value is ready to use.
+type Tracker struct {
+	delivered map[telegraf.Metric]bool
+}
+
+// Add marks the metrics as delivered.
+func (t *Tracker) Add(metrics ...telegraf.Metric) {
+	if t.delivered == nil {
+		t.delivered = make(map[telegraf.Metric]bool, len(metrics))
+	}
+	for _, m := range metrics {
+		t.delivered[m] = true
+	}
+}
+
+// Delivered returns true if the metric was marked as delivered.
+func (t *Tracker) Delivered(m telegraf.Metric) bool {
+	return t.delivered[m]
+}
+
+// Prune forgets the delivered metrics which are not part of the batch.  A
+// metric missing from a retried batch was dropped from the output buffer and
+// is never written again.
+func (t *Tracker) Prune(batch []telegraf.Metric) {
+	if len(t.delivered) == 0 {
+		return
+	}
+
+	kept := make(map[telegraf.Metric]bool)
+	for _, m := range batch {
+		if t.delivered[m] {
+			kept[m] = true
+		}
+	}
+	t.delivered = kept
+}
+
+// Pending prunes the tracker to the batch and returns the metrics of the
+// batch which were not delivered yet.
+func (t *Tracker) Pending(batch []telegraf.Metric) []telegraf.Metric {
+	t.Prune(batch)
+	if len(t.delivered) == 0 {
+		return batch
+	}
+
+	pending := make([]telegraf.Metric, 0, len(batch)-len(t.delivered))
+	for _, m := range batch {
+		if !t.delivered[m] {
+			pending = append(pending, m)
+		}
+	}
+	return pending
+}
+
+// Reset forgets all delivered metrics, it is called once the batch was
+// written completely.
+func (t *Tracker) Reset() {
+	t.delivered = nil
+}
@@ -0,0 +1,31 @@
+package delivery
+
+import (
+	"testing"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTracker(t *testing.T) {
+	m1 := testutil.TestMetric(1)
+	m2 := testutil.TestMetric(2)
+	m3 := testutil.TestMetric(3)
+
+	var tracker Tracker
+	batch := []telegraf.Metric{m1, m2, m3}
+	require.Equal(t, batch, tracker.Pending(batch))
+
+	tracker.Add(m1, m2)
+	require.True(t, tracker.Delivered(m1))
+	require.Equal(t, []telegraf.Metric{m3}, tracker.Pending(batch))
+
+	// Metrics dropped from the batch are forgotten
+	require.Equal(t, []telegraf.Metric{m3}, tracker.Pending([]telegraf.Metric{m2, m3}))
+	require.False(t, tracker.Delivered(m1))
+	require.True(t, tracker.Delivered(m2))
+
+	tracker.Reset()
+	require.False(t, tracker.Delivered(m2))
+}
@@ -0,0 +1,368 @@
+package grpcplugin
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/config"
+	"github.com/influxdata/telegraf/internal/process"
+	"github.com/influxdata/telegraf/plugins/common/grpcplugin/pluginv1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// ProtocolVersion is the version of the protocol implemented by this package.
+const ProtocolVersion = 1
+
+// SocketEnv is the environment variable holding the path of the Unix socket
+// the plugin process must listen on.
+const SocketEnv = "TELEGRAF_PLUGIN_SOCKET"
+
+// Config is the configuration shared by all external gRPC plugins.
+type Config struct {
+	Command             []string               `toml:"command"`
+	RestartDelay        config.Duration        `toml:"restart_delay"`
+	StartTimeout        config.Duration        `toml:"start_timeout"`
+	HealthCheckInterval config.Duration        `toml:"health_check_interval"`
+	Config              map[string]interface{} `toml:"config"`
+}
+
+// DefaultConfig returns the default settings.
+func DefaultConfig() Config {
+	return Config{
+		RestartDelay:        config.Duration(10 * time.Second),
+		StartTimeout:        config.Duration(10 * time.Second),
+		HealthCheckInterval: config.Duration(30 * time.Second),
+	}
+}
+
+// NewClient creates the client of a plugin of the given type.
+func (c *Config) NewClient(typ pluginv1.PluginType, log telegraf.Logger) (*Client, error) {
+	if len(c.Command) == 0 {
+		return nil, errors.New("no command specified")
+	}
+
+	var buf bytes.Buffer
+	if err := toml.NewEncoder(&buf).Encode(c.Config); err != nil {
+		return nil, fmt.Errorf("encoding plugin config: %w", err)
+	}
+
+	return &Client{
+		cfg:    *c,
+		typ:    typ,
+		config: buf.String(),
+		log:    log,
+	}, nil
+}
+
+// Client runs an external plugin as child process and connects to it.  The
+// process is restarted when it exits or fails the health checks; the plugin
+// is initialized again on the next call after a restart.
+type Client struct {
+	// OnReady is called after the plugin has been initialized, each time the
+	// process was started.  The context is canceled when the connection to
+	// the process is closed.
+	OnReady func(ctx context.Context, plugin pluginv1.PluginClient, info *pluginv1.InitResponse) error
+
+	cfg    Config
+	typ    pluginv1.PluginType
+	config string
+	log    telegraf.Logger
+
+	process *process.Process
+	dir     string
+	socket  string
+
+	sync.Mutex
+	conn       *grpc.ClientConn
+	plugin     pluginv1.PluginClient
+	health     healthpb.HealthClient
+	pid        int
+	connCtx    context.Context
+	connCancel context.CancelFunc
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// Start starts the plugin process and initializes the plugin.
+func (c *Client) Start() error {
+	var err error
+	c.dir, err = ioutil.TempDir("", "telegraf-plugin")
+	if err != nil {
+		return err
+	}
+	c.socket = filepath.Join(c.dir, "plugin.sock")
+
+	c.process, err = process.New(c.cfg.Command)
+	if err != nil {
+		return fmt.Errorf("error creating new process: %w", err)
+	}
+	c.process.Log = c.log
+	c.process.RestartDelay = time.Duration(c.cfg.RestartDelay)
+	c.process.Env = []string{SocketEnv + "=" + c.socket}
+	c.process.ReadStdoutFn = c.cmdReadOut
+	c.process.ReadStderrFn = c.cmdReadErr
+
+	c.ctx, c.cancel = context.WithCancel(context.Background())
+
+	if err := c.process.Start(); err != nil {
+		// if there was only one argument, and it contained spaces, warn the user
+		// that they may have configured it wrong.
+		if len(c.cfg.Command) == 1 && strings.Contains(c.cfg.Command[0], " ") {
+			c.log.Warn("The Command contained spaces but no arguments. " +
+				"This setting expects the program and arguments as an array of strings, " +
+				"not as a space-delimited string. See the plugin readme for an example.")
+		}
+		c.cancel()
+		os.RemoveAll(c.dir)
+		return fmt.Errorf("failed to start process %s: %w", c.cfg.Command, err)
+	}
+
+	c.Lock()
+	err = c.connect()
+	c.Unlock()
+	if err != nil {
+		c.Stop()
+		return err
+	}
+
+	if c.cfg.HealthCheckInterval > 0 {
+		c.wg.Add(1)
+		go func() {
+			defer c.wg.Done()
+			c.checkHealth()
+		}()
+	}
+	return nil
+}
+
+// Stop closes the connection and stops the plugin process.
+func (c *Client) Stop() {
+	c.cancel()
+	c.wg.Wait()
+
+	c.Lock()
+	c.disconnect()
+	c.Unlock()
+
+	c.process.Stop()
+	os.RemoveAll(c.dir)
+}
+
+// Plugin returns the client of the plugin and the context of the connection,
+// reconnecting if the process was restarted.
+func (c *Client) Plugin() (context.Context, pluginv1.PluginClient, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	if err := c.connect(); err != nil {
+		return nil, nil, err
+	}
+	return c.connCtx, c.plugin, nil
+}
+
+// connect connects to the current process and initializes the plugin, unless
+// already connected to it.
+func (c *Client) connect() error {
+	pid := c.process.Pid()
+	if c.conn != nil && c.pid == pid {
+		return nil
+	}
+	c.disconnect()
+
+	ctx, cancel := context.WithTimeout(c.ctx, time.Duration(c.cfg.StartTimeout))
+	defer cancel()
+
+	conn, err := grpc.DialContext(ctx, c.socket,
+		grpc.WithInsecure(),
+		grpc.WithBlock(),
+		// the process needs a moment to listen, retry quickly meanwhile
+		grpc.WithConnectParams(grpc.ConnectParams{
+			Backoff: backoff.Config{
+				BaseDelay:  50 * time.Millisecond,
+				Multiplier: 1.6,
+				Jitter:     0.2,
+				MaxDelay:   time.Second,
+			},
+		}),
+		grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, "unix", addr)
+		}),
+	)
+	if err != nil {
+		return fmt.Errorf("connecting to plugin: %w", err)
+	}
+
+	health := healthpb.NewHealthClient(conn)
+	for {
+		resp, err := health.Check(ctx, &healthpb.HealthCheckRequest{})
+		if err == nil && resp.Status == healthpb.HealthCheckResponse_SERVING {
+			break
+		}
+
+		select {
+		case <-ctx.Done():
+			conn.Close()
+			return fmt.Errorf("plugin not serving: %v", err)
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+
+	plugin := pluginv1.NewPluginClient(conn)
+	connCtx, connCancel := context.WithCancel(c.ctx)
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		c.readLogs(connCtx, plugin)
+	}()
+
+	info, err := plugin.Init(ctx, &pluginv1.InitRequest{
+		ProtocolVersion: ProtocolVersion,
+		Type:            c.typ,
+		Config:          c.config,
+	})
+	if err == nil && info.ProtocolVersion != ProtocolVersion {
+		err = fmt.Errorf("unsupported protocol version %d", info.ProtocolVersion)
+	}
+	if err == nil && info.Type != c.typ {
+		err = fmt.Errorf("expected plugin type %s but got %s", c.typ, info.Type)
+	}
+	if err == nil && c.OnReady != nil {
+		err = c.OnReady(connCtx, plugin, info)
+	}
+	if err != nil {
+		connCancel()
+		conn.Close()
+		return fmt.Errorf("initializing plugin: %w", err)
+	}
+
+	c.conn = conn
+	c.plugin = plugin
+	c.health = health
+	c.pid = pid
+	c.connCtx = connCtx
+	c.connCancel = connCancel
+	return nil
+}
+
+func (c *Client) disconnect() {
+	if c.conn == nil {
+		return
+	}
+	c.connCancel()
+	c.conn.Close()
+	c.conn = nil
+	c.plugin = nil
+	c.health = nil
+}
+
+// checkHealth kills the process when it fails a health check, the process
+// is then restarted after the restart delay.
+func (c *Client) checkHealth() {
+	ticker := time.NewTicker(time.Duration(c.cfg.HealthCheckInterval))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		err := c.check()
+		if err == nil || c.ctx.Err() != nil {
+			continue
+		}
+
+		c.log.Errorf("Health check failed: %v", err)
+		if pid := c.process.Pid(); pid != 0 {
+			if p, err := os.FindProcess(pid); err == nil {
+				p.Kill()
+			}
+		}
+	}
+}
+
+func (c *Client) check() error {
+	c.Lock()
+	defer c.Unlock()
+
+	if err := c.connect(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(c.ctx, time.Duration(c.cfg.StartTimeout))
+	defer cancel()
+
+	resp, err := c.health.Check(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		return err
+	}
+	if resp.Status != healthpb.HealthCheckResponse_SERVING {
+		return fmt.Errorf("plugin is %s", resp.Status)
+	}
+	return nil
+}
+
+func (c *Client) readLogs(ctx context.Context, plugin pluginv1.PluginClient) {
+	stream, err := plugin.Logs(ctx, &pluginv1.LogsRequest{})
+	if err != nil {
+		return
+	}
+
+	for {
+		entry, err := stream.Recv()
+		if err != nil {
+			return
+		}
+
+		switch entry.Level {
+		case pluginv1.LogEntry_LEVEL_ERROR:
+			c.log.Error(entry.Message)
+		case pluginv1.LogEntry_LEVEL_WARN:
+			c.log.Warn(entry.Message)
+		case pluginv1.LogEntry_LEVEL_DEBUG:
+			c.log.Debug(entry.Message)
+		default:
+			c.log.Info(entry.Message)
+		}
+	}
+}
+
+func (c *Client) cmdReadOut(out io.Reader) {
+	scanner := bufio.NewScanner(out)
+
+	for scanner.Scan() {
+		c.log.Info(scanner.Text())
+	}
+}
+
+func (c *Client) cmdReadErr(out io.Reader) {
+	scanner := bufio.NewScanner(out)
+
+	for scanner.Scan() {
+		c.log.Errorf("stderr: %q", scanner.Text())
+	}
+
+	if err := scanner.Err(); err != nil {
+		c.log.Errorf("Error reading stderr: %s", err)
+	}
+}
@@ -0,0 +1,124 @@
+package grpcplugin
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/common/grpcplugin/pluginv1"
+)
+
+var toValueType = map[pluginv1.Metric_Type]telegraf.ValueType{
+	pluginv1.Metric_TYPE_UNTYPED:   telegraf.Untyped,
+	pluginv1.Metric_TYPE_COUNTER:   telegraf.Counter,
+	pluginv1.Metric_TYPE_GAUGE:     telegraf.Gauge,
+	pluginv1.Metric_TYPE_SUMMARY:   telegraf.Summary,
+	pluginv1.Metric_TYPE_HISTOGRAM: telegraf.Histogram,
+}
+
+var fromValueType = map[telegraf.ValueType]pluginv1.Metric_Type{
+	telegraf.Untyped:   pluginv1.Metric_TYPE_UNTYPED,
+	telegraf.Counter:   pluginv1.Metric_TYPE_COUNTER,
+	telegraf.Gauge:     pluginv1.Metric_TYPE_GAUGE,
+	telegraf.Summary:   pluginv1.Metric_TYPE_SUMMARY,
+	telegraf.Histogram: pluginv1.Metric_TYPE_HISTOGRAM,
+}
+
+// ToProto converts a metric to its protocol representation.
+func ToProto(m telegraf.Metric) (*pluginv1.Metric, error) {
+	pm := &pluginv1.Metric{
+		Name:      m.Name(),
+		Tags:      m.Tags(),
+		Fields:    make([]*pluginv1.Field, 0, len(m.FieldList())),
+		Timestamp: m.Time().UnixNano(),
+		Type:      fromValueType[m.Type()],
+	}
+
+	for _, field := range m.FieldList() {
+		f := &pluginv1.Field{Key: field.Key}
+		switch v := field.Value.(type) {
+		case float64:
+			f.Value = &pluginv1.Field_DoubleValue{DoubleValue: v}
+		case int64:
+			f.Value = &pluginv1.Field_IntValue{IntValue: v}
+		case uint64:
+			f.Value = &pluginv1.Field_UintValue{UintValue: v}
+		case string:
+			f.Value = &pluginv1.Field_StringValue{StringValue: v}
+		case bool:
+			f.Value = &pluginv1.Field_BoolValue{BoolValue: v}
+		default:
+			return nil, fmt.Errorf("field %q of %q has unsupported type %T", field.Key, m.Name(), v)
+		}
+		pm.Fields = append(pm.Fields, f)
+	}
+	return pm, nil
+}
+
+// FromProto converts a metric from its protocol representation.
+func FromProto(pm *pluginv1.Metric) (telegraf.Metric, error) {
+	fields := make(map[string]interface{}, len(pm.Fields))
+	for _, f := range pm.Fields {
+		switch v := f.Value.(type) {
+		case *pluginv1.Field_DoubleValue:
+			fields[f.Key] = v.DoubleValue
+		case *pluginv1.Field_IntValue:
+			fields[f.Key] = v.IntValue
+		case *pluginv1.Field_UintValue:
+			fields[f.Key] = v.UintValue
+		case *pluginv1.Field_StringValue:
+			fields[f.Key] = v.StringValue
+		case *pluginv1.Field_BoolValue:
+			fields[f.Key] = v.BoolValue
+		default:
+			return nil, fmt.Errorf("field %q of %q has no value", f.Key, pm.Name)
+		}
+	}
+
+	tp, ok := toValueType[pm.Type]
+	if !ok {
+		tp = telegraf.Untyped
+	}
+	return metric.New(pm.Name, pm.Tags, fields, time.Unix(0, pm.Timestamp), tp)
+}
+
+// ToProtoMetrics converts a batch of metrics, metrics that cannot be
+// converted are dropped and reported with the returned error.
+func ToProtoMetrics(metrics []telegraf.Metric) (*pluginv1.Metrics, error) {
+	var firstErr error
+	batch := &pluginv1.Metrics{Metrics: make([]*pluginv1.Metric, 0, len(metrics))}
+	for _, m := range metrics {
+		pm, err := ToProto(m)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		batch.Metrics = append(batch.Metrics, pm)
+	}
+	return batch, firstErr
+}
+
+// FromProtoMetrics converts a batch of metrics, metrics that cannot be
+// converted are dropped and reported with the returned error.
+func FromProtoMetrics(batch *pluginv1.Metrics) ([]telegraf.Metric, error) {
+	return fromProtoList(batch.GetMetrics())
+}
+
+func fromProtoList(list []*pluginv1.Metric) ([]telegraf.Metric, error) {
+	var firstErr error
+	metrics := make([]telegraf.Metric, 0, len(list))
+	for _, pm := range list {
+		m, err := FromProto(pm)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		metrics = append(metrics, m)
+	}
+	return metrics, firstErr
+}
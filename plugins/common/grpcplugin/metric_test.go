@@ -0,0 +1,48 @@
+package grpcplugin
+
+import (
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/common/grpcplugin/pluginv1"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricRoundTrip(t *testing.T) {
+	m := testutil.MustMetric("cpu",
+		map[string]string{"host": "a"},
+		map[string]interface{}{
+			"float":  1.5,
+			"int":    int64(-2),
+			"uint":   uint64(3),
+			"string": "four",
+			"bool":   true,
+		},
+		time.Unix(0, 1600000000123456789),
+		telegraf.Counter)
+
+	pm, err := ToProto(m)
+	require.NoError(t, err)
+	require.Equal(t, pluginv1.Metric_TYPE_COUNTER, pm.Type)
+
+	actual, err := FromProto(pm)
+	require.NoError(t, err)
+	testutil.RequireMetricEqual(t, m, actual)
+	require.Equal(t, telegraf.Counter, actual.Type())
+}
+
+func TestFromProtoDropsInvalidMetrics(t *testing.T) {
+	batch := &pluginv1.Metrics{Metrics: []*pluginv1.Metric{
+		{Name: "invalid", Fields: []*pluginv1.Field{{Key: "value"}}},
+		{Name: "valid", Fields: []*pluginv1.Field{
+			{Key: "value", Value: &pluginv1.Field_IntValue{IntValue: 42}},
+		}},
+	}}
+
+	metrics, err := FromProtoMetrics(batch)
+	require.Error(t, err)
+	require.Len(t, metrics, 1)
+	require.Equal(t, "valid", metrics[0].Name())
+}
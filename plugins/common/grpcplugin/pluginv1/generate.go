@@ -0,0 +1,5 @@
+// Package pluginv1 contains the generated code of version 1 of the protocol
+// between Telegraf and external plugins.
+package pluginv1
+
+//go:generate protoc --go_out=plugins=grpc,paths=source_relative:. plugin.proto
@@ -0,0 +1,62 @@
+package server
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/influxdata/telegraf/plugins/common/grpcplugin/pluginv1"
+)
+
+// logger sends the log messages of the plugin to Telegraf.
+type logger struct {
+	logs chan *pluginv1.LogEntry
+}
+
+func sendLog(logs chan *pluginv1.LogEntry, level pluginv1.LogEntry_Level, message string) {
+	select {
+	case logs <- &pluginv1.LogEntry{Level: level, Message: message}:
+	default:
+		// Telegraf is not reading the logs, fall back to stderr
+		log.Print(message)
+	}
+}
+
+// Errorf logs an error message, patterned after log.Printf.
+func (l *logger) Errorf(format string, args ...interface{}) {
+	sendLog(l.logs, pluginv1.LogEntry_LEVEL_ERROR, fmt.Sprintf(format, args...))
+}
+
+// Error logs an error message, patterned after log.Print.
+func (l *logger) Error(args ...interface{}) {
+	sendLog(l.logs, pluginv1.LogEntry_LEVEL_ERROR, fmt.Sprint(args...))
+}
+
+// Debugf logs a debug message, patterned after log.Printf.
+func (l *logger) Debugf(format string, args ...interface{}) {
+	sendLog(l.logs, pluginv1.LogEntry_LEVEL_DEBUG, fmt.Sprintf(format, args...))
+}
+
+// Debug logs a debug message, patterned after log.Print.
+func (l *logger) Debug(args ...interface{}) {
+	sendLog(l.logs, pluginv1.LogEntry_LEVEL_DEBUG, fmt.Sprint(args...))
+}
+
+// Warnf logs a warning message, patterned after log.Printf.
+func (l *logger) Warnf(format string, args ...interface{}) {
+	sendLog(l.logs, pluginv1.LogEntry_LEVEL_WARN, fmt.Sprintf(format, args...))
+}
+
+// Warn logs a warning message, patterned after log.Print.
+func (l *logger) Warn(args ...interface{}) {
+	sendLog(l.logs, pluginv1.LogEntry_LEVEL_WARN, fmt.Sprint(args...))
+}
+
+// Infof logs an information message, patterned after log.Printf.
+func (l *logger) Infof(format string, args ...interface{}) {
+	sendLog(l.logs, pluginv1.LogEntry_LEVEL_INFO, fmt.Sprintf(format, args...))
+}
+
+// Info logs an information message, patterned after log.Print.
+func (l *logger) Info(args ...interface{}) {
+	sendLog(l.logs, pluginv1.LogEntry_LEVEL_INFO, fmt.Sprint(args...))
+}
@@ -0,0 +1,546 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"os"
+	"os/signal"
+	"reflect"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/agent"
+	"github.com/influxdata/telegraf/plugins/common/grpcplugin"
+	"github.com/influxdata/telegraf/plugins/common/grpcplugin/pluginv1"
+	"github.com/influxdata/telegraf/plugins/processors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// maxBatchSize is the maximum number of metrics sent in one message
+const maxBatchSize = 1000
+
+// HealthChecker can be implemented by plugins to report their health to
+// Telegraf; a plugin failing the check is restarted.
+type HealthChecker interface {
+	CheckHealth() error
+}
+
+// Serve serves the plugin, an input, processor, aggregator or output, on
+// the socket Telegraf passed to the process.  It returns once Telegraf closes
+// the stdin of the process or the process receives SIGINT or SIGTERM.
+func Serve(plugin interface{}) error {
+	socket := os.Getenv(grpcplugin.SocketEnv)
+	if socket == "" {
+		return fmt.Errorf("%s is not set, the plugin must be started by Telegraf", grpcplugin.SocketEnv)
+	}
+
+	s, err := New(plugin)
+	if err != nil {
+		return err
+	}
+
+	os.Remove(socket)
+	listener, err := net.Listen("unix", socket)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		quit := make(chan os.Signal, 1)
+		signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+
+		stdinClosed := make(chan struct{})
+		go func() {
+			io.Copy(ioutil.Discard, os.Stdin)
+			close(stdinClosed)
+		}()
+
+		select {
+		case <-quit:
+		case <-stdinClosed:
+		}
+		s.Shutdown()
+	}()
+
+	return s.Serve(listener)
+}
+
+// Server implements the protocol for a plugin.
+type Server struct {
+	pluginv1.UnimplementedPluginServer
+
+	plugin interface{}
+	// the plugin as given, which holds the settings of wrapped processors
+	config interface{}
+	typ    pluginv1.PluginType
+	server *grpc.Server
+	logs   chan *pluginv1.LogEntry
+	done   chan struct{}
+
+	// serializes the calls to the plugin
+	sync.Mutex
+	initialized bool
+	started     bool
+	streamCh    chan telegraf.Metric
+}
+
+// New creates the server of a plugin.
+func New(plugin interface{}) (*Server, error) {
+	s := &Server{
+		plugin: plugin,
+		config: plugin,
+		logs:   make(chan *pluginv1.LogEntry, 1000),
+		done:   make(chan struct{}),
+	}
+
+	switch p := plugin.(type) {
+	case telegraf.Input:
+		s.typ = pluginv1.PluginType_PLUGIN_TYPE_INPUT
+		s.streamCh = make(chan telegraf.Metric, maxBatchSize)
+	case telegraf.StreamingProcessor:
+		s.typ = pluginv1.PluginType_PLUGIN_TYPE_PROCESSOR
+	case telegraf.Processor:
+		s.typ = pluginv1.PluginType_PLUGIN_TYPE_PROCESSOR
+		s.plugin = processors.NewStreamingProcessorFromProcessor(p)
+	case telegraf.Aggregator:
+		s.typ = pluginv1.PluginType_PLUGIN_TYPE_AGGREGATOR
+	case telegraf.Output:
+		s.typ = pluginv1.PluginType_PLUGIN_TYPE_OUTPUT
+	default:
+		return nil, fmt.Errorf("unsupported plugin type %T", plugin)
+	}
+
+	s.server = grpc.NewServer()
+	pluginv1.RegisterPluginServer(s.server, s)
+	healthpb.RegisterHealthServer(s.server, &healthServer{plugin: plugin})
+	return s, nil
+}
+
+// Serve serves the plugin on the listener until Shutdown is called.
+func (s *Server) Serve(listener net.Listener) error {
+	return s.server.Serve(listener)
+}
+
+// Shutdown stops the plugin, if Telegraf did not already stop it, and the
+// server.
+func (s *Server) Shutdown() {
+	s.Lock()
+	s.stop()
+	s.Unlock()
+
+	close(s.done)
+
+	stopped := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		s.server.Stop()
+	}
+}
+
+func (s *Server) Init(ctx context.Context, req *pluginv1.InitRequest) (*pluginv1.InitResponse, error) {
+	if req.ProtocolVersion != grpcplugin.ProtocolVersion {
+		return nil, status.Errorf(codes.FailedPrecondition, "unsupported protocol version %d", req.ProtocolVersion)
+	}
+	if req.Type != s.typ {
+		return nil, status.Errorf(codes.InvalidArgument, "plugin is a %s", s.typ)
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	if s.initialized {
+		return nil, status.Error(codes.FailedPrecondition, "plugin already initialized")
+	}
+
+	if _, err := toml.Decode(req.Config, s.config); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid config: %v", err)
+	}
+	setLoggerOnPlugin(s.config, &logger{logs: s.logs})
+	if p, ok := s.plugin.(telegraf.Initializer); ok {
+		if err := p.Init(); err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "init failed: %v", err)
+		}
+	}
+	s.initialized = true
+
+	resp := &pluginv1.InitResponse{
+		ProtocolVersion: grpcplugin.ProtocolVersion,
+		Type:            s.typ,
+	}
+	if p, ok := s.plugin.(telegraf.PluginDescriber); ok {
+		resp.Description = p.Description()
+	}
+	_, resp.Service = s.plugin.(telegraf.ServiceInput)
+	return resp, nil
+}
+
+func (s *Server) Logs(req *pluginv1.LogsRequest, stream pluginv1.Plugin_LogsServer) error {
+	for {
+		select {
+		case entry := <-s.logs:
+			if err := stream.Send(entry); err != nil {
+				return err
+			}
+		case <-stream.Context().Done():
+			return nil
+		case <-s.done:
+			return nil
+		}
+	}
+}
+
+func (s *Server) Start(ctx context.Context, req *pluginv1.StartRequest) (*pluginv1.StartResponse, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.started {
+		return &pluginv1.StartResponse{}, nil
+	}
+
+	switch p := s.plugin.(type) {
+	case telegraf.ServiceInput:
+		acc := agent.NewAccumulator(s, s.streamCh)
+		acc.SetPrecision(time.Nanosecond)
+		if err := p.Start(acc); err != nil {
+			return nil, status.Errorf(codes.Unknown, "failed to start input: %v", err)
+		}
+	case telegraf.ServiceAggregator:
+		if err := p.Start(); err != nil {
+			return nil, status.Errorf(codes.Unknown, "failed to start aggregator: %v", err)
+		}
+	case telegraf.Output:
+		if err := p.Connect(); err != nil {
+			return nil, status.Errorf(codes.Unavailable, "failed to connect output: %v", err)
+		}
+	}
+	s.started = true
+	return &pluginv1.StartResponse{}, nil
+}
+
+func (s *Server) Stop(ctx context.Context, req *pluginv1.StopRequest) (*pluginv1.StopResponse, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	if err := s.stop(); err != nil {
+		return nil, status.Errorf(codes.Unknown, "failed to stop plugin: %v", err)
+	}
+	return &pluginv1.StopResponse{}, nil
+}
+
+func (s *Server) stop() error {
+	if !s.started {
+		return nil
+	}
+	s.started = false
+
+	switch p := s.plugin.(type) {
+	case telegraf.ServiceInput:
+		p.Stop()
+	case telegraf.ServiceAggregator:
+		p.Stop()
+	case telegraf.Output:
+		return p.Close()
+	}
+	return nil
+}
+
+func (s *Server) Gather(ctx context.Context, req *pluginv1.GatherRequest) (*pluginv1.GatherResponse, error) {
+	input, ok := s.plugin.(telegraf.Input)
+	if !ok {
+		return nil, status.Errorf(codes.Unimplemented, "plugin is a %s", s.typ)
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	gacc := &gatherAccumulator{}
+	metrics, err := collect(s, func(acc telegraf.Accumulator) error {
+		gacc.Accumulator = acc
+		return input.Gather(gacc)
+	})
+	if err != nil {
+		gacc.AddError(err)
+	}
+
+	batch, err := grpcplugin.ToProtoMetrics(metrics)
+	if err != nil {
+		gacc.AddError(err)
+	}
+	return &pluginv1.GatherResponse{Metrics: batch.Metrics, Errors: gacc.errors}, nil
+}
+
+func (s *Server) Stream(req *pluginv1.StreamRequest, stream pluginv1.Plugin_StreamServer) error {
+	if s.streamCh == nil {
+		return status.Errorf(codes.Unimplemented, "plugin is a %s", s.typ)
+	}
+	return sendMetrics(stream.Context(), s.done, s.streamCh, stream.Send)
+}
+
+func (s *Server) Process(stream pluginv1.Plugin_ProcessServer) error {
+	processor, ok := s.plugin.(telegraf.StreamingProcessor)
+	if !ok {
+		return status.Errorf(codes.Unimplemented, "plugin is a %s", s.typ)
+	}
+
+	out := make(chan telegraf.Metric, maxBatchSize)
+	acc := agent.NewAccumulator(s, out)
+	acc.SetPrecision(time.Nanosecond)
+	if err := processor.Start(acc); err != nil {
+		return status.Errorf(codes.Unknown, "failed to start processor: %v", err)
+	}
+
+	sendErr := make(chan error, 1)
+	go func() {
+		sendErr <- sendMetrics(stream.Context(), nil, out, stream.Send)
+	}()
+
+	var err error
+	for {
+		var batch *pluginv1.Metrics
+		batch, err = stream.Recv()
+		if err != nil {
+			break
+		}
+
+		metrics, err := grpcplugin.FromProtoMetrics(batch)
+		if err != nil {
+			s.log(pluginv1.LogEntry_LEVEL_ERROR, err.Error())
+		}
+		for _, m := range metrics {
+			if err := processor.Add(m, acc); err != nil {
+				s.log(pluginv1.LogEntry_LEVEL_ERROR, err.Error())
+			}
+		}
+	}
+
+	// Flush the remaining metrics of the processor
+	processor.Stop()
+	close(out)
+	if serr := <-sendErr; serr != nil {
+		return serr
+	}
+	if err == io.EOF {
+		return nil
+	}
+	return err
+}
+
+func (s *Server) Add(ctx context.Context, batch *pluginv1.Metrics) (*pluginv1.AddResponse, error) {
+	aggregator, ok := s.plugin.(telegraf.Aggregator)
+	if !ok {
+		return nil, status.Errorf(codes.Unimplemented, "plugin is a %s", s.typ)
+	}
+
+	metrics, err := grpcplugin.FromProtoMetrics(batch)
+	if err != nil {
+		s.log(pluginv1.LogEntry_LEVEL_ERROR, err.Error())
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	for _, m := range metrics {
+		aggregator.Add(m)
+	}
+	return &pluginv1.AddResponse{}, nil
+}
+
+func (s *Server) Push(ctx context.Context, req *pluginv1.PushRequest) (*pluginv1.Metrics, error) {
+	aggregator, ok := s.plugin.(telegraf.Aggregator)
+	if !ok {
+		return nil, status.Errorf(codes.Unimplemented, "plugin is a %s", s.typ)
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	metrics, _ := collect(s, func(acc telegraf.Accumulator) error {
+		aggregator.Push(acc)
+		return nil
+	})
+	batch, err := grpcplugin.ToProtoMetrics(metrics)
+	if err != nil {
+		s.log(pluginv1.LogEntry_LEVEL_ERROR, err.Error())
+	}
+	return batch, nil
+}
+
+func (s *Server) Reset(ctx context.Context, req *pluginv1.ResetRequest) (*pluginv1.ResetResponse, error) {
+	aggregator, ok := s.plugin.(telegraf.Aggregator)
+	if !ok {
+		return nil, status.Errorf(codes.Unimplemented, "plugin is a %s", s.typ)
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	aggregator.Reset()
+	return &pluginv1.ResetResponse{}, nil
+}
+
+func (s *Server) Write(ctx context.Context, batch *pluginv1.Metrics) (*pluginv1.WriteResponse, error) {
+	output, ok := s.plugin.(telegraf.Output)
+	if !ok {
+		return nil, status.Errorf(codes.Unimplemented, "plugin is a %s", s.typ)
+	}
+
+	metrics, err := grpcplugin.FromProtoMetrics(batch)
+	if err != nil {
+		s.log(pluginv1.LogEntry_LEVEL_ERROR, err.Error())
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	if err := output.Write(metrics); err != nil {
+		return nil, status.Errorf(codes.Unavailable, "write failed: %v", err)
+	}
+	return &pluginv1.WriteResponse{}, nil
+}
+
+func (s *Server) log(level pluginv1.LogEntry_Level, message string) {
+	sendLog(s.logs, level, message)
+}
+
+// LogName satisfies the MetricMaker interface
+func (s *Server) LogName() string {
+	return ""
+}
+
+// MakeMetric satisfies the MetricMaker interface
+func (s *Server) MakeMetric(m telegraf.Metric) telegraf.Metric {
+	return m
+}
+
+// Log satisfies the MetricMaker interface
+func (s *Server) Log() telegraf.Logger {
+	return &logger{logs: s.logs}
+}
+
+// collect returns the metrics added to the accumulator by fn.
+func collect(maker agent.MetricMaker, fn func(acc telegraf.Accumulator) error) ([]telegraf.Metric, error) {
+	ch := make(chan telegraf.Metric, maxBatchSize)
+	acc := agent.NewAccumulator(maker, ch)
+	acc.SetPrecision(time.Nanosecond)
+
+	var metrics []telegraf.Metric
+	collected := make(chan struct{})
+	go func() {
+		for m := range ch {
+			metrics = append(metrics, m)
+		}
+		close(collected)
+	}()
+
+	err := fn(acc)
+	close(ch)
+	<-collected
+	return metrics, err
+}
+
+// sendMetrics sends the metrics read from ch in batches until ch is closed
+// or the context or done is closed.
+func sendMetrics(ctx context.Context, done chan struct{}, ch chan telegraf.Metric, send func(*pluginv1.Metrics) error) error {
+	for {
+		var batch []telegraf.Metric
+		select {
+		case m, open := <-ch:
+			if !open {
+				return nil
+			}
+			batch = append(batch, m)
+		case <-ctx.Done():
+			return nil
+		case <-done:
+			return nil
+		}
+
+	drain:
+		for len(batch) < maxBatchSize {
+			select {
+			case m, open := <-ch:
+				if !open {
+					break drain
+				}
+				batch = append(batch, m)
+			default:
+				break drain
+			}
+		}
+
+		pb, err := grpcplugin.ToProtoMetrics(batch)
+		if err != nil {
+			log.Print(err)
+		}
+		if err := send(pb); err != nil {
+			return err
+		}
+		for _, m := range batch {
+			m.Accept()
+		}
+	}
+}
+
+// gatherAccumulator records the errors of a gather to return them to
+// Telegraf instead of logging them.
+type gatherAccumulator struct {
+	telegraf.Accumulator
+
+	sync.Mutex
+	errors []string
+}
+
+func (acc *gatherAccumulator) AddError(err error) {
+	if err == nil {
+		return
+	}
+	acc.Lock()
+	acc.errors = append(acc.errors, err.Error())
+	acc.Unlock()
+}
+
+type healthServer struct {
+	plugin interface{}
+}
+
+func (h *healthServer) Check(ctx context.Context, req *healthpb.HealthCheckRequest) (*healthpb.HealthCheckResponse, error) {
+	if p, ok := h.plugin.(HealthChecker); ok {
+		if err := p.CheckHealth(); err != nil {
+			return &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_NOT_SERVING}, nil
+		}
+	}
+	return &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING}, nil
+}
+
+func (h *healthServer) Watch(req *healthpb.HealthCheckRequest, stream healthpb.Health_WatchServer) error {
+	return status.Error(codes.Unimplemented, "watch is not supported")
+}
+
+// setLoggerOnPlugin injects the logger into the plugin if it defines
+// Log telegraf.Logger.
+func setLoggerOnPlugin(i interface{}, log telegraf.Logger) {
+	valI := reflect.ValueOf(i)
+	if valI.Type().Kind() != reflect.Ptr {
+		return
+	}
+
+	field := valI.Elem().FieldByName("Log")
+	if !field.IsValid() || !field.CanSet() {
+		return
+	}
+	if field.Type().String() == "telegraf.Logger" {
+		field.Set(reflect.ValueOf(log))
+	}
+}
@@ -0,0 +1,86 @@
+package shim
+
+import (
+	"bufio"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/agent"
+	"github.com/influxdata/telegraf/plugins/parsers"
+)
+
+// Commands sent by the aggregators.execd plugin on stdin, in between the
+// metrics, at the end of each aggregation period.  Lines starting with '#'
+// are never valid line protocol.
+const (
+	// PushCommand asks the aggregator to push its aggregates.  The shim
+	// writes PushedMarker to stdout once all aggregates have been written.
+	PushCommand = "#telegraf:push"
+	// ResetCommand asks the aggregator to reset its aggregates.
+	ResetCommand = "#telegraf:reset"
+	// PushedMarker follows the aggregates written after a PushCommand.
+	PushedMarker = "#telegraf:pushed"
+)
+
+// AddAggregator adds the aggregator to the shim. Later calls to Run() will run this.
+func (s *Shim) AddAggregator(aggregator telegraf.Aggregator) error {
+	setLoggerOnPlugin(aggregator, NewLogger())
+	if p, ok := aggregator.(telegraf.Initializer); ok {
+		err := p.Init()
+		if err != nil {
+			return fmt.Errorf("failed to init aggregator: %s", err)
+		}
+	}
+
+	s.Aggregator = aggregator
+	return nil
+}
+
+func (s *Shim) RunAggregator() error {
+	acc := agent.NewAccumulator(s, s.metricCh)
+	acc.SetPrecision(time.Nanosecond)
+
+	parser, err := parsers.NewInfluxParser()
+	if err != nil {
+		return fmt.Errorf("Failed to create new parser: %w", err)
+	}
+
+	if p, ok := s.Aggregator.(telegraf.ServiceAggregator); ok {
+		if err := p.Start(); err != nil {
+			return fmt.Errorf("failed to start aggregator: %w", err)
+		}
+		defer p.Stop()
+	}
+
+	wg := sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		s.writeProcessedMetrics()
+		wg.Done()
+	}()
+
+	scanner := bufio.NewScanner(s.stdin)
+	for scanner.Scan() {
+		switch line := scanner.Text(); line {
+		case PushCommand:
+			s.Aggregator.Push(acc)
+			// A nil metric tells the writer to mark the end of the push
+			s.metricCh <- nil
+		case ResetCommand:
+			s.Aggregator.Reset()
+		default:
+			m, err := parser.ParseLine(line)
+			if err != nil {
+				fmt.Fprintf(s.stderr, "Failed to parse metric: %s\n", err)
+				continue
+			}
+			s.Aggregator.Add(m)
+		}
+	}
+
+	close(s.metricCh)
+	wg.Wait()
+	return nil
+}
@@ -0,0 +1,107 @@
+package shim
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/parsers"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAggregatorShim(t *testing.T) {
+	a := &testAggregator{}
+
+	stdinReader, stdinWriter := io.Pipe()
+	stdoutReader, stdoutWriter := io.Pipe()
+
+	s := New()
+	// inject test into shim
+	s.stdin = stdinReader
+	s.stdout = stdoutWriter
+	err := s.AddAggregator(a)
+	require.NoError(t, err)
+
+	wg := sync.WaitGroup{}
+
+	wg.Add(1)
+	go func() {
+		err := s.RunAggregator()
+		require.NoError(t, err)
+		wg.Done()
+	}()
+
+	parser, _ := parsers.NewInfluxParser()
+	r := bufio.NewReader(stdoutReader)
+
+	push := func() int64 {
+		_, err := fmt.Fprintln(stdinWriter, PushCommand)
+		require.NoError(t, err)
+
+		out, err := r.ReadString('\n')
+		require.NoError(t, err)
+		mOut, err := parser.ParseLine(out)
+		require.NoError(t, err)
+		count, ok := mOut.GetField("count")
+		require.True(t, ok)
+
+		out, err = r.ReadString('\n')
+		require.NoError(t, err)
+		require.Equal(t, PushedMarker, strings.TrimSpace(out))
+		return count.(int64)
+	}
+
+	for i := 0; i < 3; i++ {
+		_, err = fmt.Fprintf(stdinWriter, "thing,a=b v=%di %d\n", i, time.Now().UnixNano())
+		require.NoError(t, err)
+	}
+	require.Equal(t, int64(3), push())
+
+	// Aggregates are only reset on request
+	require.Equal(t, int64(3), push())
+	_, err = fmt.Fprintln(stdinWriter, ResetCommand)
+	require.NoError(t, err)
+	require.Equal(t, int64(0), push())
+
+	err = stdinWriter.Close()
+	require.NoError(t, err)
+
+	go ioutil.ReadAll(r)
+	wg.Wait()
+}
+
+type testAggregator struct {
+	count int64
+}
+
+func (a *testAggregator) Add(in telegraf.Metric) {
+	a.count++
+}
+
+func (a *testAggregator) Push(acc telegraf.Accumulator) {
+	m, _ := metric.New("count",
+		map[string]string{},
+		map[string]interface{}{"count": a.count},
+		time.Now(),
+	)
+	acc.AddMetric(m)
+}
+
+func (a *testAggregator) Reset() {
+	a.count = 0
+}
+
+func (a *testAggregator) SampleConfig() string {
+	return ""
+}
+
+func (a *testAggregator) Description() string {
+	return ""
+}
@@ -9,21 +9,24 @@ import (
 
 	"github.com/BurntSushi/toml"
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/aggregators"
 	"github.com/influxdata/telegraf/plugins/inputs"
 	"github.com/influxdata/telegraf/plugins/outputs"
 	"github.com/influxdata/telegraf/plugins/processors"
 )
 
 type config struct {
-	Inputs     map[string][]toml.Primitive
-	Processors map[string][]toml.Primitive
-	Outputs    map[string][]toml.Primitive
+	Inputs      map[string][]toml.Primitive
+	Processors  map[string][]toml.Primitive
+	Aggregators map[string][]toml.Primitive
+	Outputs     map[string][]toml.Primitive
 }
 
 type loadedConfig struct {
-	Input     telegraf.Input
-	Processor telegraf.StreamingProcessor
-	Output    telegraf.Output
+	Input      telegraf.Input
+	Processor  telegraf.StreamingProcessor
+	Aggregator telegraf.Aggregator
+	Output     telegraf.Output
 }
 
 // LoadConfig Adds plugins to the shim
@@ -40,6 +43,10 @@ func (s *Shim) LoadConfig(filePath *string) error {
 		if err = s.AddStreamingProcessor(conf.Processor); err != nil {
 			return fmt.Errorf("Failed to add Processor: %w", err)
 		}
+	} else if conf.Aggregator != nil {
+		if err = s.AddAggregator(conf.Aggregator); err != nil {
+			return fmt.Errorf("Failed to add Aggregator: %w", err)
+		}
 	} else if conf.Output != nil {
 		if err = s.AddOutput(conf.Output); err != nil {
 			return fmt.Errorf("Failed to add Output: %w", err)
@@ -124,6 +131,23 @@ func createPluginsWithTomlConfig(md toml.MetaData, conf config) (loadedConfig, e
 		break
 	}
 
+	for name, primitives := range conf.Aggregators {
+		creator, ok := aggregators.Aggregators[name]
+		if !ok {
+			return loadedConf, errors.New("unknown aggregator " + name)
+		}
+
+		plugin := creator()
+		if len(primitives) > 0 {
+			primitive := primitives[0]
+			if err := md.PrimitiveDecode(primitive, plugin); err != nil {
+				return loadedConf, err
+			}
+		}
+		loadedConf.Aggregator = plugin
+		break
+	}
+
 	for name, primitives := range conf.Outputs {
 		creator, ok := outputs.Outputs[name]
 		if !ok {
@@ -148,9 +172,10 @@ func createPluginsWithTomlConfig(md toml.MetaData, conf config) (loadedConfig, e
 // without having to define a config dead easy.
 func DefaultImportedPlugins() (config, error) {
 	conf := config{
-		Inputs:     map[string][]toml.Primitive{},
-		Processors: map[string][]toml.Primitive{},
-		Outputs:    map[string][]toml.Primitive{},
+		Inputs:      map[string][]toml.Primitive{},
+		Processors:  map[string][]toml.Primitive{},
+		Aggregators: map[string][]toml.Primitive{},
+		Outputs:     map[string][]toml.Primitive{},
 	}
 	for name := range inputs.Inputs {
 		log.Println("No config found. Loading default config for plugin", name)
@@ -162,6 +187,11 @@ func DefaultImportedPlugins() (config, error) {
 		conf.Processors[name] = []toml.Primitive{}
 		return conf, nil
 	}
+	for name := range aggregators.Aggregators {
+		log.Println("No config found. Loading default config for plugin", name)
+		conf.Aggregators[name] = []toml.Primitive{}
+		return conf, nil
+	}
 	for name := range outputs.Outputs {
 		log.Println("No config found. Loading default config for plugin", name)
 		conf.Outputs[name] = []toml.Primitive{}
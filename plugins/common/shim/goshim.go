@@ -7,10 +7,12 @@ import (
 	"os"
 	"os/signal"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/process"
 	"github.com/influxdata/telegraf/plugins/serializers/influx"
 )
 
@@ -28,14 +30,18 @@ const (
 	// PollIntervalDisabled is used to indicate that you want to disable polling,
 	// as opposed to duration 0 meaning poll constantly.
 	PollIntervalDisabled = time.Duration(0)
+
+	// maxBatchSize is the maximum number of metrics in a frame
+	maxBatchSize = 1000
 )
 
 // Shim allows you to wrap your inputs and run them as if they were part of Telegraf,
 // except built externally.
 type Shim struct {
-	Input     telegraf.Input
-	Processor telegraf.StreamingProcessor
-	Output    telegraf.Output
+	Input      telegraf.Input
+	Processor  telegraf.StreamingProcessor
+	Aggregator telegraf.Aggregator
+	Output     telegraf.Output
 
 	// streams
 	stdin  io.Reader
@@ -47,6 +53,12 @@ type Shim struct {
 
 	// input only
 	gatherPromptCh chan empty
+
+	// framed protocol; batches sent by an input waiting for acknowledgement
+	framed      bool
+	batchID     uint64
+	batches     map[uint64][]telegraf.Metric
+	batchesLock sync.Mutex
 }
 
 // New creates a new shim interface
@@ -56,6 +68,8 @@ func New() *Shim {
 		stdin:    os.Stdin,
 		stdout:   os.Stdout,
 		stderr:   os.Stderr,
+		framed:   os.Getenv(process.ProtocolEnv) == process.ProtocolFramed,
+		batches:  make(map[uint64][]telegraf.Metric),
 	}
 }
 
@@ -81,6 +95,11 @@ func (s *Shim) Run(pollInterval time.Duration) error {
 		if err != nil {
 			return fmt.Errorf("RunProcessor error: %w", err)
 		}
+	} else if s.Aggregator != nil {
+		err := s.RunAggregator()
+		if err != nil {
+			return fmt.Errorf("RunAggregator error: %w", err)
+		}
 	} else if s.Output != nil {
 		err := s.RunOutput()
 		if err != nil {
@@ -105,6 +124,10 @@ func (s *Shim) writeProcessedMetrics() error {
 			if !open {
 				return nil
 			}
+			if m == nil {
+				fmt.Fprintln(s.stdout, PushedMarker)
+				continue
+			}
 			b, err := serializer.Serialize(m)
 			if err != nil {
 				return fmt.Errorf("failed to serialize metric: %s", err)
@@ -115,6 +138,65 @@ func (s *Shim) writeProcessedMetrics() error {
 	}
 }
 
+// writeFramedMetrics writes the metrics in frames of the framed protocol,
+// batching the metrics that are already waiting to be written.
+func (s *Shim) writeFramedMetrics() error {
+	serializer := influx.NewSerializer()
+	for m := range s.metricCh {
+		batch := []telegraf.Metric{m}
+	drain:
+		for len(batch) < maxBatchSize {
+			select {
+			case m, open := <-s.metricCh:
+				if !open {
+					break drain
+				}
+				batch = append(batch, m)
+			default:
+				break drain
+			}
+		}
+
+		b, err := serializer.SerializeBatch(batch)
+		if err != nil {
+			return fmt.Errorf("failed to serialize metrics: %s", err)
+		}
+
+		s.batchesLock.Lock()
+		s.batchID++
+		id := s.batchID
+		s.batches[id] = batch
+		s.batchesLock.Unlock()
+
+		f := &process.Frame{Type: process.FrameMetrics, ID: id, Metrics: string(b)}
+		if err := process.WriteFrame(s.stdout, f); err != nil {
+			return fmt.Errorf("failed to write metrics: %s", err)
+		}
+	}
+	return nil
+}
+
+// deliverBatch accepts or rejects the metrics of an acknowledged batch, which
+// notifies inputs using tracking metrics.
+func (s *Shim) deliverBatch(f *process.Frame) {
+	s.batchesLock.Lock()
+	batch, ok := s.batches[f.ID]
+	delete(s.batches, f.ID)
+	s.batchesLock.Unlock()
+
+	if !ok {
+		fmt.Fprintf(s.stderr, "acknowledgement of unknown batch %d\n", f.ID)
+		return
+	}
+	for _, m := range batch {
+		if f.Type == process.FrameAck {
+			m.Accept()
+		} else {
+			m.Reject()
+		}
+	}
+}
+
 // LogName satisfies the MetricMaker interface
 func (s *Shim) LogName() string {
 	return ""
@@ -9,6 +9,7 @@ import (
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/agent"
+	"github.com/influxdata/telegraf/internal/process"
 )
 
 // AddInput adds the input to the shim. Later calls to Run() will run this input.
@@ -54,15 +55,23 @@ func (s *Shim) RunInput(pollInterval time.Duration) error {
 	wg := sync.WaitGroup{}
 	wg.Add(1)
 	go func() {
-		s.writeProcessedMetrics()
+		if s.framed {
+			s.writeFramedMetrics()
+		} else {
+			s.writeProcessedMetrics()
+		}
 		wg.Done()
 	}()
 
 	go func() {
-		scanner := bufio.NewScanner(s.stdin)
-		for scanner.Scan() {
-			// push a non-blocking message to trigger metric collection.
-			s.pushCollectMetricsRequest()
+		if s.framed {
+			s.readInputFrames()
+		} else {
+			scanner := bufio.NewScanner(s.stdin)
+			for scanner.Scan() {
+				// push a non-blocking message to trigger metric collection.
+				s.pushCollectMetricsRequest()
+			}
 		}
 
 		cancel() // cancel gracefully stops gathering
@@ -99,6 +108,28 @@ func (s *Shim) startGathering(ctx context.Context, input telegraf.Input, acc tel
 	}
 }
 
+// readInputFrames handles the gather requests and the acknowledgements of
+// the framed protocol.
+func (s *Shim) readInputFrames() {
+	scanner := process.NewFrameScanner(s.stdin)
+	for scanner.Scan() {
+		f, err := process.ParseFrame(scanner.Bytes())
+		if err != nil {
+			fmt.Fprintf(s.stderr, "%s\n", err)
+			continue
+		}
+
+		switch f.Type {
+		case process.FrameGather:
+			s.pushCollectMetricsRequest()
+		case process.FrameAck, process.FrameNack:
+			s.deliverBatch(f)
+		default:
+			fmt.Fprintf(s.stderr, "unexpected frame type %q\n", f.Type)
+		}
+	}
+}
+
 // pushCollectMetricsRequest pushes a non-blocking (nil) message to the
 // gatherPromptCh channel to trigger metric collection.
 // The channel is defined with a buffer of 1, so while it's full, subsequent
@@ -11,6 +11,8 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/process"
+	"github.com/influxdata/telegraf/metric"
 )
 
 func TestInputShimTimer(t *testing.T) {
@@ -50,6 +52,50 @@ func TestInputShimStdinSignalingWorks(t *testing.T) {
 	<-exited
 }
 
+func TestInputShimFramed(t *testing.T) {
+	stdinReader, stdinWriter := io.Pipe()
+	stdoutReader, stdoutWriter := io.Pipe()
+
+	inp := &trackingInput{}
+	shim := New()
+	shim.framed = true
+	shim.stdin = stdinReader
+	shim.stdout = stdoutWriter
+	require.NoError(t, shim.AddInput(inp))
+
+	exited := make(chan bool, 1)
+	go func() {
+		err := shim.Run(PollIntervalDisabled)
+		require.NoError(t, err)
+		exited <- true
+	}()
+
+	scanner := process.NewFrameScanner(stdoutReader)
+	gather := func() *process.Frame {
+		require.NoError(t, process.WriteFrame(stdinWriter, &process.Frame{Type: process.FrameGather}))
+		require.True(t, scanner.Scan())
+		f, err := process.ParseFrame(scanner.Bytes())
+		require.NoError(t, err)
+		require.Equal(t, process.FrameMetrics, f.Type)
+		require.Equal(t, "measurement,tag=tag field=1i 1234000005678\n", f.Metrics)
+		return f
+	}
+
+	f := gather()
+	require.NoError(t, process.WriteFrame(stdinWriter, &process.Frame{Type: process.FrameAck, ID: f.ID}))
+	info := <-inp.acc.Delivered()
+	require.True(t, info.Delivered())
+
+	f = gather()
+	require.NoError(t, process.WriteFrame(stdinWriter, &process.Frame{Type: process.FrameNack, ID: f.ID}))
+	info = <-inp.acc.Delivered()
+	require.False(t, info.Delivered())
+
+	stdinWriter.Close()
+	go ioutil.ReadAll(stdoutReader)
+	<-exited
+}
+
 func runInputPlugin(t *testing.T, interval time.Duration, stdin io.Reader, stdout, stderr io.Writer) (metricProcessed chan bool, exited chan bool) {
 	metricProcessed = make(chan bool, 1)
 	exited = make(chan bool, 1)
@@ -139,3 +185,28 @@ func (i *serviceInput) Start(acc telegraf.Accumulator) error {
 
 func (i *serviceInput) Stop() {
 }
+
+// trackingInput adds each metric as tracking metric.
+type trackingInput struct {
+	acc telegraf.TrackingAccumulator
+}
+
+func (i *trackingInput) SampleConfig() string {
+	return ""
+}
+
+func (i *trackingInput) Description() string {
+	return ""
+}
+
+func (i *trackingInput) Gather(acc telegraf.Accumulator) error {
+	if i.acc == nil {
+		i.acc = acc.WithTracking(10)
+	}
+	m, _ := metric.New("measurement",
+		map[string]string{"tag": "tag"},
+		map[string]interface{}{"field": 1},
+		time.Unix(1234, 5678))
+	i.acc.AddTrackingMetricGroup([]telegraf.Metric{m})
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/process"
 	"github.com/influxdata/telegraf/plugins/parsers"
 )
 
@@ -34,6 +35,10 @@ func (s *Shim) RunOutput() error {
 	}
 	defer s.Output.Close()
 
+	if s.framed {
+		return s.runFramedOutput(parser)
+	}
+
 	var m telegraf.Metric
 
 	scanner := bufio.NewScanner(s.stdin)
@@ -50,3 +55,35 @@ func (s *Shim) RunOutput() error {
 
 	return nil
 }
+
+// runFramedOutput writes the batches of the framed protocol and acknowledges
+// each of them with the result of the write.
+func (s *Shim) runFramedOutput(parser parsers.Parser) error {
+	scanner := process.NewFrameScanner(s.stdin)
+	for scanner.Scan() {
+		f, err := process.ParseFrame(scanner.Bytes())
+		if err != nil {
+			fmt.Fprintf(s.stderr, "%s\n", err)
+			continue
+		}
+		if f.Type != process.FrameMetrics {
+			fmt.Fprintf(s.stderr, "unexpected frame type %q\n", f.Type)
+			continue
+		}
+
+		reply := &process.Frame{Type: process.FrameAck, ID: f.ID}
+		metrics, err := parser.Parse([]byte(f.Metrics))
+		if err == nil {
+			err = s.Output.Write(metrics)
+		}
+		if err != nil {
+			reply.Type = process.FrameNack
+			reply.Error = err.Error()
+		}
+
+		if err := process.WriteFrame(s.stdout, reply); err != nil {
+			return fmt.Errorf("failed to write acknowledgement: %w", err)
+		}
+	}
+	return nil
+}
@@ -1,12 +1,14 @@
 package shim
 
 import (
+	"errors"
 	"io"
 	"sync"
 	"testing"
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal/process"
 	"github.com/influxdata/telegraf/metric"
 	"github.com/influxdata/telegraf/plugins/serializers"
 	"github.com/influxdata/telegraf/testutil"
@@ -58,8 +60,58 @@ func TestOutputShim(t *testing.T) {
 	testutil.RequireMetricEqual(t, m, mOut)
 }
 
+func TestOutputShimFramed(t *testing.T) {
+	o := &testOutput{}
+
+	stdinReader, stdinWriter := io.Pipe()
+	stdoutReader, stdoutWriter := io.Pipe()
+
+	s := New()
+	s.framed = true
+	s.stdin = stdinReader
+	s.stdout = stdoutWriter
+	err := s.AddOutput(o)
+	require.NoError(t, err)
+
+	wg := sync.WaitGroup{}
+
+	wg.Add(1)
+	go func() {
+		err := s.RunOutput()
+		require.NoError(t, err)
+		wg.Done()
+	}()
+
+	scanner := process.NewFrameScanner(stdoutReader)
+	write := func(id uint64, metrics string) *process.Frame {
+		f := &process.Frame{Type: process.FrameMetrics, ID: id, Metrics: metrics}
+		require.NoError(t, process.WriteFrame(stdinWriter, f))
+		require.True(t, scanner.Scan())
+		reply, err := process.ParseFrame(scanner.Bytes())
+		require.NoError(t, err)
+		require.Equal(t, id, reply.ID)
+		return reply
+	}
+
+	reply := write(1, "thing,a=b v=1i 1\nthing,a=c v=2i 1\n")
+	require.Equal(t, process.FrameAck, reply.Type)
+	require.Len(t, o.MetricsWritten, 2)
+
+	o.WriteError = errors.New("connection refused")
+	reply = write(2, "thing,a=b v=1i 2\n")
+	require.Equal(t, process.FrameNack, reply.Type)
+	require.Equal(t, "connection refused", reply.Error)
+
+	reply = write(3, "thing,")
+	require.Equal(t, process.FrameNack, reply.Type)
+
+	require.NoError(t, stdinWriter.Close())
+	wg.Wait()
+}
+
 type testOutput struct {
 	MetricsWritten []telegraf.Metric
+	WriteError     error
 }
 
 func (o *testOutput) Connect() error {
@@ -69,6 +121,9 @@ func (o *testOutput) Close() error {
 	return nil
 }
 func (o *testOutput) Write(metrics []telegraf.Metric) error {
+	if o.WriteError != nil {
+		return o.WriteError
+	}
 	o.MetricsWritten = append(o.MetricsWritten, metrics...)
 	return nil
 }
@@ -2,10 +2,12 @@ package execd
 
 import (
 	"bufio"
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/influxdata/telegraf"
@@ -16,6 +18,8 @@ import (
 	"github.com/influxdata/telegraf/plugins/parsers/influx"
 )
 
+const defaultMaxUndeliveredBatches = 1000
+
 const sampleConfig = `
   ## Program to run as daemon
   command = ["telegraf-smartctl", "-d", "/dev/sda"]
@@ -33,6 +37,15 @@ const sampleConfig = `
   ## Delay before the process is restarted after an unexpected termination
   restart_delay = "10s"
 
+  ## Protocol used on STDIN and STDOUT of the process.  "plain" reads the
+  ## metrics as they are.  "framed" reads batches of metrics in frames and
+  ## acknowledges each batch once it has been written by the outputs.
+  # protocol = "plain"
+
+  ## Maximum number of batches to read before waiting for them to be
+  ## delivered when using the framed protocol.
+  # max_undelivered_batches = 1000
+
   ## Data format to consume.
   ## Each data format has its own unique set of configuration options, read
   ## more about them here:
@@ -40,15 +53,38 @@ const sampleConfig = `
   data_format = "influx"
 `
 
+type empty struct{}
+type semaphore chan empty
+
+// batch identifies a batch read from the process with the framed protocol.
+type batch struct {
+	generation uint64
+	id         uint64
+}
+
 type Execd struct {
-	Command      []string        `toml:"command"`
-	Signal       string          `toml:"signal"`
-	RestartDelay config.Duration `toml:"restart_delay"`
-	Log          telegraf.Logger `toml:"-"`
+	Command               []string        `toml:"command"`
+	Signal                string          `toml:"signal"`
+	RestartDelay          config.Duration `toml:"restart_delay"`
+	Protocol              string          `toml:"protocol"`
+	MaxUndeliveredBatches int             `toml:"max_undelivered_batches"`
+	Log                   telegraf.Logger `toml:"-"`
 
 	process *process.Process
 	acc     telegraf.Accumulator
 	parser  parsers.Parser
+
+	// framed protocol; IDs of the batches waiting for delivery.  The
+	// generation is increased when the process is restarted, the batches of
+	// an earlier process are not acknowledged to the new one.
+	trackingAcc telegraf.TrackingAccumulator
+	sem         semaphore
+	batches     map[telegraf.TrackingID]batch
+	generation  uint64
+	batchesLock sync.Mutex
+	ctx         context.Context
+	cancel      context.CancelFunc
+	wg          sync.WaitGroup
 }
 
 func (e *Execd) SampleConfig() string {
@@ -75,6 +111,22 @@ func (e *Execd) Start(acc telegraf.Accumulator) error {
 	e.process.ReadStdoutFn = e.cmdReadOut
 	e.process.ReadStderrFn = e.cmdReadErr
 
+	if e.Protocol == process.ProtocolFramed {
+		e.process.Env = []string{process.ProtocolEnv + "=" + process.ProtocolFramed}
+		e.process.ReadStdoutFn = e.cmdReadFrames
+
+		e.trackingAcc = acc.WithTracking(e.MaxUndeliveredBatches)
+		e.sem = make(semaphore, e.MaxUndeliveredBatches)
+		e.batches = make(map[telegraf.TrackingID]batch)
+
+		e.ctx, e.cancel = context.WithCancel(context.Background())
+		e.wg.Add(1)
+		go func() {
+			defer e.wg.Done()
+			e.acknowledge(e.ctx)
+		}()
+	}
+
 	if err = e.process.Start(); err != nil {
 		// if there was only one argument, and it contained spaces, warn the user
 		// that they may have configured it wrong.
@@ -90,7 +142,11 @@ func (e *Execd) Start(acc telegraf.Accumulator) error {
 }
 
 func (e *Execd) Stop() {
+	if e.cancel != nil {
+		e.cancel()
+	}
 	e.process.Stop()
+	e.wg.Wait()
 }
 
 func (e *Execd) cmdReadOut(out io.Reader) {
@@ -141,6 +197,102 @@ func (e *Execd) cmdReadOutStream(out io.Reader) {
 	}
 }
 
+// cmdReadFrames adds the batches of metrics read with the framed protocol as
+// tracking metrics.
+func (e *Execd) cmdReadFrames(out io.Reader) {
+	e.batchesLock.Lock()
+	e.generation++
+	generation := e.generation
+	e.batchesLock.Unlock()
+
+	scanner := process.NewFrameScanner(out)
+
+	for scanner.Scan() {
+		f, err := process.ParseFrame(scanner.Bytes())
+		if err != nil {
+			e.acc.AddError(err)
+			continue
+		}
+		if f.Type != process.FrameMetrics {
+			e.acc.AddError(fmt.Errorf("unexpected frame type %q", f.Type))
+			continue
+		}
+
+		metrics, err := e.parser.Parse([]byte(f.Metrics))
+		if err != nil {
+			e.acc.AddError(fmt.Errorf("parse error: %w", err))
+			e.writeFrame(&process.Frame{Type: process.FrameNack, ID: f.ID, Error: err.Error()})
+			continue
+		}
+		if len(metrics) == 0 {
+			e.writeFrame(&process.Frame{Type: process.FrameAck, ID: f.ID})
+			continue
+		}
+
+		// Wait for a slot, the process is blocked on writing in the meantime
+		select {
+		case e.sem <- empty{}:
+		case <-e.ctx.Done():
+			return
+		}
+
+		e.batchesLock.Lock()
+		id := e.trackingAcc.AddTrackingMetricGroup(metrics)
+		e.batches[id] = batch{generation: generation, id: f.ID}
+		e.batchesLock.Unlock()
+	}
+
+	if err := scanner.Err(); err != nil {
+		e.acc.AddError(fmt.Errorf("error reading stdout: %w", err))
+	}
+}
+
+// acknowledge reports the delivery of the batches to the process.
+func (e *Execd) acknowledge(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case info := <-e.trackingAcc.Delivered():
+			<-e.sem
+
+			e.batchesLock.Lock()
+			b, ok := e.batches[info.ID()]
+			delete(e.batches, info.ID())
+			current := b.generation == e.generation
+			e.batchesLock.Unlock()
+			if !ok {
+				continue
+			}
+			if !current {
+				e.Log.Debugf("Not acknowledging batch %d of a previous process", b.id)
+				continue
+			}
+
+			f := &process.Frame{Type: process.FrameAck, ID: b.id}
+			if !info.Delivered() {
+				f.Type = process.FrameNack
+			}
+			e.writeFrame(f)
+		}
+	}
+}
+
+func (e *Execd) writeFrame(f *process.Frame) {
+	if err := e.process.WriteFrame(f); err != nil {
+		e.acc.AddError(fmt.Errorf("error writing to stdin: %w", err))
+	}
+}
+
+// writeGatherPrompt asks the process to gather metrics on STDIN.
+func (e *Execd) writeGatherPrompt() error {
+	if e.Protocol == process.ProtocolFramed {
+		return e.process.WriteFrame(&process.Frame{Type: process.FrameGather})
+	}
+	_, err := io.WriteString(e.process.Stdin, "\n")
+	return err
+}
+
 func (e *Execd) cmdReadErr(out io.Reader) {
 	scanner := bufio.NewScanner(out)
 
@@ -157,14 +309,25 @@ func (e *Execd) Init() error {
 	if len(e.Command) == 0 {
 		return errors.New("no command specified")
 	}
+
+	switch e.Protocol {
+	case "", "plain", process.ProtocolFramed:
+	default:
+		return fmt.Errorf("unknown protocol %q", e.Protocol)
+	}
+	if e.MaxUndeliveredBatches <= 0 {
+		e.MaxUndeliveredBatches = defaultMaxUndeliveredBatches
+	}
 	return nil
 }
 
 func init() {
 	inputs.Add("execd", func() telegraf.Input {
 		return &Execd{
-			Signal:       "none",
-			RestartDelay: config.Duration(10 * time.Second),
+			Signal:                "none",
+			RestartDelay:          config.Duration(10 * time.Second),
+			Protocol:              "plain",
+			MaxUndeliveredBatches: defaultMaxUndeliveredBatches,
 		}
 	})
 }
@@ -4,7 +4,6 @@ package execd
 
 import (
 	"fmt"
-	"io"
 	"os"
 	"syscall"
 	"time"
@@ -32,7 +31,7 @@ func (e *Execd) Gather(acc telegraf.Accumulator) error {
 		if osStdin, ok := e.process.Stdin.(*os.File); ok {
 			osStdin.SetWriteDeadline(time.Now().Add(1 * time.Second))
 		}
-		if _, err := io.WriteString(e.process.Stdin, "\n"); err != nil {
+		if err := e.writeGatherPrompt(); err != nil {
 			return fmt.Errorf("Error writing to stdin: %s", err)
 		}
 	case "none":
@@ -15,6 +15,7 @@ import (
 	"github.com/influxdata/telegraf/config"
 	"github.com/influxdata/telegraf/metric"
 	"github.com/influxdata/telegraf/models"
+	"github.com/influxdata/telegraf/plugins/common/shim"
 	"github.com/influxdata/telegraf/testutil"
 	"github.com/stretchr/testify/require"
 
@@ -75,6 +76,58 @@ func TestExternalInputWorks(t *testing.T) {
 	require.EqualValues(t, 0, val)
 }
 
+func TestExternalInputFramed(t *testing.T) {
+	influxParser, err := parsers.NewInfluxParser()
+	require.NoError(t, err)
+
+	exe, err := os.Executable()
+	require.NoError(t, err)
+
+	e := &Execd{
+		Command:               []string{exe, "-framedcounter"},
+		RestartDelay:          config.Duration(5 * time.Second),
+		Protocol:              "framed",
+		MaxUndeliveredBatches: 10,
+		parser:                influxParser,
+		Signal:                "STDIN",
+		Log:                   testutil.Logger{},
+	}
+
+	metrics := make(chan telegraf.Metric, 10)
+	defer close(metrics)
+	acc := agent.NewAccumulator(&TestMetricMaker{}, metrics)
+
+	require.NoError(t, e.Start(acc))
+	defer e.Stop()
+
+	// gather returns the number of batches the program has seen accepted and
+	// rejected, and accepts or rejects the new batch.
+	gather := func(accept bool) (int64, int64) {
+		require.NoError(t, e.Gather(acc))
+		m := readChanWithTimeout(t, metrics, 10*time.Second)
+		require.Equal(t, "counter", m.Name())
+		if accept {
+			m.Accept()
+		} else {
+			m.Reject()
+		}
+		accepted, _ := m.GetField("accepted")
+		rejected, _ := m.GetField("rejected")
+		return accepted.(int64), rejected.(int64)
+	}
+
+	gather(false)
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		accepted, rejected := gather(true)
+		if rejected == 1 && accepted >= 1 {
+			break
+		}
+		require.True(t, time.Now().Before(deadline), "timeout waiting for acknowledgements")
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
 func TestParsesLinesContainingNewline(t *testing.T) {
 	parser, err := parsers.NewInfluxParser()
 	require.NoError(t, err)
@@ -153,12 +206,19 @@ func (tm *TestMetricMaker) Log() telegraf.Logger {
 var counter = flag.Bool("counter", false,
 	"if true, act like line input program instead of test")
 
+var framedcounter = flag.Bool("framedcounter", false,
+	"if true, run an input counting delivered batches in the shim instead of test")
+
 func TestMain(m *testing.M) {
 	flag.Parse()
 	if *counter {
 		runCounterProgram()
 		os.Exit(0)
 	}
+	if *framedcounter {
+		runFramedCounterProgram()
+		os.Exit(0)
+	}
 	code := m.Run()
 	os.Exit(code)
 }
@@ -191,3 +251,62 @@ func runCounterProgram() {
 	}
 
 }
+
+func runFramedCounterProgram() {
+	s := shim.New()
+	if err := s.AddInput(&deliveryCounter{}); err != nil {
+		fmt.Fprintf(os.Stderr, "ERR %v\n", err)
+		os.Exit(1)
+	}
+	if err := s.Run(shim.PollIntervalDisabled); err != nil {
+		fmt.Fprintf(os.Stderr, "ERR %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// deliveryCounter emits tracking metrics with the number of its previous
+// metrics that were accepted and rejected.
+type deliveryCounter struct {
+	acc      telegraf.TrackingAccumulator
+	accepted int64
+	rejected int64
+}
+
+func (c *deliveryCounter) SampleConfig() string {
+	return ""
+}
+
+func (c *deliveryCounter) Description() string {
+	return ""
+}
+
+func (c *deliveryCounter) Gather(acc telegraf.Accumulator) error {
+	if c.acc == nil {
+		c.acc = acc.WithTracking(100)
+	}
+
+	for {
+		select {
+		case info := <-c.acc.Delivered():
+			if info.Delivered() {
+				c.accepted++
+			} else {
+				c.rejected++
+			}
+			continue
+		default:
+		}
+		break
+	}
+
+	m, _ := metric.New("counter",
+		map[string]string{},
+		map[string]interface{}{
+			"accepted": c.accepted,
+			"rejected": c.rejected,
+		},
+		time.Now(),
+	)
+	c.acc.AddTrackingMetricGroup([]telegraf.Metric{m})
+	return nil
+}
@@ -0,0 +1,141 @@
+package grpc_plugin
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/common/grpcplugin"
+	"github.com/influxdata/telegraf/plugins/common/grpcplugin/pluginv1"
+	"github.com/influxdata/telegraf/plugins/inputs"
+)
+
+const sampleConfig = `
+  ## Program to run as plugin, it must serve the plugin on the socket passed
+  ## in the TELEGRAF_PLUGIN_SOCKET environment variable.
+  command = ["telegraf-smartctl-plugin"]
+
+  ## Delay before the process is restarted after an unexpected termination
+  # restart_delay = "10s"
+
+  ## Maximum time to wait for the plugin to serve after the process started
+  # start_timeout = "10s"
+
+  ## Interval of the health checks, the process is restarted when a check
+  ## fails.  Set to "0s" to disable the health checks.
+  # health_check_interval = "30s"
+
+  ## Settings of the plugin
+  # [inputs.grpc_plugin.config]
+  #   device = "/dev/sda"
+`
+
+type GRPCPlugin struct {
+	grpcplugin.Config
+	Log telegraf.Logger `toml:"-"`
+
+	client *grpcplugin.Client
+	acc    telegraf.Accumulator
+	wg     sync.WaitGroup
+}
+
+func (g *GRPCPlugin) SampleConfig() string {
+	return sampleConfig
+}
+
+func (g *GRPCPlugin) Description() string {
+	return "Run an external input plugin over gRPC"
+}
+
+func (g *GRPCPlugin) Init() error {
+	client, err := g.NewClient(pluginv1.PluginType_PLUGIN_TYPE_INPUT, g.Log)
+	if err != nil {
+		return err
+	}
+	client.OnReady = g.onReady
+	g.client = client
+	return nil
+}
+
+func (g *GRPCPlugin) Start(acc telegraf.Accumulator) error {
+	g.acc = acc
+	return g.client.Start()
+}
+
+func (g *GRPCPlugin) Stop() {
+	g.client.Stop()
+	g.wg.Wait()
+}
+
+// onReady starts service inputs and reads the metrics they send.
+func (g *GRPCPlugin) onReady(ctx context.Context, plugin pluginv1.PluginClient, info *pluginv1.InitResponse) error {
+	if !info.Service {
+		return nil
+	}
+
+	if _, err := plugin.Start(ctx, &pluginv1.StartRequest{}); err != nil {
+		return err
+	}
+	stream, err := plugin.Stream(ctx, &pluginv1.StreamRequest{})
+	if err != nil {
+		return err
+	}
+
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		g.readStream(ctx, stream)
+	}()
+	return nil
+}
+
+func (g *GRPCPlugin) readStream(ctx context.Context, stream pluginv1.Plugin_StreamClient) {
+	for {
+		batch, err := stream.Recv()
+		if err != nil {
+			if err != io.EOF && ctx.Err() == nil {
+				g.Log.Errorf("Reading metrics: %v", err)
+			}
+			return
+		}
+		addMetrics(g.acc, batch.Metrics)
+	}
+}
+
+func (g *GRPCPlugin) Gather(acc telegraf.Accumulator) error {
+	ctx, plugin, err := g.client.Plugin()
+	if err != nil {
+		return err
+	}
+
+	resp, err := plugin.Gather(ctx, &pluginv1.GatherRequest{})
+	if err != nil {
+		return err
+	}
+	for _, e := range resp.Errors {
+		acc.AddError(errors.New(e))
+	}
+	addMetrics(acc, resp.Metrics)
+	return nil
+}
+
+func addMetrics(acc telegraf.Accumulator, list []*pluginv1.Metric) {
+	for _, pm := range list {
+		m, err := grpcplugin.FromProto(pm)
+		if err != nil {
+			acc.AddError(err)
+			continue
+		}
+		acc.AddMetric(m)
+	}
+}
+
+func init() {
+	inputs.Add("grpc_plugin", func() telegraf.Input {
+		return &GRPCPlugin{
+			Config: grpcplugin.DefaultConfig(),
+		}
+	})
+}
@@ -0,0 +1,131 @@
+package grpc_plugin
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/common/grpcplugin"
+	"github.com/influxdata/telegraf/plugins/common/grpcplugin/server"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGather(t *testing.T) {
+	exe, err := os.Executable()
+	require.NoError(t, err)
+
+	g := &GRPCPlugin{Config: grpcplugin.DefaultConfig()}
+	g.Log = testutil.Logger{}
+	g.Command = []string{exe, "-counter"}
+	g.Config.Config = map[string]interface{}{"measurement": "counter"}
+	require.NoError(t, g.Init())
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, g.Start(acc))
+	defer g.Stop()
+
+	require.NoError(t, g.Gather(acc))
+	require.NoError(t, g.Gather(acc))
+
+	require.Len(t, acc.Metrics, 2)
+	require.Equal(t, "counter", acc.Metrics[1].Measurement)
+	require.Equal(t, int64(2), acc.Metrics[1].Fields["count"])
+	require.Len(t, acc.Errors, 2)
+	require.EqualError(t, acc.Errors[0], "counted 1")
+}
+
+func TestServiceInput(t *testing.T) {
+	exe, err := os.Executable()
+	require.NoError(t, err)
+
+	g := &GRPCPlugin{Config: grpcplugin.DefaultConfig()}
+	g.Log = testutil.Logger{}
+	g.Command = []string{exe, "-service"}
+	require.NoError(t, g.Init())
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, g.Start(acc))
+	defer g.Stop()
+
+	acc.Wait(1)
+	m := acc.GetTelegrafMetrics()[0]
+	require.Equal(t, "service", m.Name())
+	require.Equal(t, map[string]interface{}{"value": int64(42)}, m.Fields())
+}
+
+func TestNoCommand(t *testing.T) {
+	g := &GRPCPlugin{Config: grpcplugin.DefaultConfig()}
+	g.Log = testutil.Logger{}
+	require.Error(t, g.Init())
+}
+
+var counter = flag.Bool("counter", false,
+	"if true, act like an input plugin counting the gathers")
+var service = flag.Bool("service", false,
+	"if true, act like a service input plugin")
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+	if *counter {
+		runPlugin(&counterInput{})
+	}
+	if *service {
+		runPlugin(&serviceInput{})
+	}
+	code := m.Run()
+	os.Exit(code)
+}
+
+func runPlugin(plugin interface{}) {
+	if err := server.Serve(plugin); err != nil {
+		fmt.Fprintf(os.Stderr, "ERR %v\n", err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+type counterInput struct {
+	Measurement string `toml:"measurement"`
+
+	count int64
+}
+
+func (c *counterInput) Gather(acc telegraf.Accumulator) error {
+	c.count++
+	acc.AddFields(c.Measurement, map[string]interface{}{"count": c.count}, nil)
+	return fmt.Errorf("counted %d", c.count)
+}
+
+func (c *counterInput) SampleConfig() string {
+	return ""
+}
+
+func (c *counterInput) Description() string {
+	return ""
+}
+
+type serviceInput struct{}
+
+func (s *serviceInput) Start(acc telegraf.Accumulator) error {
+	go acc.AddFields("service", map[string]interface{}{"value": int64(42)}, nil, time.Now())
+	return nil
+}
+
+func (s *serviceInput) Stop() {}
+
+func (s *serviceInput) Gather(acc telegraf.Accumulator) error {
+	return errors.New("not supported")
+}
+
+func (s *serviceInput) SampleConfig() string {
+	return ""
+}
+
+func (s *serviceInput) Description() string {
+	return ""
+}
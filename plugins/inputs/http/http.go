@@ -1,6 +1,7 @@
 package http
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -124,12 +125,17 @@ func (h *HTTP) Init() error {
 // Gather takes in an accumulator and adds the metrics that the Input
 // gathers. This is called every "interval"
 func (h *HTTP) Gather(acc telegraf.Accumulator) error {
+	return h.GatherContext(context.Background(), acc)
+}
+
+// GatherContext is like Gather, but cancels the requests when ctx is done.
+func (h *HTTP) GatherContext(ctx context.Context, acc telegraf.Accumulator) error {
 	var wg sync.WaitGroup
 	for _, u := range h.URLs {
 		wg.Add(1)
 		go func(url string) {
 			defer wg.Done()
-			if err := h.gatherURL(acc, url); err != nil {
+			if err := h.gatherURL(ctx, acc, url); err != nil {
 				acc.AddError(fmt.Errorf("[url=%s]: %s", url, err))
 			}
 		}(u)
@@ -147,12 +153,14 @@ func (h *HTTP) SetParser(parser parsers.Parser) {
 
 // Gathers data from a particular URL
 // Parameters:
+//     ctx    : Context cancelling the request
 //     acc    : The telegraf Accumulator to use
 //     url    : endpoint to send request to
 //
 // Returns:
 //     error: Any error that may have occurred
 func (h *HTTP) gatherURL(
+	ctx context.Context,
 	acc telegraf.Accumulator,
 	url string,
 ) error {
@@ -162,7 +170,7 @@ func (h *HTTP) gatherURL(
 	}
 	defer body.Close()
 
-	request, err := http.NewRequest(h.Method, url, body)
+	request, err := http.NewRequestWithContext(ctx, h.Method, url, body)
 	if err != nil {
 		return err
 	}
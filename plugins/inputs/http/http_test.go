@@ -2,12 +2,15 @@ package http_test
 
 import (
 	"compress/gzip"
+	"context"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
+	"github.com/influxdata/telegraf/internal"
 	plugin "github.com/influxdata/telegraf/plugins/inputs/http"
 	"github.com/influxdata/telegraf/plugins/parsers"
 	"github.com/influxdata/telegraf/testutil"
@@ -252,3 +255,33 @@ func TestBodyAndContentEncoding(t *testing.T) {
 		})
 	}
 }
+
+func TestGatherContextCancel(t *testing.T) {
+	done := make(chan struct{})
+	defer close(done)
+	fakeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-done:
+		}
+	}))
+	defer fakeServer.Close()
+
+	plugin := &plugin.HTTP{
+		URLs:    []string{fakeServer.URL},
+		Timeout: internal.Duration{Duration: time.Minute},
+	}
+	parser, err := parsers.NewParser(&parsers.Config{DataFormat: "influx"})
+	require.NoError(t, err)
+	plugin.SetParser(parser)
+	require.NoError(t, plugin.Init())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	var acc testutil.Accumulator
+	start := time.Now()
+	require.NoError(t, plugin.GatherContext(ctx, &acc))
+	require.Less(t, int64(time.Since(start)), int64(10*time.Second))
+	require.Len(t, acc.Errors, 1)
+}
@@ -0,0 +1,198 @@
+package prometheus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/internal"
+)
+
+// Consul discovers targets from the service catalog of a Consul agent, or of
+// any server implementing the catalog endpoints of its HTTP API.
+type Consul struct {
+	Address         string            `toml:"address"`
+	Datacenter      string            `toml:"datacenter"`
+	Token           string            `toml:"token"`
+	Services        []string          `toml:"services"`
+	Tags            []string          `toml:"tags"`
+	Scheme          string            `toml:"scheme"`
+	MetricsPath     string            `toml:"metrics_path"`
+	RefreshInterval internal.Duration `toml:"refresh_interval"`
+
+	serviceFilter filter.Filter
+	client        *http.Client
+}
+
+// catalogService is an instance of a service as returned by the
+// /v1/catalog/service endpoint.
+type catalogService struct {
+	Node           string
+	Address        string
+	Datacenter     string
+	NodeMeta       map[string]string
+	ServiceID      string
+	ServiceName    string
+	ServiceTags    []string
+	ServiceAddress string
+	ServicePort    int
+	ServiceMeta    map[string]string
+}
+
+func (c *Consul) init(p *Prometheus) error {
+	if c.Address == "" {
+		c.Address = "http://127.0.0.1:8500"
+	}
+	if _, err := url.Parse(c.Address); err != nil {
+		return fmt.Errorf("invalid consul address %q: %v", c.Address, err)
+	}
+	if c.RefreshInterval.Duration <= 0 {
+		c.RefreshInterval.Duration = defaultRefreshInterval
+	}
+
+	var err error
+	c.serviceFilter, err = filter.Compile(c.Services)
+	if err != nil {
+		return err
+	}
+
+	c.client = &http.Client{Timeout: p.ResponseTimeout.Duration}
+	return nil
+}
+
+// refreshConsul replaces the targets discovered from Consul by the current
+// instances of the matching services.  On errors the previous targets are
+// kept.
+func (p *Prometheus) refreshConsul(ctx context.Context) {
+	targets, err := p.Consul.targets(ctx, p)
+	if err != nil {
+		p.Log.Errorf("Unable to discover targets from consul: %v", err)
+		return
+	}
+
+	p.lock.Lock()
+	p.consulTargets = targets
+	p.lock.Unlock()
+}
+
+func (c *Consul) targets(ctx context.Context, p *Prometheus) (map[string]URLAndAddress, error) {
+	// Map of the service names to the tags of all of their instances
+	var services map[string][]string
+	if err := c.get(ctx, "/v1/catalog/services", &services); err != nil {
+		return nil, err
+	}
+
+	targets := make(map[string]URLAndAddress)
+	for name, tags := range services {
+		if c.serviceFilter != nil && !c.serviceFilter.Match(name) {
+			continue
+		}
+		if !hasTags(tags, c.Tags) {
+			continue
+		}
+
+		var instances []catalogService
+		if err := c.get(ctx, "/v1/catalog/service/"+url.PathEscape(name), &instances); err != nil {
+			return nil, err
+		}
+		for _, instance := range instances {
+			if !hasTags(instance.ServiceTags, c.Tags) {
+				continue
+			}
+
+			target, ok, err := p.newTarget(c.labels(instance))
+			if err != nil {
+				p.Log.Errorf("Invalid target for service %q on node %q: %v", name, instance.Node, err)
+				continue
+			}
+			if ok {
+				targets[target.URL.String()] = target
+			}
+		}
+	}
+	return targets, nil
+}
+
+// labels returns the labels of a service instance, named like the ones of the
+// Consul service discovery in Prometheus.
+func (c *Consul) labels(s catalogService) map[string]string {
+	address := s.ServiceAddress
+	if address == "" {
+		address = s.Address
+	}
+
+	labels := map[string]string{
+		addressLabel:                    net.JoinHostPort(address, strconv.Itoa(s.ServicePort)),
+		schemeLabel:                     c.Scheme,
+		metricsPathLabel:                c.MetricsPath,
+		"__meta_consul_address":         s.Address,
+		"__meta_consul_dc":              s.Datacenter,
+		"__meta_consul_node":            s.Node,
+		"__meta_consul_service":         s.ServiceName,
+		"__meta_consul_service_address": s.ServiceAddress,
+		"__meta_consul_service_id":      s.ServiceID,
+		"__meta_consul_service_port":    strconv.Itoa(s.ServicePort),
+		"__meta_consul_tags":            "," + strings.Join(s.ServiceTags, ",") + ",",
+		"consul_service":                s.ServiceName,
+		"consul_node":                   s.Node,
+	}
+	for k, v := range s.NodeMeta {
+		labels["__meta_consul_metadata_"+k] = v
+	}
+	for k, v := range s.ServiceMeta {
+		labels["__meta_consul_service_metadata_"+k] = v
+	}
+	return labels
+}
+
+func (c *Consul) get(ctx context.Context, path string, v interface{}) error {
+	u, err := url.Parse(c.Address + path)
+	if err != nil {
+		return err
+	}
+	if c.Datacenter != "" {
+		u.RawQuery = url.Values{"dc": []string{c.Datacenter}}.Encode()
+	}
+
+	req, err := http.NewRequest("GET", u.String(), nil)
+	if err != nil {
+		return err
+	}
+	if c.Token != "" {
+		req.Header.Set("X-Consul-Token", c.Token)
+	}
+
+	resp, err := c.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned HTTP status %s", u, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// hasTags returns true if all of the wanted tags are in tags.
+func hasTags(tags []string, wanted []string) bool {
+	for _, w := range wanted {
+		found := false
+		for _, t := range tags {
+			if t == w {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,158 @@
+package prometheus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// consulCatalog serves the catalog endpoints of the Consul HTTP API.
+func consulCatalog(t *testing.T, instances map[string][]catalogService) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Consul-Token") != "secret" || r.URL.Query().Get("dc") != "dc1" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		var body interface{}
+		switch r.URL.Path {
+		case "/v1/catalog/services":
+			services := make(map[string][]string)
+			for name, list := range instances {
+				services[name] = []string{}
+				for _, s := range list {
+					services[name] = append(services[name], s.ServiceTags...)
+				}
+			}
+			body = services
+		default:
+			list, ok := instances[r.URL.Path[len("/v1/catalog/service/"):]]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			body = list
+		}
+		require.NoError(t, json.NewEncoder(w).Encode(body))
+	}))
+}
+
+func newConsul(address string) *Consul {
+	return &Consul{
+		Address:    address,
+		Datacenter: "dc1",
+		Token:      "secret",
+	}
+}
+
+func TestConsulTargets(t *testing.T) {
+	ts := consulCatalog(t, map[string][]catalogService{
+		"node_exporter": {
+			{Node: "a", Address: "10.0.0.1", ServiceName: "node_exporter", ServicePort: 9100, ServiceTags: []string{"prometheus"}},
+			{Node: "b", Address: "10.0.0.2", ServiceName: "node_exporter", ServicePort: 9100},
+		},
+		"app": {
+			{Node: "a", Address: "10.0.0.1", ServiceName: "app", ServiceAddress: "172.17.0.2", ServicePort: 8080,
+				ServiceTags: []string{"prometheus", "canary"}, ServiceMeta: map[string]string{"version": "2"}},
+		},
+		"consul": {
+			{Node: "a", Address: "10.0.0.1", ServiceName: "consul", ServicePort: 8300},
+		},
+	})
+	defer ts.Close()
+
+	p := &Prometheus{
+		Log:    testutil.Logger{},
+		Consul: newConsul(ts.URL),
+	}
+	p.Consul.Services = []string{"node_exporter", "app"}
+	p.Consul.Tags = []string{"prometheus"}
+	p.Consul.MetricsPath = "/stats"
+	require.NoError(t, p.Init())
+
+	targets, err := p.Consul.targets(context.Background(), p)
+	require.NoError(t, err)
+	require.Len(t, targets, 2)
+	require.Equal(t, map[string]string{"consul_service": "node_exporter", "consul_node": "a"},
+		targets["http://10.0.0.1:9100/stats"].Tags)
+	require.Equal(t, map[string]string{"consul_service": "app", "consul_node": "a"},
+		targets["http://172.17.0.2:8080/stats"].Tags)
+
+	// Drop canaries based on the tags and metadata
+	p.RelabelRules = []*RelabelRule{
+		{SourceLabels: []string{"__meta_consul_tags"}, Regex: ".*,canary,.*", Action: "drop"},
+	}
+	require.NoError(t, p.Init())
+	targets, err = p.Consul.targets(context.Background(), p)
+	require.NoError(t, err)
+	require.Len(t, targets, 1)
+
+	p.RelabelRules = []*RelabelRule{
+		{SourceLabels: []string{"__meta_consul_service_metadata_version"}, Regex: "2", Action: "keep"},
+	}
+	require.NoError(t, p.Init())
+	targets, err = p.Consul.targets(context.Background(), p)
+	require.NoError(t, err)
+	require.Len(t, targets, 1)
+	require.Contains(t, targets, "http://172.17.0.2:8080/stats")
+}
+
+func TestConsulGather(t *testing.T) {
+	metrics := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, sampleGaugeTextFormat)
+	}))
+	defer metrics.Close()
+
+	_, portString, err := net.SplitHostPort(metrics.Listener.Addr().String())
+	require.NoError(t, err)
+	port, err := strconv.Atoi(portString)
+	require.NoError(t, err)
+
+	ts := consulCatalog(t, map[string][]catalogService{
+		"app": {{Node: "a", Address: "127.0.0.1", ServiceName: "app", ServicePort: port}},
+	})
+	defer ts.Close()
+
+	p := &Prometheus{
+		Log:           testutil.Logger{},
+		Consul:        newConsul(ts.URL),
+		URLTag:        "url",
+		MetricVersion: 2,
+	}
+	require.NoError(t, p.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, p.Start(&acc))
+	defer p.Stop()
+
+	require.NoError(t, acc.GatherError(p.Gather))
+	require.True(t, acc.HasFloatField("prometheus", "go_goroutines"))
+	require.Equal(t, "app", acc.TagValue("prometheus", "consul_service"))
+	require.Equal(t, metrics.URL+"/metrics", acc.TagValue("prometheus", "url"))
+}
+
+func TestConsulErrors(t *testing.T) {
+	ts := consulCatalog(t, map[string][]catalogService{})
+	defer ts.Close()
+
+	p := &Prometheus{
+		Log:    testutil.Logger{},
+		Consul: &Consul{Address: ts.URL},
+	}
+	require.NoError(t, p.Init())
+	_, err := p.Consul.targets(context.Background(), p)
+	require.Error(t, err)
+
+	// Previous targets are kept on errors
+	p.consulTargets = map[string]URLAndAddress{"http://10.0.0.1:9100/metrics": {}}
+	p.refreshConsul(context.Background())
+	require.Len(t, p.consulTargets, 1)
+}
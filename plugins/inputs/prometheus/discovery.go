@@ -0,0 +1,241 @@
+package prometheus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/influxdata/telegraf/internal/globpath"
+)
+
+const (
+	// Labels with this prefix are only available to the relabel rules and
+	// are not added as tags.
+	reservedLabelPrefix = "__"
+
+	addressLabel     = "__address__"
+	schemeLabel      = "__scheme__"
+	metricsPathLabel = "__metrics_path__"
+)
+
+// RelabelRule keeps or drops discovered targets depending on whether the
+// values of the source labels match the regular expression.
+type RelabelRule struct {
+	SourceLabels []string `toml:"source_labels"`
+	Separator    string   `toml:"separator"`
+	Regex        string   `toml:"regex"`
+	Action       string   `toml:"action"`
+
+	regex *regexp.Regexp
+}
+
+func (r *RelabelRule) init() error {
+	switch r.Action {
+	case "keep", "drop":
+	default:
+		return fmt.Errorf("unknown relabel action %q", r.Action)
+	}
+
+	if r.Separator == "" {
+		r.Separator = ";"
+	}
+	if r.Regex == "" {
+		r.Regex = "(.*)"
+	}
+
+	// As in Prometheus the expression has to match the whole value
+	var err error
+	r.regex, err = regexp.Compile("^(?:" + r.Regex + ")$")
+	if err != nil {
+		return fmt.Errorf("invalid relabel regex %q: %v", r.Regex, err)
+	}
+	return nil
+}
+
+func (r *RelabelRule) keep(labels map[string]string) bool {
+	values := make([]string, 0, len(r.SourceLabels))
+	for _, l := range r.SourceLabels {
+		values = append(values, labels[l])
+	}
+	matched := r.regex.MatchString(strings.Join(values, r.Separator))
+	if r.Action == "drop" {
+		return !matched
+	}
+	return matched
+}
+
+// newTarget returns the target to scrape for the labels of a discovered
+// target, false is returned if the target is dropped by the relabel rules.
+func (p *Prometheus) newTarget(labels map[string]string) (URLAndAddress, bool, error) {
+	for _, r := range p.RelabelRules {
+		if !r.keep(labels) {
+			return URLAndAddress{}, false, nil
+		}
+	}
+
+	address := labels[addressLabel]
+	if address == "" {
+		return URLAndAddress{}, false, fmt.Errorf("target has no address")
+	}
+	scheme := labels[schemeLabel]
+	if scheme == "" {
+		scheme = "http"
+	}
+	path := labels[metricsPathLabel]
+	if path == "" {
+		path = "/metrics"
+	}
+
+	URL, err := url.Parse(scheme + "://" + address + path)
+	if err != nil {
+		return URLAndAddress{}, false, err
+	}
+
+	tags := make(map[string]string)
+	for k, v := range labels {
+		if !strings.HasPrefix(k, reservedLabelPrefix) {
+			tags[k] = v
+		}
+	}
+	return URLAndAddress{URL: URL, OriginalURL: URL, Tags: tags}, true, nil
+}
+
+// targetGroup is a list of targets sharing the same labels in the file_sd
+// format of Prometheus.
+type targetGroup struct {
+	Targets []string          `json:"targets"`
+	Labels  map[string]string `json:"labels"`
+}
+
+type targetFile struct {
+	modTime time.Time
+	targets map[string]URLAndAddress
+}
+
+func (p *Prometheus) initFileSD() error {
+	p.fileGlobs = nil
+	for _, pattern := range p.FileSDFiles {
+		g, err := globpath.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("invalid file_sd_files pattern %q: %v", pattern, err)
+		}
+		p.fileGlobs = append(p.fileGlobs, g)
+	}
+	p.targetFiles = make(map[string]*targetFile)
+	return nil
+}
+
+// refreshFiles reloads the target files which were added or modified since
+// the last call and forgets the targets of removed files.  If a file cannot
+// be read its previous targets are kept.
+func (p *Prometheus) refreshFiles() {
+	seen := make(map[string]bool)
+	for _, g := range p.fileGlobs {
+		for _, path := range g.Match() {
+			seen[path] = true
+
+			info, err := os.Stat(path)
+			if err != nil {
+				p.Log.Errorf("Unable to read target file: %v", err)
+				continue
+			}
+
+			previous, ok := p.targetFiles[path]
+			if ok && previous.modTime.Equal(info.ModTime()) {
+				continue
+			}
+
+			targets, err := p.readTargetFile(path)
+			if err != nil {
+				p.Log.Errorf("Unable to load targets from %q: %v", path, err)
+				if !ok {
+					continue
+				}
+				targets = previous.targets
+			} else {
+				p.Log.Debugf("Loaded %d targets from %q", len(targets), path)
+			}
+
+			p.lock.Lock()
+			p.targetFiles[path] = &targetFile{modTime: info.ModTime(), targets: targets}
+			p.lock.Unlock()
+		}
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	for path := range p.targetFiles {
+		if !seen[path] {
+			p.Log.Debugf("Removed targets of %q", path)
+			delete(p.targetFiles, path)
+		}
+	}
+}
+
+func (p *Prometheus) readTargetFile(path string) (map[string]URLAndAddress, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var groups []targetGroup
+	switch filepath.Ext(path) {
+	case ".json":
+		err = json.Unmarshal(data, &groups)
+	case ".yml", ".yaml":
+		err = yaml.Unmarshal(data, &groups)
+	default:
+		err = fmt.Errorf("unsupported file extension, expected .json, .yml or .yaml")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	targets := make(map[string]URLAndAddress)
+	for _, group := range groups {
+		for _, address := range group.Targets {
+			labels := map[string]string{
+				"__meta_filepath": path,
+			}
+			for k, v := range group.Labels {
+				labels[k] = v
+			}
+			labels[addressLabel] = address
+
+			target, ok, err := p.newTarget(labels)
+			if err != nil {
+				return nil, fmt.Errorf("invalid target %q: %v", address, err)
+			}
+			if ok {
+				targets[target.URL.String()] = target
+			}
+		}
+	}
+	return targets, nil
+}
+
+// refreshEvery calls refresh on every interval until the context is done.
+func (p *Prometheus) refreshEvery(ctx context.Context, interval time.Duration, refresh func(ctx context.Context)) {
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				refresh(ctx)
+			}
+		}
+	}()
+}
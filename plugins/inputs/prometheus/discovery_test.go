@@ -0,0 +1,179 @@
+package prometheus
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRelabelRules(t *testing.T) {
+	labels := map[string]string{
+		"__address__": "10.0.0.1:9100",
+		"env":         "production",
+		"job":         "node",
+	}
+
+	tests := []struct {
+		rule RelabelRule
+		keep bool
+	}{
+		{RelabelRule{SourceLabels: []string{"env"}, Regex: "production", Action: "keep"}, true},
+		{RelabelRule{SourceLabels: []string{"env"}, Regex: "prod", Action: "keep"}, false},
+		{RelabelRule{SourceLabels: []string{"env"}, Regex: "prod.*", Action: "drop"}, false},
+		{RelabelRule{SourceLabels: []string{"job", "env"}, Regex: "node;production", Action: "keep"}, true},
+		{RelabelRule{SourceLabels: []string{"job", "env"}, Separator: "/", Regex: "node/.*", Action: "keep"}, true},
+		{RelabelRule{SourceLabels: []string{"missing"}, Action: "drop"}, false},
+		{RelabelRule{SourceLabels: []string{"missing"}, Regex: ".+", Action: "keep"}, false},
+	}
+	for _, tt := range tests {
+		require.NoError(t, tt.rule.init())
+		require.Equal(t, tt.keep, tt.rule.keep(labels), tt.rule)
+	}
+
+	require.Error(t, (&RelabelRule{Action: "replace"}).init())
+	require.Error(t, (&RelabelRule{Regex: "(", Action: "keep"}).init())
+}
+
+func TestNewTarget(t *testing.T) {
+	p := &Prometheus{Log: testutil.Logger{}}
+
+	target, ok, err := p.newTarget(map[string]string{
+		"__address__":      "10.0.0.1:9100",
+		"__scheme__":       "https",
+		"__metrics_path__": "/federate",
+		"__meta_filepath":  "targets.json",
+		"env":              "production",
+	})
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "https://10.0.0.1:9100/federate", target.URL.String())
+	require.Equal(t, map[string]string{"env": "production"}, target.Tags)
+
+	target, ok, err = p.newTarget(map[string]string{"__address__": "10.0.0.1:9100"})
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, "http://10.0.0.1:9100/metrics", target.URL.String())
+
+	_, _, err = p.newTarget(map[string]string{"env": "production"})
+	require.Error(t, err)
+}
+
+func writeFile(t *testing.T, path string, content string, modTime time.Time) {
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0644))
+	require.NoError(t, os.Chtimes(path, modTime, modTime))
+}
+
+func targetURLs(t *testing.T, p *Prometheus) []string {
+	urls, err := p.GetAllURLs()
+	require.NoError(t, err)
+
+	var keys []string
+	for k := range urls {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func TestFileSD(t *testing.T) {
+	dir, err := ioutil.TempDir("", "file_sd")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	now := time.Now()
+	jsonFile := filepath.Join(dir, "targets.json")
+	yamlFile := filepath.Join(dir, "targets.yml")
+	writeFile(t, jsonFile, `[
+		{"targets": ["10.0.0.1:9100", "10.0.0.2:9100"], "labels": {"env": "production"}},
+		{"targets": ["10.0.0.3:9100"], "labels": {"env": "staging"}}
+	]`, now)
+	writeFile(t, yamlFile, `
+- targets: ["10.0.0.4:8080"]
+  labels:
+    __metrics_path__: /stats
+    job: app
+`, now)
+
+	p := &Prometheus{
+		Log:         testutil.Logger{},
+		FileSDFiles: []string{filepath.Join(dir, "*")},
+		RelabelRules: []*RelabelRule{
+			{SourceLabels: []string{"env"}, Regex: "staging", Action: "drop"},
+		},
+	}
+	require.NoError(t, p.Init())
+
+	p.refreshFiles()
+	require.Equal(t, []string{
+		"http://10.0.0.1:9100/metrics",
+		"http://10.0.0.2:9100/metrics",
+		"http://10.0.0.4:8080/stats",
+	}, targetURLs(t, p))
+	require.Equal(t, map[string]string{"job": "app"},
+		p.targetFiles[yamlFile].targets["http://10.0.0.4:8080/stats"].Tags)
+
+	// Unmodified files are not read again
+	require.NoError(t, ioutil.WriteFile(yamlFile, []byte("invalid"), 0644))
+	require.NoError(t, os.Chtimes(yamlFile, now, now))
+	p.refreshFiles()
+	require.Len(t, targetURLs(t, p), 3)
+
+	// Invalid files keep their previous targets
+	writeFile(t, yamlFile, "invalid", now.Add(time.Second))
+	p.refreshFiles()
+	require.Len(t, targetURLs(t, p), 3)
+
+	writeFile(t, jsonFile, `[{"targets": ["10.0.0.5:9100"]}]`, now.Add(time.Second))
+	require.NoError(t, os.Remove(yamlFile))
+	p.refreshFiles()
+	require.Equal(t, []string{"http://10.0.0.5:9100/metrics"}, targetURLs(t, p))
+}
+
+func TestFileSDGather(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintln(w, sampleGaugeTextFormat)
+	}))
+	defer ts.Close()
+
+	dir, err := ioutil.TempDir("", "file_sd")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "targets.json")
+	writeFile(t, file, fmt.Sprintf(`[{"targets": [%q], "labels": {"env": "production"}}]`,
+		ts.Listener.Addr().String()), time.Now())
+
+	p := &Prometheus{
+		Log:           testutil.Logger{},
+		FileSDFiles:   []string{file},
+		URLTag:        "url",
+		MetricVersion: 2,
+	}
+	require.NoError(t, p.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, p.Start(&acc))
+	defer p.Stop()
+
+	require.NoError(t, acc.GatherError(p.Gather))
+	require.True(t, acc.HasFloatField("prometheus", "go_goroutines"))
+	require.Equal(t, "production", acc.TagValue("prometheus", "env"))
+	require.Equal(t, ts.URL+"/metrics", acc.TagValue("prometheus", "url"))
+}
+
+func TestFileSDInvalidPattern(t *testing.T) {
+	p := &Prometheus{
+		Log:         testutil.Logger{},
+		FileSDFiles: []string{"/etc/telegraf/**/[.json"},
+	}
+	require.Error(t, p.Init())
+}
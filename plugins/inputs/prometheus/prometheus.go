@@ -13,12 +13,15 @@ import (
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/globpath"
 	"github.com/influxdata/telegraf/plugins/common/tls"
 	"github.com/influxdata/telegraf/plugins/inputs"
 )
 
 const acceptHeader = `application/vnd.google.protobuf;proto=io.prometheus.client.MetricFamily;encoding=delimited;q=0.7,text/plain;version=0.0.4;q=0.3,*/*;q=0.1`
 
+const defaultRefreshInterval = 30 * time.Second
+
 type Prometheus struct {
 	// An array of urls to scrape metrics from.
 	URLs []string `toml:"urls"`
@@ -35,6 +38,16 @@ type Prometheus struct {
 	// Field Selector/s for Kubernetes
 	KubernetesFieldSelector string `toml:"kubernetes_field_selector"`
 
+	// Files with targets in the file_sd format of Prometheus
+	FileSDFiles           []string          `toml:"file_sd_files"`
+	FileSDRefreshInterval internal.Duration `toml:"file_sd_refresh_interval"`
+
+	// Service discovery from a Consul catalog
+	Consul *Consul `toml:"consul"`
+
+	// Rules for keeping or dropping discovered targets
+	RelabelRules []*RelabelRule `toml:"relabel"`
+
 	// Bearer Token authorization file path
 	BearerToken       string `toml:"bearer_token"`
 	BearerTokenString string `toml:"bearer_token_string"`
@@ -60,6 +73,9 @@ type Prometheus struct {
 	PodNamespace   string `toml:"monitor_kubernetes_pods_namespace"`
 	lock           sync.Mutex
 	kubernetesPods map[string]URLAndAddress
+	fileGlobs      []*globpath.GlobPath
+	targetFiles    map[string]*targetFile
+	consulTargets  map[string]URLAndAddress
 	cancel         context.CancelFunc
 	wg             sync.WaitGroup
 }
@@ -102,6 +118,43 @@ var sampleConfig = `
   # eg. To scrape pods on a specific node
   # kubernetes_field_selector = "spec.nodeName=$HOSTNAME"
 
+  ## Files with targets in the file_sd format of Prometheus, either JSON or
+  ## YAML.  The files are checked for changes on every refresh interval.
+  ## The labels of the targets are added as tags.
+  # file_sd_files = ["/etc/telegraf/targets/*.json", "/etc/telegraf/targets/*.yml"]
+  # file_sd_refresh_interval = "30s"
+
+  ## Discover targets from the services in a Consul catalog.  The service
+  ## name and node are added as "consul_service" and "consul_node" tags.
+  # [inputs.prometheus.consul]
+  #   ## Address of the Consul HTTP API.
+  #   address = "http://127.0.0.1:8500"
+  #   ## Datacenter to query, by default the one of the agent.
+  #   # datacenter = ""
+  #   ## ACL token for the requests.
+  #   # token = ""
+  #   ## Glob patterns of the services to scrape, by default all.
+  #   # services = ["node_exporter", "*-metrics"]
+  #   ## Only scrape service instances having all of these tags.
+  #   # tags = ["prometheus"]
+  #   ## Scheme and path of the metrics endpoint of the instances.
+  #   # scheme = "http"
+  #   # metrics_path = "/metrics"
+  #   ## Interval for querying the catalog.
+  #   # refresh_interval = "30s"
+
+  ## Rules for keeping or dropping discovered targets, applied in order to
+  ## file and Consul targets.  The values of the source labels are joined by
+  ## the separator and matched against the regular expression.  Besides the
+  ## target labels, the "__address__", "__meta_filepath" and "__meta_consul_*"
+  ## labels are available.
+  # [[inputs.prometheus.relabel]]
+  #   source_labels = ["__meta_consul_tags"]
+  #   # separator = ";"
+  #   regex = ".*,production,.*"
+  #   ## Either "keep" or "drop" targets with matching values.
+  #   action = "keep"
+
   ## Use bearer token for authorization. ('bearer_token' takes priority)
   # bearer_token = "/path/to/bearer/token"
   ## OR
@@ -136,6 +189,25 @@ func (p *Prometheus) Init() error {
 		p.Log.Warnf("Use of deprecated configuration: 'metric_version = 1'; please update to 'metric_version = 2'")
 	}
 
+	for _, r := range p.RelabelRules {
+		if err := r.init(); err != nil {
+			return err
+		}
+	}
+
+	if err := p.initFileSD(); err != nil {
+		return err
+	}
+	if p.FileSDRefreshInterval.Duration <= 0 {
+		p.FileSDRefreshInterval.Duration = defaultRefreshInterval
+	}
+
+	if p.Consul != nil {
+		if err := p.Consul.init(p); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -184,6 +256,15 @@ func (p *Prometheus) GetAllURLs() (map[string]URLAndAddress, error) {
 	for k, v := range p.kubernetesPods {
 		allURLs[k] = v
 	}
+	// and the targets discovered from files and Consul
+	for _, f := range p.targetFiles {
+		for k, v := range f.targets {
+			allURLs[k] = v
+		}
+	}
+	for k, v := range p.consulTargets {
+		allURLs[k] = v
+	}
 
 	for _, service := range p.KubernetesServices {
 		URL, err := url.Parse(service)
@@ -363,18 +444,32 @@ func (p *Prometheus) gatherURL(u URLAndAddress, acc telegraf.Accumulator) error
 	return nil
 }
 
-// Start will start the Kubernetes scraping if enabled in the configuration
+// Start will start the service discovery and the Kubernetes scraping if
+// enabled in the configuration
 func (p *Prometheus) Start(a telegraf.Accumulator) error {
+	var ctx context.Context
+	ctx, p.cancel = context.WithCancel(context.Background())
+
+	// Discover the initial targets before the first gather
+	if len(p.fileGlobs) > 0 {
+		p.refreshFiles()
+		p.refreshEvery(ctx, p.FileSDRefreshInterval.Duration, func(context.Context) {
+			p.refreshFiles()
+		})
+	}
+	if p.Consul != nil {
+		p.refreshConsul(ctx)
+		p.refreshEvery(ctx, p.Consul.RefreshInterval.Duration, p.refreshConsul)
+	}
+
 	if p.MonitorPods {
-		var ctx context.Context
-		ctx, p.cancel = context.WithCancel(context.Background())
 		return p.start(ctx)
 	}
 	return nil
 }
 
 func (p *Prometheus) Stop() {
-	if p.MonitorPods {
+	if p.cancel != nil {
 		p.cancel()
 	}
 	p.wg.Wait()
@@ -0,0 +1,163 @@
+package replay
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/inputs"
+	"github.com/influxdata/telegraf/plugins/parsers/influx"
+)
+
+const (
+	timingOriginal = "original"
+	timingFast     = "fast"
+)
+
+const sampleConfig = `
+  ## File with the recorded metrics in line protocol, as written by the
+  ## record_file setting of inputs.  Files ending with ".gz" are gzip
+  ## compressed.
+  file = "/var/lib/telegraf/mysql.rec.gz"
+
+  ## Timing of the replay, either "original" to keep the relative timing of
+  ## the metric timestamps or "fast" to replay as fast as possible.
+  # timing = "original"
+
+  ## Shift the timestamps of the metrics, so that the first metric is at the
+  ## time the replay started.  Aggregators only accept metrics with recent
+  ## timestamps.
+  # shift_timestamps = false
+`
+
+type Replay struct {
+	File            string          `toml:"file"`
+	Timing          string          `toml:"timing"`
+	ShiftTimestamps bool            `toml:"shift_timestamps"`
+	Log             telegraf.Logger `toml:"-"`
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func (r *Replay) SampleConfig() string {
+	return sampleConfig
+}
+
+func (r *Replay) Description() string {
+	return "Replay metrics recorded from an input"
+}
+
+func (r *Replay) Init() error {
+	if r.File == "" {
+		return errors.New("file is required")
+	}
+
+	switch r.Timing {
+	case "":
+		r.Timing = timingOriginal
+	case timingOriginal, timingFast:
+	default:
+		return fmt.Errorf("invalid timing %q, expected %q or %q", r.Timing, timingOriginal, timingFast)
+	}
+	return nil
+}
+
+func (r *Replay) Start(acc telegraf.Accumulator) error {
+	file, err := os.Open(r.File)
+	if err != nil {
+		return err
+	}
+
+	var reader io.Reader = file
+	if strings.HasSuffix(r.File, ".gz") {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			file.Close()
+			return fmt.Errorf("reading %q failed: %v", r.File, err)
+		}
+		reader = gz
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		defer file.Close()
+		r.replay(ctx, acc, reader)
+	}()
+	return nil
+}
+
+func (r *Replay) replay(ctx context.Context, acc telegraf.Accumulator, reader io.Reader) {
+	parser := influx.NewStreamParser(reader)
+
+	start := time.Now()
+	var first time.Time
+	var count int
+	for {
+		m, err := parser.Next()
+		if err != nil {
+			if err == influx.EOF {
+				r.Log.Infof("Finished replaying %d metrics", count)
+				return
+			}
+			if parseErr, ok := err.(*influx.ParseError); ok {
+				acc.AddError(parseErr)
+				continue
+			}
+			// A recording may end with a partial line or gzip stream if it
+			// was not closed properly.
+			acc.AddError(fmt.Errorf("reading %q failed after %d metrics: %v", r.File, count, err))
+			return
+		}
+
+		if first.IsZero() {
+			first = m.Time()
+		}
+		offset := m.Time().Sub(first)
+
+		if r.Timing == timingOriginal && offset > 0 {
+			if err := internal.SleepContext(ctx, time.Until(start.Add(offset))); err != nil {
+				return
+			}
+		}
+		if r.ShiftTimestamps {
+			m.SetTime(start.Add(offset))
+		}
+
+		acc.AddMetric(m)
+		count++
+
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+func (r *Replay) Gather(acc telegraf.Accumulator) error {
+	return nil
+}
+
+func (r *Replay) Stop() {
+	if r.cancel != nil {
+		r.cancel()
+	}
+	r.wg.Wait()
+}
+
+func init() {
+	inputs.Add("replay", func() telegraf.Input {
+		return &Replay{}
+	})
+}
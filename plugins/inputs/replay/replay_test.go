@@ -0,0 +1,136 @@
+package replay
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/models"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func expectedMetrics() []telegraf.Metric {
+	return []telegraf.Metric{
+		testutil.MustMetric("cpu",
+			map[string]string{"cpu": "cpu0", "host": "db01"},
+			map[string]interface{}{"usage_idle": 98.5, "usage_user": 1.5},
+			time.Unix(1591000000, 0),
+		),
+		testutil.MustMetric("mem",
+			map[string]string{"host": "db01"},
+			map[string]interface{}{"used": int64(4096), "available_percent": 72.25},
+			time.Unix(1591000000, 0),
+		),
+		testutil.MustMetric("mysql",
+			map[string]string{"host": "db01", "server": "127.0.0.1:3306"},
+			map[string]interface{}{"queries": uint64(1234), "version": "8.0.20", "read_only": false},
+			time.Unix(1591000010, 0),
+		),
+	}
+}
+
+func TestInit(t *testing.T) {
+	r := &Replay{File: "testdata/metrics.lp"}
+	require.NoError(t, r.Init())
+	require.Equal(t, timingOriginal, r.Timing)
+
+	require.Error(t, (&Replay{}).Init())
+	require.Error(t, (&Replay{File: "testdata/metrics.lp", Timing: "slow"}).Init())
+}
+
+func TestReplayFast(t *testing.T) {
+	r := &Replay{
+		File:   "testdata/metrics.lp",
+		Timing: timingFast,
+		Log:    testutil.Logger{},
+	}
+	require.NoError(t, r.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, r.Start(&acc))
+	acc.Wait(3)
+	r.Stop()
+
+	require.Empty(t, acc.Errors)
+	testutil.RequireMetricsEqual(t, expectedMetrics(), acc.GetTelegrafMetrics())
+}
+
+func TestReplayOriginalTiming(t *testing.T) {
+	dir, err := ioutil.TempDir("", "replay")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "metrics.lp")
+	require.NoError(t, ioutil.WriteFile(file, []byte(
+		"test value=1i 1000000000\n"+
+			"test value=2i 1100000000\n"+
+			"test value=3i 1200000000\n"), 0640))
+
+	r := &Replay{
+		File:            file,
+		ShiftTimestamps: true,
+		Log:             testutil.Logger{},
+	}
+	require.NoError(t, r.Init())
+
+	var acc testutil.Accumulator
+	start := time.Now()
+	require.NoError(t, r.Start(&acc))
+	acc.Wait(3)
+	elapsed := time.Since(start)
+	r.Stop()
+
+	require.True(t, elapsed >= 200*time.Millisecond, "replayed in %s", elapsed)
+
+	// The relative timing of the shifted timestamps is kept
+	metrics := acc.GetTelegrafMetrics()
+	require.Len(t, metrics, 3)
+	require.False(t, metrics[0].Time().Before(start.Truncate(time.Second)))
+	require.Equal(t, 100*time.Millisecond, metrics[1].Time().Sub(metrics[0].Time()))
+	require.Equal(t, 200*time.Millisecond, metrics[2].Time().Sub(metrics[0].Time()))
+}
+
+func TestReplayRecording(t *testing.T) {
+	dir, err := ioutil.TempDir("", "replay")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	// Record in two sessions, each appending a gzip stream
+	file := filepath.Join(dir, "metrics.rec.gz")
+	expected := expectedMetrics()
+	for _, metrics := range [][]telegraf.Metric{expected[:2], expected[2:]} {
+		recorder, err := models.NewRecorder(file)
+		require.NoError(t, err)
+		for _, m := range metrics {
+			require.NoError(t, recorder.Record(m))
+		}
+		require.NoError(t, recorder.Close())
+	}
+
+	r := &Replay{
+		File:   file,
+		Timing: timingFast,
+		Log:    testutil.Logger{},
+	}
+	require.NoError(t, r.Init())
+
+	var acc testutil.Accumulator
+	require.NoError(t, r.Start(&acc))
+	acc.Wait(3)
+	r.Stop()
+
+	require.Empty(t, acc.Errors)
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+}
+
+func TestReplayMissingFile(t *testing.T) {
+	r := &Replay{File: "testdata/missing.lp", Log: testutil.Logger{}}
+	require.NoError(t, r.Init())
+
+	var acc testutil.Accumulator
+	require.Error(t, r.Start(&acc))
+}
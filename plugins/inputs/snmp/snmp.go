@@ -28,6 +28,11 @@ const sampleConfig = `
   ##            agents = ["tcp://127.0.0.1:161"]
   agents = ["udp://127.0.0.1:161"]
 
+  ## Paths to MIB files and directories, loaded on startup to translate OIDs
+  ## without the net-snmp tools.  OIDs not found in these MIBs are translated
+  ## with the net-snmp tools, if installed.
+  # path = ["/usr/share/snmp/mibs"]
+
   ## Timeout for each request.
   # timeout = "5s"
 
@@ -95,6 +100,9 @@ type Snmp struct {
 	// udp://1.2.3.4:161).  If the scheme is not specified then "udp" is used.
 	Agents []string `toml:"agents"`
 
+	// Paths to MIB files loaded for OID translation.
+	Path []string `toml:"path"`
+
 	snmp.ClientConfig
 
 	Tables []Table `toml:"table"`
@@ -105,6 +113,8 @@ type Snmp struct {
 	Name   string  // deprecated in 1.14; use name_override
 	Fields []Field `toml:"field"`
 
+	Log telegraf.Logger `toml:"-"`
+
 	connectionCache []snmpConnection
 	initialized     bool
 }
@@ -116,6 +126,12 @@ func (s *Snmp) init() error {
 
 	s.connectionCache = make([]snmpConnection, len(s.Agents))
 
+	if len(s.Path) != 0 {
+		if err := snmp.LoadMibsFromPath(s.Path, s.Log); err != nil {
+			return err
+		}
+	}
+
 	for i := range s.Tables {
 		if err := s.Tables[i].Init(); err != nil {
 			return fmt.Errorf("initializing table %s: %w", s.Tables[i].Name, err)
@@ -151,9 +167,41 @@ type Table struct {
 	// given OID.
 	Oid string
 
+	// IndexTags translates the components of each row's table index into
+	// tags.  Each entry is of the form "<tag>:<type>", see parseIndexTag for
+	// the supported types.
+	IndexTags []string `toml:"index_tags"`
+
+	// Joins are the tables whose rows are joined onto the rows of this table.
+	Joins []Join `toml:"join"`
+
+	indexTags   []indexTag
 	initialized bool
 }
 
+// Join holds the configuration for a table whose columns are added to the
+// rows of another table.
+type Join struct {
+	// OID of the joined table for automatic field population.
+	Oid string
+
+	// IndexField is the name of the tag or field of the primary table holding
+	// the index of the row to join.  If empty, rows with the same index are
+	// joined.
+	IndexField string `toml:"index_field"`
+
+	// Fields is the tags and values to look up.
+	Fields []Field `toml:"field"`
+
+	table Table
+}
+
+// indexTag is a component of a table index translated into a tag.
+type indexTag struct {
+	name string
+	kind string
+}
+
 // Init() builds & initializes the nested fields.
 func (t *Table) Init() error {
 	if t.initialized {
@@ -171,13 +219,30 @@ func (t *Table) Init() error {
 		}
 	}
 
+	t.indexTags = make([]indexTag, 0, len(t.IndexTags))
+	for _, spec := range t.IndexTags {
+		it, err := parseIndexTag(spec)
+		if err != nil {
+			return err
+		}
+		t.indexTags = append(t.indexTags, it)
+	}
+
+	for i := range t.Joins {
+		j := &t.Joins[i]
+		j.table = Table{Oid: j.Oid, Fields: j.Fields}
+		if err := j.table.Init(); err != nil {
+			return fmt.Errorf("initializing join %s: %w", j.Oid, err)
+		}
+	}
+
 	t.initialized = true
 	return nil
 }
 
 // initBuild initializes the table if it has an OID configured. If so, the
-// net-snmp tools will be used to look up the OID and auto-populate the table's
-// fields.
+// loaded MIBs or the net-snmp tools will be used to look up the OID and
+// auto-populate the table's fields.
 func (t *Table) initBuild() error {
 	if t.Oid == "" {
 		return nil
@@ -226,8 +291,12 @@ type Field struct {
 	//  "int" will conver the value into an integer.
 	//  "hwaddr" will convert a 6-byte string to a MAC address.
 	//  "ipaddr" will convert the value to an IPv4 or IPv6 address.
+	//  "enum" will convert the value to its label in the MIB.
 	Conversion string
 
+	// enums are the labels of the enumerated values, used by the enum
+	// conversion.
+	enums       map[int64]string
 	initialized bool
 }
 
@@ -249,7 +318,16 @@ func (f *Field) init() error {
 		f.Conversion = conversion
 	}
 
-	//TODO use textual convention conversion from the MIB
+	if f.Conversion == "enum" {
+		node, _, err := snmp.LookupOid(f.Oid)
+		if err != nil {
+			return fmt.Errorf("enum conversion requires the MIB to be loaded from path: %w", err)
+		}
+		if len(node.Enums) == 0 {
+			return fmt.Errorf("enum conversion: %s::%s is not an enumeration", node.Module, node.Name)
+		}
+		f.enums = node.Enums
+	}
 
 	f.initialized = true
 	return nil
@@ -385,6 +463,31 @@ func (s *Snmp) gatherTable(acc telegraf.Accumulator, gs snmpConnection, t Table,
 
 // Build retrieves all the fields specified in the table and constructs the RTable.
 func (t Table) Build(gs snmpConnection, walk bool) (*RTable, error) {
+	rows, err := t.buildRows(gs, walk)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, j := range t.Joins {
+		if err := j.join(gs, rows); err != nil {
+			return nil, fmt.Errorf("joining table %s: %w", j.Oid, err)
+		}
+	}
+
+	rt := RTable{
+		Name: t.Name,
+		Time: time.Now(), //TODO record time at start
+		Rows: make([]RTableRow, 0, len(rows)),
+	}
+	for _, r := range rows {
+		rt.Rows = append(rt.Rows, r)
+	}
+	return &rt, nil
+}
+
+// buildRows retrieves all the fields specified in the table and returns the
+// rows by their table index.
+func (t Table) buildRows(gs snmpConnection, walk bool) (map[string]RTableRow, error) {
 	rows := map[string]RTableRow{}
 
 	tagCount := 0
@@ -417,7 +520,7 @@ func (t Table) Build(gs snmpConnection, walk bool) (*RTable, error) {
 				return nil, fmt.Errorf("performing get on field %s: %w", f.Name, err)
 			} else if pkt != nil && len(pkt.Variables) > 0 && pkt.Variables[0].Type != gosnmp.NoSuchObject && pkt.Variables[0].Type != gosnmp.NoSuchInstance {
 				ent := pkt.Variables[0]
-				fv, err := fieldConvert(f.Conversion, ent.Value)
+				fv, err := f.convert(ent.Value)
 				if err != nil {
 					return nil, fmt.Errorf("converting %q (OID %s) for field %s: %w", ent.Value, ent.Name, f.Name, err)
 				}
@@ -450,7 +553,7 @@ func (t Table) Build(gs snmpConnection, walk bool) (*RTable, error) {
 					}, idx)
 				}
 
-				fv, err := fieldConvert(f.Conversion, ent.Value)
+				fv, err := f.convert(ent.Value)
 				if err != nil {
 					return &walkError{
 						msg: fmt.Sprintf("converting %q (OID %s) for field %s", ent.Value, ent.Name, f.Name),
@@ -478,6 +581,15 @@ func (t Table) Build(gs snmpConnection, walk bool) (*RTable, error) {
 				rtr.Fields = map[string]interface{}{}
 				rows[idx] = rtr
 			}
+			if len(t.indexTags) != 0 && idx != "" {
+				// Rows with an index not matching the index tags are
+				// left without them.
+				if tags, err := decodeIndex(idx, t.indexTags); err == nil {
+					for k, v := range tags {
+						rtr.Tags[k] = v
+					}
+				}
+			}
 			if t.IndexAsTag && idx != "" {
 				if idx[0] == '.' {
 					idx = idx[1:]
@@ -499,15 +611,185 @@ func (t Table) Build(gs snmpConnection, walk bool) (*RTable, error) {
 		}
 	}
 
-	rt := RTable{
-		Name: t.Name,
-		Time: time.Now(), //TODO record time at start
-		Rows: make([]RTableRow, 0, len(rows)),
+	return rows, nil
+}
+
+// join adds the tags and fields of the joined table to the matching rows.
+// Tags and fields already present in a row are kept.
+func (j *Join) join(gs snmpConnection, rows map[string]RTableRow) error {
+	joined, err := j.table.buildRows(gs, true)
+	if err != nil {
+		return err
 	}
-	for _, r := range rows {
-		rt.Rows = append(rt.Rows, r)
+
+	for idx, row := range rows {
+		if j.IndexField != "" {
+			v, ok := row.Tags[j.IndexField]
+			if !ok {
+				fv, ok := row.Fields[j.IndexField]
+				if !ok {
+					continue
+				}
+				v = fmt.Sprintf("%v", fv)
+			}
+			idx = "." + strings.TrimPrefix(v, ".")
+		}
+
+		jrow, ok := joined[idx]
+		if !ok {
+			continue
+		}
+		for k, v := range jrow.Tags {
+			if _, ok := row.Tags[k]; !ok {
+				row.Tags[k] = v
+			}
+		}
+		for k, v := range jrow.Fields {
+			if _, ok := row.Fields[k]; !ok {
+				row.Fields[k] = v
+			}
+		}
 	}
-	return &rt, nil
+	return nil
+}
+
+// parseIndexTag parses an index tag specification of the form
+// "<tag>:<type>".  The supported types are:
+//  "int" a single sub-identifier.
+//  "ipaddr" an IPv4 address of four sub-identifiers.
+//  "ipv6addr" an IPv6 address of sixteen sub-identifiers.
+//  "inetaddr" a length prefixed IPv4 or IPv6 address.
+//  "hwaddr" a MAC address of six sub-identifiers.
+//  "string" a length prefixed string.
+//  "implied_string" a string made of the remaining sub-identifiers.
+//  "oid" a length prefixed OID.
+//  "implied_oid" an OID made of the remaining sub-identifiers.
+func parseIndexTag(spec string) (indexTag, error) {
+	i := strings.LastIndex(spec, ":")
+	if i < 1 {
+		return indexTag{}, fmt.Errorf("invalid index tag %q: expected <tag>:<type>", spec)
+	}
+
+	it := indexTag{name: spec[:i], kind: spec[i+1:]}
+	switch it.kind {
+	case "int", "ipaddr", "ipv6addr", "inetaddr", "hwaddr", "string", "implied_string", "oid", "implied_oid":
+		return it, nil
+	}
+	return indexTag{}, fmt.Errorf("invalid index tag %q: unknown type %q", spec, it.kind)
+}
+
+// decodeIndex translates the components of a table index into tags.
+func decodeIndex(idx string, indexTags []indexTag) (map[string]string, error) {
+	parts := strings.Split(strings.TrimPrefix(idx, "."), ".")
+	subids := make([]uint64, 0, len(parts))
+	for _, part := range parts {
+		v, err := strconv.ParseUint(part, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid index %q", idx)
+		}
+		subids = append(subids, v)
+	}
+
+	take := func(n uint64) ([]uint64, error) {
+		if n > uint64(len(subids)) {
+			return nil, fmt.Errorf("index %q too short", idx)
+		}
+		v := subids[:n]
+		subids = subids[n:]
+		return v, nil
+	}
+	takeBytes := func(n uint64) ([]byte, error) {
+		v, err := take(n)
+		if err != nil {
+			return nil, err
+		}
+		b := make([]byte, 0, len(v))
+		for _, id := range v {
+			if id > 255 {
+				return nil, fmt.Errorf("invalid octet %d in index %q", id, idx)
+			}
+			b = append(b, byte(id))
+		}
+		return b, nil
+	}
+	takeLength := func() (uint64, error) {
+		v, err := take(1)
+		if err != nil {
+			return 0, err
+		}
+		return v[0], nil
+	}
+
+	tags := make(map[string]string, len(indexTags))
+	for _, it := range indexTags {
+		var value string
+		switch it.kind {
+		case "int":
+			v, err := take(1)
+			if err != nil {
+				return nil, err
+			}
+			value = strconv.FormatUint(v[0], 10)
+		case "ipaddr", "ipv6addr", "inetaddr":
+			n := uint64(net.IPv4len)
+			if it.kind == "ipv6addr" {
+				n = net.IPv6len
+			} else if it.kind == "inetaddr" {
+				var err error
+				if n, err = takeLength(); err != nil {
+					return nil, err
+				}
+				if n != net.IPv4len && n != net.IPv6len {
+					return nil, fmt.Errorf("invalid address length %d in index %q", n, idx)
+				}
+			}
+			b, err := takeBytes(n)
+			if err != nil {
+				return nil, err
+			}
+			value = net.IP(b).String()
+		case "hwaddr":
+			b, err := takeBytes(6)
+			if err != nil {
+				return nil, err
+			}
+			value = net.HardwareAddr(b).String()
+		case "string", "implied_string":
+			n := uint64(len(subids))
+			if it.kind == "string" {
+				var err error
+				if n, err = takeLength(); err != nil {
+					return nil, err
+				}
+			}
+			b, err := takeBytes(n)
+			if err != nil {
+				return nil, err
+			}
+			value = string(b)
+		case "oid", "implied_oid":
+			n := uint64(len(subids))
+			if it.kind == "oid" {
+				var err error
+				if n, err = takeLength(); err != nil {
+					return nil, err
+				}
+			}
+			v, err := take(n)
+			if err != nil {
+				return nil, err
+			}
+			for _, id := range v {
+				value += "." + strconv.FormatUint(id, 10)
+			}
+		}
+		tags[it.name] = value
+	}
+
+	if len(subids) != 0 {
+		return nil, fmt.Errorf("index %q longer than the index tags", idx)
+	}
+	return tags, nil
 }
 
 // snmpConnection is an interface which wraps a *gosnmp.GoSNMP object.
@@ -673,6 +955,33 @@ func fieldConvert(conv string, v interface{}) (interface{}, error) {
 	return nil, fmt.Errorf("invalid conversion type '%s'", conv)
 }
 
+// convert applies the conversion of the field to a value.
+func (f *Field) convert(v interface{}) (interface{}, error) {
+	if f.Conversion != "enum" {
+		return fieldConvert(f.Conversion, v)
+	}
+
+	var i int64
+	switch vt := v.(type) {
+	case int:
+		i = int64(vt)
+	case int32:
+		i = int64(vt)
+	case int64:
+		i = vt
+	case uint:
+		i = int64(vt)
+	case uint32:
+		i = int64(vt)
+	default:
+		return nil, fmt.Errorf("invalid type (%T) for enum conversion", v)
+	}
+	if label, ok := f.enums[i]; ok {
+		return label, nil
+	}
+	return strconv.FormatInt(i, 10), nil
+}
+
 type snmpTableCache struct {
 	mibName string
 	oidNum  string
@@ -704,6 +1013,12 @@ func snmpTable(oid string) (mibName string, oidNum string, oidText string, field
 }
 
 func snmpTableCall(oid string) (mibName string, oidNum string, oidText string, fields []Field, err error) {
+	if snmp.MibsLoaded() {
+		if table, err := snmp.LookupTable(oid); err == nil {
+			return mibTable(table)
+		}
+	}
+
 	mibName, oidNum, oidText, _, err = SnmpTranslate(oid)
 	if err != nil {
 		return "", "", "", nil, fmt.Errorf("translating: %w", err)
@@ -762,6 +1077,26 @@ func snmpTableCall(oid string) (mibName string, oidNum string, oidText string, f
 	return mibName, oidNum, oidText, fields, err
 }
 
+// mibTable converts a table of the loaded MIBs to the table fields.
+func mibTable(table *snmp.MibTable) (mibName string, oidNum string, oidText string, fields []Field, err error) {
+	mibPrefix := table.Table.Module + "::"
+
+	tagOids := map[string]struct{}{}
+	for _, col := range table.Index {
+		tagOids[col] = struct{}{}
+	}
+
+	for _, col := range table.Columns {
+		_, isTag := tagOids[col.Name]
+		fields = append(fields, Field{Name: col.Name, Oid: mibPrefix + col.Name, IsTag: isTag})
+	}
+	if len(fields) == 0 {
+		return "", "", "", nil, fmt.Errorf("could not find any columns in table")
+	}
+
+	return table.Table.Module, table.Table.Oid, table.Table.Name, fields, nil
+}
+
 type snmpTranslateCache struct {
 	mibName    string
 	oidNum     string
@@ -823,6 +1158,13 @@ func SnmpTranslateClear() {
 }
 
 func snmpTranslateCall(oid string) (mibName string, oidNum string, oidText string, conversion string, err error) {
+	if snmp.MibsLoaded() {
+		mibName, oidNum, oidText, conversion, err = snmp.TranslateOid(oid)
+		if err == nil {
+			return mibName, oidNum, oidText, conversion, nil
+		}
+	}
+
 	var out []byte
 	if strings.ContainsAny(oid, ":abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ") {
 		out, err = execCmd("snmptranslate", "-Td", "-Ob", oid)
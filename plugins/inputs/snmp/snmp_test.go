@@ -587,6 +587,167 @@ func TestTableBuild_noWalk(t *testing.T) {
 	assert.Contains(t, tb.Rows, rtr)
 }
 
+func TestTableBuild_join(t *testing.T) {
+	gs := &testSNMPConnection{
+		host: "tsc",
+		values: map[string]interface{}{
+			// primary table
+			".1.0.1.1.1.1": []byte("eth0"),
+			".1.0.1.1.1.2": []byte("eth1"),
+			".1.0.1.1.2.1": 100,
+			".1.0.1.1.2.2": 200,
+			".1.0.1.1.3.1": 7,
+			// secondary table with the same index
+			".1.0.2.1.1.1": []byte("uplink"),
+			".1.0.2.1.1.3": []byte("unmatched"),
+			".1.0.2.1.2.1": 1000,
+			// table indexed by the foreign index field
+			".1.0.3.1.1.7": []byte("chassis"),
+		},
+	}
+
+	tbl := Table{
+		Name: "mytable",
+		Fields: []Field{
+			{Name: "name", Oid: ".1.0.1.1.1", IsTag: true, initialized: true},
+			{Name: "in", Oid: ".1.0.1.1.2", initialized: true},
+			{Name: "physical", Oid: ".1.0.1.1.3", initialized: true},
+		},
+		Joins: []Join{
+			{
+				Fields: []Field{
+					{Name: "alias", Oid: ".1.0.2.1.1", IsTag: true, initialized: true},
+					{Name: "speed", Oid: ".1.0.2.1.2", initialized: true},
+					{Name: "in", Oid: ".1.0.2.1.2", initialized: true},
+				},
+			},
+			{
+				IndexField: "physical",
+				Fields: []Field{
+					{Name: "entity", Oid: ".1.0.3.1.1", IsTag: true, initialized: true},
+				},
+			},
+		},
+	}
+	require.NoError(t, tbl.Init())
+
+	tb, err := tbl.Build(gs, true)
+	require.NoError(t, err)
+
+	assert.Len(t, tb.Rows, 2)
+	assert.Contains(t, tb.Rows, RTableRow{
+		Tags: map[string]string{
+			"name":   "eth0",
+			"alias":  "uplink",
+			"entity": "chassis",
+		},
+		Fields: map[string]interface{}{
+			"in":       100,
+			"physical": 7,
+			"speed":    1000,
+		},
+	})
+	assert.Contains(t, tb.Rows, RTableRow{
+		Tags: map[string]string{
+			"name": "eth1",
+		},
+		Fields: map[string]interface{}{
+			"in": 200,
+		},
+	})
+}
+
+func TestTableBuild_indexTags(t *testing.T) {
+	gs := &testSNMPConnection{
+		host: "tsc",
+		values: map[string]interface{}{
+			".1.0.1.1.1.10.0.0.1":                      1,
+			".1.0.1.1.1.192.168.1.254":                 2,
+			".1.0.2.1.1.4.101.116.104.48.1.4.10.0.0.1": 3,
+			".1.0.2.1.1.2.101.116.104.48":              4,
+		},
+	}
+
+	tbl := Table{
+		Name:      "addresses",
+		IndexTags: []string{"address:ipaddr"},
+		Fields: []Field{
+			{Name: "ifindex", Oid: ".1.0.1.1.1", initialized: true},
+		},
+	}
+	require.NoError(t, tbl.Init())
+
+	tb, err := tbl.Build(gs, true)
+	require.NoError(t, err)
+	assert.Len(t, tb.Rows, 2)
+	assert.Contains(t, tb.Rows, RTableRow{
+		Tags:   map[string]string{"address": "10.0.0.1"},
+		Fields: map[string]interface{}{"ifindex": 1},
+	})
+	assert.Contains(t, tb.Rows, RTableRow{
+		Tags:   map[string]string{"address": "192.168.1.254"},
+		Fields: map[string]interface{}{"ifindex": 2},
+	})
+
+	// Rows not matching the index tags are kept without them.
+	tbl = Table{
+		Name:      "neighbors",
+		IndexTags: []string{"interface:string", "type:int", "address:inetaddr"},
+		Fields: []Field{
+			{Name: "value", Oid: ".1.0.2.1.1", initialized: true},
+		},
+	}
+	require.NoError(t, tbl.Init())
+
+	tb, err = tbl.Build(gs, true)
+	require.NoError(t, err)
+	assert.Len(t, tb.Rows, 2)
+	assert.Contains(t, tb.Rows, RTableRow{
+		Tags:   map[string]string{"interface": "eth0", "type": "1", "address": "10.0.0.1"},
+		Fields: map[string]interface{}{"value": 3},
+	})
+	assert.Contains(t, tb.Rows, RTableRow{
+		Tags:   map[string]string{},
+		Fields: map[string]interface{}{"value": 4},
+	})
+}
+
+func TestDecodeIndex(t *testing.T) {
+	tests := []struct {
+		idx      string
+		specs    []string
+		expected map[string]string
+	}{
+		{".5", []string{"a:int"}, map[string]string{"a": "5"}},
+		{".0.17.34.51.68.85", []string{"mac:hwaddr"}, map[string]string{"mac": "00:11:22:33:44:55"}},
+		{".32.1.13.184.0.0.0.0.0.0.0.0.0.0.0.1", []string{"a:ipv6addr"}, map[string]string{"a": "2001:db8::1"}},
+		{".3.102.111.111.98.97.114", []string{"a:string", "b:implied_string"}, map[string]string{"a": "foo", "b": "bar"}},
+		{".2.1.3.4.5", []string{"a:oid", "b:implied_oid"}, map[string]string{"a": ".1.3", "b": ".4.5"}},
+	}
+	for _, tt := range tests {
+		var indexTags []indexTag
+		for _, spec := range tt.specs {
+			it, err := parseIndexTag(spec)
+			require.NoError(t, err)
+			indexTags = append(indexTags, it)
+		}
+
+		tags, err := decodeIndex(tt.idx, indexTags)
+		require.NoError(t, err, tt.idx)
+		require.Equal(t, tt.expected, tags)
+	}
+
+	for _, idx := range []string{".1.2", ".1.2.3.256", ".5.1.2", ".1.2.3.4.5"} {
+		_, err := decodeIndex(idx, []indexTag{{"a", "ipaddr"}})
+		require.Error(t, err, idx)
+	}
+
+	for _, spec := range []string{"a", ":int", "a:float"} {
+		_, err := parseIndexTag(spec)
+		require.Error(t, err, spec)
+	}
+}
+
 func TestGather(t *testing.T) {
 	s := &Snmp{
 		Agents: []string{"TestGather"},
@@ -800,3 +961,53 @@ func TestSnmpTableCache_hit(t *testing.T) {
 	assert.Equal(t, []Field{{Name: "d"}}, fields)
 	assert.Equal(t, fmt.Errorf("e"), err)
 }
+
+func TestSnmpInit_mibPath(t *testing.T) {
+	// The net-snmp tools must not be used for OIDs found in the loaded MIBs.
+	defer func(cmd func(string, ...string) *exec.Cmd) { execCommand = cmd }(execCommand)
+	execCommand = func(arg0 string, args ...string) *exec.Cmd {
+		t.Errorf("unexpected call to %s %v", arg0, args)
+		return exec.Command("false")
+	}
+	snmpTranslateCaches = nil
+	snmpTableCaches = nil
+	defer func() {
+		snmpTranslateCaches = nil
+		snmpTableCaches = nil
+	}()
+
+	s := &Snmp{
+		Path: []string{"testdata"},
+		Tables: []Table{
+			{Oid: "TEST::testTable"},
+		},
+		Fields: []Field{
+			{Oid: "TEST::hostname"},
+			{Oid: "TEST-ENUM::testStatus.0", Conversion: "enum"},
+		},
+		Log: testutil.Logger{},
+	}
+	require.NoError(t, s.init())
+
+	assert.Equal(t, "testTable", s.Tables[0].Name)
+	assert.Len(t, s.Tables[0].Fields, 4)
+	assert.Contains(t, s.Tables[0].Fields, Field{Oid: ".1.0.0.0.1.1", Name: "server", IsTag: true, initialized: true})
+	assert.Contains(t, s.Tables[0].Fields, Field{Oid: ".1.0.0.0.1.2", Name: "connections", initialized: true})
+	assert.Contains(t, s.Tables[0].Fields, Field{Oid: ".1.0.0.0.1.3", Name: "latency", initialized: true})
+	assert.Contains(t, s.Tables[0].Fields, Field{Oid: ".1.0.0.0.1.4", Name: "description", initialized: true})
+
+	assert.Equal(t, Field{Oid: ".1.0.0.1.1", Name: "hostname", initialized: true}, s.Fields[0])
+	assert.Equal(t, ".1.0.0.2.0", s.Fields[1].Oid)
+	assert.Equal(t, "testStatus.0", s.Fields[1].Name)
+
+	for v, expected := range map[interface{}]string{1: "up", int64(2): "down", uint32(3): "3"} {
+		actual, err := s.Fields[1].convert(v)
+		require.NoError(t, err)
+		assert.Equal(t, expected, actual)
+	}
+	_, err := s.Fields[1].convert("up")
+	assert.Error(t, err)
+
+	f := Field{Oid: "TEST::hostname", Conversion: "enum"}
+	assert.Error(t, f.init())
+}
@@ -13,6 +13,7 @@ import (
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/snmp"
 	"github.com/influxdata/telegraf/plugins/inputs"
 
 	"github.com/soniah/gosnmp"
@@ -32,6 +33,7 @@ type SnmpTrap struct {
 	ServiceAddress string            `toml:"service_address"`
 	Timeout        internal.Duration `toml:"timeout"`
 	Version        string            `toml:"version"`
+	Path           []string          `toml:"path"`
 
 	// Settings for version 3
 	// Values: "noAuthNoPriv", "authNoPriv", "authPriv"
@@ -68,6 +70,10 @@ var sampleConfig = `
   ## 1024.  See README.md for details
   ##
   # service_address = "udp://:162"
+  ## Paths to MIB files and directories, loaded on startup to translate OIDs
+  ## and decode values without the net-snmp tools.  OIDs not found in these
+  ## MIBs are translated with snmptranslate, if installed.
+  # path = ["/usr/share/snmp/mibs"]
   ## Timeout running snmptranslate command
   # timeout = "5s"
   ## Snmp version, defaults to 2c
@@ -125,6 +131,12 @@ func realExecCmd(Timeout internal.Duration, arg0 string, args ...string) ([]byte
 func (s *SnmpTrap) Init() error {
 	s.cache = map[string]mibEntry{}
 	s.execCmd = realExecCmd
+
+	if len(s.Path) != 0 {
+		if err := snmp.LoadMibsFromPath(s.Path, s.Log); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -305,10 +317,9 @@ func makeTrapHandler(s *SnmpTrap) handler {
 
 			var value interface{}
 
-			// todo: format the pdu value based on its snmp type and
-			// the mib's textual convention.  The snmp input plugin
-			// only handles textual convention for ip and mac
-			// addresses
+			// Values are formatted based on the mib's textual
+			// convention and enumeration if the mibs are loaded from
+			// path.
 
 			switch v.Type {
 			case gosnmp.ObjectIdentifier:
@@ -346,6 +357,10 @@ func makeTrapHandler(s *SnmpTrap) handler {
 
 			name := e.oidText
 
+			if v.Type != gosnmp.ObjectIdentifier {
+				value = s.decode(v.Name, value)
+			}
+
 			fields[name] = value
 		}
 
@@ -368,8 +383,8 @@ func (s *SnmpTrap) lookup(oid string) (e mibEntry, err error) {
 	defer s.cacheLock.Unlock()
 	var ok bool
 	if e, ok = s.cache[oid]; !ok {
-		// cache miss.  exec snmptranslate
-		e, err = s.snmptranslate(oid)
+		// cache miss.  translate using the mibs or snmptranslate
+		e, err = s.translate(oid)
 		if err == nil {
 			s.cache[oid] = e
 		}
@@ -390,6 +405,31 @@ func (s *SnmpTrap) load(oid string, e mibEntry) {
 	s.cache[oid] = e
 }
 
+// translate resolves the oid using the mibs loaded from path, falling back
+// to snmptranslate.
+func (s *SnmpTrap) translate(oid string) (mibEntry, error) {
+	if len(s.Path) != 0 {
+		mibName, _, oidText, _, err := snmp.TranslateOid(oid)
+		if err == nil {
+			return mibEntry{mibName, oidText}, nil
+		}
+	}
+	return s.snmptranslate(oid)
+}
+
+// decode formats the value of the variable with the given oid according to
+// the textual convention and enumeration in the mibs loaded from path.
+func (s *SnmpTrap) decode(oid string, value interface{}) interface{} {
+	if len(s.Path) == 0 {
+		return value
+	}
+	node, _, err := snmp.LookupOid(oid)
+	if err != nil {
+		return value
+	}
+	return node.FormatValue(value)
+}
+
 func (s *SnmpTrap) snmptranslate(oid string) (e mibEntry, err error) {
 	var out []byte
 	out, err = s.execCmd(s.Timeout, "snmptranslate", "-Td", "-Ob", "-m", "all", oid)
@@ -36,6 +36,31 @@ func TestLoad(t *testing.T) {
 	require.Equal(t, "coldStart", e.oidText)
 }
 
+func TestLoadMibPath(t *testing.T) {
+	s := &SnmpTrap{
+		Path: []string{"testdata"},
+		Log:  testutil.Logger{},
+	}
+	require.NoError(t, s.Init())
+	// Don't look up oid with snmptranslate.
+	s.execCmd = fakeExecCmd
+
+	e, err := s.lookup(".1.3.6.1.4.1.65535.0.1")
+	require.NoError(t, err)
+	require.Equal(t, mibEntry{"TEST-TRAP-MIB", "testLinkChange"}, e)
+
+	e, err = s.lookup(".1.3.6.1.4.1.65535.1.0")
+	require.NoError(t, err)
+	require.Equal(t, mibEntry{"TEST-TRAP-MIB", "testLinkStatus.0"}, e)
+
+	require.Equal(t, "down", s.decode(".1.3.6.1.4.1.65535.1.0", 2))
+	require.Equal(t, uint32(5), s.decode(".1.3.6.1.2.1.1.3.0", uint32(5)))
+
+	// Not found in the mibs, falls back to snmptranslate
+	_, err = s.lookup(".2.1")
+	require.Error(t, err)
+}
+
 func fakeExecCmd(_ internal.Duration, x string, y ...string) ([]byte, error) {
 	return nil, fmt.Errorf("mock " + x + " " + strings.Join(y, " "))
 }
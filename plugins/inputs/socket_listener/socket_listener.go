@@ -23,6 +23,16 @@ type setReadBufferer interface {
 	SetReadBuffer(bytes int) error
 }
 
+// streamSplitter is implemented by parsers of formats that are not newline
+// delimited, to split a stream into messages.
+type streamSplitter interface {
+	Split(data []byte, atEOF bool) (advance int, token []byte, err error)
+
+	// MaxTokenSize returns the size of the largest message, including its
+	// framing.
+	MaxTokenSize() int
+}
+
 type streamSocketListener struct {
 	net.Listener
 	*SocketListener
@@ -117,6 +127,10 @@ func (ssl *streamSocketListener) read(c net.Conn) {
 	}
 
 	scnr := bufio.NewScanner(decoder)
+	if s, ok := ssl.Parser.(streamSplitter); ok {
+		scnr.Split(s.Split)
+		scnr.Buffer(make([]byte, 0, 4096), s.MaxTokenSize())
+	}
 	for {
 		if ssl.ReadTimeout != nil && ssl.ReadTimeout.Duration > 0 {
 			c.SetReadDeadline(time.Now().Add(ssl.ReadTimeout.Duration))
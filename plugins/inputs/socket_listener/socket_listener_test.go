@@ -9,10 +9,14 @@ import (
 	"net"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
+	msgpackparser "github.com/influxdata/telegraf/plugins/parsers/msgpack"
+	"github.com/influxdata/telegraf/plugins/serializers/msgpack"
 	"github.com/influxdata/telegraf/testutil"
 	"github.com/influxdata/wlog"
 	"github.com/stretchr/testify/assert"
@@ -263,3 +267,74 @@ func testSocketListener(t *testing.T, sl *SocketListener, client net.Conn) {
 	assert.Equal(t, map[string]interface{}{"v": int64(3)}, m3.Fields)
 	assert.True(t, time.Unix(0, 123456791).Equal(m3.Time))
 }
+
+func TestSocketListenerMsgpack_tcp(t *testing.T) {
+	defer testEmptyLog(t)()
+
+	sl := newSocketListener()
+	sl.Log = testutil.Logger{}
+	sl.ServiceAddress = "tcp://127.0.0.1:0"
+	sl.Parser = msgpackparser.NewParser(nil)
+
+	acc := &testutil.Accumulator{}
+	err := sl.Start(acc)
+	require.NoError(t, err)
+	defer sl.Stop()
+
+	client, err := net.Dial("tcp", sl.Closer.(net.Listener).Addr().String())
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("test", map[string]string{"foo": "bar"},
+			map[string]interface{}{"v": int64(1), "u": uint64(2)}, time.Unix(0, 123456789)),
+		testutil.MustMetric("test", map[string]string{"foo": "baz"},
+			map[string]interface{}{"v": 2.5, "s": "line\nbreak"}, time.Unix(0, 123456790)),
+	}
+
+	serializer, err := msgpack.NewSerializer()
+	require.NoError(t, err)
+	for _, m := range expected {
+		b, err := serializer.Serialize(m)
+		require.NoError(t, err)
+
+		// write the metric in two parts to check the framing
+		client.Write(b[:3])
+		client.Write(b[3:])
+	}
+
+	acc.Wait(len(expected))
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+}
+
+func TestSocketListenerMsgpackLargeFrame_tcp(t *testing.T) {
+	defer testEmptyLog(t)()
+
+	sl := newSocketListener()
+	sl.Log = testutil.Logger{}
+	sl.ServiceAddress = "tcp://127.0.0.1:0"
+	sl.Parser = msgpackparser.NewParser(nil)
+
+	acc := &testutil.Accumulator{}
+	err := sl.Start(acc)
+	require.NoError(t, err)
+	defer sl.Stop()
+
+	client, err := net.Dial("tcp", sl.Closer.(net.Listener).Addr().String())
+	require.NoError(t, err)
+
+	// larger than the default buffer of the scanner
+	expected := []telegraf.Metric{
+		testutil.MustMetric("test", map[string]string{"foo": "bar"},
+			map[string]interface{}{"s": strings.Repeat("x", 100*1024)}, time.Unix(0, 123456789)),
+	}
+
+	serializer, err := msgpack.NewSerializer()
+	require.NoError(t, err)
+	b, err := serializer.Serialize(expected[0])
+	require.NoError(t, err)
+	require.True(t, len(b) > 64*1024)
+	client.Write(b)
+
+	acc.Wait(len(expected))
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+}
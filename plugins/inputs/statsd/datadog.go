@@ -72,8 +72,14 @@ func (s *Statsd) parseEventMessage(now time.Time, message string, defaultHostnam
 	}
 
 	name := rawTitle
+	if s.DataDogEventMeasurement != "" {
+		name = s.DataDogEventMeasurement
+	}
 	tags := make(map[string]string, strings.Count(message, ",")+2) // allocate for the approximate number of tags
 	fields := make(map[string]interface{}, 9)
+	if s.DataDogEventMeasurement != "" {
+		fields["title"] = rawTitle
+	}
 	fields["alert_type"] = eventInfo // default event type
 	fields["text"] = uncommenter.Replace(string(rawText))
 	if defaultHostname != "" {
@@ -137,6 +143,70 @@ func (s *Statsd) parseEventMessage(now time.Time, message string, defaultHostnam
 	return nil
 }
 
+func (s *Statsd) parseServiceCheckMessage(now time.Time, message string, defaultHostname string) error {
+	// _sc|name|status
+	//  [
+	//   |d:timestamp
+	//   |h:hostname
+	//   |#tag1,tag2
+	//   |m:service_check_message
+	//  ]
+	rawFields := strings.Split(message, "|")
+	if len(rawFields) < 3 || rawFields[0] != "_sc" || rawFields[1] == "" {
+		return fmt.Errorf("Invalid service check format")
+	}
+
+	status, err := strconv.ParseInt(rawFields[2], 10, 64)
+	if err != nil || status < 0 || status > 3 {
+		return fmt.Errorf("Invalid service check format, could not parse status: '%s'", rawFields[2])
+	}
+
+	name := s.DataDogServiceCheckMeasurement
+	if name == "" {
+		name = defaultServiceCheckMeasurement
+	}
+	tags := map[string]string{"check": rawFields[1]}
+	if defaultHostname != "" {
+		tags["source"] = defaultHostname
+	}
+	fields := map[string]interface{}{"status": status}
+
+	for i, rawField := range rawFields[3:] {
+		if len(rawField) < 2 {
+			return errors.New("too short metadata field")
+		}
+		switch rawField[:2] {
+		case "d:":
+			ts, err := strconv.ParseInt(rawField[2:], 10, 64)
+			if err != nil {
+				continue
+			}
+			fields["ts"] = ts
+		case "h:":
+			tags["source"] = rawField[2:]
+		case "m:":
+			// The message is the last field and may contain pipes
+			fields["message"] = uncommenter.Replace(strings.Join(rawFields[3+i:], "|")[2:])
+		default:
+			if rawField[0] == '#' {
+				parseDataDogTags(tags, rawField[1:])
+			} else {
+				return fmt.Errorf("unknown metadata type: '%s'", rawField)
+			}
+		}
+		if _, ok := fields["message"]; ok {
+			break
+		}
+	}
+	if host, ok := tags["host"]; ok {
+		delete(tags, "host")
+		tags["source"] = host
+	}
+	tags["check"] = rawFields[1]
+	s.acc.AddFields(name, fields, tags, now)
+	return nil
+}
+
 func parseDataDogTags(tags map[string]string, message string) {
 	if len(message) == 0 {
 		return
@@ -482,3 +482,81 @@ func TestEventError(t *testing.T) {
 	err = s.parseEventMessage(now, "_e{5,4}:title|text|x:1234", "default-hostname")
 	require.Error(t, err)
 }
+
+func TestEventMeasurement(t *testing.T) {
+	acc := &testutil.Accumulator{}
+	s := NewTestStatsd()
+	s.DataDogEventMeasurement = "statsd_event"
+	s.acc = acc
+
+	require.NoError(t, s.parseEventMessage(time.Now(), "_e{10,9}:test title|test text|#env:prod", "default-hostname"))
+	acc.AssertContainsTaggedFields(t, "statsd_event",
+		map[string]interface{}{
+			"title":      "test title",
+			"text":       "test text",
+			"priority":   priorityNormal,
+			"alert_type": eventInfo,
+		},
+		map[string]string{"source": "default-hostname", "env": "prod"})
+}
+
+func TestServiceChecks(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		tags    map[string]string
+		fields  map[string]interface{}
+	}{
+		{
+			name:    "minimal",
+			message: "_sc|db.up|0",
+			tags:    map[string]string{"check": "db.up", "source": "default-hostname"},
+			fields:  map[string]interface{}{"status": int64(0)},
+		},
+		{
+			name:    "metadata",
+			message: "_sc|db.up|2|d:21|h:db01|#env:prod,check:other|m:connection refused\\nretrying",
+			tags:    map[string]string{"check": "db.up", "source": "db01", "env": "prod"},
+			fields: map[string]interface{}{
+				"status":  int64(2),
+				"ts":      int64(21),
+				"message": "connection refused\nretrying",
+			},
+		},
+		{
+			name:    "message with pipes",
+			message: "_sc|db.up|1|m:slow|degraded",
+			tags:    map[string]string{"check": "db.up", "source": "default-hostname"},
+			fields:  map[string]interface{}{"status": int64(1), "message": "slow|degraded"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			acc := &testutil.Accumulator{}
+			s := NewTestStatsd()
+			s.acc = acc
+
+			require.NoError(t, s.parseServiceCheckMessage(time.Now(), tt.message, "default-hostname"))
+			require.Len(t, acc.Metrics, 1)
+			require.Equal(t, defaultServiceCheckMeasurement, acc.Metrics[0].Measurement)
+			require.Equal(t, tt.tags, acc.Metrics[0].Tags)
+			require.Equal(t, tt.fields, acc.Metrics[0].Fields)
+		})
+	}
+}
+
+func TestServiceChecksErrors(t *testing.T) {
+	s := NewTestStatsd()
+	s.acc = &testutil.Accumulator{}
+
+	for _, message := range []string{
+		"_sc|db.up",
+		"_sc||0",
+		"_sc|db.up|5",
+		"_sc|db.up|ok",
+		"_sc|db.up|0|x:1",
+		"_sc|db.up|0|d",
+	} {
+		require.Error(t, s.parseServiceCheckMessage(time.Now(), message, ""), message)
+	}
+}
@@ -0,0 +1,53 @@
+package statsd
+
+import (
+	"sort"
+)
+
+// histogram counts values into buckets with fixed upper bounds, reported
+// cumulatively in the style of Prometheus histograms.
+type histogram struct {
+	bounds []float64
+	counts []int64
+
+	n   int64
+	sum float64
+}
+
+func newHistogram(bounds []float64) *histogram {
+	return &histogram{
+		bounds: bounds,
+		counts: make([]int64, len(bounds)),
+	}
+}
+
+// AddValue adds count occurrences of v to the histogram.
+func (h *histogram) AddValue(v float64, count int64) {
+	h.n += count
+	h.sum += v * float64(count)
+
+	// Values above the largest bound only end up in the +Inf bucket
+	i := sort.SearchFloat64s(h.bounds, v)
+	if i < len(h.counts) {
+		h.counts[i] += count
+	}
+}
+
+// Cumulative returns the number of values less than or equal to each bound.
+func (h *histogram) Cumulative() []int64 {
+	cumulative := make([]int64, len(h.counts))
+	var n int64
+	for i, c := range h.counts {
+		n += c
+		cumulative[i] = n
+	}
+	return cumulative
+}
+
+func (h *histogram) Count() int64 {
+	return h.n
+}
+
+func (h *histogram) Sum() float64 {
+	return h.sum
+}
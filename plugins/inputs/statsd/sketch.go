@@ -0,0 +1,156 @@
+package statsd
+
+import (
+	"math"
+	"sort"
+)
+
+const (
+	defaultRelativeAccuracy = 0.01
+
+	// Maximum number of bins kept per sign; once exceeded the bins closest to
+	// zero are merged, sacrificing accuracy of the smallest values.
+	sketchMaxBins = 2048
+)
+
+// sketch estimates quantiles with a bounded relative error using
+// logarithmically sized bins, as described in the DDSketch paper
+// https://arxiv.org/abs/1908.10693
+type sketch struct {
+	// quantiles to report
+	quantiles []float64
+
+	gamma    float64
+	logGamma float64
+
+	positive map[int]int64
+	negative map[int]int64
+	zero     int64
+
+	n     int64
+	sum   float64
+	lower float64
+	upper float64
+}
+
+func newSketch(relativeAccuracy float64, quantiles []float64) *sketch {
+	gamma := (1 + relativeAccuracy) / (1 - relativeAccuracy)
+	return &sketch{
+		quantiles: quantiles,
+		gamma:     gamma,
+		logGamma:  math.Log(gamma),
+		positive:  make(map[int]int64),
+		negative:  make(map[int]int64),
+	}
+}
+
+func (s *sketch) index(v float64) int {
+	return int(math.Ceil(math.Log(v) / s.logGamma))
+}
+
+func (s *sketch) value(i int) float64 {
+	return 2 * math.Pow(s.gamma, float64(i)) / (s.gamma + 1)
+}
+
+// AddValue adds count occurrences of v to the sketch.
+func (s *sketch) AddValue(v float64, count int64) {
+	if s.n == 0 || v < s.lower {
+		s.lower = v
+	}
+	if s.n == 0 || v > s.upper {
+		s.upper = v
+	}
+	s.n += count
+	s.sum += v * float64(count)
+
+	switch {
+	case v > 0:
+		addToBin(s.positive, s.index(v), count)
+	case v < 0:
+		addToBin(s.negative, s.index(-v), count)
+	default:
+		s.zero += count
+	}
+}
+
+func addToBin(bins map[int]int64, i int, count int64) {
+	bins[i] += count
+	if len(bins) <= sketchMaxBins {
+		return
+	}
+
+	// Merge the two lowest bins
+	lowest, second := math.MaxInt64, math.MaxInt64
+	for k := range bins {
+		if k < lowest {
+			lowest, second = k, lowest
+		} else if k < second {
+			second = k
+		}
+	}
+	bins[second] += bins[lowest]
+	delete(bins, lowest)
+}
+
+func (s *sketch) Count() int64 {
+	return s.n
+}
+
+func (s *sketch) Sum() float64 {
+	return s.sum
+}
+
+// Quantile returns the estimated value at quantile q, between 0 and 1.
+func (s *sketch) Quantile(q float64) float64 {
+	if s.n == 0 {
+		return 0
+	}
+	if q <= 0 {
+		return s.lower
+	}
+	if q >= 1 {
+		return s.upper
+	}
+
+	rank := int64(q * float64(s.n-1))
+	var v float64
+	switch {
+	case rank < total(s.negative):
+		// Negative values are ordered by descending magnitude
+		v = -s.value(s.bin(s.negative, total(s.negative)-1-rank))
+	case rank < total(s.negative)+s.zero:
+		v = 0
+	default:
+		v = s.value(s.bin(s.positive, rank-total(s.negative)-s.zero))
+	}
+
+	// Never report values outside of the observed range
+	return math.Max(s.lower, math.Min(s.upper, v))
+}
+
+// bin returns the index of the bin containing the value with the given
+// rank, counting from the lowest bin.
+func (s *sketch) bin(bins map[int]int64, rank int64) int {
+	keys := make([]int, 0, len(bins))
+	for k := range bins {
+		keys = append(keys, k)
+	}
+	sort.Ints(keys)
+
+	var n int64
+	for _, k := range keys {
+		n += bins[k]
+		if n > rank {
+			return k
+		}
+	}
+	return keys[len(keys)-1]
+}
+
+func total(bins map[int]int64) int64 {
+	var n int64
+	for _, c := range bins {
+		n += c
+	}
+	return n
+}
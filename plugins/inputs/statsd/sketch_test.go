@@ -0,0 +1,58 @@
+package statsd
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSketch_Quantiles(t *testing.T) {
+	sk := newSketch(0.01, nil)
+	values := make([]float64, 0, 100000)
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 100000; i++ {
+		v := r.ExpFloat64() * 100
+		if i%10 == 0 {
+			v = -v
+		}
+		values = append(values, v)
+		sk.AddValue(v, 1)
+	}
+	sort.Float64s(values)
+
+	require.Equal(t, int64(len(values)), sk.Count())
+	require.Equal(t, values[0], sk.Quantile(0))
+	require.Equal(t, values[len(values)-1], sk.Quantile(1))
+	for _, q := range []float64{0.01, 0.05, 0.5, 0.9, 0.99, 0.999} {
+		expected := values[int(q*float64(len(values)-1))]
+		require.InDelta(t, expected, sk.Quantile(q), math.Abs(expected)*0.01, "quantile %v", q)
+	}
+}
+
+func TestSketch_Zero(t *testing.T) {
+	sk := newSketch(0.01, nil)
+	sk.AddValue(0, 1)
+	sk.AddValue(10, 3)
+
+	require.Equal(t, float64(0), sk.Quantile(0.1))
+	require.InDelta(t, 10, sk.Quantile(0.5), 0.1)
+	require.Equal(t, float64(30), sk.Sum())
+}
+
+func TestSketch_BoundedBins(t *testing.T) {
+	sk := newSketch(0.01, nil)
+	for i := 0; i < 10000; i++ {
+		sk.AddValue(math.Pow(1.05, float64(i%5000)-2500), 1)
+	}
+	require.True(t, len(sk.positive) <= sketchMaxBins)
+	require.InEpsilon(t, math.Pow(1.05, 2499), sk.Quantile(1), 1e-9)
+}
+
+func TestSketch_Empty(t *testing.T) {
+	sk := newSketch(0.01, nil)
+	require.Equal(t, float64(0), sk.Quantile(0.5))
+	require.Equal(t, int64(0), sk.Count())
+}
@@ -13,6 +13,7 @@ import (
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
 	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/plugins/inputs"
 	"github.com/influxdata/telegraf/plugins/parsers/graphite"
@@ -33,8 +34,12 @@ const (
 	MaxTCPConnections          = 250
 
 	parserGoRoutines = 5
+
+	defaultServiceCheckMeasurement = "statsd_service_check"
 )
 
+var defaultQuantiles = []float64{0.5, 0.9, 0.99}
+
 // Statsd allows the importing of statsd and dogstatsd data.
 type Statsd struct {
 	// Protocol used on listener - udp or tcp
@@ -69,6 +74,14 @@ type Statsd struct {
 	// http://docs.datadoghq.com/guides/dogstatsd/
 	DataDogExtensions bool `toml:"datadog_extensions"`
 
+	// Measurements for datadog events and service checks
+	DataDogEventMeasurement        string `toml:"datadog_event_measurement"`
+	DataDogServiceCheckMeasurement string `toml:"datadog_service_check_measurement"`
+
+	// Timings configures how timings, histograms and distributions matching
+	// a bucket pattern are aggregated.
+	Timings []*Timing `toml:"timing"`
+
 	// UDPPacketSize is deprecated, it's only here for legacy support
 	// we now always create 1 max size buffer and then copy only what we need
 	// into the in channel
@@ -177,9 +190,64 @@ type cachedcounter struct {
 }
 
 type cachedtimings struct {
-	name   string
-	fields map[string]RunningStats
-	tags   map[string]string
+	name       string
+	fields     map[string]RunningStats
+	histograms map[string]*histogram
+	sketches   map[string]*sketch
+	tags       map[string]string
+}
+
+// Timing selects the aggregation of timings, histograms and distributions
+// whose bucket matches one of the patterns.
+type Timing struct {
+	Patterns         []string  `toml:"patterns"`
+	Mode             string    `toml:"mode"`
+	Buckets          []float64 `toml:"buckets"`
+	Quantiles        []float64 `toml:"quantiles"`
+	RelativeAccuracy float64   `toml:"relative_accuracy"`
+
+	filter filter.Filter
+}
+
+func (t *Timing) init() error {
+	var err error
+	t.filter, err = filter.Compile(t.Patterns)
+	if err != nil {
+		return err
+	}
+	if t.filter == nil {
+		return errors.New("timing requires at least one pattern")
+	}
+
+	switch t.Mode {
+	case "", "percentiles":
+		t.Mode = "percentiles"
+	case "histogram":
+		if len(t.Buckets) == 0 {
+			return errors.New("histogram timing requires buckets")
+		}
+		if !sort.Float64sAreSorted(t.Buckets) {
+			return errors.New("histogram buckets must be in increasing order")
+		}
+	case "sketch":
+		if len(t.Quantiles) == 0 {
+			t.Quantiles = defaultQuantiles
+		}
+		for _, q := range t.Quantiles {
+			if q < 0 || q > 1 {
+				return fmt.Errorf("quantile %v must be between 0 and 1", q)
+			}
+		}
+		if t.RelativeAccuracy == 0 {
+			t.RelativeAccuracy = defaultRelativeAccuracy
+		}
+		if t.RelativeAccuracy < 0 || t.RelativeAccuracy >= 1 {
+			return fmt.Errorf("relative_accuracy %v must be between 0 and 1", t.RelativeAccuracy)
+		}
+	default:
+		return fmt.Errorf("unknown timing mode %q", t.Mode)
+	}
+	return nil
 }
 
 func (_ *Statsd) Description() string {
@@ -229,6 +297,13 @@ const sampleConfig = `
   ## Parses datadog extensions to the statsd format
   datadog_extensions = false
 
+  ## Measurement for datadog events; the title is stored in the "title" field.
+  ## If empty, the title of the event is used as measurement name.
+  # datadog_event_measurement = ""
+
+  ## Measurement for datadog service checks
+  # datadog_service_check_measurement = "statsd_service_check"
+
   ## Statsd data translation templates, more info can be read here:
   ## https://github.com/influxdata/telegraf/blob/master/docs/TEMPLATE_PATTERN.md
   # templates = [
@@ -243,6 +318,29 @@ const sampleConfig = `
   ## calculation of percentiles. Raising this limit increases the accuracy
   ## of percentiles but also increases the memory usage and cpu time.
   percentile_limit = 1000
+
+  ## Aggregation of timings, histograms & distributions by bucket pattern.
+  ## The first matching entry is used; other buckets use the percentiles.
+  # [[inputs.statsd.timing]]
+  #   ## Glob patterns matching the statsd bucket names
+  #   patterns = ["http.request.*"]
+  #
+  #   ## Aggregation mode, one of "percentiles", "histogram" or "sketch"
+  #   mode = "histogram"
+  #
+  #   ## Upper bounds of the cumulative histogram buckets
+  #   buckets = [0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1.0, 2.5, 5.0, 10.0]
+  #
+  # [[inputs.statsd.timing]]
+  #   patterns = ["db.*"]
+  #   mode = "sketch"
+  #
+  #   ## Quantiles to estimate with the sketch, between 0 and 1
+  #   quantiles = [0.5, 0.9, 0.99]
+  #
+  #   ## Relative accuracy of the estimated quantiles, the memory used grows
+  #   ## with the accuracy
+  #   relative_accuracy = 0.01
 `
 
 func (_ *Statsd) SampleConfig() string {
@@ -275,8 +373,44 @@ func (s *Statsd) Gather(acc telegraf.Accumulator) error {
 				fields[name] = stats.Percentile(percentile.Value)
 			}
 		}
+		if len(fields) > 0 {
+			acc.AddFields(m.name, fields, m.tags, now)
+		}
 
-		acc.AddFields(m.name, fields, m.tags, now)
+		// Histograms and sketches are reported with one metric per bucket or
+		// quantile, as expected by the prometheus outputs.
+		if len(m.histograms) > 0 {
+			fields := make(map[string]interface{})
+			for fieldName, h := range m.histograms {
+				for i, count := range h.Cumulative() {
+					le := strconv.FormatFloat(h.bounds[i], 'f', -1, 64)
+					acc.AddHistogram(m.name,
+						map[string]interface{}{fieldName + "_bucket": count},
+						withTag(m.tags, "le", le), now)
+				}
+				acc.AddHistogram(m.name,
+					map[string]interface{}{fieldName + "_bucket": h.Count()},
+					withTag(m.tags, "le", "+Inf"), now)
+				fields[fieldName+"_sum"] = h.Sum()
+				fields[fieldName+"_count"] = h.Count()
+			}
+			acc.AddHistogram(m.name, fields, m.tags, now)
+		}
+
+		if len(m.sketches) > 0 {
+			fields := make(map[string]interface{})
+			for fieldName, sk := range m.sketches {
+				for _, q := range sk.quantiles {
+					quantile := strconv.FormatFloat(q, 'f', -1, 64)
+					acc.AddSummary(m.name,
+						map[string]interface{}{fieldName: sk.Quantile(q)},
+						withTag(m.tags, "quantile", quantile), now)
+				}
+				fields[fieldName+"_sum"] = sk.Sum()
+				fields[fieldName+"_count"] = sk.Count()
+			}
+			acc.AddSummary(m.name, fields, m.tags, now)
+		}
 	}
 	if s.DeleteTimings {
 		s.timings = make(map[string]cachedtimings)
@@ -315,6 +449,12 @@ func (s *Statsd) Start(ac telegraf.Accumulator) error {
 		s.Log.Warn("'parse_data_dog_tags' config option is deprecated, please use 'datadog_extensions' instead")
 	}
 
+	for _, t := range s.Timings {
+		if err := t.init(); err != nil {
+			return err
+		}
+	}
+
 	s.acc = ac
 
 	// Make data structures
@@ -514,6 +654,8 @@ func (s *Statsd) parser() error {
 				case line == "":
 				case s.DataDogExtensions && strings.HasPrefix(line, "_e"):
 					s.parseEventMessage(in.Time, line, in.Addr)
+				case s.DataDogExtensions && strings.HasPrefix(line, "_sc"):
+					s.parseServiceCheckMessage(in.Time, line, in.Addr)
 				default:
 					s.parseStatsdLine(line)
 				}
@@ -590,7 +732,7 @@ func (s *Statsd) parseStatsdLine(line string) error {
 
 		// Validate metric type
 		switch pipesplit[1] {
-		case "g", "c", "s", "ms", "h":
+		case "g", "c", "s", "ms", "h", "d":
 			m.mtype = pipesplit[1]
 		default:
 			s.Log.Errorf("Metric type %q unsupported", pipesplit[1])
@@ -607,7 +749,7 @@ func (s *Statsd) parseStatsdLine(line string) error {
 		}
 
 		switch m.mtype {
-		case "g", "ms", "h":
+		case "g", "ms", "h", "d":
 			v, err := strconv.ParseFloat(pipesplit[0], 64)
 			if err != nil {
 				s.Log.Errorf("Parsing value to float64, unable to parse metric: %s", line)
@@ -647,6 +789,8 @@ func (s *Statsd) parseStatsdLine(line string) error {
 			m.tags["metric_type"] = "timing"
 		case "h":
 			m.tags["metric_type"] = "histogram"
+		case "d":
+			m.tags["metric_type"] = "distribution"
 		}
 		if len(lineTags) > 0 {
 			for k, v := range lineTags {
@@ -735,32 +879,50 @@ func parseKeyValue(keyvalue string) (string, string) {
 // Delete* options, because those are dealt with in the Gather function.
 func (s *Statsd) aggregate(m metric) {
 	switch m.mtype {
-	case "ms", "h":
+	case "ms", "h", "d":
 		// Check if the measurement exists
 		cached, ok := s.timings[m.hash]
 		if !ok {
 			cached = cachedtimings{
-				name:   m.name,
-				fields: make(map[string]RunningStats),
-				tags:   m.tags,
+				name:       m.name,
+				fields:     make(map[string]RunningStats),
+				histograms: make(map[string]*histogram),
+				sketches:   make(map[string]*sketch),
+				tags:       m.tags,
 			}
 		}
+		count := int64(1)
+		if m.samplerate > 0 {
+			count = int64(1.0 / m.samplerate)
+		}
+
 		// Check if the field exists. If we've not enabled multiple fields per timer
 		// this will be the default field name, eg. "value"
-		field, ok := cached.fields[m.field]
-		if !ok {
-			field = RunningStats{
-				PercLimit: s.PercentileLimit,
+		_, ok = cached.fields[m.field]
+		if !ok && cached.histograms[m.field] == nil && cached.sketches[m.field] == nil {
+			switch t := s.timing(m.bucket); t.Mode {
+			case "histogram":
+				cached.histograms[m.field] = newHistogram(t.Buckets)
+			case "sketch":
+				cached.sketches[m.field] = newSketch(t.RelativeAccuracy, t.Quantiles)
+			default:
+				cached.fields[m.field] = RunningStats{
+					PercLimit: s.PercentileLimit,
+				}
 			}
 		}
-		if m.samplerate > 0 {
-			for i := 0; i < int(1.0/m.samplerate); i++ {
+
+		if h, ok := cached.histograms[m.field]; ok {
+			h.AddValue(m.floatvalue, count)
+		} else if sk, ok := cached.sketches[m.field]; ok {
+			sk.AddValue(m.floatvalue, count)
+		} else {
+			field := cached.fields[m.field]
+			for i := int64(0); i < count; i++ {
 				field.AddValue(m.floatvalue)
 			}
-		} else {
-			field.AddValue(m.floatvalue)
+			cached.fields[m.field] = field
 		}
-		cached.fields[m.field] = field
 		s.timings[m.hash] = cached
 	case "c":
 		// check if the measurement exists
@@ -819,6 +981,27 @@ func (s *Statsd) aggregate(m metric) {
 	}
 }
 
+// timing returns the configuration of the timing bucket, the first entry with
+// a matching pattern.
+func (s *Statsd) timing(bucket string) *Timing {
+	name := strings.SplitN(bucket, ",", 2)[0]
+	for _, t := range s.Timings {
+		if t.filter.Match(name) {
+			return t
+		}
+	}
+	return &Timing{Mode: "percentiles"}
+}
+
+func withTag(tags map[string]string, key, value string) map[string]string {
+	result := make(map[string]string, len(tags)+1)
+	for k, v := range tags {
+		result[k] = v
+	}
+	result[key] = value
+	return result
+}
+
 // handler handles a single TCP Connection
 func (s *Statsd) handler(conn *net.TCPConn, id string) {
 	s.CurrentConnections.Incr(1)
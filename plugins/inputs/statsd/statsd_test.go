@@ -6,6 +6,7 @@ import (
 	"github.com/influxdata/telegraf/internal"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"math"
 	"net"
 	"sync"
 	"testing"
@@ -459,6 +460,137 @@ func TestParse_Timings(t *testing.T) {
 	acc.AssertContainsFields(t, "test_timing", valid)
 }
 
+func TestParse_Distributions(t *testing.T) {
+	s := NewTestStatsd()
+	acc := &testutil.Accumulator{}
+
+	require.NoError(t, s.parseStatsdLine("test.distribution:1|d"))
+	require.NoError(t, s.parseStatsdLine("test.distribution:3|d|@0.5"))
+	require.NoError(t, s.parseStatsdLine("test.distribution:5|d"))
+	require.NoError(t, s.Gather(acc))
+
+	acc.AssertContainsTaggedFields(t, "test_distribution",
+		map[string]interface{}{
+			"count":  int64(4),
+			"lower":  float64(1),
+			"mean":   float64(3),
+			"stddev": math.Sqrt(2),
+			"sum":    float64(12),
+			"upper":  float64(5),
+		},
+		map[string]string{"metric_type": "distribution"})
+}
+
+func TestParse_TimingsHistogram(t *testing.T) {
+	s := NewTestStatsd()
+	s.Timings = []*Timing{
+		{Patterns: []string{"http.*"}, Mode: "histogram", Buckets: []float64{0.1, 0.5, 1}},
+	}
+	require.NoError(t, s.Timings[0].init())
+	acc := &testutil.Accumulator{}
+
+	for _, line := range []string{
+		"http.request:0.05|ms",
+		"http.request:0.3|ms",
+		"http.request:0.5|ms",
+		"http.request:2|ms|@0.5",
+		"other.request:0.3|ms",
+	} {
+		require.NoError(t, s.parseStatsdLine(line))
+	}
+	require.NoError(t, s.Gather(acc))
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric("http_request",
+			map[string]string{"metric_type": "timing", "le": "0.1"},
+			map[string]interface{}{"value_bucket": int64(1)},
+			time.Unix(0, 0), telegraf.Histogram),
+		testutil.MustMetric("http_request",
+			map[string]string{"metric_type": "timing", "le": "0.5"},
+			map[string]interface{}{"value_bucket": int64(3)},
+			time.Unix(0, 0), telegraf.Histogram),
+		testutil.MustMetric("http_request",
+			map[string]string{"metric_type": "timing", "le": "1"},
+			map[string]interface{}{"value_bucket": int64(3)},
+			time.Unix(0, 0), telegraf.Histogram),
+		testutil.MustMetric("http_request",
+			map[string]string{"metric_type": "timing", "le": "+Inf"},
+			map[string]interface{}{"value_bucket": int64(5)},
+			time.Unix(0, 0), telegraf.Histogram),
+		testutil.MustMetric("http_request",
+			map[string]string{"metric_type": "timing"},
+			map[string]interface{}{"value_sum": float64(4.85), "value_count": int64(5)},
+			time.Unix(0, 0), telegraf.Histogram),
+	}
+	var actual []telegraf.Metric
+	for _, m := range acc.GetTelegrafMetrics() {
+		if m.Name() == "http_request" {
+			actual = append(actual, m)
+		}
+	}
+	testutil.RequireMetricsEqual(t, expected, actual, testutil.IgnoreTime(), testutil.SortMetrics())
+
+	// Buckets not matching any pattern use percentiles
+	acc.AssertContainsFields(t, "other_request", map[string]interface{}{
+		"count":  int64(1),
+		"lower":  float64(0.3),
+		"mean":   float64(0.3),
+		"stddev": float64(0),
+		"sum":    float64(0.3),
+		"upper":  float64(0.3),
+	})
+}
+
+func TestParse_TimingsSketch(t *testing.T) {
+	s := NewTestStatsd()
+	s.DataDogExtensions = true
+	s.Timings = []*Timing{
+		{Patterns: []string{"db.*"}, Mode: "sketch", Quantiles: []float64{0, 0.5, 1}},
+	}
+	require.NoError(t, s.Timings[0].init())
+	acc := &testutil.Accumulator{}
+
+	for i := 1; i <= 100; i++ {
+		require.NoError(t, s.parseStatsdLine(fmt.Sprintf("db.query:%d|d|#table:users", i)))
+	}
+	require.NoError(t, s.Gather(acc))
+
+	tags := map[string]string{"metric_type": "distribution", "table": "users"}
+	quantiles := map[string]float64{}
+	for _, m := range acc.GetTelegrafMetrics() {
+		require.Equal(t, telegraf.Summary, m.Type())
+		if q, ok := m.GetTag("quantile"); ok {
+			v, _ := m.GetField("value")
+			quantiles[q] = v.(float64)
+			continue
+		}
+		require.Equal(t, tags, m.Tags())
+		require.Equal(t, map[string]interface{}{"value_sum": float64(5050), "value_count": int64(100)}, m.Fields())
+	}
+	require.Len(t, quantiles, 3)
+	require.Equal(t, float64(1), quantiles["0"])
+	require.InEpsilon(t, 50, quantiles["0.5"], 0.01)
+	require.Equal(t, float64(100), quantiles["1"])
+}
+
+func TestTimingInit(t *testing.T) {
+	timing := &Timing{Patterns: []string{"db.*"}, Mode: "sketch"}
+	require.NoError(t, timing.init())
+	require.Equal(t, defaultQuantiles, timing.Quantiles)
+	require.Equal(t, defaultRelativeAccuracy, timing.RelativeAccuracy)
+
+	for _, timing := range []*Timing{
+		{Mode: "histogram", Buckets: []float64{1}},
+		{Patterns: []string{"*"}, Mode: "histogram"},
+		{Patterns: []string{"*"}, Mode: "histogram", Buckets: []float64{2, 1}},
+		{Patterns: []string{"*"}, Mode: "sketch", Quantiles: []float64{50}},
+		{Patterns: []string{"*"}, Mode: "sketch", RelativeAccuracy: 1},
+		{Patterns: []string{"*"}, Mode: "tdigest"},
+	} {
+		require.Error(t, timing.init())
+	}
+}
+
 func TestParseScientificNotation(t *testing.T) {
 	s := NewTestStatsd()
 	sciNotationLines := []string{
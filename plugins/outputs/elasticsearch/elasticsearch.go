@@ -3,9 +3,11 @@ package elasticsearch
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"text/template"
@@ -13,6 +15,7 @@ import (
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/common/delivery"
 	"github.com/influxdata/telegraf/plugins/common/tls"
 	"github.com/influxdata/telegraf/plugins/outputs"
 	"gopkg.in/olivere/elastic.v5"
@@ -32,9 +35,17 @@ type Elasticsearch struct {
 	TemplateName        string
 	OverwriteTemplate   bool
 	MajorReleaseNumber  int
+	DataStream          bool   `toml:"data_stream"`
+	Pipeline            string `toml:"pipeline"`
+	ILMPolicyName       string `toml:"ilm_policy_name"`
+	ILMPolicy           string `toml:"ilm_policy"`
+	OverwriteILMPolicy  bool   `toml:"overwrite_ilm_policy"`
+	MaxRetries          int    `toml:"max_retries"`
 	tls.ClientConfig
 
 	Client *elastic.Client
+
+	delivery delivery.Tracker
 }
 
 var sampleConfig = `
@@ -71,6 +82,19 @@ var sampleConfig = `
   # default_tag_value = "none"
   index_name = "telegraf-%Y.%m.%d" # required.
 
+  ## Write to a data stream instead of an index, requires Elasticsearch 7.9
+  ## or later.  The index_name is used as name of the data stream and the
+  ## documents are created with the "create" operation.
+  # data_stream = false
+
+  ## Name of the ingest pipeline processing the documents.
+  # pipeline = ""
+
+  ## Number of times documents rejected with a temporary error, such as
+  ## HTTP status 429 or 5xx, are retried within a write.  Documents rejected
+  ## with other errors, for example mapping conflicts, are dropped.
+  # max_retries = 3
+
   ## Optional TLS Config
   # tls_ca = "/etc/telegraf/ca.pem"
   # tls_cert = "/etc/telegraf/cert.pem"
@@ -86,17 +110,36 @@ var sampleConfig = `
   template_name = "telegraf"
   ## Set to true if you want telegraf to overwrite an existing template
   overwrite_template = false
+
+  ## ILM Config
+  ## Name of the index lifecycle policy set in the index template.
+  # ilm_policy_name = ""
+  ## Body of the lifecycle policy, if set telegraf creates the policy when it
+  ## does not exist.  Without data streams the policy may not use rollover.
+  # ilm_policy = '''
+  # {"policy": {"phases": {"delete": {"min_age": "30d", "actions": {"delete": {}}}}}}
+  # '''
+  ## Set to true if you want telegraf to overwrite an existing policy
+  # overwrite_ilm_policy = false
 `
 
 const telegrafTemplate = `
 {
-	{{ if (lt .Version 6) }}
+	{{ if .DataStream }}
+	"index_patterns" : [ "{{.TemplatePattern}}" ],
+	"data_stream": {},
+	"priority": 200,
+	"template": {
+	{{ else if (lt .Version 6) }}
 	"template": "{{.TemplatePattern}}",
 	{{ else }}
 	"index_patterns" : [ "{{.TemplatePattern}}" ],
 	{{ end }}
 	"settings": {
 		"index": {
+			{{ if .ILMPolicy }}
+			"lifecycle.name": "{{.ILMPolicy}}",
+			{{ end }}
 			"refresh_interval": "10s",
 			"mapping.total_fields.limit": 5000,
 			"auto_expand_replicas" : "0-1",
@@ -156,11 +199,21 @@ const telegrafTemplate = `
 		}
 		{{ end }}
 	}
+	{{ if .DataStream }}
+	}
+	{{ end }}
 }`
 
 type templatePart struct {
 	TemplatePattern string
 	Version         int
+	DataStream      bool
+	ILMPolicy       string
+}
+
+// Bulk item status codes worth retrying, other failures are permanent
+func retryable(status int) bool {
+	return status == http.StatusTooManyRequests || status >= 500
 }
 
 func (a *Elasticsearch) Connect() error {
@@ -168,6 +221,15 @@ func (a *Elasticsearch) Connect() error {
 		return fmt.Errorf("Elasticsearch urls or index_name is not defined")
 	}
 
+	if a.ILMPolicy != "" {
+		if a.ILMPolicyName == "" {
+			return fmt.Errorf("Elasticsearch ilm_policy_name configuration not defined")
+		}
+		if !json.Valid([]byte(a.ILMPolicy)) {
+			return fmt.Errorf("Elasticsearch ilm_policy is not valid JSON")
+		}
+	}
+
 	ctx, cancel := context.WithTimeout(context.Background(), a.Timeout.Duration)
 	defer cancel()
 
@@ -220,16 +282,35 @@ func (a *Elasticsearch) Connect() error {
 	}
 
 	// quit if ES version is not supported
-	majorReleaseNumber, err := strconv.Atoi(strings.Split(esVersion, ".")[0])
+	version := strings.Split(esVersion, ".")
+	majorReleaseNumber, err := strconv.Atoi(version[0])
 	if err != nil || majorReleaseNumber < 5 {
 		return fmt.Errorf("Elasticsearch version not supported: %s", esVersion)
 	}
 
 	log.Println("I! Elasticsearch version: " + esVersion)
 
+	if a.DataStream {
+		var minorReleaseNumber int
+		if len(version) > 1 {
+			minorReleaseNumber, _ = strconv.Atoi(version[1])
+		}
+		// data streams were added in 7.9
+		if majorReleaseNumber < 7 || (majorReleaseNumber == 7 && minorReleaseNumber < 9) {
+			return fmt.Errorf("Elasticsearch version %s does not support data streams", esVersion)
+		}
+	}
+
 	a.Client = client
 	a.MajorReleaseNumber = majorReleaseNumber
 
+	if a.ILMPolicy != "" {
+		err := a.manageILMPolicy(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
 	if a.ManageTemplate {
 		err := a.manageTemplate(ctx)
 		if err != nil {
@@ -247,7 +328,16 @@ func (a *Elasticsearch) Write(metrics []telegraf.Metric) error {
 		return nil
 	}
 
-	bulkRequest := a.Client.Bulk()
+	// Only the metrics not indexed, or dropped, yet are sent when a failed
+	// batch is retried.
+	metrics = a.delivery.Pending(metrics)
+	if len(metrics) == 0 {
+		a.delivery.Reset()
+		return nil
+	}
+
+	requests := make([]elastic.BulkableRequest, 0, len(metrics))
+	pending := make([]telegraf.Metric, 0, len(metrics))
 
 	for _, metric := range metrics {
 		var name = metric.Name()
@@ -265,32 +355,136 @@ func (a *Elasticsearch) Write(metrics []telegraf.Metric) error {
 
 		br := elastic.NewBulkIndexRequest().Index(indexName).Doc(m)
 
-		if a.MajorReleaseNumber <= 6 {
+		if a.DataStream {
+			// data streams only accept new documents
+			br.OpType("create")
+		} else if a.MajorReleaseNumber <= 6 {
 			br.Type("metrics")
 		}
 
-		bulkRequest.Add(br)
+		if a.Pipeline != "" {
+			br.Pipeline(a.Pipeline)
+		}
+
+		requests = append(requests, br)
+		pending = append(pending, metric)
+	}
+
+	sent := pending
+	for attempt := 0; ; attempt++ {
+		retry, err := a.bulk(requests)
+		if err != nil {
+			a.markDelivered(sent, pending)
+			return err
+		}
+
+		if len(retry) == 0 {
+			a.delivery.Reset()
+			return nil
+		}
+
+		retryRequests := make([]elastic.BulkableRequest, 0, len(retry))
+		retryMetrics := make([]telegraf.Metric, 0, len(retry))
+		for _, i := range retry {
+			retryRequests = append(retryRequests, requests[i])
+			retryMetrics = append(retryMetrics, pending[i])
+		}
+		requests, pending = retryRequests, retryMetrics
+
+		if attempt >= a.MaxRetries {
+			a.markDelivered(sent, pending)
+			return fmt.Errorf("W! Elasticsearch failed to index %d metrics", len(retry))
+		}
+
+		log.Printf("D! Elasticsearch retrying %d metrics", len(retry))
+		time.Sleep(time.Duration(1<<uint(attempt)) * 100 * time.Millisecond)
+	}
+}
+
+// markDelivered remembers the sent metrics which are no longer pending, so
+// that they are skipped when the batch is retried.
+func (a *Elasticsearch) markDelivered(sent []telegraf.Metric, pending []telegraf.Metric) {
+	failed := make(map[telegraf.Metric]bool, len(pending))
+	for _, metric := range pending {
+		failed[metric] = true
+	}
 
+	for _, metric := range sent {
+		if !failed[metric] {
+			a.delivery.Add(metric)
+		}
 	}
+}
 
+// bulk sends the requests and returns the indexes of the ones rejected with
+// a temporary error.  Documents rejected with other errors are logged and
+// dropped since sending them again would fail as well.
+func (a *Elasticsearch) bulk(requests []elastic.BulkableRequest) ([]int, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), a.Timeout.Duration)
 	defer cancel()
 
-	res, err := bulkRequest.Do(ctx)
+	res, err := a.Client.Bulk().Add(requests...).Do(ctx)
 
 	if err != nil {
-		return fmt.Errorf("Error sending bulk request to Elasticsearch: %s", err)
+		return nil, fmt.Errorf("Error sending bulk request to Elasticsearch: %s", err)
+	}
+
+	if !res.Errors {
+		return nil, nil
 	}
 
-	if res.Errors {
-		for id, err := range res.Failed() {
-			log.Printf("E! Elasticsearch indexing failure, id: %d, error: %s, caused by: %s, %s", id, err.Error.Reason, err.Error.CausedBy["reason"], err.Error.CausedBy["type"])
+	// items are in the order of the requests
+	if len(res.Items) != len(requests) {
+		return nil, fmt.Errorf("Elasticsearch returned %d results for %d metrics", len(res.Items), len(requests))
+	}
+
+	var retry []int
+	for id, item := range res.Items {
+		for _, result := range item {
+			if result.Status >= 200 && result.Status <= 299 {
+				continue
+			}
+
+			if retryable(result.Status) {
+				retry = append(retry, id)
+				continue
+			}
+
+			switch {
+			case result.Error == nil:
+				log.Printf("E! Elasticsearch indexing failure, id: %d, status: %d", id, result.Status)
+			case result.Error.CausedBy == nil:
+				log.Printf("E! Elasticsearch indexing failure, id: %d, status: %d, error: %s", id, result.Status, result.Error.Reason)
+			default:
+				log.Printf("E! Elasticsearch indexing failure, id: %d, status: %d, error: %s, caused by: %s, %s", id, result.Status, result.Error.Reason, result.Error.CausedBy["reason"], result.Error.CausedBy["type"])
+			}
 		}
-		return fmt.Errorf("W! Elasticsearch failed to index %d metrics", len(res.Failed()))
 	}
 
-	return nil
+	return retry, nil
+}
+
+func (a *Elasticsearch) manageILMPolicy(ctx context.Context) error {
+	path := "/_ilm/policy/" + url.PathEscape(a.ILMPolicyName)
 
+	if !a.OverwriteILMPolicy {
+		res, err := a.Client.PerformRequest(ctx, "GET", path, nil, nil, http.StatusNotFound)
+		if err != nil {
+			return fmt.Errorf("Elasticsearch ILM policy check failed, policy name: %s, error: %s", a.ILMPolicyName, err)
+		}
+		if res.StatusCode == http.StatusOK {
+			log.Println("D! Found existing Elasticsearch ILM policy. Skipping policy management")
+			return nil
+		}
+	}
+
+	_, err := a.Client.PerformRequest(ctx, "PUT", path, nil, a.ILMPolicy)
+	if err != nil {
+		return fmt.Errorf("Elasticsearch failed to create ILM policy %s : %s", a.ILMPolicyName, err)
+	}
+
+	log.Printf("D! Elasticsearch ILM policy %s created or updated\n", a.ILMPolicyName)
+	return nil
 }
 
 func (a *Elasticsearch) manageTemplate(ctx context.Context) error {
@@ -298,7 +492,7 @@ func (a *Elasticsearch) manageTemplate(ctx context.Context) error {
 		return fmt.Errorf("Elasticsearch template_name configuration not defined")
 	}
 
-	templateExists, errExists := a.Client.IndexTemplateExists(a.TemplateName).Do(ctx)
+	templateExists, errExists := a.templateExists(ctx)
 
 	if errExists != nil {
 		return fmt.Errorf("Elasticsearch template check failed, template name: %s, error: %s", a.TemplateName, errExists)
@@ -322,13 +516,15 @@ func (a *Elasticsearch) manageTemplate(ctx context.Context) error {
 		tp := templatePart{
 			TemplatePattern: templatePattern + "*",
 			Version:         a.MajorReleaseNumber,
+			DataStream:      a.DataStream,
+			ILMPolicy:       a.ILMPolicyName,
 		}
 
 		t := template.Must(template.New("template").Parse(telegrafTemplate))
 		var tmpl bytes.Buffer
 
 		t.Execute(&tmpl, tp)
-		_, errCreateTemplate := a.Client.IndexPutTemplate(a.TemplateName).BodyString(tmpl.String()).Do(ctx)
+		errCreateTemplate := a.putTemplate(ctx, tmpl.String())
 
 		if errCreateTemplate != nil {
 			return fmt.Errorf("Elasticsearch failed to create index template %s : %s", a.TemplateName, errCreateTemplate)
@@ -344,6 +540,30 @@ func (a *Elasticsearch) manageTemplate(ctx context.Context) error {
 	return nil
 }
 
+// Data streams require a composable index template, while the legacy
+// templates are kept for regular indexes to support older versions.
+func (a *Elasticsearch) templateExists(ctx context.Context) (bool, error) {
+	if !a.DataStream {
+		return a.Client.IndexTemplateExists(a.TemplateName).Do(ctx)
+	}
+
+	res, err := a.Client.PerformRequest(ctx, "HEAD", "/_index_template/"+url.PathEscape(a.TemplateName), nil, nil, http.StatusNotFound)
+	if err != nil {
+		return false, err
+	}
+	return res.StatusCode == http.StatusOK, nil
+}
+
+func (a *Elasticsearch) putTemplate(ctx context.Context, body string) error {
+	if !a.DataStream {
+		_, err := a.Client.IndexPutTemplate(a.TemplateName).BodyString(body).Do(ctx)
+		return err
+	}
+
+	_, err := a.Client.PerformRequest(ctx, "PUT", "/_index_template/"+url.PathEscape(a.TemplateName), nil, body)
+	return err
+}
+
 func (a *Elasticsearch) GetTagKeys(indexName string) (string, []string) {
 
 	tagKeys := []string{}
@@ -424,6 +644,7 @@ func init() {
 		return &Elasticsearch{
 			Timeout:             internal.Duration{Duration: time.Second * 5},
 			HealthCheckInterval: internal.Duration{Duration: time.Second * 10},
+			MaxRetries:          3,
 		}
 	})
 }
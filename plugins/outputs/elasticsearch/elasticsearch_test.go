@@ -2,10 +2,17 @@ package elasticsearch
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/testutil"
 	"github.com/stretchr/testify/require"
@@ -257,3 +264,212 @@ func TestGetIndexName(t *testing.T) {
 		}
 	}
 }
+
+// esServer mocks the Elasticsearch endpoints used by the output, answering
+// each bulk request with the next list of item statuses.
+type esServer struct {
+	*httptest.Server
+
+	version  string
+	statuses [][]int
+	bulks    [][]map[string]interface{}
+	requests map[string]string
+}
+
+func newESServer(t *testing.T, statuses ...[]int) *esServer {
+	s := &esServer{version: "7.10.0", statuses: statuses, requests: make(map[string]string)}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		s.requests[r.Method+" "+r.URL.Path] = string(body)
+
+		switch {
+		case r.URL.Path == "/":
+			fmt.Fprintf(w, `{"version": {"number": %q}}`, s.version)
+		case r.URL.Path == "/_bulk":
+			var lines []map[string]interface{}
+			for _, line := range strings.Split(strings.TrimSpace(string(body)), "\n") {
+				var m map[string]interface{}
+				require.NoError(t, json.Unmarshal([]byte(line), &m))
+				lines = append(lines, m)
+			}
+			s.bulks = append(s.bulks, lines)
+
+			status := s.statuses[0]
+			if len(s.statuses) > 1 {
+				s.statuses = s.statuses[1:]
+			}
+			require.Len(t, status, len(lines)/2)
+
+			res := map[string]interface{}{"took": 1, "errors": false}
+			var items []map[string]interface{}
+			for i, code := range status {
+				var action string
+				for k := range lines[2*i] {
+					action = k
+				}
+				item := map[string]interface{}{"status": code}
+				if code > 299 {
+					res["errors"] = true
+					item["error"] = map[string]interface{}{"type": "error", "reason": http.StatusText(code)}
+				}
+				items = append(items, map[string]interface{}{action: item})
+			}
+			res["items"] = items
+			require.NoError(t, json.NewEncoder(w).Encode(res))
+		case r.Method == "HEAD" || r.Method == "GET":
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			fmt.Fprint(w, `{"acknowledged": true}`)
+		}
+	}))
+	return s
+}
+
+func newElasticsearch(url string) *Elasticsearch {
+	return &Elasticsearch{
+		URLs:      []string{url},
+		IndexName: "test-%Y.%m.%d",
+		Timeout:   internal.Duration{Duration: time.Second * 5},
+	}
+}
+
+func testMetrics(names ...string) []telegraf.Metric {
+	var metrics []telegraf.Metric
+	for _, name := range names {
+		metrics = append(metrics, testutil.MustMetric(name,
+			map[string]string{"host": "a"},
+			map[string]interface{}{"value": 1.0},
+			time.Unix(0, 0)))
+	}
+	return metrics
+}
+
+// measurements returns the measurement names of the documents of a bulk
+// request.
+func measurements(lines []map[string]interface{}) []string {
+	var names []string
+	for i := 1; i < len(lines); i += 2 {
+		names = append(names, lines[i]["measurement_name"].(string))
+	}
+	return names
+}
+
+func TestWriteRetriesTemporaryErrors(t *testing.T) {
+	ts := newESServer(t, []int{201, 429, 400, 503}, []int{201, 201})
+	defer ts.Close()
+
+	e := newElasticsearch(ts.URL)
+	e.MaxRetries = 3
+	require.NoError(t, e.Connect())
+
+	require.NoError(t, e.Write(testMetrics("a", "b", "c", "d")))
+	require.Len(t, ts.bulks, 2)
+	require.Equal(t, []string{"a", "b", "c", "d"}, measurements(ts.bulks[0]))
+	// only documents rejected with a temporary error are sent again
+	require.Equal(t, []string{"b", "d"}, measurements(ts.bulks[1]))
+}
+
+func TestWriteRetriesExhausted(t *testing.T) {
+	ts := newESServer(t, []int{201, 429}, []int{429})
+	defer ts.Close()
+
+	e := newElasticsearch(ts.URL)
+	e.MaxRetries = 2
+	require.NoError(t, e.Connect())
+
+	metrics := testMetrics("a", "b")
+	require.Error(t, e.Write(metrics))
+	require.Len(t, ts.bulks, 3)
+
+	// only the failed document is sent when the batch is retried
+	ts.statuses = [][]int{{201}}
+	require.NoError(t, e.Write(metrics))
+	require.Len(t, ts.bulks, 4)
+	require.Equal(t, []string{"b"}, measurements(ts.bulks[3]))
+
+	ts.statuses = [][]int{{201, 201}}
+	require.NoError(t, e.Write(metrics))
+	require.Len(t, ts.bulks, 5)
+}
+
+func TestWriteDataStream(t *testing.T) {
+	ts := newESServer(t, []int{201})
+	defer ts.Close()
+
+	e := newElasticsearch(ts.URL)
+	e.IndexName = "metrics-telegraf-{{host}}"
+	e.DataStream = true
+	e.Pipeline = "telegraf"
+	require.NoError(t, e.Connect())
+
+	require.NoError(t, e.Write(testMetrics("cpu")))
+	require.Len(t, ts.bulks, 1)
+	require.Equal(t, map[string]interface{}{
+		"create": map[string]interface{}{
+			"_index":   "metrics-telegraf-a",
+			"pipeline": "telegraf",
+		},
+	}, ts.bulks[0][0])
+}
+
+func TestDataStreamVersion(t *testing.T) {
+	ts := newESServer(t, []int{201})
+	defer ts.Close()
+	ts.version = "7.8.1"
+
+	e := newElasticsearch(ts.URL)
+	e.IndexName = "metrics-telegraf"
+	e.DataStream = true
+	require.Error(t, e.Connect())
+
+	ts.version = "7.9.0"
+	require.NoError(t, e.Connect())
+}
+
+func TestManageDataStreamTemplate(t *testing.T) {
+	ts := newESServer(t, []int{201})
+	defer ts.Close()
+
+	policy := `{"policy": {"phases": {"delete": {"min_age": "30d", "actions": {"delete": {}}}}}}`
+
+	e := newElasticsearch(ts.URL)
+	e.IndexName = "metrics-telegraf"
+	e.DataStream = true
+	e.ManageTemplate = true
+	e.TemplateName = "telegraf"
+	e.ILMPolicyName = "telegraf"
+	e.ILMPolicy = policy
+	require.NoError(t, e.Connect())
+
+	require.JSONEq(t, policy, ts.requests["PUT /_ilm/policy/telegraf"])
+
+	var tmpl struct {
+		IndexPatterns []string               `json:"index_patterns"`
+		DataStream    map[string]interface{} `json:"data_stream"`
+		Template      struct {
+			Settings struct {
+				Index map[string]interface{} `json:"index"`
+			} `json:"settings"`
+			Mappings map[string]interface{} `json:"mappings"`
+		} `json:"template"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(ts.requests["PUT /_index_template/telegraf"]), &tmpl))
+	require.Equal(t, []string{"metrics-telegraf*"}, tmpl.IndexPatterns)
+	require.NotNil(t, tmpl.DataStream)
+	require.Equal(t, "telegraf", tmpl.Template.Settings.Index["lifecycle.name"])
+	require.Contains(t, tmpl.Template.Mappings, "dynamic_templates")
+}
+
+func TestConnectErrors(t *testing.T) {
+	ts := newESServer(t, []int{201})
+	defer ts.Close()
+
+	e := newElasticsearch(ts.URL)
+	e.ILMPolicy = `{"policy": {}}`
+	require.Error(t, e.Connect())
+
+	e.ILMPolicyName = "telegraf"
+	e.ILMPolicy = `{"policy": `
+	require.Error(t, e.Connect())
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/influxdata/telegraf"
@@ -21,6 +22,15 @@ const sampleConfig = `
   ## Delay before the process is restarted after an unexpected termination
   restart_delay = "10s"
 
+  ## Protocol used on STDIN and STDOUT of the process.  "plain" writes the
+  ## metrics as they are.  "framed" sends each batch of metrics in a frame and
+  ## waits for the process to acknowledge it, so failed writes are retried.
+  # protocol = "plain"
+
+  ## Maximum time to wait for the acknowledgement of a batch when using the
+  ## framed protocol.
+  # ack_timeout = "10s"
+
   ## Data format to export.
   ## Each data format has its own unique set of configuration options, read
   ## more about them here:
@@ -31,10 +41,17 @@ const sampleConfig = `
 type Execd struct {
 	Command      []string        `toml:"command"`
 	RestartDelay config.Duration `toml:"restart_delay"`
+	Protocol     string          `toml:"protocol"`
+	AckTimeout   config.Duration `toml:"ack_timeout"`
 	Log          telegraf.Logger
 
 	process    *process.Process
 	serializer serializers.Serializer
+
+	// framed protocol; batches waiting for acknowledgement
+	sync.Mutex
+	batchID uint64
+	waiting map[uint64]chan *process.Frame
 }
 
 func (e *Execd) SampleConfig() string {
@@ -54,6 +71,12 @@ func (e *Execd) Init() error {
 		return fmt.Errorf("no command specified")
 	}
 
+	switch e.Protocol {
+	case "", "plain", process.ProtocolFramed:
+	default:
+		return fmt.Errorf("unknown protocol %q", e.Protocol)
+	}
+
 	var err error
 
 	e.process, err = process.New(e.Command)
@@ -65,6 +88,15 @@ func (e *Execd) Init() error {
 	e.process.ReadStdoutFn = e.cmdReadOut
 	e.process.ReadStderrFn = e.cmdReadErr
 
+	if e.Protocol == process.ProtocolFramed {
+		if e.AckTimeout <= 0 {
+			return fmt.Errorf("ack_timeout must be greater than zero")
+		}
+		e.process.Env = []string{process.ProtocolEnv + "=" + process.ProtocolFramed}
+		e.process.ReadStdoutFn = e.cmdReadFrames
+		e.waiting = make(map[uint64]chan *process.Frame)
+	}
+
 	return nil
 }
 
@@ -89,6 +121,10 @@ func (e *Execd) Close() error {
 }
 
 func (e *Execd) Write(metrics []telegraf.Metric) error {
+	if e.Protocol == process.ProtocolFramed {
+		return e.writeFrame(metrics)
+	}
+
 	for _, m := range metrics {
 		b, err := e.serializer.Serialize(m)
 		if err != nil {
@@ -102,6 +138,43 @@ func (e *Execd) Write(metrics []telegraf.Metric) error {
 	return nil
 }
 
+// writeFrame writes the metrics as one batch and waits for the process to
+// acknowledge it.
+func (e *Execd) writeFrame(metrics []telegraf.Metric) error {
+	b, err := e.serializer.SerializeBatch(metrics)
+	if err != nil {
+		return fmt.Errorf("error serializing metrics: %s", err)
+	}
+
+	reply := make(chan *process.Frame, 1)
+	e.Lock()
+	e.batchID++
+	id := e.batchID
+	e.waiting[id] = reply
+	e.Unlock()
+
+	defer func() {
+		e.Lock()
+		delete(e.waiting, id)
+		e.Unlock()
+	}()
+
+	f := &process.Frame{Type: process.FrameMetrics, ID: id, Metrics: string(b)}
+	if err := e.process.WriteFrame(f); err != nil {
+		return fmt.Errorf("error writing metrics %s", err)
+	}
+
+	select {
+	case f := <-reply:
+		if f.Type == process.FrameNack {
+			return fmt.Errorf("process rejected metrics: %s", f.Error)
+		}
+		return nil
+	case <-time.After(time.Duration(e.AckTimeout)):
+		return fmt.Errorf("timeout waiting for the process to acknowledge metrics")
+	}
+}
+
 func (e *Execd) cmdReadErr(out io.Reader) {
 	scanner := bufio.NewScanner(out)
 
@@ -122,8 +195,45 @@ func (e *Execd) cmdReadOut(out io.Reader) {
 	}
 }
 
+func (e *Execd) cmdReadFrames(out io.Reader) {
+	scanner := process.NewFrameScanner(out)
+
+	for scanner.Scan() {
+		f, err := process.ParseFrame(scanner.Bytes())
+		if err != nil {
+			// Not a frame, most likely output of the program
+			e.Log.Info(scanner.Text())
+			continue
+		}
+
+		if f.Type != process.FrameAck && f.Type != process.FrameNack {
+			e.Log.Errorf("Unexpected frame type %q", f.Type)
+			continue
+		}
+
+		e.Lock()
+		reply, ok := e.waiting[f.ID]
+		e.Unlock()
+		if !ok {
+			e.Log.Debugf("Acknowledgement of unknown or expired batch %d", f.ID)
+			continue
+		}
+		select {
+		case reply <- f:
+		default:
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		e.Log.Errorf("Error reading stdout: %s", err)
+	}
+}
+
 func init() {
 	outputs.Add("execd", func() telegraf.Output {
-		return &Execd{}
+		return &Execd{
+			Protocol:   "plain",
+			AckTimeout: config.Duration(10 * time.Second),
+		}
 	})
 }
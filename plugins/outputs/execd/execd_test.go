@@ -2,6 +2,7 @@ package execd
 
 import (
 	"bufio"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
@@ -14,6 +15,7 @@ import (
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/config"
 	"github.com/influxdata/telegraf/metric"
+	"github.com/influxdata/telegraf/plugins/common/shim"
 	"github.com/influxdata/telegraf/plugins/parsers/influx"
 	"github.com/influxdata/telegraf/plugins/serializers"
 	"github.com/influxdata/telegraf/testutil"
@@ -69,15 +71,77 @@ func TestExternalOutputWorks(t *testing.T) {
 	wg.Wait()
 }
 
+func TestExternalOutputFramed(t *testing.T) {
+	influxSerializer, err := serializers.NewInfluxSerializer()
+	require.NoError(t, err)
+
+	exe, err := os.Executable()
+	require.NoError(t, err)
+
+	e := &Execd{
+		Command:      []string{exe, "-framedoutput"},
+		RestartDelay: config.Duration(5 * time.Second),
+		Protocol:     "framed",
+		AckTimeout:   config.Duration(5 * time.Second),
+		serializer:   influxSerializer,
+		Log:          testutil.Logger{},
+	}
+
+	require.NoError(t, e.Init())
+	require.NoError(t, e.Connect())
+	defer e.Close()
+
+	m := testutil.MustMetric("cpu",
+		map[string]string{"name": "cpu1"},
+		map[string]interface{}{"idle": 50, "sys": 30},
+		now,
+	)
+	rejected := testutil.MustMetric("reject",
+		map[string]string{},
+		map[string]interface{}{"value": 1},
+		now,
+	)
+
+	require.NoError(t, e.Write([]telegraf.Metric{m, m}))
+	err = e.Write([]telegraf.Metric{m, rejected})
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "metric rejected")
+}
+
+func TestUnknownProtocol(t *testing.T) {
+	e := &Execd{
+		Command:  []string{"cat"},
+		Protocol: "msgpack",
+		Log:      testutil.Logger{},
+	}
+	require.Error(t, e.Init())
+}
+
+func TestInvalidAckTimeout(t *testing.T) {
+	e := &Execd{
+		Command:  []string{"cat"},
+		Protocol: "framed",
+		Log:      testutil.Logger{},
+	}
+	require.Error(t, e.Init())
+}
+
 var testoutput = flag.Bool("testoutput", false,
 	"if true, act like line input program instead of test")
 
+var framedoutput = flag.Bool("framedoutput", false,
+	"if true, run an output rejecting \"reject\" metrics in the shim instead of test")
+
 func TestMain(m *testing.M) {
 	flag.Parse()
 	if *testoutput {
 		runOutputConsumerProgram()
 		os.Exit(0)
 	}
+	if *framedoutput {
+		runFramedOutputProgram()
+		os.Exit(0)
+	}
 	code := m.Run()
 	os.Exit(code)
 }
@@ -111,3 +175,42 @@ func runOutputConsumerProgram() {
 		}
 	}
 }
+
+func runFramedOutputProgram() {
+	s := shim.New()
+	if err := s.AddOutput(&rejectingOutput{}); err != nil {
+		fmt.Fprintf(os.Stderr, "ERR %v\n", err)
+		os.Exit(1)
+	}
+	if err := s.Run(shim.PollIntervalDisabled); err != nil {
+		fmt.Fprintf(os.Stderr, "ERR %v\n", err)
+		os.Exit(1)
+	}
+}
+
+type rejectingOutput struct{}
+
+func (o *rejectingOutput) Connect() error {
+	return nil
+}
+
+func (o *rejectingOutput) Close() error {
+	return nil
+}
+
+func (o *rejectingOutput) Write(metrics []telegraf.Metric) error {
+	for _, m := range metrics {
+		if m.Name() == "reject" {
+			return errors.New("metric rejected")
+		}
+	}
+	return nil
+}
+
+func (o *rejectingOutput) SampleConfig() string {
+	return ""
+}
+
+func (o *rejectingOutput) Description() string {
+	return ""
+}
@@ -4,25 +4,84 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/internal/rotate"
+	"github.com/influxdata/telegraf/models"
 	"github.com/influxdata/telegraf/plugins/outputs"
 	"github.com/influxdata/telegraf/plugins/serializers"
 )
 
+// Files created from a templated path are closed once no metric has been
+// written to them for this long, so that paths containing a date do not
+// accumulate open file handles.
+const templatedFileIdleTimeout = time.Hour
+
+// timeVerbRe matches the time placeholders replaced in file paths
+var timeVerbRe = regexp.MustCompile(`%[YymdHV]`)
+
 type File struct {
 	Files               []string          `toml:"files"`
 	RotationInterval    internal.Duration `toml:"rotation_interval"`
 	RotationMaxSize     internal.Size     `toml:"rotation_max_size"`
 	RotationMaxArchives int               `toml:"rotation_max_archives"`
 	UseBatchFormat      bool              `toml:"use_batch_format"`
+	Destinations        []*Destination    `toml:"destination"`
 	Log                 telegraf.Logger   `toml:"-"`
 
+	destinations        []*destination
+	serializer          serializers.Serializer
+	serializerFunc      serializers.SerializerFunc
+	destSerializerFuncs []serializers.SerializerFunc
+}
+
+// Destination is a set of files with its own metric filter.  The data format
+// options of a destination are handled by the config loader, which sets the
+// serializers with SetDestinationSerializerFuncs.
+type Destination struct {
+	Files          []string `toml:"files"`
+	UseBatchFormat bool     `toml:"use_batch_format"`
+
+	NamePass   []string            `toml:"namepass"`
+	NameDrop   []string            `toml:"namedrop"`
+	FieldPass  []string            `toml:"fieldpass"`
+	FieldDrop  []string            `toml:"fielddrop"`
+	TagPass    map[string][]string `toml:"tagpass"`
+	TagDrop    map[string][]string `toml:"tagdrop"`
+	TagExclude []string            `toml:"tagexclude"`
+	TagInclude []string            `toml:"taginclude"`
+}
+
+// destination is the runtime state of a Destination, or of the top level
+// files option.
+type destination struct {
+	filter         models.Filter
+	newSerializer  serializers.SerializerFunc
+	useBatchFormat bool
+
+	// files are the files with a static path.
+	files []*outputFile
+
+	// templates are the files with a path depending on the metric.
+	templates []*pathTemplate
+	templated map[string]*outputFile
+}
+
+// outputFile is an open file with its own serializer, so that data formats
+// with a header write it at the start of every file.
+type outputFile struct {
+	path       string
 	writer     io.Writer
-	closers    []io.Closer
+	closer     io.Closer
 	serializer serializers.Serializer
+	written    bool
+	lastWrite  time.Time
 }
 
 var sampleConfig = `
@@ -51,44 +110,185 @@ var sampleConfig = `
   ## more about them here:
   ## https://github.com/influxdata/telegraf/blob/master/docs/DATA_FORMATS_OUTPUT.md
   data_format = "influx"
+
+  ## Additional destinations, each with its own files, data format and
+  ## metric filters.  The data format options and the namepass, namedrop,
+  ## tagpass, tagdrop, fieldpass, fielddrop, taginclude and tagexclude
+  ## filters are supported.  Filters are applied after the filters of the
+  ## output itself.
+  ##
+  ## File paths may be templated with the metric name and tags using Go
+  ## templates, and with the metric time using %Y, %y, %m, %d, %H and %V.
+  ## Each generated path is rotated independently, and missing directories
+  ## are created.
+  # [[outputs.file.destination]]
+  #   files = ['/var/metrics/{{ .Name }}/%Y-%m-%d.json']
+  #   data_format = "json"
+  #   namepass = ["cpu"]
+  #
+  #   [outputs.file.destination.tagpass]
+  #     cpu = ["cpu-total"]
 `
 
 func (f *File) SetSerializer(serializer serializers.Serializer) {
 	f.serializer = serializer
 }
 
-func (f *File) Connect() error {
-	writers := []io.Writer{}
+// SetSerializerFunc sets the function creating the serializer of each file.
+func (f *File) SetSerializerFunc(fn serializers.SerializerFunc) {
+	f.serializerFunc = fn
+}
 
-	if len(f.Files) == 0 {
+// SetDestinationSerializerFuncs sets the functions creating the serializers
+// of the destinations, in the order of the Destinations option.
+func (f *File) SetDestinationSerializerFuncs(fns []serializers.SerializerFunc) {
+	f.destSerializerFuncs = fns
+}
+
+func (f *File) Connect() error {
+	if len(f.Files) == 0 && len(f.Destinations) == 0 {
 		f.Files = []string{"stdout"}
 	}
 
-	for _, file := range f.Files {
-		if file == "stdout" {
-			writers = append(writers, os.Stdout)
-		} else {
-			of, err := rotate.NewFileWriter(
-				file, f.RotationInterval.Duration, f.RotationMaxSize.Size, f.RotationMaxArchives)
+	f.destinations = f.destinations[:0]
+	if len(f.Files) != 0 {
+		dest := &destination{
+			newSerializer:  f.serializerFunc,
+			useBatchFormat: f.UseBatchFormat,
+		}
+		if dest.newSerializer == nil {
+			dest.newSerializer = func() (serializers.Serializer, error) {
+				return f.serializer, nil
+			}
+		}
+		if err := f.openFiles(dest, f.Files); err != nil {
+			return err
+		}
+		f.destinations = append(f.destinations, dest)
+	}
+
+	for i, d := range f.Destinations {
+		dest, err := f.newDestination(i, d)
+		if err != nil {
+			f.Close()
+			return err
+		}
+		f.destinations = append(f.destinations, dest)
+	}
+	return nil
+}
+
+func (f *File) newDestination(index int, d *Destination) (*destination, error) {
+	if len(d.Files) == 0 {
+		return nil, fmt.Errorf("destination requires at least one file")
+	}
+
+	filter := models.Filter{
+		NamePass:   d.NamePass,
+		NameDrop:   d.NameDrop,
+		FieldPass:  d.FieldPass,
+		FieldDrop:  d.FieldDrop,
+		TagInclude: d.TagInclude,
+		TagExclude: d.TagExclude,
+		TagPass:    tagFilters(d.TagPass),
+		TagDrop:    tagFilters(d.TagDrop),
+	}
+	if err := filter.Compile(); err != nil {
+		return nil, err
+	}
+
+	dest := &destination{
+		filter:         filter,
+		newSerializer:  serializers.NewInfluxSerializer,
+		useBatchFormat: d.UseBatchFormat,
+	}
+	if index < len(f.destSerializerFuncs) {
+		dest.newSerializer = f.destSerializerFuncs[index]
+	}
+	if err := f.openFiles(dest, d.Files); err != nil {
+		return nil, err
+	}
+	return dest, nil
+}
+
+func tagFilters(tags map[string][]string) []models.TagFilter {
+	var filters []models.TagFilter
+	for name, values := range tags {
+		filters = append(filters, models.TagFilter{Name: name, Filter: values})
+	}
+	return filters
+}
+
+func (f *File) openFiles(dest *destination, files []string) error {
+	dest.templated = make(map[string]*outputFile)
+	for _, file := range files {
+		if isTemplated(file) {
+			tmpl, err := newPathTemplate(file)
 			if err != nil {
+				dest.close()
 				return err
 			}
+			dest.templates = append(dest.templates, tmpl)
+			continue
+		}
 
-			writers = append(writers, of)
-			f.closers = append(f.closers, of)
+		of, err := f.openFile(dest, file)
+		if err != nil {
+			dest.close()
+			return err
 		}
+		dest.files = append(dest.files, of)
 	}
-	f.writer = io.MultiWriter(writers...)
 	return nil
 }
 
+func (f *File) openFile(dest *destination, path string) (*outputFile, error) {
+	serializer, err := dest.newSerializer()
+	if err != nil {
+		return nil, err
+	}
+
+	if path == "stdout" {
+		return &outputFile{path: path, writer: os.Stdout, serializer: serializer}, nil
+	}
+
+	w, err := rotate.NewFileWriter(
+		path, f.RotationInterval.Duration, f.RotationMaxSize.Size, f.RotationMaxArchives)
+	if err != nil {
+		return nil, err
+	}
+	return &outputFile{path: path, writer: w, closer: w, serializer: serializer}, nil
+}
+
 func (f *File) Close() error {
 	var err error
-	for _, c := range f.closers {
-		errClose := c.Close()
+	for _, dest := range f.destinations {
+		if errClose := dest.close(); errClose != nil {
+			err = errClose
+		}
+	}
+	return err
+}
+
+// close closes the files of the destination.
+func (d *destination) close() error {
+	var err error
+	for _, of := range d.files {
+		if of.closer == nil {
+			continue
+		}
+		errClose := of.closer.Close()
+		if errClose != nil {
+			err = errClose
+		}
+	}
+	d.files = nil
+	for path, of := range d.templated {
+		errClose := of.closer.Close()
 		if errClose != nil {
 			err = errClose
 		}
+		delete(d.templated, path)
 	}
 	return err
 }
@@ -104,24 +304,134 @@ func (f *File) Description() string {
 func (f *File) Write(metrics []telegraf.Metric) error {
 	var writeErr error = nil
 
-	if f.UseBatchFormat {
-		octets, err := f.serializer.SerializeBatch(metrics)
+	for _, dest := range f.destinations {
+		selected := dest.selectMetrics(metrics)
+		if len(selected) == 0 {
+			continue
+		}
+
+		for _, of := range dest.files {
+			if err := f.write(dest, of, selected); err != nil {
+				writeErr = err
+			}
+		}
+
+		if len(dest.templates) != 0 {
+			if err := f.writeTemplated(dest, selected); err != nil {
+				writeErr = err
+			}
+		}
+	}
+
+	return writeErr
+}
+
+// selectMetrics returns the metrics passing the destination filter.
+func (d *destination) selectMetrics(metrics []telegraf.Metric) []telegraf.Metric {
+	if !d.filter.IsActive() {
+		return metrics
+	}
+
+	selected := make([]telegraf.Metric, 0, len(metrics))
+	for _, metric := range metrics {
+		if !d.filter.Select(metric) {
+			continue
+		}
+
+		// Avoid modifying the metric as it is shared with the other
+		// destinations.
+		metric = metric.Copy()
+		metric.Accept()
+		d.filter.Modify(metric)
+		if len(metric.FieldList()) == 0 {
+			continue
+		}
+		selected = append(selected, metric)
+	}
+	return selected
+}
+
+func (f *File) writeTemplated(dest *destination, metrics []telegraf.Metric) error {
+	var writeErr error = nil
+
+	now := time.Now()
+	groups := make(map[string][]telegraf.Metric)
+	var paths []string
+	for _, metric := range metrics {
+		for _, tmpl := range dest.templates {
+			path, err := tmpl.Execute(metric)
+			if err != nil {
+				f.Log.Errorf("Could not create file path: %v", err)
+				continue
+			}
+			if _, ok := groups[path]; !ok {
+				paths = append(paths, path)
+			}
+			groups[path] = append(groups[path], metric)
+		}
+	}
+
+	for _, path := range paths {
+		of, ok := dest.templated[path]
+		if !ok {
+			err := os.MkdirAll(filepath.Dir(path), 0755)
+			if err != nil {
+				writeErr = fmt.Errorf("E! [outputs.file] failed to create directory for %q: %v", path, err)
+				continue
+			}
+
+			of, err = f.openFile(dest, path)
+			if err != nil {
+				writeErr = fmt.Errorf("E! [outputs.file] failed to open file %q: %v", path, err)
+				continue
+			}
+			dest.templated[path] = of
+		}
+
+		of.lastWrite = now
+		if err := f.write(dest, of, groups[path]); err != nil {
+			writeErr = err
+		}
+	}
+
+	for path, of := range dest.templated {
+		if now.Sub(of.lastWrite) > templatedFileIdleTimeout {
+			if err := of.closer.Close(); err != nil {
+				f.Log.Errorf("Error closing file %q: %v", path, err)
+			}
+			delete(dest.templated, path)
+		}
+	}
+
+	return writeErr
+}
+
+func (f *File) write(dest *destination, of *outputFile, metrics []telegraf.Metric) error {
+	var writeErr error = nil
+
+	if err := f.checkRotated(dest, of); err != nil {
+		return err
+	}
+	of.written = true
+
+	if dest.useBatchFormat {
+		octets, err := of.serializer.SerializeBatch(metrics)
 		if err != nil {
 			f.Log.Errorf("Could not serialize metric: %v", err)
 		}
 
-		_, err = f.writer.Write(octets)
+		_, err = of.writer.Write(octets)
 		if err != nil {
 			f.Log.Errorf("Error writing to file: %v", err)
 		}
 	} else {
 		for _, metric := range metrics {
-			b, err := f.serializer.Serialize(metric)
+			b, err := of.serializer.Serialize(metric)
 			if err != nil {
 				f.Log.Debugf("Could not serialize metric: %v", err)
 			}
 
-			_, err = f.writer.Write(b)
+			_, err = of.writer.Write(b)
 			if err != nil {
 				writeErr = fmt.Errorf("E! [outputs.file] failed to write message: %v", err)
 			}
@@ -131,6 +441,93 @@ func (f *File) Write(metrics []telegraf.Metric) error {
 	return writeErr
 }
 
+// checkRotated replaces the serializer of a file that has been rotated since
+// it was last written, so that the new file starts with a header as well.
+func (f *File) checkRotated(dest *destination, of *outputFile) error {
+	if !of.written || of.closer == nil {
+		return nil
+	}
+	if f.RotationInterval.Duration == 0 && f.RotationMaxSize.Size <= 0 {
+		return nil
+	}
+
+	info, err := os.Stat(of.path)
+	if err != nil || info.Size() != 0 {
+		return nil
+	}
+
+	serializer, err := dest.newSerializer()
+	if err != nil {
+		return err
+	}
+	of.serializer = serializer
+	of.written = false
+	return nil
+}
+
+// pathTemplate creates a file path from the metric name, tags and time.
+type pathTemplate struct {
+	tmpl *template.Template
+}
+
+// isTemplated returns true if the path depends on the metric.  A "%" is
+// only a time verb if it is followed by one of the supported letters.
+func isTemplated(path string) bool {
+	return strings.Contains(path, "{{") || timeVerbRe.MatchString(path)
+}
+
+func newPathTemplate(path string) (*pathTemplate, error) {
+	tmpl, err := template.New("path").Parse(path)
+	if err != nil {
+		return nil, fmt.Errorf("invalid file path template %q: %v", path, err)
+	}
+	return &pathTemplate{tmpl: tmpl}, nil
+}
+
+func (p *pathTemplate) Execute(metric telegraf.Metric) (string, error) {
+	var b strings.Builder
+	if err := p.tmpl.Execute(&b, &templateMetric{metric}); err != nil {
+		return "", err
+	}
+
+	path := b.String()
+	if strings.Contains(path, "%") {
+		t := metric.Time().UTC()
+		_, week := t.ISOWeek()
+		path = strings.NewReplacer(
+			"%Y", t.Format("2006"),
+			"%y", t.Format("06"),
+			"%m", t.Format("01"),
+			"%d", t.Format("02"),
+			"%H", t.Format("15"),
+			"%V", fmt.Sprintf("%02d", week),
+		).Replace(path)
+	}
+	return path, nil
+}
+
+// pathReplacer replaces the separators and parent directory references in
+// metric names and tag values, so they cannot change the directory of a path.
+var pathReplacer = strings.NewReplacer("/", "_", `\`, "_", "..", "_")
+
+// templateMetric is the value passed to file path templates.
+type templateMetric struct {
+	metric telegraf.Metric
+}
+
+func (m *templateMetric) Name() string {
+	return pathReplacer.Replace(m.metric.Name())
+}
+
+func (m *templateMetric) Tag(key string) string {
+	tagString, _ := m.metric.GetTag(key)
+	return pathReplacer.Replace(tagString)
+}
+
+func (m *templateMetric) Time() time.Time {
+	return m.metric.Time()
+}
+
 func init() {
 	outputs.Add("file", func() telegraf.Output {
 		return &File{}
@@ -5,10 +5,14 @@ import (
 	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
+	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/plugins/serializers"
 	"github.com/influxdata/telegraf/testutil"
@@ -180,6 +184,234 @@ func TestFileStdout(t *testing.T) {
 	assert.Equal(t, expNewFile, out)
 }
 
+func TestFileDestinations(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	influxFile := filepath.Join(dir, "metrics.out")
+	jsonFile := filepath.Join(dir, "metrics.json")
+	cpuFile := filepath.Join(dir, "cpu.out")
+
+	s, _ := serializers.NewInfluxSerializer()
+	f := File{
+		Files: []string{influxFile},
+		Destinations: []*Destination{
+			{
+				Files:    []string{jsonFile},
+				NamePass: []string{"test1"},
+			},
+			{
+				Files:   []string{cpuFile},
+				TagPass: map[string][]string{"cpu": {"cpu0"}},
+			},
+		},
+		Log:        testutil.Logger{},
+		serializer: s,
+	}
+	f.SetDestinationSerializerFuncs([]serializers.SerializerFunc{
+		func() (serializers.Serializer, error) {
+			return serializers.NewJsonSerializer(time.Second)
+		},
+	})
+
+	err = f.Connect()
+	require.NoError(t, err)
+
+	metrics := []telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{"cpu": "cpu0"},
+			map[string]interface{}{"value": 100}, time.Unix(1455312810, 12459582)),
+		testutil.MustMetric("test1", map[string]string{"tag1": "value1"},
+			map[string]interface{}{"value": 1}, time.Unix(1257894000, 0)),
+	}
+	err = f.Write(metrics)
+	require.NoError(t, err)
+
+	err = f.Close()
+	require.NoError(t, err)
+
+	validateFile(influxFile, "cpu,cpu=cpu0 value=100i 1455312810012459582\n"+
+		"test1,tag1=value1 value=1i 1257894000000000000\n", t)
+	validateFile(jsonFile, `{"fields":{"value":1},"name":"test1","tags":{"tag1":"value1"},"timestamp":1257894000}`+"\n", t)
+	validateFile(cpuFile, "cpu,cpu=cpu0 value=100i 1455312810012459582\n", t)
+}
+
+func TestFileTemplatedPath(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	s, _ := serializers.NewInfluxSerializer()
+	f := File{
+		Files:      []string{filepath.Join(dir, "{{ .Name }}", `{{ .Tag "host" }}-%Y-%m-%d.out`)},
+		Log:        testutil.Logger{},
+		serializer: s,
+	}
+
+	err = f.Connect()
+	require.NoError(t, err)
+
+	metrics := []telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{"host": "a"},
+			map[string]interface{}{"value": 1}, time.Date(2020, 7, 1, 12, 0, 0, 0, time.UTC)),
+		testutil.MustMetric("cpu", map[string]string{"host": "a"},
+			map[string]interface{}{"value": 2}, time.Date(2020, 7, 2, 12, 0, 0, 0, time.UTC)),
+		testutil.MustMetric("mem", map[string]string{"host": "b"},
+			map[string]interface{}{"value": 3}, time.Date(2020, 7, 1, 12, 0, 0, 0, time.UTC)),
+		testutil.MustMetric("cpu", map[string]string{"host": "a"},
+			map[string]interface{}{"value": 4}, time.Date(2020, 7, 1, 13, 0, 0, 0, time.UTC)),
+	}
+	err = f.Write(metrics)
+	require.NoError(t, err)
+
+	err = f.Close()
+	require.NoError(t, err)
+
+	validateFile(filepath.Join(dir, "cpu", "a-2020-07-01.out"),
+		"cpu,host=a value=1i 1593604800000000000\ncpu,host=a value=4i 1593608400000000000\n", t)
+	validateFile(filepath.Join(dir, "cpu", "a-2020-07-02.out"),
+		"cpu,host=a value=2i 1593691200000000000\n", t)
+	validateFile(filepath.Join(dir, "mem", "b-2020-07-01.out"),
+		"mem,host=b value=3i 1593604800000000000\n", t)
+}
+
+func TestFileLiteralPercent(t *testing.T) {
+	require.False(t, isTemplated("/var/log/100%.out"))
+	require.False(t, isTemplated("/var/log/50%-full.out"))
+	require.True(t, isTemplated("/var/log/%Y.out"))
+	require.True(t, isTemplated("/var/log/{{ .Name }}.out"))
+
+	dir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	s, _ := serializers.NewInfluxSerializer()
+	path := filepath.Join(dir, "100%.out")
+	f := File{
+		Files:      []string{path},
+		Log:        testutil.Logger{},
+		serializer: s,
+	}
+	require.NoError(t, f.Connect())
+	require.NoError(t, f.Write([]telegraf.Metric{testutil.TestMetric(1)}))
+	require.NoError(t, f.Close())
+
+	_, err = os.Stat(path)
+	require.NoError(t, err)
+}
+
+func TestFileConnectErrorClosesFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	s, _ := serializers.NewInfluxSerializer()
+	f := File{
+		Files: []string{filepath.Join(dir, "ok.out")},
+		Destinations: []*Destination{
+			{Files: []string{filepath.Join(dir, "second.out")}},
+			{Files: []string{filepath.Join(dir, "third.out"), filepath.Join(dir, "missing", "x.out")}},
+		},
+		Log:        testutil.Logger{},
+		serializer: s,
+	}
+	require.Error(t, f.Connect())
+
+	// All files opened before the failure are closed
+	for _, dest := range f.destinations {
+		require.Empty(t, dest.files)
+	}
+}
+
+func TestFileTemplatedPathSanitized(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	f := File{
+		Destinations: []*Destination{
+			{
+				Files: []string{filepath.Join(dir, `{{ .Tag "path" }}`, "%Y-W%V.out")},
+			},
+		},
+		Log: testutil.Logger{},
+	}
+	f.SetDestinationSerializerFuncs([]serializers.SerializerFunc{serializers.NewInfluxSerializer})
+
+	err = f.Connect()
+	require.NoError(t, err)
+
+	metrics := []telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{"path": "../../etc"},
+			map[string]interface{}{"value": 1}, time.Date(2020, 1, 2, 12, 0, 0, 0, time.UTC)),
+	}
+	err = f.Write(metrics)
+	require.NoError(t, err)
+
+	err = f.Close()
+	require.NoError(t, err)
+
+	validateFile(filepath.Join(dir, "____etc", "2020-W01.out"),
+		"cpu,path=../../etc value=1i 1577966400000000000\n", t)
+}
+
+func TestFileCSVHeaderPerFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	newSerializer := func() (serializers.Serializer, error) {
+		return serializers.NewSerializer(&serializers.Config{DataFormat: "csv", CSVHeader: "once"})
+	}
+	s, _ := newSerializer()
+	f := File{
+		Files:               []string{filepath.Join(dir, "metrics.csv")},
+		RotationMaxSize:     internal.Size{Size: 60},
+		RotationMaxArchives: -1,
+		UseBatchFormat:      true,
+		Destinations: []*Destination{
+			{
+				Files:          []string{filepath.Join(dir, "hosts", `{{ .Tag "host" }}.csv`)},
+				UseBatchFormat: true,
+			},
+		},
+		Log:        testutil.Logger{},
+		serializer: s,
+	}
+	f.SetSerializerFunc(newSerializer)
+	f.SetDestinationSerializerFuncs([]serializers.SerializerFunc{newSerializer})
+
+	err = f.Connect()
+	require.NoError(t, err)
+	defer f.Close()
+
+	ts := time.Unix(1593604800, 0)
+	err = f.Write([]telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{"host": "a"}, map[string]interface{}{"value": 1}, ts),
+		testutil.MustMetric("cpu", map[string]string{"host": "b"}, map[string]interface{}{"value": 2}, ts),
+	})
+	require.NoError(t, err)
+
+	// Every templated file has its own header
+	validateFile(filepath.Join(dir, "hosts", "a.csv"), "timestamp,measurement,host,value\n1593604800,cpu,a,1\n", t)
+	validateFile(filepath.Join(dir, "hosts", "b.csv"), "timestamp,measurement,host,value\n1593604800,cpu,b,2\n", t)
+
+	// The file exceeded the maximum size and was rotated
+	archives, err := filepath.Glob(filepath.Join(dir, "metrics.*-*.csv"))
+	require.NoError(t, err)
+	require.Equal(t, 1, len(archives))
+	validateFile(archives[0], "timestamp,measurement,host,value\n1593604800,cpu,a,1\n1593604800,cpu,b,2\n", t)
+	validateFile(filepath.Join(dir, "metrics.csv"), "", t)
+
+	err = f.Write([]telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{"host": "a"}, map[string]interface{}{"value": 3}, ts),
+	})
+	require.NoError(t, err)
+
+	// The new file starts with a header as well
+	validateFile(filepath.Join(dir, "metrics.csv"), "timestamp,measurement,host,value\n1593604800,cpu,a,3\n", t)
+}
+
 func createFile() *os.File {
 	f, err := ioutil.TempFile("", "")
 	if err != nil {
@@ -0,0 +1,101 @@
+package grpc_plugin
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/common/grpcplugin"
+	"github.com/influxdata/telegraf/plugins/common/grpcplugin/pluginv1"
+	"github.com/influxdata/telegraf/plugins/outputs"
+)
+
+const sampleConfig = `
+  ## Program to run as plugin, it must serve the plugin on the socket passed
+  ## in the TELEGRAF_PLUGIN_SOCKET environment variable.
+  command = ["telegraf-kafka-plugin"]
+
+  ## Delay before the process is restarted after an unexpected termination
+  # restart_delay = "10s"
+
+  ## Maximum time to wait for the plugin to serve after the process started
+  # start_timeout = "10s"
+
+  ## Interval of the health checks, the process is restarted when a check
+  ## fails.  Set to "0s" to disable the health checks.
+  # health_check_interval = "30s"
+
+  ## Settings of the plugin
+  # [outputs.grpc_plugin.config]
+  #   brokers = ["localhost:9092"]
+`
+
+type GRPCPlugin struct {
+	grpcplugin.Config
+	Log telegraf.Logger `toml:"-"`
+
+	client *grpcplugin.Client
+}
+
+func (g *GRPCPlugin) SampleConfig() string {
+	return sampleConfig
+}
+
+func (g *GRPCPlugin) Description() string {
+	return "Run an external output plugin over gRPC"
+}
+
+func (g *GRPCPlugin) Init() error {
+	client, err := g.NewClient(pluginv1.PluginType_PLUGIN_TYPE_OUTPUT, g.Log)
+	if err != nil {
+		return err
+	}
+	client.OnReady = func(ctx context.Context, plugin pluginv1.PluginClient, info *pluginv1.InitResponse) error {
+		_, err := plugin.Start(ctx, &pluginv1.StartRequest{})
+		return err
+	}
+	g.client = client
+	return nil
+}
+
+func (g *GRPCPlugin) Connect() error {
+	return g.client.Start()
+}
+
+func (g *GRPCPlugin) Close() error {
+	ctx, plugin, err := g.client.Plugin()
+	if err == nil {
+		_, err = plugin.Stop(ctx, &pluginv1.StopRequest{})
+	}
+	g.client.Stop()
+	return err
+}
+
+func (g *GRPCPlugin) Write(metrics []telegraf.Metric) error {
+	batch := &pluginv1.Metrics{Metrics: make([]*pluginv1.Metric, 0, len(metrics))}
+	for _, m := range metrics {
+		pm, err := grpcplugin.ToProto(m)
+		if err != nil {
+			g.Log.Errorf("Dropping metric: %v", err)
+			continue
+		}
+		batch.Metrics = append(batch.Metrics, pm)
+	}
+
+	ctx, plugin, err := g.client.Plugin()
+	if err != nil {
+		return err
+	}
+	if _, err := plugin.Write(ctx, batch); err != nil {
+		return fmt.Errorf("writing metrics: %w", err)
+	}
+	return nil
+}
+
+func init() {
+	outputs.Add("grpc_plugin", func() telegraf.Output {
+		return &GRPCPlugin{
+			Config: grpcplugin.DefaultConfig(),
+		}
+	})
+}
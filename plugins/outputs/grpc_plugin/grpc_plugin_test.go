@@ -0,0 +1,111 @@
+package grpc_plugin
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/common/grpcplugin"
+	"github.com/influxdata/telegraf/plugins/common/grpcplugin/server"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrite(t *testing.T) {
+	exe, err := os.Executable()
+	require.NoError(t, err)
+
+	dir, err := ioutil.TempDir("", "grpc_plugin")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "metrics")
+
+	g := &GRPCPlugin{Config: grpcplugin.DefaultConfig()}
+	g.Log = testutil.Logger{}
+	g.Command = []string{exe, "-file"}
+	g.Config.Config = map[string]interface{}{"path": file}
+	require.NoError(t, g.Init())
+	require.NoError(t, g.Connect())
+
+	now := time.Now()
+	require.NoError(t, g.Write([]telegraf.Metric{
+		testutil.MustMetric("cpu", nil, map[string]interface{}{"value": 1}, now),
+		testutil.MustMetric("mem", nil, map[string]interface{}{"value": 2}, now),
+	}))
+
+	// the plugin rejects metrics named fail
+	require.Error(t, g.Write([]telegraf.Metric{
+		testutil.MustMetric("fail", nil, map[string]interface{}{"value": 3}, now),
+	}))
+	require.NoError(t, g.Close())
+
+	f, err := os.Open(file)
+	require.NoError(t, err)
+	defer f.Close()
+
+	var names []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		names = append(names, scanner.Text())
+	}
+	require.Equal(t, []string{"cpu", "mem"}, names)
+}
+
+var file = flag.Bool("file", false,
+	"if true, act like an output plugin writing the metric names to a file")
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+	if *file {
+		if err := server.Serve(&fileOutput{}); err != nil {
+			fmt.Fprintf(os.Stderr, "ERR %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	code := m.Run()
+	os.Exit(code)
+}
+
+type fileOutput struct {
+	Path string `toml:"path"`
+
+	f *os.File
+}
+
+func (o *fileOutput) Connect() error {
+	var err error
+	o.f, err = os.Create(o.Path)
+	return err
+}
+
+func (o *fileOutput) Close() error {
+	return o.f.Close()
+}
+
+func (o *fileOutput) Write(metrics []telegraf.Metric) error {
+	for _, m := range metrics {
+		if m.Name() == "fail" {
+			return errors.New("failed")
+		}
+	}
+	for _, m := range metrics {
+		fmt.Fprintln(o.f, m.Name())
+	}
+	return nil
+}
+
+func (o *fileOutput) SampleConfig() string {
+	return ""
+}
+
+func (o *fileOutput) Description() string {
+	return ""
+}
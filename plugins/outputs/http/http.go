@@ -7,11 +7,17 @@ import (
 	"io"
 	"io/ioutil"
 	"net/http"
+	"sort"
 	"strings"
+	"text/template"
 	"time"
 
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	v4 "github.com/aws/aws-sdk-go/aws/signer/v4"
 	"github.com/influxdata/telegraf"
+	internalaws "github.com/influxdata/telegraf/config/aws"
 	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/common/delivery"
 	"github.com/influxdata/telegraf/plugins/common/tls"
 	"github.com/influxdata/telegraf/plugins/outputs"
 	"github.com/influxdata/telegraf/plugins/serializers"
@@ -25,6 +31,10 @@ const (
 
 var sampleConfig = `
   ## URL is the address to send metrics to
+  ## The URL may be a Go template referencing the metric name and tags, in
+  ## which case metrics are grouped by the rendered URL and each group is sent
+  ## in its own request:
+  ##   url = 'http://{{ .Tag "tenant" }}.example.org/telegraf'
   url = "http://127.0.0.1:8080/telegraf"
 
   ## Timeout for HTTP message
@@ -43,6 +53,24 @@ var sampleConfig = `
   # token_url = "https://indentityprovider/oauth2/v1/token"
   # scopes = ["urn:opc:idm:__myscopes__"]
 
+  ## AWS Signature Version 4 request signing, enabled when aws_service is set.
+  ## Credentials are loaded in the following order
+  ## 1) Assumed credentials via STS if role_arn is specified
+  ## 2) explicit credentials from 'access_key' and 'secret_key'
+  ## 3) shared profile from 'profile'
+  ## 4) environment variables
+  ## 5) shared credentials file
+  ## 6) EC2 Instance Profile
+  # aws_service = "execute-api"
+  # region = "us-east-1"
+  # access_key = ""
+  # secret_key = ""
+  # token = ""
+  # role_arn = ""
+  # profile = ""
+  # shared_credential_file = ""
+  # endpoint_url = ""
+
   ## Optional TLS Config
   # tls_ca = "/etc/telegraf/ca.pem"
   # tls_cert = "/etc/telegraf/cert.pem"
@@ -60,10 +88,26 @@ var sampleConfig = `
   ## compress body or "identity" to apply no encoding.
   # content_encoding = "identity"
 
+  ## Maximum number of metrics to send in a single request, 0 means unlimited.
+  # max_metrics_per_request = 0
+
+  ## Maximum size of the serialized request body before content encoding, 0
+  ## means unlimited.  Batches exceeding this size are split into multiple
+  ## requests; a single metric exceeding it is dropped.
+  # max_body_size = "0B"
+
+  ## HTTP status codes that indicate the request can never succeed.  Metrics
+  ## sent in such a request are dropped instead of being retried.  All other
+  ## non-2xx status codes are retried.  For example [400, 409, 413] drops
+  ## requests rejected as malformed, conflicting or too large.
+  # non_retryable_statuscodes = []
+
   ## Additional HTTP headers
+  ## Header values may be Go templates in the same way as the url option.
   # [outputs.http.headers]
   #   # Should be set manually to "application/json" for json data_format
   #   Content-Type = "text/plain; charset=utf-8"
+  #   X-Scope-OrgID = '{{ .Tag "tenant" }}'
 `
 
 const (
@@ -73,21 +117,95 @@ const (
 )
 
 type HTTP struct {
-	URL             string            `toml:"url"`
-	Timeout         internal.Duration `toml:"timeout"`
-	Method          string            `toml:"method"`
-	Username        string            `toml:"username"`
-	Password        string            `toml:"password"`
-	Headers         map[string]string `toml:"headers"`
-	ClientID        string            `toml:"client_id"`
-	ClientSecret    string            `toml:"client_secret"`
-	TokenURL        string            `toml:"token_url"`
-	Scopes          []string          `toml:"scopes"`
-	ContentEncoding string            `toml:"content_encoding"`
+	URL                     string            `toml:"url"`
+	Timeout                 internal.Duration `toml:"timeout"`
+	Method                  string            `toml:"method"`
+	Username                string            `toml:"username"`
+	Password                string            `toml:"password"`
+	Headers                 map[string]string `toml:"headers"`
+	ClientID                string            `toml:"client_id"`
+	ClientSecret            string            `toml:"client_secret"`
+	TokenURL                string            `toml:"token_url"`
+	Scopes                  []string          `toml:"scopes"`
+	ContentEncoding         string            `toml:"content_encoding"`
+	MaxMetricsPerRequest    int               `toml:"max_metrics_per_request"`
+	MaxBodySize             internal.Size     `toml:"max_body_size"`
+	NonRetryableStatusCodes []int             `toml:"non_retryable_statuscodes"`
 	tls.ClientConfig
 
+	AwsService  string `toml:"aws_service"`
+	Region      string `toml:"region"`
+	AccessKey   string `toml:"access_key"`
+	SecretKey   string `toml:"secret_key"`
+	RoleARN     string `toml:"role_arn"`
+	Profile     string `toml:"profile"`
+	Filename    string `toml:"shared_credential_file"`
+	Token       string `toml:"token"`
+	EndpointURL string `toml:"endpoint_url"`
+
+	Log telegraf.Logger `toml:"-"`
+
 	client     *http.Client
 	serializer serializers.Serializer
+	signer     *v4.Signer
+
+	urlTmpl     *template.Template
+	headerTmpls map[string]*template.Template
+
+	delivery delivery.Tracker
+}
+
+// destination is a fully rendered request target for a group of metrics.
+type destination struct {
+	url     string
+	headers map[string]string
+}
+
+// key returns a string uniquely identifying the destination.
+func (d *destination) key() string {
+	keys := make([]string, 0, len(d.headers))
+	for k := range d.headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString(d.url)
+	for _, k := range keys {
+		b.WriteByte(0)
+		b.WriteString(k)
+		b.WriteByte(0)
+		b.WriteString(d.headers[k])
+	}
+	return b.String()
+}
+
+// templateMetric is the value passed to url and header templates.
+type templateMetric struct {
+	metric telegraf.Metric
+}
+
+func (m *templateMetric) Name() string {
+	return m.metric.Name()
+}
+
+func (m *templateMetric) Tag(key string) string {
+	tagString, _ := m.metric.GetTag(key)
+	return tagString
+}
+
+func (m *templateMetric) Time() time.Time {
+	return m.metric.Time()
+}
+
+// permanentError is returned from write when the server response indicates
+// the request should not be retried.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
 }
 
 func (h *HTTP) SetSerializer(serializer serializers.Serializer) {
@@ -122,6 +240,51 @@ func (h *HTTP) createClient(ctx context.Context) (*http.Client, error) {
 	return client, nil
 }
 
+func (h *HTTP) createSigner() *v4.Signer {
+	credentialConfig := &internalaws.CredentialConfig{
+		Region:      h.Region,
+		AccessKey:   h.AccessKey,
+		SecretKey:   h.SecretKey,
+		RoleARN:     h.RoleARN,
+		Profile:     h.Profile,
+		Filename:    h.Filename,
+		Token:       h.Token,
+		EndpointURL: h.EndpointURL,
+	}
+	configProvider := credentialConfig.Credentials()
+
+	var creds *credentials.Credentials
+	if cfg := configProvider.ClientConfig(h.AwsService).Config; cfg != nil {
+		creds = cfg.Credentials
+	}
+	return v4.NewSigner(creds)
+}
+
+func (h *HTTP) parseTemplates() error {
+	h.urlTmpl = nil
+	h.headerTmpls = make(map[string]*template.Template)
+
+	if strings.Contains(h.URL, "{{") {
+		t, err := template.New("url").Parse(h.URL)
+		if err != nil {
+			return fmt.Errorf("invalid url template: %v", err)
+		}
+		h.urlTmpl = t
+	}
+
+	for k, v := range h.Headers {
+		if !strings.Contains(v, "{{") {
+			continue
+		}
+		t, err := template.New(k).Parse(v)
+		if err != nil {
+			return fmt.Errorf("invalid template for header %q: %v", k, err)
+		}
+		h.headerTmpls[k] = t
+	}
+	return nil
+}
+
 func (h *HTTP) Connect() error {
 	if h.Method == "" {
 		h.Method = http.MethodPost
@@ -135,6 +298,14 @@ func (h *HTTP) Connect() error {
 		h.Timeout.Duration = defaultClientTimeout
 	}
 
+	if err := h.parseTemplates(); err != nil {
+		return err
+	}
+
+	if h.AwsService != "" {
+		h.signer = h.createSigner()
+	}
+
 	ctx := context.Background()
 	client, err := h.createClient(ctx)
 	if err != nil {
@@ -159,22 +330,150 @@ func (h *HTTP) SampleConfig() string {
 }
 
 func (h *HTTP) Write(metrics []telegraf.Metric) error {
-	reqBody, err := h.serializer.SerializeBatch(metrics)
+	metrics = h.delivery.Pending(metrics)
+
+	dests, groups, err := h.groupByDestination(metrics)
 	if err != nil {
 		return err
 	}
 
-	if err := h.write(reqBody); err != nil {
+	// A failing destination does not stop the others, only the metrics not
+	// delivered yet are sent again when the batch is retried.
+	var firstErr error
+	for i, dest := range dests {
+		for _, batch := range h.splitByCount(groups[i]) {
+			if err := h.writeBatch(dest, batch); err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				break
+			}
+		}
+	}
+
+	if firstErr != nil {
+		return firstErr
+	}
+	h.delivery.Reset()
+	return nil
+}
+
+// groupByDestination renders the url and header templates for each metric
+// and groups the metrics by the result, preserving the metric order.
+func (h *HTTP) groupByDestination(metrics []telegraf.Metric) ([]*destination, [][]telegraf.Metric, error) {
+	if h.urlTmpl == nil && len(h.headerTmpls) == 0 {
+		dest := &destination{url: h.URL, headers: h.Headers}
+		return []*destination{dest}, [][]telegraf.Metric{metrics}, nil
+	}
+
+	var dests []*destination
+	var groups [][]telegraf.Metric
+	index := make(map[string]int)
+	for _, m := range metrics {
+		dest, err := h.render(m)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		key := dest.key()
+		i, ok := index[key]
+		if !ok {
+			i = len(dests)
+			index[key] = i
+			dests = append(dests, dest)
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], m)
+	}
+	return dests, groups, nil
+}
+
+func (h *HTTP) render(m telegraf.Metric) (*destination, error) {
+	tm := &templateMetric{m}
+	dest := &destination{url: h.URL, headers: make(map[string]string, len(h.Headers))}
+
+	if h.urlTmpl != nil {
+		var b strings.Builder
+		if err := h.urlTmpl.Execute(&b, tm); err != nil {
+			return nil, fmt.Errorf("failed to execute url template: %v", err)
+		}
+		dest.url = b.String()
+	}
+
+	for k, v := range h.Headers {
+		if t, ok := h.headerTmpls[k]; ok {
+			var b strings.Builder
+			if err := t.Execute(&b, tm); err != nil {
+				return nil, fmt.Errorf("failed to execute template for header %q: %v", k, err)
+			}
+			v = b.String()
+		}
+		dest.headers[k] = v
+	}
+	return dest, nil
+}
+
+func (h *HTTP) splitByCount(metrics []telegraf.Metric) [][]telegraf.Metric {
+	if h.MaxMetricsPerRequest <= 0 || len(metrics) <= h.MaxMetricsPerRequest {
+		return [][]telegraf.Metric{metrics}
+	}
+
+	var batches [][]telegraf.Metric
+	for len(metrics) > h.MaxMetricsPerRequest {
+		batches = append(batches, metrics[:h.MaxMetricsPerRequest])
+		metrics = metrics[h.MaxMetricsPerRequest:]
+	}
+	return append(batches, metrics)
+}
+
+// writeBatch serializes and sends the metrics, splitting the batch in half
+// until each request body fits within max_body_size.
+func (h *HTTP) writeBatch(dest *destination, metrics []telegraf.Metric) error {
+	reqBody, err := h.serializer.SerializeBatch(metrics)
+	if err != nil {
 		return err
 	}
 
+	if h.MaxBodySize.Size > 0 && int64(len(reqBody)) > h.MaxBodySize.Size {
+		if len(metrics) == 1 {
+			h.Log.Errorf("Dropping metric %q: serialized size %d exceeds max_body_size",
+				metrics[0].Name(), len(reqBody))
+			h.delivery.Add(metrics...)
+			return nil
+		}
+
+		half := len(metrics) / 2
+		if err := h.writeBatch(dest, metrics[:half]); err != nil {
+			return err
+		}
+		return h.writeBatch(dest, metrics[half:])
+	}
+
+	err = h.write(dest, reqBody)
+	if err, ok := err.(*permanentError); ok {
+		h.Log.Errorf("Dropping %d metrics: %v", len(metrics), err)
+		h.delivery.Add(metrics...)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	h.delivery.Add(metrics...)
 	return nil
 }
 
-func (h *HTTP) write(reqBody []byte) error {
+func (h *HTTP) isRetryable(statusCode int) bool {
+	for _, code := range h.NonRetryableStatusCodes {
+		if code == statusCode {
+			return false
+		}
+	}
+	return true
+}
+
+func (h *HTTP) write(dest *destination, reqBody []byte) error {
 	var reqBodyBuffer io.Reader = bytes.NewBuffer(reqBody)
 
-	var err error
 	if h.ContentEncoding == "gzip" {
 		rc, err := internal.CompressWithGzip(reqBodyBuffer)
 		if err != nil {
@@ -184,7 +483,18 @@ func (h *HTTP) write(reqBody []byte) error {
 		reqBodyBuffer = rc
 	}
 
-	req, err := http.NewRequest(h.Method, h.URL, reqBodyBuffer)
+	// The signer needs to seek the body in order to compute its hash.
+	var signBody io.ReadSeeker
+	if h.signer != nil {
+		body, err := ioutil.ReadAll(reqBodyBuffer)
+		if err != nil {
+			return err
+		}
+		signBody = bytes.NewReader(body)
+		reqBodyBuffer = signBody
+	}
+
+	req, err := http.NewRequest(h.Method, dest.url, reqBodyBuffer)
 	if err != nil {
 		return err
 	}
@@ -198,13 +508,20 @@ func (h *HTTP) write(reqBody []byte) error {
 	if h.ContentEncoding == "gzip" {
 		req.Header.Set("Content-Encoding", "gzip")
 	}
-	for k, v := range h.Headers {
+	for k, v := range dest.headers {
 		if strings.ToLower(k) == "host" {
 			req.Host = v
 		}
 		req.Header.Set(k, v)
 	}
 
+	if h.signer != nil {
+		_, err = h.signer.Sign(req, signBody, h.AwsService, h.Region, time.Now())
+		if err != nil {
+			return err
+		}
+	}
+
 	resp, err := h.client.Do(req)
 	if err != nil {
 		return err
@@ -213,7 +530,11 @@ func (h *HTTP) write(reqBody []byte) error {
 	_, err = ioutil.ReadAll(resp.Body)
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return fmt.Errorf("when writing to [%s] received status code: %d", h.URL, resp.StatusCode)
+		err := fmt.Errorf("when writing to [%s] received status code: %d", dest.url, resp.StatusCode)
+		if !h.isRetryable(resp.StatusCode) {
+			return &permanentError{err}
+		}
+		return err
 	}
 
 	return nil
@@ -7,6 +7,8 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -14,6 +16,7 @@ import (
 	"github.com/influxdata/telegraf/internal"
 	"github.com/influxdata/telegraf/metric"
 	"github.com/influxdata/telegraf/plugins/serializers/influx"
+	"github.com/influxdata/telegraf/testutil"
 	"github.com/stretchr/testify/require"
 )
 
@@ -451,3 +454,259 @@ func TestDefaultUserAgent(t *testing.T) {
 		require.NoError(t, err)
 	})
 }
+
+func getTaggedMetrics() []telegraf.Metric {
+	return []telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{"tenant": "a"},
+			map[string]interface{}{"value": 1.0}, time.Unix(0, 0)),
+		testutil.MustMetric("cpu", map[string]string{"tenant": "b"},
+			map[string]interface{}{"value": 2.0}, time.Unix(0, 0)),
+		testutil.MustMetric("cpu", map[string]string{"tenant": "a"},
+			map[string]interface{}{"value": 3.0}, time.Unix(0, 0)),
+		testutil.MustMetric("cpu", map[string]string{"tenant": "b"},
+			map[string]interface{}{"value": 4.0}, time.Unix(0, 0)),
+	}
+}
+
+func TestBatchSplitting(t *testing.T) {
+	ts := httptest.NewServer(http.NotFoundHandler())
+	defer ts.Close()
+
+	u, err := url.Parse(fmt.Sprintf("http://%s", ts.Listener.Addr().String()))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name     string
+		plugin   *HTTP
+		expected []int
+	}{
+		{
+			name: "unlimited",
+			plugin: &HTTP{
+				URL: u.String(),
+			},
+			expected: []int{4},
+		},
+		{
+			name: "max metrics per request",
+			plugin: &HTTP{
+				URL:                  u.String(),
+				MaxMetricsPerRequest: 3,
+			},
+			expected: []int{3, 1},
+		},
+		{
+			name: "max body size",
+			plugin: &HTTP{
+				URL:         u.String(),
+				MaxBodySize: internal.Size{Size: 60},
+			},
+			expected: []int{2, 2},
+		},
+		{
+			name: "metric larger than max body size is dropped",
+			plugin: &HTTP{
+				URL:         u.String(),
+				MaxBodySize: internal.Size{Size: 10},
+			},
+			expected: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var actual []int
+			ts.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				payload, err := ioutil.ReadAll(r.Body)
+				require.NoError(t, err)
+				actual = append(actual, strings.Count(string(payload), "\n"))
+				w.WriteHeader(http.StatusNoContent)
+			})
+
+			serializer := influx.NewSerializer()
+			tt.plugin.SetSerializer(serializer)
+			tt.plugin.Log = testutil.Logger{}
+			err = tt.plugin.Connect()
+			require.NoError(t, err)
+
+			err = tt.plugin.Write(getTaggedMetrics())
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestNonRetryableStatusCode(t *testing.T) {
+	ts := httptest.NewServer(http.NotFoundHandler())
+	defer ts.Close()
+
+	u, err := url.Parse(fmt.Sprintf("http://%s", ts.Listener.Addr().String()))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name       string
+		statusCode int
+		errFunc    func(t *testing.T, err error)
+	}{
+		{
+			name:       "non-retryable status is dropped",
+			statusCode: http.StatusRequestEntityTooLarge,
+			errFunc: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			name:       "other status is retried",
+			statusCode: http.StatusServiceUnavailable,
+			errFunc: func(t *testing.T, err error) {
+				require.Error(t, err)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.statusCode)
+			})
+
+			plugin := &HTTP{
+				URL:                     u.String(),
+				NonRetryableStatusCodes: []int{http.StatusBadRequest, http.StatusRequestEntityTooLarge},
+				Log:                     testutil.Logger{},
+			}
+			plugin.SetSerializer(influx.NewSerializer())
+			err = plugin.Connect()
+			require.NoError(t, err)
+
+			err = plugin.Write([]telegraf.Metric{getMetric()})
+			tt.errFunc(t, err)
+		})
+	}
+}
+
+func TestTemplatedDestination(t *testing.T) {
+	ts := httptest.NewServer(http.NotFoundHandler())
+	defer ts.Close()
+
+	u, err := url.Parse(fmt.Sprintf("http://%s", ts.Listener.Addr().String()))
+	require.NoError(t, err)
+
+	var mu sync.Mutex
+	received := make(map[string]int)
+	ts.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		tenant := strings.TrimPrefix(r.URL.Path, "/")
+		require.Equal(t, tenant, r.Header.Get("X-Scope-OrgID"))
+		require.Equal(t, "static", r.Header.Get("X-Static"))
+
+		mu.Lock()
+		received[tenant] += strings.Count(string(payload), "\n")
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	plugin := &HTTP{
+		URL: u.String() + `/{{ .Tag "tenant" }}`,
+		Headers: map[string]string{
+			"X-Scope-OrgID": `{{ .Tag "tenant" }}`,
+			"X-Static":      "static",
+		},
+	}
+	plugin.SetSerializer(influx.NewSerializer())
+	err = plugin.Connect()
+	require.NoError(t, err)
+
+	err = plugin.Write(getTaggedMetrics())
+	require.NoError(t, err)
+	require.Equal(t, map[string]int{"a": 2, "b": 2}, received)
+}
+
+func TestInvalidTemplate(t *testing.T) {
+	plugin := &HTTP{
+		URL: `http://localhost/{{ .Tag "tenant" }`,
+	}
+	err := plugin.Connect()
+	require.Error(t, err)
+}
+
+func TestAWSSigV4(t *testing.T) {
+	ts := httptest.NewServer(http.NotFoundHandler())
+	defer ts.Close()
+
+	u, err := url.Parse(fmt.Sprintf("http://%s", ts.Listener.Addr().String()))
+	require.NoError(t, err)
+
+	ts.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		require.True(t, strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/"), auth)
+		require.Contains(t, auth, "/us-east-1/execute-api/aws4_request")
+		require.NotEmpty(t, r.Header.Get("X-Amz-Date"))
+
+		payload, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.Contains(t, string(payload), "cpu value=42")
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	plugin := &HTTP{
+		URL:        u.String(),
+		AwsService: "execute-api",
+		Region:     "us-east-1",
+		AccessKey:  "AKIDEXAMPLE",
+		SecretKey:  "secret",
+	}
+	plugin.SetSerializer(influx.NewSerializer())
+	err = plugin.Connect()
+	require.NoError(t, err)
+
+	err = plugin.Write([]telegraf.Metric{getMetric()})
+	require.NoError(t, err)
+}
+
+func TestRetryOnlyFailedDestination(t *testing.T) {
+	ts := httptest.NewServer(http.NotFoundHandler())
+	defer ts.Close()
+
+	u, err := url.Parse(fmt.Sprintf("http://%s", ts.Listener.Addr().String()))
+	require.NoError(t, err)
+
+	var mu sync.Mutex
+	failB := true
+	received := make(map[string]int)
+	ts.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		mu.Lock()
+		defer mu.Unlock()
+		tenant := strings.TrimPrefix(r.URL.Path, "/")
+		if tenant == "b" && failB {
+			failB = false
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		received[tenant] += strings.Count(string(payload), "\n")
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	plugin := &HTTP{
+		URL: u.String() + `/{{ .Tag "tenant" }}`,
+		Log: testutil.Logger{},
+	}
+	plugin.SetSerializer(influx.NewSerializer())
+	require.NoError(t, plugin.Connect())
+
+	// The retry of the batch only sends the metrics of the failed destination
+	metrics := getTaggedMetrics()
+	require.Error(t, plugin.Write(metrics))
+	require.Equal(t, map[string]int{"a": 2}, received)
+	require.NoError(t, plugin.Write(metrics))
+	require.Equal(t, map[string]int{"a": 2, "b": 2}, received)
+
+	// Once the batch is written, metrics are sent again
+	require.NoError(t, plugin.Write(metrics))
+	require.Equal(t, map[string]int{"a": 4, "b": 4}, received)
+}
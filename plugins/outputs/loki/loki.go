@@ -0,0 +1,386 @@
+package loki
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-logfmt/logfmt"
+	"github.com/golang/snappy"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/common/tls"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const (
+	defaultURL     = "http://127.0.0.1:3100/loki/api/v1/push"
+	defaultTimeout = 5 * time.Second
+)
+
+var sampleConfig = `
+  ## URL of the push API of Loki
+  url = "http://127.0.0.1:3100/loki/api/v1/push"
+
+  ## Timeout for HTTP requests
+  # timeout = "5s"
+
+  ## HTTP Basic Auth credentials
+  # username = "username"
+  # password = "pa$$word"
+
+  ## Tenant ID sent in the X-Scope-OrgID header for multi-tenant setups
+  # tenant_id = ""
+
+  ## Encoding of the push requests, either "json" or "protobuf".  Protobuf
+  ## requests are always compressed with snappy.
+  # format = "json"
+
+  ## HTTP Content-Encoding of JSON requests, can be set to "gzip" to compress
+  ## the body or "identity" to apply no encoding.
+  # content_encoding = "gzip"
+
+  ## Field used as log line, for example "message" for the tail, syslog and
+  ## docker_log inputs.  Metrics without this field, or all metrics if unset,
+  ## are sent with their fields formatted as logfmt.
+  # message_field = "message"
+
+  ## Label holding the measurement name, set to "" to not add it.
+  # name_label = "measurement"
+
+  ## Optional TLS Config
+  # tls_ca = "/etc/telegraf/ca.pem"
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  ## Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
+
+  ## Additional HTTP headers
+  # [outputs.loki.headers]
+  #   X-Custom-Header = "value"
+`
+
+type Loki struct {
+	URL             string            `toml:"url"`
+	Timeout         internal.Duration `toml:"timeout"`
+	Username        string            `toml:"username"`
+	Password        string            `toml:"password"`
+	TenantID        string            `toml:"tenant_id"`
+	Format          string            `toml:"format"`
+	ContentEncoding string            `toml:"content_encoding"`
+	MessageField    string            `toml:"message_field"`
+	NameLabel       string            `toml:"name_label"`
+	Headers         map[string]string `toml:"headers"`
+	tls.ClientConfig
+
+	Log telegraf.Logger `toml:"-"`
+
+	client *http.Client
+}
+
+// stream is a list of log entries sharing the same labels.
+type stream struct {
+	labels  map[string]string
+	entries []entry
+}
+
+type entry struct {
+	timestamp time.Time
+	line      string
+}
+
+func (l *Loki) Description() string {
+	return "Send logs to Loki"
+}
+
+func (l *Loki) SampleConfig() string {
+	return sampleConfig
+}
+
+func (l *Loki) Connect() error {
+	switch l.Format {
+	case "":
+		l.Format = "json"
+	case "json", "protobuf":
+	default:
+		return fmt.Errorf("unknown format %q", l.Format)
+	}
+
+	switch l.ContentEncoding {
+	case "", "identity", "gzip":
+	default:
+		return fmt.Errorf("unknown content_encoding %q", l.ContentEncoding)
+	}
+
+	if l.Timeout.Duration == 0 {
+		l.Timeout.Duration = defaultTimeout
+	}
+
+	tlsCfg, err := l.ClientConfig.TLSConfig()
+	if err != nil {
+		return err
+	}
+
+	l.client = &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: tlsCfg,
+			Proxy:           http.ProxyFromEnvironment,
+		},
+		Timeout: l.Timeout.Duration,
+	}
+	return nil
+}
+
+func (l *Loki) Close() error {
+	return nil
+}
+
+func (l *Loki) Write(metrics []telegraf.Metric) error {
+	if len(metrics) == 0 {
+		return nil
+	}
+
+	streams := l.streams(metrics)
+
+	var body []byte
+	var err error
+	if l.Format == "protobuf" {
+		body = snappy.Encode(nil, marshalProtobuf(streams))
+	} else {
+		body, err = marshalJSON(streams)
+	}
+	if err != nil {
+		return err
+	}
+
+	return l.push(body)
+}
+
+// streams groups the metrics by their labels; the entries of each stream are
+// sorted by time since Loki rejects entries older than the latest entry of
+// the stream.
+func (l *Loki) streams(metrics []telegraf.Metric) []*stream {
+	var streams []*stream
+	index := make(map[string]*stream)
+	for _, m := range metrics {
+		labels := make(map[string]string, len(m.TagList())+1)
+		for _, tag := range m.TagList() {
+			labels[sanitizeLabel(tag.Key)] = tag.Value
+		}
+		if l.NameLabel != "" {
+			labels[sanitizeLabel(l.NameLabel)] = m.Name()
+		}
+
+		key := labelString(labels)
+		s, ok := index[key]
+		if !ok {
+			s = &stream{labels: labels}
+			index[key] = s
+			streams = append(streams, s)
+		}
+		s.entries = append(s.entries, entry{timestamp: m.Time(), line: l.line(m)})
+	}
+
+	for _, s := range streams {
+		sort.SliceStable(s.entries, func(i, j int) bool {
+			return s.entries[i].timestamp.Before(s.entries[j].timestamp)
+		})
+	}
+	return streams
+}
+
+// line returns the log line of the metric, the raw message field if present
+// or else all fields in logfmt.
+func (l *Loki) line(m telegraf.Metric) string {
+	if l.MessageField != "" {
+		if v, ok := m.GetField(l.MessageField); ok {
+			if s, ok := v.(string); ok {
+				return s
+			}
+			return fmt.Sprint(v)
+		}
+	}
+
+	fields := m.FieldList()
+	sort.Slice(fields, func(i, j int) bool {
+		return fields[i].Key < fields[j].Key
+	})
+
+	keyvals := make([]interface{}, 0, 2*len(fields))
+	for _, field := range fields {
+		keyvals = append(keyvals, field.Key, field.Value)
+	}
+	line, err := logfmt.MarshalKeyvals(keyvals...)
+	if err != nil {
+		l.Log.Debugf("Could not format fields of %q: %v", m.Name(), err)
+	}
+	return string(line)
+}
+
+func (l *Loki) push(body []byte) error {
+	var reader io.Reader = bytes.NewReader(body)
+	gzipped := l.Format == "json" && l.ContentEncoding == "gzip"
+	if gzipped {
+		rc, err := internal.CompressWithGzip(reader)
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+		reader = rc
+	}
+
+	req, err := http.NewRequest(http.MethodPost, l.URL, reader)
+	if err != nil {
+		return err
+	}
+
+	if l.Username != "" || l.Password != "" {
+		req.SetBasicAuth(l.Username, l.Password)
+	}
+
+	req.Header.Set("User-Agent", internal.ProductToken())
+	if l.Format == "protobuf" {
+		req.Header.Set("Content-Type", "application/x-protobuf")
+	} else {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if gzipped {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	if l.TenantID != "" {
+		req.Header.Set("X-Scope-OrgID", l.TenantID)
+	}
+	for k, v := range l.Headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	msg, _ := ioutil.ReadAll(resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		err := fmt.Errorf("when writing to [%s] received status code %d: %s",
+			l.URL, resp.StatusCode, strings.TrimSpace(string(msg)))
+		// Rejected entries, for example out of order ones, are never accepted
+		if resp.StatusCode == http.StatusBadRequest {
+			l.Log.Errorf("Dropping metrics: %v", err)
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+func marshalJSON(streams []*stream) ([]byte, error) {
+	type jsonStream struct {
+		Stream map[string]string `json:"stream"`
+		Values [][2]string       `json:"values"`
+	}
+
+	request := struct {
+		Streams []jsonStream `json:"streams"`
+	}{}
+	for _, s := range streams {
+		js := jsonStream{Stream: s.labels}
+		for _, e := range s.entries {
+			js.Values = append(js.Values, [2]string{strconv.FormatInt(e.timestamp.UnixNano(), 10), e.line})
+		}
+		request.Streams = append(request.Streams, js)
+	}
+	return json.Marshal(request)
+}
+
+// marshalProtobuf encodes the streams as PushRequest message of the Loki
+// protobuf API:
+//
+//	message PushRequest { repeated StreamAdapter streams = 1; }
+//	message StreamAdapter { string labels = 1; repeated EntryAdapter entries = 2; }
+//	message EntryAdapter { google.protobuf.Timestamp timestamp = 1; string line = 2; }
+func marshalProtobuf(streams []*stream) []byte {
+	var request []byte
+	for _, s := range streams {
+		var sb []byte
+		sb = protowire.AppendTag(sb, 1, protowire.BytesType)
+		sb = protowire.AppendString(sb, labelString(s.labels))
+		for _, e := range s.entries {
+			var ts []byte
+			ts = protowire.AppendTag(ts, 1, protowire.VarintType)
+			ts = protowire.AppendVarint(ts, uint64(e.timestamp.Unix()))
+			ts = protowire.AppendTag(ts, 2, protowire.VarintType)
+			ts = protowire.AppendVarint(ts, uint64(e.timestamp.Nanosecond()))
+
+			var eb []byte
+			eb = protowire.AppendTag(eb, 1, protowire.BytesType)
+			eb = protowire.AppendBytes(eb, ts)
+			eb = protowire.AppendTag(eb, 2, protowire.BytesType)
+			eb = protowire.AppendString(eb, e.line)
+
+			sb = protowire.AppendTag(sb, 2, protowire.BytesType)
+			sb = protowire.AppendBytes(sb, eb)
+		}
+		request = protowire.AppendTag(request, 1, protowire.BytesType)
+		request = protowire.AppendBytes(request, sb)
+	}
+	return request
+}
+
+// labelString formats the labels as Prometheus selector, the format of the
+// labels in the protobuf API, with the label names sorted.
+func labelString(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, k := range keys {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(k)
+		b.WriteByte('=')
+		b.WriteString(strconv.Quote(labels[k]))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// sanitizeLabel replaces the characters not allowed in label names.
+func sanitizeLabel(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		switch {
+		case r == '_', r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+		case r >= '0' && r <= '9' && i > 0:
+		default:
+			r = '_'
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func init() {
+	outputs.Add("loki", func() telegraf.Output {
+		return &Loki{
+			URL:             defaultURL,
+			Timeout:         internal.Duration{Duration: defaultTimeout},
+			Format:          "json",
+			ContentEncoding: "gzip",
+			NameLabel:       "measurement",
+		}
+	})
+}
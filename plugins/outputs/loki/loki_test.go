@@ -0,0 +1,181 @@
+package loki
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+type pushRequest struct {
+	Streams []struct {
+		Stream map[string]string `json:"stream"`
+		Values [][2]string       `json:"values"`
+	} `json:"streams"`
+}
+
+func newLoki(url string) *Loki {
+	return &Loki{
+		URL:       url,
+		NameLabel: "measurement",
+		Log:       testutil.Logger{},
+	}
+}
+
+func logMetric(path string, message string, ts int64) telegraf.Metric {
+	return testutil.MustMetric("tail",
+		map[string]string{"path": path},
+		map[string]interface{}{"message": message},
+		time.Unix(0, ts))
+}
+
+func TestWriteJSON(t *testing.T) {
+	var request pushRequest
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/loki/api/v1/push", r.URL.Path)
+		require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		require.Equal(t, "gzip", r.Header.Get("Content-Encoding"))
+		require.Equal(t, "tenant", r.Header.Get("X-Scope-OrgID"))
+
+		gz, err := gzip.NewReader(r.Body)
+		require.NoError(t, err)
+		require.NoError(t, json.NewDecoder(gz).Decode(&request))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	l := newLoki(ts.URL + "/loki/api/v1/push")
+	l.ContentEncoding = "gzip"
+	l.TenantID = "tenant"
+	l.MessageField = "message"
+	require.NoError(t, l.Connect())
+
+	require.NoError(t, l.Write([]telegraf.Metric{
+		logMetric("/var/log/a.log", "second", 2),
+		logMetric("/var/log/b.log", "other", 3),
+		logMetric("/var/log/a.log", "first", 1),
+	}))
+
+	require.Len(t, request.Streams, 2)
+	require.Equal(t, map[string]string{"path": "/var/log/a.log", "measurement": "tail"}, request.Streams[0].Stream)
+	require.Equal(t, [][2]string{{"1", "first"}, {"2", "second"}}, request.Streams[0].Values)
+	require.Equal(t, map[string]string{"path": "/var/log/b.log", "measurement": "tail"}, request.Streams[1].Stream)
+	require.Equal(t, [][2]string{{"3", "other"}}, request.Streams[1].Values)
+}
+
+func TestWriteProtobuf(t *testing.T) {
+	var body []byte
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "application/x-protobuf", r.Header.Get("Content-Type"))
+		require.Empty(t, r.Header.Get("Content-Encoding"))
+
+		compressed, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+		body, err = snappy.Decode(nil, compressed)
+		require.NoError(t, err)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer ts.Close()
+
+	l := newLoki(ts.URL)
+	l.Format = "protobuf"
+	l.ContentEncoding = "gzip"
+	l.MessageField = "message"
+	require.NoError(t, l.Connect())
+
+	require.NoError(t, l.Write([]telegraf.Metric{logMetric("/var/log/a.log", "hello", 1500000001)}))
+
+	// PushRequest.streams
+	num, typ, n := protowire.ConsumeTag(body)
+	require.Equal(t, protowire.Number(1), num)
+	require.Equal(t, protowire.BytesType, typ)
+	s, m := protowire.ConsumeBytes(body[n:])
+	require.Equal(t, len(body), n+m)
+
+	// StreamAdapter.labels
+	_, _, n = protowire.ConsumeTag(s)
+	labels, m := protowire.ConsumeString(s[n:])
+	require.Equal(t, `{measurement="tail", path="/var/log/a.log"}`, labels)
+	s = s[n+m:]
+
+	// StreamAdapter.entries
+	num, _, n = protowire.ConsumeTag(s)
+	require.Equal(t, protowire.Number(2), num)
+	e, _ := protowire.ConsumeBytes(s[n:])
+
+	// EntryAdapter.timestamp
+	_, _, n = protowire.ConsumeTag(e)
+	ts2, m := protowire.ConsumeBytes(e[n:])
+	e = e[n+m:]
+	_, _, n = protowire.ConsumeTag(ts2)
+	seconds, m := protowire.ConsumeVarint(ts2[n:])
+	require.Equal(t, uint64(1), seconds)
+	ts2 = ts2[n+m:]
+	_, _, n = protowire.ConsumeTag(ts2)
+	nanos, _ := protowire.ConsumeVarint(ts2[n:])
+	require.Equal(t, uint64(500000001), nanos)
+
+	// EntryAdapter.line
+	num, _, n = protowire.ConsumeTag(e)
+	require.Equal(t, protowire.Number(2), num)
+	line, _ := protowire.ConsumeString(e[n:])
+	require.Equal(t, "hello", line)
+}
+
+func TestLine(t *testing.T) {
+	l := newLoki("")
+	m := testutil.MustMetric("syslog",
+		map[string]string{},
+		map[string]interface{}{"message": "hello world", "severity_code": int64(6), "ok": true},
+		time.Unix(0, 0))
+	require.Equal(t, `message="hello world" ok=true severity_code=6`, l.line(m))
+
+	l.MessageField = "message"
+	require.Equal(t, "hello world", l.line(m))
+
+	l.MessageField = "msg"
+	require.Equal(t, `message="hello world" ok=true severity_code=6`, l.line(m))
+}
+
+func TestSanitizeLabel(t *testing.T) {
+	require.Equal(t, "container_name", sanitizeLabel("container_name"))
+	require.Equal(t, "com_docker_compose_service", sanitizeLabel("com.docker.compose.service"))
+	require.Equal(t, "_xx", sanitizeLabel("0xx"))
+	require.Equal(t, "a_b", sanitizeLabel("a-b"))
+}
+
+func TestWriteErrors(t *testing.T) {
+	status := http.StatusBadRequest
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	defer ts.Close()
+
+	l := newLoki(ts.URL)
+	require.NoError(t, l.Connect())
+
+	metrics := []telegraf.Metric{logMetric("/var/log/a.log", "hello", 0)}
+
+	// Rejected entries are dropped
+	require.NoError(t, l.Write(metrics))
+
+	status = http.StatusTooManyRequests
+	require.Error(t, l.Write(metrics))
+
+	status = http.StatusInternalServerError
+	require.Error(t, l.Write(metrics))
+}
+
+func TestConnectErrors(t *testing.T) {
+	require.Error(t, (&Loki{Format: "msgpack"}).Connect())
+	require.Error(t, (&Loki{ContentEncoding: "snappy"}).Connect())
+}
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
 	"github.com/influxdata/telegraf/internal"
 	tlsint "github.com/influxdata/telegraf/plugins/common/tls"
 	"github.com/influxdata/telegraf/plugins/outputs"
@@ -52,6 +53,13 @@ var sampleConfig = `
   ## Expiration interval for each metric. 0 == no expiration
   # expiration_interval = "60s"
 
+  ## Expiration intervals of the metrics with matching names, overriding the
+  ## expiration_interval.  The first matching entry is used.
+  # [[outputs.prometheus_client.expiration]]
+  #   namepass = ["kubernetes_*"]
+  #   # namedrop = []
+  #   interval = "5m"
+
   ## Collectors to enable, valid entries are "gocollector" and "process".
   ## If unset, both are enabled.
   # collectors_exclude = ["gocollector", "process"]
@@ -70,6 +78,16 @@ var sampleConfig = `
 
   ## Export metric collection time.
   # export_timestamp = false
+
+  ## Serve the OpenMetrics text format to clients requesting it.
+  # enable_openmetrics = false
+
+  ## Additional paths serving the metrics with matching names.  All metrics
+  ## are still served on the path above.
+  # [[outputs.prometheus_client.endpoint]]
+  #   path = "/metrics/system"
+  #   namepass = ["cpu", "disk*", "mem"]
+  #   # namedrop = []
 `
 
 type Collector interface {
@@ -78,6 +96,23 @@ type Collector interface {
 	Add(metrics []telegraf.Metric) error
 }
 
+type Expiration struct {
+	Namepass []string          `toml:"namepass"`
+	Namedrop []string          `toml:"namedrop"`
+	Interval internal.Duration `toml:"interval"`
+
+	filter filter.Filter
+}
+
+type Endpoint struct {
+	Path     string   `toml:"path"`
+	Namepass []string `toml:"namepass"`
+	Namedrop []string `toml:"namedrop"`
+
+	filter    filter.Filter
+	collector Collector
+}
+
 type PrometheusClient struct {
 	Listen             string            `toml:"listen"`
 	MetricVersion      int               `toml:"metric_version"`
@@ -89,6 +124,9 @@ type PrometheusClient struct {
 	CollectorsExclude  []string          `toml:"collectors_exclude"`
 	StringAsLabel      bool              `toml:"string_as_label"`
 	ExportTimestamp    bool              `toml:"export_timestamp"`
+	EnableOpenMetrics  bool              `toml:"enable_openmetrics"`
+	Expirations        []*Expiration     `toml:"expiration"`
+	Endpoints          []*Endpoint       `toml:"endpoint"`
 	tlsint.ServerConfig
 
 	Log telegraf.Logger `toml:"-"`
@@ -128,24 +166,23 @@ func (p *PrometheusClient) Init() error {
 		}
 	}
 
-	switch p.MetricVersion {
-	default:
-		fallthrough
-	case 1:
-		p.Log.Warnf("Use of deprecated configuration: metric_version = 1; please update to metric_version = 2")
-		p.collector = v1.NewCollector(p.ExpirationInterval.Duration, p.StringAsLabel, p.Log)
-		err := registry.Register(p.collector)
-		if err != nil {
-			return err
-		}
-	case 2:
-		p.collector = v2.NewCollector(p.ExpirationInterval.Duration, p.StringAsLabel, p.ExportTimestamp)
-		err := registry.Register(p.collector)
+	for _, e := range p.Expirations {
+		var err error
+		e.filter, err = filter.NewIncludeExcludeFilter(e.Namepass, e.Namedrop)
 		if err != nil {
 			return err
 		}
 	}
 
+	if p.MetricVersion != 2 {
+		p.Log.Warnf("Use of deprecated configuration: metric_version = 1; please update to metric_version = 2")
+	}
+	p.collector = p.newCollector()
+	err := registry.Register(p.collector)
+	if err != nil {
+		return err
+	}
+
 	ipRange := make([]*net.IPNet, 0, len(p.IPRange))
 	for _, cidr := range p.IPRange {
 		_, ipNet, err := net.ParseCIDR(cidr)
@@ -158,13 +195,36 @@ func (p *PrometheusClient) Init() error {
 
 	authHandler := internal.AuthHandler(p.BasicUsername, p.BasicPassword, "prometheus", onAuthError)
 	rangeHandler := internal.IPRangeHandler(ipRange, onError)
-	promHandler := promhttp.HandlerFor(registry, promhttp.HandlerOpts{ErrorHandling: promhttp.ContinueOnError})
+	handlerOpts := promhttp.HandlerOpts{
+		ErrorHandling:     promhttp.ContinueOnError,
+		EnableOpenMetrics: p.EnableOpenMetrics,
+	}
 
 	mux := http.NewServeMux()
 	if p.Path == "" {
 		p.Path = "/"
 	}
-	mux.Handle(p.Path, authHandler(rangeHandler(promHandler)))
+	mux.Handle(p.Path, authHandler(rangeHandler(promhttp.HandlerFor(registry, handlerOpts))))
+
+	paths := map[string]bool{p.Path: true}
+	for _, e := range p.Endpoints {
+		if e.Path == "" || paths[e.Path] {
+			return fmt.Errorf("endpoint path %q is empty or used more than once", e.Path)
+		}
+		paths[e.Path] = true
+
+		e.filter, err = filter.NewIncludeExcludeFilter(e.Namepass, e.Namedrop)
+		if err != nil {
+			return err
+		}
+
+		e.collector = p.newCollector()
+		endpointRegistry := prometheus.NewRegistry()
+		if err := endpointRegistry.Register(e.collector); err != nil {
+			return err
+		}
+		mux.Handle(e.Path, authHandler(rangeHandler(promhttp.HandlerFor(endpointRegistry, handlerOpts))))
+	}
 
 	tlsConfig, err := p.TLSConfig()
 	if err != nil {
@@ -180,6 +240,24 @@ func (p *PrometheusClient) Init() error {
 	return nil
 }
 
+func (p *PrometheusClient) newCollector() Collector {
+	if p.MetricVersion == 2 {
+		return v2.NewCollector(p.expiration, p.StringAsLabel, p.ExportTimestamp, p.Log)
+	}
+	return v1.NewCollector(p.expiration, p.StringAsLabel, p.Log)
+}
+
+// expiration returns the expiration interval of the first matching
+// expiration rule, or the default interval.
+func (p *PrometheusClient) expiration(metric telegraf.Metric) time.Duration {
+	for _, e := range p.Expirations {
+		if e.filter.Match(metric.Name()) {
+			return e.Interval.Duration
+		}
+	}
+	return p.ExpirationInterval.Duration
+}
+
 func (p *PrometheusClient) listen() (net.Listener, error) {
 	if p.server.TLSConfig != nil {
 		return tls.Listen("tcp", p.Listen, p.server.TLSConfig)
@@ -247,6 +325,17 @@ func (p *PrometheusClient) Close() error {
 }
 
 func (p *PrometheusClient) Write(metrics []telegraf.Metric) error {
+	for _, e := range p.Endpoints {
+		var selected []telegraf.Metric
+		for _, metric := range metrics {
+			if e.filter.Match(metric.Name()) {
+				selected = append(selected, metric)
+			}
+		}
+		if err := e.collector.Add(selected); err != nil {
+			return err
+		}
+	}
 	return p.collector.Add(metrics)
 }
 
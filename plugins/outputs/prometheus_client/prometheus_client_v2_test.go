@@ -10,8 +10,11 @@ import (
 	"time"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
 	inputs "github.com/influxdata/telegraf/plugins/inputs/prometheus"
 	"github.com/influxdata/telegraf/testutil"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/stretchr/testify/require"
 )
 
@@ -461,3 +464,173 @@ rpc_duration_seconds_count 2693
 		})
 	}
 }
+
+func TestExpirationRules(t *testing.T) {
+	output := &PrometheusClient{
+		Listen:             "127.0.0.1:0",
+		Path:               defaultPath,
+		MetricVersion:      2,
+		ExpirationInterval: internal.Duration{Duration: time.Hour},
+		Expirations: []*Expiration{
+			{Namepass: []string{"cpu"}, Interval: internal.Duration{Duration: time.Nanosecond}},
+			{Namepass: []string{"mem"}},
+		},
+		CollectorsExclude: []string{"gocollector", "process"},
+		Log:               testutil.Logger{},
+	}
+	require.NoError(t, output.Init())
+
+	metrics := []telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"time_idle": 42.0}, time.Unix(0, 0)),
+		testutil.MustMetric("mem", map[string]string{}, map[string]interface{}{"free": 1.0}, time.Unix(0, 0)),
+		testutil.MustMetric("disk", map[string]string{}, map[string]interface{}{"free": 2.0}, time.Unix(0, 0)),
+	}
+	require.Equal(t, time.Nanosecond, output.expiration(metrics[0]))
+	require.Equal(t, time.Duration(0), output.expiration(metrics[1]))
+	require.Equal(t, time.Hour, output.expiration(metrics[2]))
+
+	require.NoError(t, output.Write(metrics))
+	time.Sleep(time.Millisecond)
+
+	registry := prometheus.NewRegistry()
+	require.NoError(t, registry.Register(output.collector))
+	families, err := registry.Gather()
+	require.NoError(t, err)
+
+	var names []string
+	for _, family := range families {
+		names = append(names, family.GetName())
+	}
+	require.Equal(t, []string{"disk_free", "mem_free"}, names)
+}
+
+func TestExpirationRulesSameFamily(t *testing.T) {
+	output := &PrometheusClient{
+		Listen:             "127.0.0.1:0",
+		Path:               defaultPath,
+		MetricVersion:      2,
+		ExpirationInterval: internal.Duration{Duration: time.Hour},
+		Expirations: []*Expiration{
+			{Namepass: []string{"cpu"}},
+		},
+		CollectorsExclude: []string{"gocollector", "process"},
+		Log:               testutil.Logger{},
+	}
+	require.NoError(t, output.Init())
+
+	// Both metrics are in the cpu_time_idle family, with a different
+	// expiration interval.
+	metrics := []telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{"host": "a"}, map[string]interface{}{"time_idle": 1.0}, time.Unix(0, 0)),
+		testutil.MustMetric("cpu_time", map[string]string{"host": "a"}, map[string]interface{}{"idle": 2.0}, time.Unix(0, 0)),
+		testutil.MustMetric("cpu_time", map[string]string{"host": "b"}, map[string]interface{}{"idle": 3.0}, time.Unix(0, 0)),
+		testutil.MustMetric("cpu_time", map[string]string{"host": "c"}, map[string]interface{}{"idle": 4.0}, time.Unix(0, 0), telegraf.Counter),
+	}
+	require.NoError(t, output.Write(metrics))
+
+	registry := prometheus.NewRegistry()
+	require.NoError(t, registry.Register(output.collector))
+	families, err := registry.Gather()
+	require.NoError(t, err)
+	require.Len(t, families, 1)
+	require.Equal(t, "cpu_time_idle", families[0].GetName())
+	require.Equal(t, dto.MetricType_UNTYPED, families[0].GetType())
+
+	values := make(map[string]float64)
+	for _, m := range families[0].Metric {
+		values[m.Label[0].GetValue()] = m.GetUntyped().GetValue()
+	}
+	require.Equal(t, map[string]float64{"a": 1.0, "b": 3.0}, values)
+}
+
+func TestEndpoints(t *testing.T) {
+	output := &PrometheusClient{
+		Listen:        "127.0.0.1:0",
+		Path:          defaultPath,
+		MetricVersion: 2,
+		Endpoints: []*Endpoint{
+			{Path: "/metrics/cpu", Namepass: []string{"cpu"}},
+			{Path: "/metrics/other", Namedrop: []string{"cpu"}},
+		},
+		CollectorsExclude: []string{"gocollector", "process"},
+		Log:               testutil.Logger{},
+	}
+	require.NoError(t, output.Init())
+	require.NoError(t, output.Connect())
+	defer output.Close()
+
+	require.NoError(t, output.Write([]telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"time_idle": 42.0}, time.Unix(0, 0)),
+		testutil.MustMetric("mem", map[string]string{}, map[string]interface{}{"free": 1.0}, time.Unix(0, 0)),
+	}))
+
+	get := func(path string) string {
+		resp, err := http.Get(strings.TrimSuffix(output.URL(), defaultPath) + path)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		body, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return string(body)
+	}
+
+	all := get("/metrics")
+	require.Contains(t, all, "cpu_time_idle 42")
+	require.Contains(t, all, "mem_free 1")
+
+	cpu := get("/metrics/cpu")
+	require.Contains(t, cpu, "cpu_time_idle 42")
+	require.NotContains(t, cpu, "mem_free")
+
+	other := get("/metrics/other")
+	require.NotContains(t, other, "cpu_time_idle")
+	require.Contains(t, other, "mem_free 1")
+}
+
+func TestEndpointsDuplicatePath(t *testing.T) {
+	output := &PrometheusClient{
+		Listen:            "127.0.0.1:0",
+		Path:              defaultPath,
+		MetricVersion:     2,
+		Endpoints:         []*Endpoint{{Path: defaultPath}},
+		CollectorsExclude: []string{"gocollector", "process"},
+		Log:               testutil.Logger{},
+	}
+	require.Error(t, output.Init())
+}
+
+func TestOpenMetrics(t *testing.T) {
+	output := &PrometheusClient{
+		Listen:            "127.0.0.1:0",
+		Path:              defaultPath,
+		MetricVersion:     2,
+		EnableOpenMetrics: true,
+		CollectorsExclude: []string{"gocollector", "process"},
+		Log:               testutil.Logger{},
+	}
+	require.NoError(t, output.Init())
+	require.NoError(t, output.Connect())
+	defer output.Close()
+
+	require.NoError(t, output.Write([]telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"time_idle": 42.0}, time.Unix(0, 0)),
+	}))
+
+	req, err := http.NewRequest("GET", output.URL(), nil)
+	require.NoError(t, err)
+	req.Header.Set("Accept", "application/openmetrics-text; version=0.0.1")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	require.True(t, strings.HasPrefix(resp.Header.Get("Content-Type"), "application/openmetrics-text"))
+	body, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.True(t, strings.HasSuffix(string(body), "# EOF\n"))
+
+	// Clients not asking for OpenMetrics get the Prometheus text format
+	resp, err = http.Get(output.URL())
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.True(t, strings.HasPrefix(resp.Header.Get("Content-Type"), "text/plain"))
+}
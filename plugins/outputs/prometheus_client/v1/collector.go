@@ -35,7 +35,8 @@ type Sample struct {
 	Sum   float64
 	// Metric timestamp
 	Timestamp time.Time
-	// Expiration is the deadline that this Sample is valid until.
+	// Expiration is the deadline that this Sample is valid until, zero if it
+	// does not expire.
 	Expiration time.Time
 }
 
@@ -51,21 +52,23 @@ type MetricFamily struct {
 }
 
 type Collector struct {
-	ExpirationInterval time.Duration
-	StringAsLabel      bool
-	ExportTimestamp    bool
-	Log                telegraf.Logger
+	// Expiration returns the expiration interval of a metric, 0 if it does
+	// not expire.
+	Expiration      func(telegraf.Metric) time.Duration
+	StringAsLabel   bool
+	ExportTimestamp bool
+	Log             telegraf.Logger
 
 	sync.Mutex
 	fam map[string]*MetricFamily
 }
 
-func NewCollector(expire time.Duration, stringsAsLabel bool, logger telegraf.Logger) *Collector {
+func NewCollector(expire func(telegraf.Metric) time.Duration, stringsAsLabel bool, logger telegraf.Logger) *Collector {
 	return &Collector{
-		ExpirationInterval: expire,
-		StringAsLabel:      stringsAsLabel,
-		Log:                logger,
-		fam:                make(map[string]*MetricFamily),
+		Expiration:    expire,
+		StringAsLabel: stringsAsLabel,
+		Log:           logger,
+		fam:           make(map[string]*MetricFamily),
 	}
 }
 
@@ -77,7 +80,7 @@ func (c *Collector) Collect(ch chan<- prometheus.Metric) {
 	c.Lock()
 	defer c.Unlock()
 
-	c.Expire(time.Now(), c.ExpirationInterval)
+	c.Expire(time.Now())
 
 	for name, family := range c.fam {
 		// Get list of all labels on MetricFamily
@@ -197,6 +200,11 @@ func (c *Collector) Add(metrics []telegraf.Metric) error {
 	now := time.Now()
 
 	for _, point := range sorted(metrics) {
+		var expiration time.Time
+		if expire := c.Expiration(point); expire != 0 {
+			expiration = now.Add(expire)
+		}
+
 		tags := point.Tags()
 		sampleID := CreateSampleID(tags)
 
@@ -261,7 +269,7 @@ func (c *Collector) Add(metrics []telegraf.Metric) error {
 				Count:        count,
 				Sum:          sum,
 				Timestamp:    point.Time(),
-				Expiration:   now.Add(c.ExpirationInterval),
+				Expiration:   expiration,
 			}
 			mname = sanitize(point.Name())
 
@@ -307,7 +315,7 @@ func (c *Collector) Add(metrics []telegraf.Metric) error {
 				Count:          count,
 				Sum:            sum,
 				Timestamp:      point.Time(),
-				Expiration:     now.Add(c.ExpirationInterval),
+				Expiration:     expiration,
 			}
 			mname = sanitize(point.Name())
 
@@ -336,7 +344,7 @@ func (c *Collector) Add(metrics []telegraf.Metric) error {
 					Labels:     labels,
 					Value:      value,
 					Timestamp:  point.Time(),
-					Expiration: now.Add(c.ExpirationInterval),
+					Expiration: expiration,
 				}
 
 				// Special handling of value field; supports passthrough from
@@ -370,14 +378,10 @@ func (c *Collector) Add(metrics []telegraf.Metric) error {
 	return nil
 }
 
-func (c *Collector) Expire(now time.Time, age time.Duration) {
-	if age == 0 {
-		return
-	}
-
+func (c *Collector) Expire(now time.Time) {
 	for name, family := range c.fam {
 		for key, sample := range family.Samples {
-			if age != 0 && now.After(sample.Expiration) {
+			if !sample.Expiration.IsZero() && now.After(sample.Expiration) {
 				for k := range sample.Labels {
 					family.LabelSet[k]--
 				}
@@ -1,6 +1,8 @@
 package v2
 
 import (
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
@@ -39,11 +41,15 @@ func (m *Metric) Write(out *dto.Metric) error {
 
 type Collector struct {
 	sync.Mutex
-	expireDuration time.Duration
-	coll           *serializer.Collection
+	expire func(telegraf.Metric) time.Duration
+	config serializer.FormatConfig
+	log    telegraf.Logger
+
+	// Metrics are kept in a collection per expiration interval
+	colls map[time.Duration]*serializer.Collection
 }
 
-func NewCollector(expire time.Duration, stringsAsLabel bool, exportTimestamp bool) *Collector {
+func NewCollector(expire func(telegraf.Metric) time.Duration, stringsAsLabel bool, exportTimestamp bool, logger telegraf.Logger) *Collector {
 	config := serializer.FormatConfig{}
 	if stringsAsLabel {
 		config.StringHandling = serializer.StringAsLabel
@@ -54,8 +60,10 @@ func NewCollector(expire time.Duration, stringsAsLabel bool, exportTimestamp boo
 	}
 
 	return &Collector{
-		expireDuration: expire,
-		coll:           serializer.NewCollection(config),
+		expire: expire,
+		config: config,
+		log:    logger,
+		colls:  make(map[time.Duration]*serializer.Collection),
 	}
 }
 
@@ -72,30 +80,92 @@ func (c *Collector) Collect(ch chan<- prometheus.Metric) {
 
 	// Expire metrics, doing this on Collect ensure metrics are removed even if no
 	// new metrics are added to the output.
-	if c.expireDuration != 0 {
-		c.coll.Expire(time.Now(), c.expireDuration)
-	}
+	c.expireMetrics(time.Now())
 
-	for _, family := range c.coll.GetProto() {
+	for _, family := range c.families() {
 		for _, metric := range family.Metric {
 			ch <- &Metric{family: family, metric: metric}
 		}
 	}
 }
 
+// families returns the metric families of all collections, merging the
+// families with the same name.  The registry rejects a family collected with
+// different types or with the same labels twice, so metrics of a conflicting
+// type or with duplicate labels are dropped.
+func (c *Collector) families() []*dto.MetricFamily {
+	expires := make([]time.Duration, 0, len(c.colls))
+	for expire := range c.colls {
+		expires = append(expires, expire)
+	}
+	sort.Slice(expires, func(i, j int) bool { return expires[i] < expires[j] })
+
+	var result []*dto.MetricFamily
+	byName := make(map[string]*dto.MetricFamily)
+	seen := make(map[string]bool)
+	for _, expire := range expires {
+		for _, family := range c.colls[expire].GetProto() {
+			merged, ok := byName[family.GetName()]
+			if !ok {
+				merged = &dto.MetricFamily{Name: family.Name, Help: family.Help, Type: family.Type}
+				byName[family.GetName()] = merged
+				result = append(result, merged)
+			} else if merged.GetType() != family.GetType() {
+				c.log.Debugf("Dropping metrics of %q with type %s, the family has type %s",
+					family.GetName(), family.GetType(), merged.GetType())
+				continue
+			}
+
+			for _, metric := range family.Metric {
+				key := labelsKey(family.GetName(), metric.Label)
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+				merged.Metric = append(merged.Metric, metric)
+			}
+		}
+	}
+	return result
+}
+
+func labelsKey(name string, labels []*dto.LabelPair) string {
+	var b strings.Builder
+	b.WriteString(name)
+	for _, label := range labels {
+		b.WriteByte(0)
+		b.WriteString(label.GetName())
+		b.WriteByte(0)
+		b.WriteString(label.GetValue())
+	}
+	return b.String()
+}
+
 func (c *Collector) Add(metrics []telegraf.Metric) error {
 	c.Lock()
 	defer c.Unlock()
 
 	for _, metric := range metrics {
-		c.coll.Add(metric, time.Now())
+		expire := c.expire(metric)
+		coll, ok := c.colls[expire]
+		if !ok {
+			coll = serializer.NewCollection(c.config)
+			c.colls[expire] = coll
+		}
+		coll.Add(metric, time.Now())
 	}
 
 	// Expire metrics, doing this on Add ensure metrics are removed even if no
 	// one is querying the data.
-	if c.expireDuration != 0 {
-		c.coll.Expire(time.Now(), c.expireDuration)
-	}
+	c.expireMetrics(time.Now())
 
 	return nil
 }
+
+func (c *Collector) expireMetrics(now time.Time) {
+	for expire, coll := range c.colls {
+		if expire != 0 {
+			coll.Expire(now, expire)
+		}
+	}
+}
@@ -0,0 +1,655 @@
+package snmp_agent
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/snmp"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	"github.com/soniah/gosnmp"
+)
+
+const (
+	defaultServiceAddress = "udp://:161"
+	// Maximum size of a UDP datagram.
+	maxPacketSize = 65507
+	// Used for GETBULK requests without max-repetitions.
+	defaultMaxRepetitions = 10
+	// Responses to GETBULK requests are limited to this many variables.
+	maxBulkVariables = 512
+	// Messages outside of this window are rejected, in seconds.
+	timeWindow = 150
+)
+
+// Counters reported to SNMPv3 managers, from SNMP-USER-BASED-SM-MIB.
+const (
+	oidUnsupportedSecLevels = ".1.3.6.1.6.3.15.1.1.1.0"
+	oidNotInTimeWindows     = ".1.3.6.1.6.3.15.1.1.2.0"
+	oidUnknownUserNames     = ".1.3.6.1.6.3.15.1.1.3.0"
+	oidUnknownEngineIDs     = ".1.3.6.1.6.3.15.1.1.4.0"
+	oidWrongDigests         = ".1.3.6.1.6.3.15.1.1.5.0"
+)
+
+var sampleConfig = `
+  ## Transport, local address, and port to listen on.  Transport must
+  ## be "udp://".  Omit local address to listen on all interfaces.
+  ##
+  ## Special permissions may be required to listen on a port less than
+  ## 1024.  See README.md for details
+  # service_address = "udp://:161"
+
+  ## OID under which the tables are served.
+  oid = ".1.3.6.1.4.1.65535.1"
+
+  ## Rows of series not written within this interval are removed.
+  ## 0 == no expiration
+  # expiration_interval = "60s"
+
+  ## SNMP version; only 2 and 3 are supported.
+  # version = 2
+
+  ## SNMP community string accepted by the agent.
+  # community = "public"
+
+  ## SNMPv3 user accepted by the agent, and the minimum security level
+  ## required for its requests.
+  # sec_name = "myuser"
+  ## Authentication protocol; one of "MD5", "SHA", or "".
+  # auth_protocol = "MD5"
+  ## Authentication password.
+  # auth_password = "pass"
+  ## Security Level; one of "noAuthNoPriv", "authNoPriv", or "authPriv".
+  # sec_level = "authNoPriv"
+  ## Privacy protocol used for encrypted messages; one of "DES", "AES" or "".
+  # priv_protocol = ""
+  ## Privacy password used for encrypted messages.
+  # priv_password = ""
+  ## Hex encoded SNMPv3 engine ID of the agent, by default it is derived
+  ## from the hostname.
+  # engine_id = ""
+
+  ## Each table serves the selected fields of one measurement, with a row
+  ## per series.  Tables are numbered by their index under the oid, columns
+  ## by the position of the field plus one; column 1 holds the tags of the
+  ## series.
+  [[outputs.snmp_agent.table]]
+    ## Name of the measurement.
+    name = "cpu"
+    ## Number of the table under the oid, defaults to its position in the
+    ## configuration.
+    # index = 1
+    ## Fields served as columns.
+    fields = ["usage_idle", "usage_user", "usage_system"]
+`
+
+type Table struct {
+	Name   string   `toml:"name"`
+	Index  int      `toml:"index"`
+	Fields []string `toml:"fields"`
+}
+
+type SnmpAgent struct {
+	ServiceAddress     string            `toml:"service_address"`
+	Oid                string            `toml:"oid"`
+	EngineID           string            `toml:"engine_id"`
+	ExpirationInterval internal.Duration `toml:"expiration_interval"`
+	Tables             []Table           `toml:"table"`
+	snmp.ClientConfig
+
+	Log telegraf.Logger `toml:"-"`
+
+	params   *gosnmp.GoSNMP
+	usm      *usm
+	conn     *net.UDPConn
+	wg       sync.WaitGroup
+	start    time.Time
+	counters map[string]uint32
+
+	mu     sync.Mutex
+	tables map[string]*table
+	// view holds the variables sorted by OID, it is rebuilt on the next
+	// request after a write.
+	view []variable
+	now  func() time.Time
+}
+
+type table struct {
+	oid     []int
+	columns map[string]int
+	rows    map[uint64]*row
+	nextRow int
+}
+
+type row struct {
+	index   int
+	series  string
+	fields  map[string]interface{}
+	updated time.Time
+}
+
+type variable struct {
+	oid []int
+	pdu gosnmp.SnmpPDU
+}
+
+func (a *SnmpAgent) Description() string {
+	return "Serve the latest metric values with an SNMP agent"
+}
+
+func (a *SnmpAgent) SampleConfig() string {
+	return sampleConfig
+}
+
+func (a *SnmpAgent) Init() error {
+	base, err := parseOid(a.Oid)
+	if err != nil {
+		return fmt.Errorf("invalid oid %q: %v", a.Oid, err)
+	}
+
+	a.tables = make(map[string]*table, len(a.Tables))
+	indexes := make(map[int]bool, len(a.Tables))
+	for i, t := range a.Tables {
+		if t.Name == "" {
+			return errors.New("table name is required")
+		}
+		if _, ok := a.tables[t.Name]; ok {
+			return fmt.Errorf("duplicate table for measurement %q", t.Name)
+		}
+		if len(t.Fields) == 0 {
+			return fmt.Errorf("no fields for table %q", t.Name)
+		}
+
+		index := t.Index
+		if index == 0 {
+			index = i + 1
+		}
+		if index < 0 || indexes[index] {
+			return fmt.Errorf("invalid index %d for table %q", index, t.Name)
+		}
+		indexes[index] = true
+
+		columns := make(map[string]int, len(t.Fields))
+		for j, field := range t.Fields {
+			columns[field] = j + 2
+		}
+
+		oid := make([]int, 0, len(base)+2)
+		oid = append(oid, base...)
+		a.tables[t.Name] = &table{
+			oid:     append(oid, index, 1),
+			columns: columns,
+			rows:    make(map[uint64]*row),
+			nextRow: 1,
+		}
+	}
+
+	switch a.Version {
+	case 0, 2, 3:
+	default:
+		return fmt.Errorf("unsupported version %d", a.Version)
+	}
+
+	gs, err := snmp.NewWrapper(a.ClientConfig)
+	if err != nil {
+		return err
+	}
+	a.params = gs.GoSNMP
+
+	if a.Version == 3 {
+		engineID, err := snmp.ParseEngineID(a.EngineID)
+		if err != nil {
+			return err
+		}
+
+		sp := a.params.SecurityParameters.(*gosnmp.UsmSecurityParameters)
+		a.usm, err = newUsm(engineID, sp)
+		if err != nil {
+			return err
+		}
+		if a.params.MsgFlags&gosnmp.AuthNoPriv != 0 && a.usm.authProtocol == gosnmp.NoAuth {
+			return fmt.Errorf("security level %q requires an authentication protocol", a.SecLevel)
+		}
+		if a.params.MsgFlags&gosnmp.AuthPriv == gosnmp.AuthPriv && a.usm.privProtocol == gosnmp.NoPriv {
+			return fmt.Errorf("security level %q requires a privacy protocol", a.SecLevel)
+		}
+
+		// Use the localized keys when decoding requests to the agent.
+		sp.AuthoritativeEngineID = engineID
+		sp.SecretKey = a.usm.authKey
+		sp.PrivacyKey = a.usm.privKey
+	}
+
+	return nil
+}
+
+func (a *SnmpAgent) Connect() error {
+	split := strings.SplitN(a.ServiceAddress, "://", 2)
+	if len(split) != 2 || split[0] != "udp" {
+		return fmt.Errorf("invalid service address: %s", a.ServiceAddress)
+	}
+
+	addr, err := net.ResolveUDPAddr("udp", split[1])
+	if err != nil {
+		return err
+	}
+	a.conn, err = net.ListenUDP("udp", addr)
+	if err != nil {
+		return err
+	}
+	a.Log.Infof("Listening on %s", a.conn.LocalAddr())
+
+	a.start = a.now()
+	a.counters = make(map[string]uint32)
+
+	a.wg.Add(1)
+	go a.listen()
+	return nil
+}
+
+func (a *SnmpAgent) Close() error {
+	if a.conn == nil {
+		return nil
+	}
+	err := a.conn.Close()
+	a.wg.Wait()
+	a.conn = nil
+	return err
+}
+
+func (a *SnmpAgent) Write(metrics []telegraf.Metric) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := a.now()
+	for _, m := range metrics {
+		t, ok := a.tables[m.Name()]
+		if !ok {
+			continue
+		}
+
+		id := m.HashID()
+		r, ok := t.rows[id]
+		if !ok {
+			var tags []string
+			for _, tag := range m.TagList() {
+				tags = append(tags, tag.Key+"="+tag.Value)
+			}
+			r = &row{
+				index:  t.nextRow,
+				series: strings.Join(tags, ","),
+				fields: make(map[string]interface{}),
+			}
+			t.rows[id] = r
+			t.nextRow++
+		}
+
+		for _, field := range m.FieldList() {
+			if _, ok := t.columns[field.Key]; ok {
+				r.fields[field.Key] = field.Value
+			}
+		}
+		r.updated = now
+	}
+
+	if a.ExpirationInterval.Duration > 0 {
+		expired := now.Add(-a.ExpirationInterval.Duration)
+		for _, t := range a.tables {
+			for id, r := range t.rows {
+				if r.updated.Before(expired) {
+					delete(t.rows, id)
+				}
+			}
+		}
+	}
+
+	a.view = nil
+	return nil
+}
+
+// variables returns all variables served by the agent sorted by OID.
+func (a *SnmpAgent) variables() []variable {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.view != nil {
+		return a.view
+	}
+
+	view := make([]variable, 0)
+	for _, t := range a.tables {
+		for _, r := range t.rows {
+			view = append(view, newVariable(t.oid, 1, r.index, r.series))
+			for field, value := range r.fields {
+				view = append(view, newVariable(t.oid, t.columns[field], r.index, value))
+			}
+		}
+	}
+	sort.Slice(view, func(i, j int) bool {
+		return compareOids(view[i].oid, view[j].oid) < 0
+	})
+
+	a.view = view
+	return view
+}
+
+func newVariable(entry []int, column int, index int, value interface{}) variable {
+	oid := make([]int, 0, len(entry)+2)
+	oid = append(oid, entry...)
+	oid = append(oid, column, index)
+
+	pdu := gosnmp.SnmpPDU{Name: formatOid(oid)}
+	switch v := value.(type) {
+	case int64:
+		if v >= math.MinInt32 && v <= math.MaxInt32 {
+			pdu.Type, pdu.Value = gosnmp.Integer, int(v)
+		} else if v >= 0 {
+			pdu.Type, pdu.Value = gosnmp.Counter64, uint64(v)
+		} else {
+			pdu.Type, pdu.Value = gosnmp.OctetString, strconv.FormatInt(v, 10)
+		}
+	case uint64:
+		pdu.Type, pdu.Value = gosnmp.Counter64, v
+	case float64:
+		pdu.Type, pdu.Value = gosnmp.OctetString, strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		// TruthValue from SNMPv2-TC
+		pdu.Type, pdu.Value = gosnmp.Integer, 2
+		if v {
+			pdu.Value = 1
+		}
+	default:
+		pdu.Type, pdu.Value = gosnmp.OctetString, fmt.Sprintf("%v", v)
+	}
+	return variable{oid: oid, pdu: pdu}
+}
+
+func (a *SnmpAgent) listen() {
+	defer a.wg.Done()
+
+	buf := make([]byte, maxPacketSize)
+	for {
+		n, addr, err := a.conn.ReadFromUDP(buf)
+		if err != nil {
+			if !strings.Contains(err.Error(), "use of closed network connection") {
+				a.Log.Errorf("Error reading request: %v", err)
+			}
+			return
+		}
+
+		msg := make([]byte, n)
+		copy(msg, buf[:n])
+
+		resp, err := a.handle(msg)
+		if err != nil {
+			a.Log.Debugf("Dropping request from %s: %v", addr, err)
+			continue
+		}
+
+		if _, err := a.conn.WriteToUDP(resp, addr); err != nil {
+			a.Log.Errorf("Error sending response to %s: %v", addr, err)
+		}
+	}
+}
+
+// handle returns the encoded response to the request msg.
+func (a *SnmpAgent) handle(msg []byte) (resp []byte, err error) {
+	// Decoding can panic on malformed or unexpectedly encrypted messages.
+	defer func() {
+		if r := recover(); r != nil {
+			resp, err = nil, fmt.Errorf("decoding request: %v", r)
+		}
+	}()
+
+	// Decoding authenticated and encrypted messages modifies the buffer,
+	// the original message is needed to verify its digest.
+	buf := make([]byte, len(msg))
+	copy(buf, msg)
+	req, err := a.params.SnmpDecodePacket(buf)
+	if err != nil {
+		return nil, err
+	}
+	if req.Version != a.params.Version {
+		return nil, fmt.Errorf("unexpected version %v", req.Version)
+	}
+
+	var packet *gosnmp.SnmpPacket
+	if req.Version == gosnmp.Version3 {
+		packet, err = a.verify(msg, req)
+		if err != nil {
+			return nil, err
+		}
+	} else if req.Community != a.params.Community {
+		return nil, errors.New("unknown community")
+	}
+
+	if packet == nil {
+		packet, err = a.response(req, gosnmp.GetResponse, req.MsgFlags&gosnmp.AuthPriv)
+		if err != nil {
+			return nil, err
+		}
+		a.respond(req, packet)
+	}
+	return packet.MarshalMsg()
+}
+
+// verify checks the security parameters of an SNMPv3 request, if they are
+// not accepted the report to send back is returned.
+func (a *SnmpAgent) verify(msg []byte, req *gosnmp.SnmpPacket) (*gosnmp.SnmpPacket, error) {
+	sp, ok := req.SecurityParameters.(*gosnmp.UsmSecurityParameters)
+	if !ok || req.SecurityModel != gosnmp.UserSecurityModel {
+		return nil, errors.New("unsupported security model")
+	}
+
+	level := req.MsgFlags & gosnmp.AuthPriv
+	switch {
+	case sp.AuthoritativeEngineID != a.usm.engineID:
+		// Engine discovery
+		return a.report(req, oidUnknownEngineIDs, gosnmp.NoAuthNoPriv)
+	case sp.UserName != a.usm.userName:
+		return a.report(req, oidUnknownUserNames, gosnmp.NoAuthNoPriv)
+	case level < a.params.MsgFlags&gosnmp.AuthPriv:
+		return a.report(req, oidUnsupportedSecLevels, gosnmp.NoAuthNoPriv)
+	case level&gosnmp.AuthNoPriv == 0:
+		return nil, nil
+	case !a.usm.authentic(msg, sp.AuthenticationParameters):
+		return a.report(req, oidWrongDigests, gosnmp.NoAuthNoPriv)
+	}
+
+	engineTime := int64(a.engineTime())
+	if sp.AuthoritativeEngineBoots != a.usm.boots || math.Abs(float64(int64(sp.AuthoritativeEngineTime)-engineTime)) > timeWindow {
+		// Sent authenticated so the manager can synchronize its clock.
+		return a.report(req, oidNotInTimeWindows, gosnmp.AuthNoPriv)
+	}
+	return nil, nil
+}
+
+func (a *SnmpAgent) engineTime() uint32 {
+	return uint32(a.now().Sub(a.start) / time.Second)
+}
+
+func (a *SnmpAgent) report(req *gosnmp.SnmpPacket, oid string, flags gosnmp.SnmpV3MsgFlags) (*gosnmp.SnmpPacket, error) {
+	a.counters[oid]++
+
+	packet, err := a.response(req, gosnmp.Report, flags)
+	if err != nil {
+		return nil, err
+	}
+	packet.Variables = []gosnmp.SnmpPDU{
+		{Name: oid, Type: gosnmp.Counter32, Value: a.counters[oid]},
+	}
+	return packet, nil
+}
+
+// response returns an empty response packet to the request.
+func (a *SnmpAgent) response(req *gosnmp.SnmpPacket, pduType gosnmp.PDUType, flags gosnmp.SnmpV3MsgFlags) (*gosnmp.SnmpPacket, error) {
+	packet := &gosnmp.SnmpPacket{
+		Version:   req.Version,
+		Community: req.Community,
+		PDUType:   pduType,
+		RequestID: req.RequestID,
+		Variables: []gosnmp.SnmpPDU{},
+	}
+
+	if req.Version == gosnmp.Version3 {
+		userName := ""
+		if sp, ok := req.SecurityParameters.(*gosnmp.UsmSecurityParameters); ok {
+			userName = sp.UserName
+		}
+		sp, err := a.usm.params(userName, a.engineTime())
+		if err != nil {
+			return nil, err
+		}
+
+		packet.MsgID = req.MsgID
+		packet.MsgFlags = flags
+		packet.SecurityModel = gosnmp.UserSecurityModel
+		packet.SecurityParameters = sp
+		packet.ContextEngineID = a.usm.engineID
+		packet.ContextName = req.ContextName
+	}
+	return packet, nil
+}
+
+// respond fills in the variables requested by req into the response packet.
+func (a *SnmpAgent) respond(req *gosnmp.SnmpPacket, packet *gosnmp.SnmpPacket) {
+	view := a.variables()
+
+	switch req.PDUType {
+	case gosnmp.GetRequest:
+		for _, v := range req.Variables {
+			packet.Variables = append(packet.Variables, get(view, v.Name))
+		}
+	case gosnmp.GetNextRequest:
+		for _, v := range req.Variables {
+			packet.Variables = append(packet.Variables, next(view, v.Name))
+		}
+	case gosnmp.GetBulkRequest:
+		nonRepeaters := int(req.NonRepeaters)
+		if nonRepeaters > len(req.Variables) {
+			nonRepeaters = len(req.Variables)
+		}
+		maxRepetitions := int(req.MaxRepetitions)
+		if maxRepetitions == 0 {
+			maxRepetitions = defaultMaxRepetitions
+		}
+
+		for _, v := range req.Variables[:nonRepeaters] {
+			packet.Variables = append(packet.Variables, next(view, v.Name))
+		}
+
+		repeaters := req.Variables[nonRepeaters:]
+		names := make([]string, len(repeaters))
+		for i, v := range repeaters {
+			names[i] = v.Name
+		}
+		for i := 0; i < maxRepetitions && len(names) > 0; i++ {
+			if len(packet.Variables)+len(names) > maxBulkVariables {
+				break
+			}
+
+			done := true
+			for j, name := range names {
+				pdu := next(view, name)
+				packet.Variables = append(packet.Variables, pdu)
+				names[j] = pdu.Name
+				if pdu.Type != gosnmp.EndOfMibView {
+					done = false
+				}
+			}
+			if done {
+				break
+			}
+		}
+	default:
+		// All variables are read-only.
+		packet.Error = gosnmp.NotWritable
+		packet.ErrorIndex = 1
+		packet.Variables = req.Variables
+	}
+}
+
+// get returns the variable with the OID name.
+func get(view []variable, name string) gosnmp.SnmpPDU {
+	oid, err := parseOid(name)
+	if err == nil {
+		i := sort.Search(len(view), func(i int) bool {
+			return compareOids(view[i].oid, oid) >= 0
+		})
+		if i < len(view) && compareOids(view[i].oid, oid) == 0 {
+			return view[i].pdu
+		}
+	}
+	return gosnmp.SnmpPDU{Name: name, Type: gosnmp.NoSuchObject}
+}
+
+// next returns the first variable following the OID name.
+func next(view []variable, name string) gosnmp.SnmpPDU {
+	oid, err := parseOid(name)
+	if err == nil {
+		i := sort.Search(len(view), func(i int) bool {
+			return compareOids(view[i].oid, oid) > 0
+		})
+		if i < len(view) {
+			return view[i].pdu
+		}
+	}
+	return gosnmp.SnmpPDU{Name: name, Type: gosnmp.EndOfMibView}
+}
+
+func parseOid(s string) ([]int, error) {
+	s = strings.TrimPrefix(s, ".")
+	if s == "" {
+		return nil, errors.New("empty OID")
+	}
+
+	parts := strings.Split(s, ".")
+	oid := make([]int, len(parts))
+	for i, part := range parts {
+		n, err := strconv.ParseUint(part, 10, 32)
+		if err != nil {
+			return nil, err
+		}
+		oid[i] = int(n)
+	}
+	return oid, nil
+}
+
+func formatOid(oid []int) string {
+	var b strings.Builder
+	for _, n := range oid {
+		b.WriteByte('.')
+		b.WriteString(strconv.Itoa(n))
+	}
+	return b.String()
+}
+
+func compareOids(a, b []int) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			if a[i] < b[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return len(a) - len(b)
+}
+
+func init() {
+	outputs.Add("snmp_agent", func() telegraf.Output {
+		return &SnmpAgent{
+			ServiceAddress:     defaultServiceAddress,
+			ExpirationInterval: internal.Duration{Duration: 60 * time.Second},
+			now:                time.Now,
+		}
+	})
+}
@@ -0,0 +1,220 @@
+package snmp_agent
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/snmp"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/soniah/gosnmp"
+	"github.com/stretchr/testify/require"
+)
+
+const testOid = ".1.3.6.1.4.1.65535.1"
+
+func newAgent(t *testing.T, config snmp.ClientConfig) *SnmpAgent {
+	a := &SnmpAgent{
+		ServiceAddress: "udp://127.0.0.1:0",
+		Oid:            testOid,
+		Tables: []Table{
+			{Name: "cpu", Fields: []string{"usage_idle", "count"}},
+			{Name: "mem", Index: 5, Fields: []string{"free"}},
+		},
+		ClientConfig: config,
+		Log:          testutil.Logger{},
+		now:          time.Now,
+	}
+	require.NoError(t, a.Init())
+	require.NoError(t, a.Connect())
+	t.Cleanup(func() { a.Close() })
+	return a
+}
+
+func newClient(t *testing.T, a *SnmpAgent, config snmp.ClientConfig) *gosnmp.GoSNMP {
+	config.Timeout = internal.Duration{Duration: time.Second}
+	gs, err := snmp.NewWrapper(config)
+	require.NoError(t, err)
+
+	addr := a.conn.LocalAddr().(*net.UDPAddr)
+	gs.Target = addr.IP.String()
+	gs.Port = uint16(addr.Port)
+	require.NoError(t, gs.Connect())
+	t.Cleanup(func() { gs.Conn.Close() })
+	return gs.GoSNMP
+}
+
+func testMetrics() []telegraf.Metric {
+	return []telegraf.Metric{
+		testutil.MustMetric("cpu",
+			map[string]string{"cpu": "cpu0", "host": "a"},
+			map[string]interface{}{"usage_idle": 99.5, "count": int64(42), "ignored": 1},
+			time.Unix(0, 0)),
+		testutil.MustMetric("cpu",
+			map[string]string{"cpu": "cpu1", "host": "a"},
+			map[string]interface{}{"usage_idle": 12.0},
+			time.Unix(0, 0)),
+		testutil.MustMetric("mem",
+			map[string]string{},
+			map[string]interface{}{"free": uint64(1 << 40)},
+			time.Unix(0, 0)),
+		testutil.MustMetric("disk",
+			map[string]string{},
+			map[string]interface{}{"free": int64(1)},
+			time.Unix(0, 0)),
+	}
+}
+
+func TestGet(t *testing.T) {
+	config := snmp.ClientConfig{Version: 2, Community: "secret"}
+	a := newAgent(t, config)
+	require.NoError(t, a.Write(testMetrics()))
+
+	gs := newClient(t, a, config)
+	resp, err := gs.Get([]string{
+		testOid + ".1.1.1.1",
+		testOid + ".1.1.2.1",
+		testOid + ".1.1.3.1",
+		testOid + ".5.1.2.1",
+		testOid + ".1.1.3.2",
+	})
+	require.NoError(t, err)
+	require.Equal(t, []gosnmp.SnmpPDU{
+		{Name: testOid + ".1.1.1.1", Type: gosnmp.OctetString, Value: []byte("cpu=cpu0,host=a")},
+		{Name: testOid + ".1.1.2.1", Type: gosnmp.OctetString, Value: []byte("99.5")},
+		{Name: testOid + ".1.1.3.1", Type: gosnmp.Integer, Value: 42},
+		{Name: testOid + ".5.1.2.1", Type: gosnmp.Counter64, Value: uint64(1 << 40)},
+		{Name: testOid + ".1.1.3.2", Type: gosnmp.NoSuchObject, Value: nil},
+	}, resp.Variables)
+}
+
+func TestWalk(t *testing.T) {
+	config := snmp.ClientConfig{Version: 2}
+	a := newAgent(t, config)
+	require.NoError(t, a.Write(testMetrics()))
+
+	gs := newClient(t, a, config)
+
+	var expected = []string{
+		testOid + ".1.1.1.1",
+		testOid + ".1.1.1.2",
+		testOid + ".1.1.2.1",
+		testOid + ".1.1.2.2",
+		testOid + ".1.1.3.1",
+		testOid + ".5.1.1.1",
+		testOid + ".5.1.2.1",
+	}
+
+	pdus, err := gs.WalkAll(testOid)
+	require.NoError(t, err)
+	var names []string
+	for _, pdu := range pdus {
+		names = append(names, pdu.Name)
+	}
+	require.Equal(t, expected, names)
+
+	gs.MaxRepetitions = 3
+	pdus, err = gs.BulkWalkAll(testOid)
+	require.NoError(t, err)
+	names = nil
+	for _, pdu := range pdus {
+		names = append(names, pdu.Name)
+	}
+	require.Equal(t, expected, names)
+}
+
+func TestWrongCommunity(t *testing.T) {
+	a := newAgent(t, snmp.ClientConfig{Version: 2, Community: "secret"})
+	require.NoError(t, a.Write(testMetrics()))
+
+	gs := newClient(t, a, snmp.ClientConfig{Version: 2, Community: "public"})
+	gs.Timeout = 100 * time.Millisecond
+	_, err := gs.Get([]string{testOid + ".1.1.1.1"})
+	require.Error(t, err)
+}
+
+func TestExpiration(t *testing.T) {
+	config := snmp.ClientConfig{Version: 2}
+	a := newAgent(t, config)
+	a.ExpirationInterval = internal.Duration{Duration: time.Minute}
+
+	now := time.Now()
+	a.now = func() time.Time { return now }
+	require.NoError(t, a.Write(testMetrics()))
+
+	now = now.Add(2 * time.Minute)
+	require.NoError(t, a.Write(testMetrics()[:1]))
+
+	gs := newClient(t, a, config)
+	pdus, err := gs.WalkAll(testOid)
+	require.NoError(t, err)
+	require.Len(t, pdus, 3)
+	for _, pdu := range pdus {
+		require.Regexp(t, `\.1\.1\.\d\.1$`, pdu.Name)
+	}
+}
+
+func TestV3(t *testing.T) {
+	config := snmp.ClientConfig{
+		Version:      3,
+		SecLevel:     "authPriv",
+		SecName:      "telegraf",
+		AuthProtocol: "SHA",
+		AuthPassword: "authpassword",
+		PrivProtocol: "AES",
+		PrivPassword: "privpassword",
+	}
+	a := newAgent(t, config)
+	require.NoError(t, a.Write(testMetrics()))
+
+	for _, privProtocol := range []string{"AES", "DES"} {
+		t.Run(privProtocol, func(t *testing.T) {
+			config := config
+			config.PrivProtocol = privProtocol
+			a := newAgent(t, config)
+			require.NoError(t, a.Write(testMetrics()))
+
+			gs := newClient(t, a, config)
+			resp, err := gs.Get([]string{testOid + ".1.1.3.1"})
+			require.NoError(t, err)
+			require.Equal(t, []gosnmp.SnmpPDU{
+				{Name: testOid + ".1.1.3.1", Type: gosnmp.Integer, Value: 42},
+			}, resp.Variables)
+		})
+	}
+
+	t.Run("wrong password", func(t *testing.T) {
+		config := config
+		config.AuthPassword = "wrongpassword"
+		gs := newClient(t, a, config)
+		_, err := gs.Get([]string{testOid + ".1.1.3.1"})
+		require.Error(t, err)
+	})
+
+	t.Run("security level", func(t *testing.T) {
+		config := config
+		config.SecLevel = "authNoPriv"
+		config.PrivProtocol = ""
+		gs := newClient(t, a, config)
+		_, err := gs.Get([]string{testOid + ".1.1.3.1"})
+		require.Error(t, err)
+	})
+}
+
+func TestInitErrors(t *testing.T) {
+	tests := []*SnmpAgent{
+		{Oid: "", Tables: []Table{{Name: "cpu", Fields: []string{"a"}}}},
+		{Oid: testOid, Tables: []Table{{Name: "cpu"}}},
+		{Oid: testOid, Tables: []Table{{Name: "cpu", Fields: []string{"a"}}, {Name: "cpu", Fields: []string{"a"}}}},
+		{Oid: testOid, Tables: []Table{{Name: "cpu", Fields: []string{"a"}}, {Name: "mem", Index: 1, Fields: []string{"a"}}}},
+		{Oid: testOid, ClientConfig: snmp.ClientConfig{Version: 1}},
+		{Oid: testOid, ClientConfig: snmp.ClientConfig{Version: 3, SecLevel: "authNoPriv", SecName: "a"}},
+		{Oid: testOid, ClientConfig: snmp.ClientConfig{Version: 3, SecName: "a", AuthProtocol: "MD5"}},
+		{Oid: testOid, EngineID: "zz", ClientConfig: snmp.ClientConfig{Version: 3, SecName: "a"}},
+	}
+	for _, a := range tests {
+		require.Error(t, a.Init())
+	}
+}
@@ -0,0 +1,137 @@
+package snmp_agent
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha1"
+	"errors"
+	"hash"
+
+	"github.com/soniah/gosnmp"
+)
+
+// Length of the truncated HMAC used by the HMAC-MD5-96 and HMAC-SHA-96
+// authentication protocols.
+const digestLength = 12
+
+// usm holds the localized keys of the configured user, as used by the
+// agent acting as the authoritative engine (RFC 3414).
+type usm struct {
+	engineID     string
+	boots        uint32
+	userName     string
+	authProtocol gosnmp.SnmpV3AuthProtocol
+	privProtocol gosnmp.SnmpV3PrivProtocol
+	authKey      []byte
+	privKey      []byte
+}
+
+func newUsm(engineID string, sp *gosnmp.UsmSecurityParameters) (*usm, error) {
+	u := &usm{
+		engineID:     engineID,
+		boots:        1,
+		userName:     sp.UserName,
+		authProtocol: sp.AuthenticationProtocol,
+		privProtocol: sp.PrivacyProtocol,
+	}
+
+	if u.authProtocol != gosnmp.NoAuth {
+		if sp.AuthenticationPassphrase == "" {
+			return nil, errors.New("auth_password is required with an authentication protocol")
+		}
+		u.authKey = localizeKey(u.hash(), sp.AuthenticationPassphrase, engineID)
+	}
+
+	if u.privProtocol != gosnmp.NoPriv {
+		if u.authProtocol == gosnmp.NoAuth {
+			return nil, errors.New("privacy requires an authentication protocol")
+		}
+		if sp.PrivacyPassphrase == "" {
+			return nil, errors.New("priv_password is required with a privacy protocol")
+		}
+		u.privKey = localizeKey(u.hash(), sp.PrivacyPassphrase, engineID)
+		if u.privProtocol == gosnmp.AES {
+			u.privKey = u.privKey[:16]
+		}
+	}
+	return u, nil
+}
+
+func (u *usm) hash() hash.Hash {
+	if u.authProtocol == gosnmp.SHA {
+		return sha1.New()
+	}
+	return md5.New()
+}
+
+// authentic reports if the digest sent along with the raw message msg is
+// valid for the configured user.
+func (u *usm) authentic(msg []byte, digest string) bool {
+	if len(u.authKey) == 0 || len(digest) != digestLength {
+		return false
+	}
+
+	// The digest is calculated over the whole message with the
+	// authentication parameters set to zero.
+	idx := bytes.Index(msg, append([]byte{byte(gosnmp.OctetString), digestLength}, digest...))
+	if idx < 0 {
+		return false
+	}
+	buf := make([]byte, len(msg))
+	copy(buf, msg)
+	for i := idx + 2; i < idx+2+digestLength; i++ {
+		buf[i] = 0
+	}
+
+	mac := hmac.New(u.hash, u.authKey)
+	mac.Write(buf)
+	return hmac.Equal(mac.Sum(nil)[:digestLength], []byte(digest))
+}
+
+// params returns the security parameters for an outgoing message to
+// userName.
+func (u *usm) params(userName string, engineTime uint32) (*gosnmp.UsmSecurityParameters, error) {
+	sp := &gosnmp.UsmSecurityParameters{
+		UserName:                 userName,
+		AuthoritativeEngineID:    u.engineID,
+		AuthoritativeEngineBoots: u.boots,
+		AuthoritativeEngineTime:  engineTime,
+		AuthenticationProtocol:   u.authProtocol,
+		PrivacyProtocol:          u.privProtocol,
+		SecretKey:                u.authKey,
+		PrivacyKey:               u.privKey,
+	}
+
+	if u.privProtocol != gosnmp.NoPriv {
+		sp.PrivacyParameters = make([]byte, 8)
+		if _, err := rand.Read(sp.PrivacyParameters); err != nil {
+			return nil, err
+		}
+	}
+	return sp, nil
+}
+
+// localizeKey derives the key of the password localized to the engine as
+// described in RFC 3414 A.2.
+func localizeKey(h hash.Hash, password string, engineID string) []byte {
+	const expansion = 1048576
+
+	pw := []byte(password)
+	buf := make([]byte, 64)
+	for count, i := 0, 0; count < expansion; count += len(buf) {
+		for j := range buf {
+			buf[j] = pw[i%len(pw)]
+			i++
+		}
+		h.Write(buf)
+	}
+	key := h.Sum(nil)
+
+	h.Reset()
+	h.Write(key)
+	h.Write([]byte(engineID))
+	h.Write(key)
+	return h.Sum(nil)
+}
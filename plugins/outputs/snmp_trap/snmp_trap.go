@@ -0,0 +1,514 @@
+package snmp_trap
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/snmp"
+	"github.com/influxdata/telegraf/plugins/common/delivery"
+	"github.com/influxdata/telegraf/plugins/outputs"
+	"github.com/influxdata/telegraf/selfstat"
+	"github.com/soniah/gosnmp"
+)
+
+const (
+	oidSysUpTime   = ".1.3.6.1.2.1.1.3.0"
+	oidSnmpTrapOID = ".1.3.6.1.6.3.1.1.4.1.0"
+	// Generic traps of SNMPv2-MIB, see RFC 3584 section 3.
+	oidSnmpTraps = ".1.3.6.1.6.3.1.1.5"
+)
+
+var sampleConfig = `
+  ## Managers to send the notifications to.
+  ##   example: targets = ["udp://127.0.0.1:162", "udp://[::1]:162"]
+  targets = ["udp://127.0.0.1:162"]
+
+  ## Send inform requests instead of traps.  Informs are acknowledged by the
+  ## manager and retransmitted on timeout.
+  # inform = false
+
+  ## Timeout to wait for the acknowledgement of an inform.
+  # timeout = "5s"
+  ## Number of retransmissions of an unacknowledged inform.
+  # retries = 3
+
+  ## SNMP version; can be 1, 2, or 3.
+  # version = 2
+
+  ## SNMP community string.
+  # community = "public"
+
+  ## Address of the agent in SNMPv1 traps, by default the local address of
+  ## the connection to the target.
+  # agent_address = ""
+
+  ## SNMPv3 authentication and encryption options.
+  ##
+  ## Security Name.
+  # sec_name = "myuser"
+  ## Authentication protocol; one of "MD5", "SHA", or "".
+  # auth_protocol = "MD5"
+  ## Authentication password.
+  # auth_password = "pass"
+  ## Security Level; one of "noAuthNoPriv", "authNoPriv", or "authPriv".
+  # sec_level = "authNoPriv"
+  ## Context Name.
+  # context_name = ""
+  ## Privacy protocol used for encrypted messages; one of "DES", "AES" or "".
+  # priv_protocol = ""
+  ## Privacy password used for encrypted messages.
+  # priv_password = ""
+  ## Hex encoded SNMPv3 engine ID of the sender of traps, by default it is
+  ## derived from the hostname.  Informs use the engine ID of the manager.
+  # engine_id = ""
+
+  ## Paths to MIB files and directories, loaded on startup to translate OID
+  ## names used in this configuration.
+  # path = ["/usr/share/snmp/mibs"]
+
+  ## Trap OID for each measurement, metrics of other measurements are not
+  ## sent unless a default trap OID is set.
+  # default_trap_oid = ""
+  [outputs.snmp_trap.trap_oids]
+    # alarm = ".1.3.6.1.4.1.65535.2.0.1"
+
+  ## Variable bindings to add from a tag or field of the metric.  They are
+  ## sent in the order of the configuration and are left out if the metric
+  ## has no such tag or field.
+  # [[outputs.snmp_trap.varbind]]
+  #   ## Name of the tag or the field.
+  #   tag = "host"
+  #   # field = "value"
+  #   ## OID of the variable.
+  #   oid = ".1.3.6.1.4.1.65535.2.1.1"
+  #   ## Type of the variable; one of "integer", "unsigned", "counter32",
+  #   ## "counter64", "timeticks", "string", "ipaddress", or "oid".  By
+  #   ## default it is chosen by the type of the value.
+  #   # type = ""
+`
+
+var validTypes = map[string]bool{
+	"":          true,
+	"integer":   true,
+	"unsigned":  true,
+	"counter32": true,
+	"counter64": true,
+	"timeticks": true,
+	"string":    true,
+	"ipaddress": true,
+	"oid":       true,
+}
+
+type Varbind struct {
+	Tag   string `toml:"tag"`
+	Field string `toml:"field"`
+	Oid   string `toml:"oid"`
+	Type  string `toml:"type"`
+}
+
+type SnmpTrap struct {
+	Targets        []string          `toml:"targets"`
+	Inform         bool              `toml:"inform"`
+	AgentAddress   string            `toml:"agent_address"`
+	EngineID       string            `toml:"engine_id"`
+	Path           []string          `toml:"path"`
+	DefaultTrapOid string            `toml:"default_trap_oid"`
+	TrapOids       map[string]string `toml:"trap_oids"`
+	Varbinds       []Varbind         `toml:"varbind"`
+	snmp.ClientConfig
+
+	Log telegraf.Logger `toml:"-"`
+
+	targets []*target
+	start   time.Time
+	now     func() time.Time
+}
+
+type target struct {
+	address string
+	gs      *gosnmp.GoSNMP
+
+	sent         selfstat.Stat
+	acknowledged selfstat.Stat
+	failed       selfstat.Stat
+
+	delivery delivery.Tracker
+}
+
+func (s *SnmpTrap) Description() string {
+	return "Send metrics as SNMP traps or informs"
+}
+
+func (s *SnmpTrap) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *SnmpTrap) Init() error {
+	if len(s.Targets) == 0 {
+		return errors.New("no targets configured")
+	}
+
+	if len(s.Path) != 0 {
+		if err := snmp.LoadMibsFromPath(s.Path, s.Log); err != nil {
+			return err
+		}
+	}
+
+	var err error
+	if s.DefaultTrapOid != "" {
+		if s.DefaultTrapOid, err = translate(s.DefaultTrapOid); err != nil {
+			return err
+		}
+	}
+	for name, oid := range s.TrapOids {
+		if s.TrapOids[name], err = translate(oid); err != nil {
+			return err
+		}
+	}
+
+	for i, v := range s.Varbinds {
+		if (v.Tag == "") == (v.Field == "") {
+			return fmt.Errorf("varbind %q must have either a tag or a field", v.Oid)
+		}
+		if s.Varbinds[i].Oid, err = translate(v.Oid); err != nil {
+			return err
+		}
+		if !validTypes[v.Type] {
+			return fmt.Errorf("unknown type %q for varbind %q", v.Type, v.Oid)
+		}
+	}
+
+	if s.Version == 1 && s.Inform {
+		return errors.New("informs are not supported by SNMPv1")
+	}
+
+	return nil
+}
+
+// translate returns the numeric OID of oid, which may also be an object name
+// found in the loaded MIBs.
+func translate(oid string) (string, error) {
+	if oid == "" {
+		return "", errors.New("missing oid")
+	}
+	if strings.Trim(oid, ".0123456789") == "" {
+		if !strings.HasPrefix(oid, ".") {
+			oid = "." + oid
+		}
+		return oid, nil
+	}
+	if !snmp.MibsLoaded() {
+		return "", fmt.Errorf("cannot translate %q without MIBs", oid)
+	}
+	_, oidNum, _, _, err := snmp.TranslateOid(oid)
+	return oidNum, err
+}
+
+func (s *SnmpTrap) Connect() error {
+	s.start = s.now()
+
+	var engineID string
+	if s.Version == 3 && !s.Inform {
+		// The sender of a trap is the authoritative engine.
+		var err error
+		if engineID, err = snmp.ParseEngineID(s.EngineID); err != nil {
+			return err
+		}
+	}
+
+	s.targets = make([]*target, 0, len(s.Targets))
+	for _, address := range s.Targets {
+		gs, err := snmp.NewWrapper(s.ClientConfig)
+		if err != nil {
+			return err
+		}
+		if err := gs.SetAgent(withDefaultPort(address)); err != nil {
+			return err
+		}
+		if gs.Transport != "udp" {
+			return fmt.Errorf("unsupported transport in target %s", address)
+		}
+
+		if sp, ok := gs.SecurityParameters.(*gosnmp.UsmSecurityParameters); ok && engineID != "" {
+			sp.AuthoritativeEngineID = engineID
+			sp.AuthoritativeEngineBoots = 1
+		}
+
+		if err := gs.Connect(); err != nil {
+			return fmt.Errorf("connecting to %s: %v", address, err)
+		}
+
+		tags := map[string]string{"target": address}
+		s.targets = append(s.targets, &target{
+			address:      address,
+			gs:           gs.GoSNMP,
+			sent:         selfstat.Register("snmp_trap", "sent", tags),
+			acknowledged: selfstat.Register("snmp_trap", "informs_acknowledged", tags),
+			failed:       selfstat.Register("snmp_trap", "informs_failed", tags),
+		})
+	}
+	return nil
+}
+
+// withDefaultPort adds the default port for notifications to the address if
+// it has none.
+func withDefaultPort(address string) string {
+	if !strings.Contains(address, "://") {
+		address = "udp://" + address
+	}
+
+	u, err := url.Parse(address)
+	if err != nil || u.Port() != "" {
+		return address
+	}
+	u.Host = net.JoinHostPort(u.Hostname(), "162")
+	return u.String()
+}
+
+func (s *SnmpTrap) Close() error {
+	for _, t := range s.targets {
+		t.gs.Conn.Close()
+	}
+	s.targets = nil
+	return nil
+}
+
+func (s *SnmpTrap) Write(metrics []telegraf.Metric) error {
+	// Only the metrics not yet sent to a target are sent to it again when a
+	// failed batch is retried.
+	for _, t := range s.targets {
+		t.delivery.Prune(metrics)
+	}
+
+	var firstErr error
+	failed := make(map[*target]bool)
+	for _, m := range metrics {
+		trapOid, ok := s.TrapOids[m.Name()]
+		if !ok {
+			trapOid = s.DefaultTrapOid
+		}
+		if trapOid == "" {
+			continue
+		}
+
+		variables := make([]gosnmp.SnmpPDU, 0, len(s.Varbinds))
+		for _, v := range s.Varbinds {
+			var value interface{}
+			var ok bool
+			if v.Tag != "" {
+				value, ok = m.GetTag(v.Tag)
+			} else {
+				value, ok = m.GetField(v.Field)
+			}
+			if !ok {
+				continue
+			}
+
+			asnType, value, err := convert(v.Type, value)
+			if err != nil {
+				s.Log.Errorf("Dropping varbind %s of %q: %v", v.Oid, m.Name(), err)
+				continue
+			}
+			variables = append(variables, gosnmp.SnmpPDU{Name: v.Oid, Type: asnType, Value: value})
+		}
+
+		for _, t := range s.targets {
+			if failed[t] || t.delivery.Delivered(m) {
+				continue
+			}
+
+			// Stop sending to a target after an error, the other targets
+			// still receive the batch.
+			if err := s.send(t, trapOid, variables); err != nil {
+				if firstErr == nil {
+					firstErr = err
+				} else {
+					s.Log.Error(err)
+				}
+				failed[t] = true
+				continue
+			}
+
+			t.delivery.Add(m)
+		}
+	}
+	if firstErr != nil {
+		return firstErr
+	}
+
+	for _, t := range s.targets {
+		t.delivery.Reset()
+	}
+	return nil
+}
+
+func (s *SnmpTrap) send(t *target, trapOid string, variables []gosnmp.SnmpPDU) error {
+	uptime := s.now().Sub(s.start)
+	if sp, ok := t.gs.SecurityParameters.(*gosnmp.UsmSecurityParameters); ok && !s.Inform {
+		sp.AuthoritativeEngineTime = uint32(uptime / time.Second)
+	}
+
+	trap := gosnmp.SnmpTrap{IsInform: s.Inform}
+	if t.gs.Version == gosnmp.Version1 {
+		trap.Enterprise, trap.GenericTrap, trap.SpecificTrap = v1Trap(trapOid)
+		trap.Timestamp = uint(uptime / (10 * time.Millisecond))
+		trap.AgentAddress = s.AgentAddress
+		if trap.AgentAddress == "" {
+			trap.AgentAddress = t.gs.Conn.LocalAddr().(*net.UDPAddr).IP.String()
+		}
+		trap.Variables = variables
+	} else {
+		trap.Variables = append([]gosnmp.SnmpPDU{
+			{Name: oidSysUpTime, Type: gosnmp.TimeTicks, Value: uint32(uptime / (10 * time.Millisecond))},
+			{Name: oidSnmpTrapOID, Type: gosnmp.ObjectIdentifier, Value: trapOid},
+		}, variables...)
+	}
+
+	t.sent.Incr(1)
+	_, err := t.gs.SendTrap(trap)
+	if !s.Inform {
+		if err != nil {
+			return fmt.Errorf("sending trap to %s: %v", t.address, err)
+		}
+		return nil
+	}
+
+	if err != nil {
+		t.failed.Incr(1)
+		return fmt.Errorf("inform to %s not acknowledged: %v", t.address, err)
+	}
+	t.acknowledged.Incr(1)
+	return nil
+}
+
+// v1Trap returns the SNMPv1 trap parameters for the trap OID, as described
+// in RFC 3584 section 3.2.
+func v1Trap(trapOid string) (enterprise string, generic int, specific int) {
+	if strings.HasPrefix(trapOid, oidSnmpTraps+".") {
+		n, err := strconv.Atoi(strings.TrimPrefix(trapOid, oidSnmpTraps+"."))
+		if err == nil && n >= 1 && n <= 6 {
+			return oidSnmpTraps, n - 1, 0
+		}
+	}
+
+	i := strings.LastIndex(trapOid, ".")
+	specific, _ = strconv.Atoi(trapOid[i+1:])
+	enterprise = strings.TrimSuffix(trapOid[:i], ".0")
+	return enterprise, 6, specific
+}
+
+// convert returns the variable type and value for the metric value v.
+func convert(typ string, v interface{}) (gosnmp.Asn1BER, interface{}, error) {
+	switch typ {
+	case "":
+		switch v := v.(type) {
+		case int64:
+			if v >= math.MinInt32 && v <= math.MaxInt32 {
+				return gosnmp.Integer, int(v), nil
+			}
+			return gosnmp.OctetString, strconv.FormatInt(v, 10), nil
+		case uint64:
+			return gosnmp.Counter64, v, nil
+		case float64:
+			return gosnmp.OctetString, strconv.FormatFloat(v, 'f', -1, 64), nil
+		case bool:
+			// TruthValue from SNMPv2-TC
+			if v {
+				return gosnmp.Integer, 1, nil
+			}
+			return gosnmp.Integer, 2, nil
+		default:
+			return gosnmp.OctetString, fmt.Sprintf("%v", v), nil
+		}
+	case "string":
+		return gosnmp.OctetString, fmt.Sprintf("%v", v), nil
+	case "integer":
+		n, err := toInt(v, math.MinInt32, math.MaxInt32)
+		return gosnmp.Integer, int(n), err
+	case "unsigned":
+		n, err := toInt(v, 0, math.MaxUint32)
+		return gosnmp.Gauge32, uint32(n), err
+	case "counter32":
+		n, err := toInt(v, 0, math.MaxUint32)
+		return gosnmp.Counter32, uint32(n), err
+	case "timeticks":
+		n, err := toInt(v, 0, math.MaxUint32)
+		return gosnmp.TimeTicks, uint32(n), err
+	case "counter64":
+		switch v := v.(type) {
+		case uint64:
+			return gosnmp.Counter64, v, nil
+		case string:
+			n, err := strconv.ParseUint(v, 10, 64)
+			return gosnmp.Counter64, n, err
+		}
+		n, err := toInt(v, 0, math.MaxInt64)
+		return gosnmp.Counter64, uint64(n), err
+	case "ipaddress":
+		s := fmt.Sprintf("%v", v)
+		if ip := net.ParseIP(s); ip == nil || ip.To4() == nil {
+			return gosnmp.IPAddress, nil, fmt.Errorf("invalid IPv4 address %q", s)
+		}
+		return gosnmp.IPAddress, s, nil
+	case "oid":
+		s := fmt.Sprintf("%v", v)
+		if s == "" || strings.Trim(s, ".0123456789") != "" {
+			return gosnmp.ObjectIdentifier, nil, fmt.Errorf("invalid OID %q", s)
+		}
+		return gosnmp.ObjectIdentifier, s, nil
+	default:
+		return gosnmp.Null, nil, fmt.Errorf("unknown type %q", typ)
+	}
+}
+
+func toInt(v interface{}, min, max int64) (int64, error) {
+	var n int64
+	switch v := v.(type) {
+	case int64:
+		n = v
+	case uint64:
+		if v > math.MaxInt64 {
+			return 0, fmt.Errorf("value %d out of range", v)
+		}
+		n = int64(v)
+	case float64:
+		n = int64(v)
+	case bool:
+		if v {
+			n = 1
+		}
+	case string:
+		var err error
+		if n, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return 0, err
+		}
+	default:
+		return 0, fmt.Errorf("unsupported type %T", v)
+	}
+
+	if n < min || n > max {
+		return 0, fmt.Errorf("value %d out of range", n)
+	}
+	return n, nil
+}
+
+func init() {
+	outputs.Add("snmp_trap", func() telegraf.Output {
+		return &SnmpTrap{
+			ClientConfig: snmp.ClientConfig{
+				Timeout: internal.Duration{Duration: 5 * time.Second},
+				Retries: 3,
+				Version: 2,
+			},
+			now: time.Now,
+		}
+	})
+}
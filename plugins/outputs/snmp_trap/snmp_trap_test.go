@@ -0,0 +1,309 @@
+package snmp_trap
+
+import (
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/internal/snmp"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/soniah/gosnmp"
+	"github.com/stretchr/testify/require"
+)
+
+func freePort(t *testing.T) int {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	defer conn.Close()
+	return conn.LocalAddr().(*net.UDPAddr).Port
+}
+
+// listen starts a trap receiver and returns its address along with the
+// channel of received packets.
+func listen(t *testing.T, params *gosnmp.GoSNMP) (string, chan *gosnmp.SnmpPacket) {
+	addr := "127.0.0.1:" + strconv.Itoa(freePort(t))
+	packets := make(chan *gosnmp.SnmpPacket, 10)
+
+	listener := gosnmp.NewTrapListener()
+	listener.Params = params
+	listener.OnNewTrap = func(p *gosnmp.SnmpPacket, _ *net.UDPAddr) {
+		// The listener reuses the packet for the response to informs
+		packet := *p
+		packets <- &packet
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- listener.Listen(addr)
+	}()
+	select {
+	case <-listener.Listening():
+	case err := <-errCh:
+		require.NoError(t, err)
+	}
+	t.Cleanup(listener.Close)
+
+	return "udp://" + addr, packets
+}
+
+func receive(t *testing.T, packets chan *gosnmp.SnmpPacket) *gosnmp.SnmpPacket {
+	select {
+	case p := <-packets:
+		return p
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "no trap received")
+	}
+	return nil
+}
+
+func newPlugin(targets ...string) *SnmpTrap {
+	return &SnmpTrap{
+		Targets: targets,
+		ClientConfig: snmp.ClientConfig{
+			Timeout: internal.Duration{Duration: time.Second},
+			Retries: 1,
+			Version: 2,
+		},
+		TrapOids: map[string]string{"alarm": ".1.3.6.1.4.1.65535.2.0.1"},
+		Varbinds: []Varbind{
+			{Tag: "host", Oid: ".1.3.6.1.4.1.65535.2.1.1"},
+			{Field: "value", Oid: ".1.3.6.1.4.1.65535.2.1.2"},
+			{Field: "count", Oid: "1.3.6.1.4.1.65535.2.1.3", Type: "counter32"},
+		},
+		Log: testutil.Logger{},
+		now: time.Now,
+	}
+}
+
+func testMetrics() []telegraf.Metric {
+	return []telegraf.Metric{
+		testutil.MustMetric("alarm",
+			map[string]string{"host": "server01"},
+			map[string]interface{}{"value": int64(42), "count": int64(3)},
+			time.Unix(0, 0)),
+		testutil.MustMetric("cpu",
+			map[string]string{"host": "server01"},
+			map[string]interface{}{"value": int64(1)},
+			time.Unix(0, 0)),
+	}
+}
+
+func TestTrapV2c(t *testing.T) {
+	params := *gosnmp.Default
+	addr, packets := listen(t, &params)
+
+	s := newPlugin(addr)
+	require.NoError(t, s.Init())
+	require.NoError(t, s.Connect())
+	defer s.Close()
+
+	require.NoError(t, s.Write(testMetrics()))
+
+	p := receive(t, packets)
+	require.Equal(t, gosnmp.SNMPv2Trap, p.PDUType)
+	require.Equal(t, "public", p.Community)
+	require.Len(t, p.Variables, 5)
+	require.Equal(t, ".1.3.6.1.2.1.1.3.0", p.Variables[0].Name)
+	require.Equal(t, gosnmp.TimeTicks, p.Variables[0].Type)
+	require.Equal(t, []gosnmp.SnmpPDU{
+		{Name: ".1.3.6.1.6.3.1.1.4.1.0", Type: gosnmp.ObjectIdentifier, Value: ".1.3.6.1.4.1.65535.2.0.1"},
+		{Name: ".1.3.6.1.4.1.65535.2.1.1", Type: gosnmp.OctetString, Value: []byte("server01")},
+		{Name: ".1.3.6.1.4.1.65535.2.1.2", Type: gosnmp.Integer, Value: 42},
+		{Name: ".1.3.6.1.4.1.65535.2.1.3", Type: gosnmp.Counter32, Value: uint(3)},
+	}, p.Variables[1:])
+
+	// The cpu metric has no trap OID
+	select {
+	case p := <-packets:
+		require.FailNow(t, "unexpected trap", "%v", p)
+	case <-time.After(100 * time.Millisecond):
+	}
+	require.Equal(t, int64(1), s.targets[0].sent.Get())
+}
+
+func TestTrapV1(t *testing.T) {
+	params := *gosnmp.Default
+	params.Version = gosnmp.Version1
+	addr, packets := listen(t, &params)
+
+	s := newPlugin(addr)
+	s.Version = 1
+	s.AgentAddress = "192.0.2.1"
+	s.DefaultTrapOid = ".1.3.6.1.6.3.1.1.5.3"
+	require.NoError(t, s.Init())
+	require.NoError(t, s.Connect())
+	defer s.Close()
+
+	require.NoError(t, s.Write(testMetrics()))
+
+	p := receive(t, packets)
+	require.Equal(t, gosnmp.Trap, p.PDUType)
+	require.Equal(t, ".1.3.6.1.4.1.65535.2", p.Enterprise)
+	require.Equal(t, "192.0.2.1", p.AgentAddress)
+	require.Equal(t, 6, p.GenericTrap)
+	require.Equal(t, 1, p.SpecificTrap)
+	require.Len(t, p.Variables, 3)
+
+	p = receive(t, packets)
+	require.Equal(t, ".1.3.6.1.6.3.1.1.5", p.Enterprise)
+	require.Equal(t, 2, p.GenericTrap)
+	require.Equal(t, 0, p.SpecificTrap)
+}
+
+func TestInform(t *testing.T) {
+	params := *gosnmp.Default
+	addr, packets := listen(t, &params)
+
+	s := newPlugin(addr)
+	s.Inform = true
+	require.NoError(t, s.Init())
+	require.NoError(t, s.Connect())
+	defer s.Close()
+
+	require.NoError(t, s.Write(testMetrics()))
+	p := receive(t, packets)
+	require.Equal(t, gosnmp.InformRequest, p.PDUType)
+	require.Equal(t, int64(1), s.targets[0].acknowledged.Get())
+	require.Equal(t, int64(0), s.targets[0].failed.Get())
+}
+
+func TestInformNotAcknowledged(t *testing.T) {
+	s := newPlugin("udp://127.0.0.1:" + strconv.Itoa(freePort(t)))
+	s.Inform = true
+	s.Timeout = internal.Duration{Duration: 100 * time.Millisecond}
+	require.NoError(t, s.Init())
+	require.NoError(t, s.Connect())
+	defer s.Close()
+
+	require.Error(t, s.Write(testMetrics()))
+	require.Equal(t, int64(0), s.targets[0].acknowledged.Get())
+	require.Equal(t, int64(1), s.targets[0].failed.Get())
+}
+
+func TestInformRetryOnlyFailedTargets(t *testing.T) {
+	params := *gosnmp.Default
+	addr, packets := listen(t, &params)
+
+	s := newPlugin(addr, "udp://127.0.0.1:"+strconv.Itoa(freePort(t)))
+	s.Inform = true
+	s.Timeout = internal.Duration{Duration: 100 * time.Millisecond}
+	require.NoError(t, s.Init())
+	require.NoError(t, s.Connect())
+	defer s.Close()
+
+	metrics := testMetrics()
+	require.Error(t, s.Write(metrics))
+	p := receive(t, packets)
+	require.Equal(t, gosnmp.InformRequest, p.PDUType)
+
+	// The retried batch is only sent to the target that failed
+	require.Error(t, s.Write(metrics))
+	select {
+	case p := <-packets:
+		require.FailNow(t, "unexpected inform", "%v", p)
+	case <-time.After(100 * time.Millisecond):
+	}
+	require.Equal(t, int64(1), s.targets[0].acknowledged.Get())
+	require.Equal(t, int64(2), s.targets[1].failed.Get())
+}
+
+func TestTrapV3(t *testing.T) {
+	params := *gosnmp.Default
+	params.Version = gosnmp.Version3
+	params.SecurityModel = gosnmp.UserSecurityModel
+	params.MsgFlags = gosnmp.AuthPriv
+	params.SecurityParameters = &gosnmp.UsmSecurityParameters{
+		UserName:                 "telegraf",
+		AuthenticationProtocol:   gosnmp.SHA,
+		AuthenticationPassphrase: "authpassword",
+		PrivacyProtocol:          gosnmp.AES,
+		PrivacyPassphrase:        "privpassword",
+	}
+	addr, packets := listen(t, &params)
+
+	s := newPlugin(addr)
+	s.ClientConfig = snmp.ClientConfig{
+		Timeout:      internal.Duration{Duration: time.Second},
+		Version:      3,
+		SecLevel:     "authPriv",
+		SecName:      "telegraf",
+		AuthProtocol: "SHA",
+		AuthPassword: "authpassword",
+		PrivProtocol: "AES",
+		PrivPassword: "privpassword",
+	}
+	s.EngineID = "80001f8804746573740a"
+	require.NoError(t, s.Init())
+	require.NoError(t, s.Connect())
+	defer s.Close()
+
+	require.NoError(t, s.Write(testMetrics()))
+	p := receive(t, packets)
+	require.Equal(t, gosnmp.SNMPv2Trap, p.PDUType)
+	require.Len(t, p.Variables, 5)
+	require.Equal(t, ".1.3.6.1.4.1.65535.2.0.1", p.Variables[1].Value)
+}
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		typ      string
+		value    interface{}
+		asnType  gosnmp.Asn1BER
+		expected interface{}
+	}{
+		{"", int64(-1), gosnmp.Integer, -1},
+		{"", int64(1 << 40), gosnmp.OctetString, "1099511627776"},
+		{"", uint64(1 << 40), gosnmp.Counter64, uint64(1 << 40)},
+		{"", 1.5, gosnmp.OctetString, "1.5"},
+		{"", false, gosnmp.Integer, 2},
+		{"", "up", gosnmp.OctetString, "up"},
+		{"string", int64(5), gosnmp.OctetString, "5"},
+		{"integer", "5", gosnmp.Integer, 5},
+		{"unsigned", 2.9, gosnmp.Gauge32, uint32(2)},
+		{"timeticks", uint64(100), gosnmp.TimeTicks, uint32(100)},
+		{"counter64", "18446744073709551615", gosnmp.Counter64, uint64(18446744073709551615)},
+		{"ipaddress", "192.0.2.1", gosnmp.IPAddress, "192.0.2.1"},
+		{"oid", ".1.3.6", gosnmp.ObjectIdentifier, ".1.3.6"},
+	}
+	for _, tt := range tests {
+		asnType, value, err := convert(tt.typ, tt.value)
+		require.NoError(t, err)
+		require.Equal(t, tt.asnType, asnType)
+		require.Equal(t, tt.expected, value)
+	}
+
+	for _, tt := range []struct {
+		typ   string
+		value interface{}
+	}{
+		{"integer", int64(1 << 40)},
+		{"counter32", int64(-1)},
+		{"ipaddress", "::1"},
+		{"oid", "ifIndex"},
+		{"integer", "x"},
+	} {
+		_, _, err := convert(tt.typ, tt.value)
+		require.Error(t, err, tt)
+	}
+}
+
+func TestWithDefaultPort(t *testing.T) {
+	require.Equal(t, "udp://127.0.0.1:162", withDefaultPort("127.0.0.1"))
+	require.Equal(t, "udp://[::1]:162", withDefaultPort("udp://[::1]"))
+	require.Equal(t, "udp://localhost:1162", withDefaultPort("udp://localhost:1162"))
+}
+
+func TestInitErrors(t *testing.T) {
+	for _, s := range []*SnmpTrap{
+		{},
+		{Targets: []string{"localhost"}, DefaultTrapOid: "ifIndex"},
+		{Targets: []string{"localhost"}, Varbinds: []Varbind{{Oid: ".1.3"}}},
+		{Targets: []string{"localhost"}, Varbinds: []Varbind{{Tag: "host", Oid: ".1.3", Type: "float"}}},
+		{Targets: []string{"localhost"}, Inform: true, ClientConfig: snmp.ClientConfig{Version: 1}},
+	} {
+		require.Error(t, s.Init())
+	}
+}
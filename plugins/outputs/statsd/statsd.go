@@ -0,0 +1,422 @@
+package statsd
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/filter"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/outputs"
+)
+
+const (
+	// Largest datagram not fragmented on ethernet with IPv4 and IPv6
+	defaultMTU = 1432
+
+	defaultCumulativeExpiry = 10
+)
+
+var sampleConfig = `
+  ## Address of the statsd server, the protocol is either "udp" or "tcp".
+  ##   ex: address = "udp://127.0.0.1:8125"
+  ##       address = "tcp://statsd.example.com:8125"
+  address = "udp://127.0.0.1:8125"
+
+  ## Timeout for connecting to and writing to the server.
+  # timeout = "5s"
+
+  ## Maximum size of a UDP datagram in bytes, multiple lines are sent in one
+  ## datagram up to this size.
+  # mtu = 1432
+
+  ## Prefix for all metric names.
+  # prefix = ""
+
+  ## Separator between the measurement and the field in the metric name.
+  # metric_separator = "."
+
+  ## Format of the tags; one of "none", "datadog" for DogStatsD tags like
+  ## "name:1|g|#host:a", or "influx" for tags in the name like "name,host=a:1|g".
+  # tag_format = "none"
+
+  ## Number of writes after which the previous value of a cumulative field is
+  ## forgotten if its series was not written since.
+  # cumulative_expiry = 10
+
+  ## Rules selecting the statsd type of the fields, the first matching rule is
+  ## used.  Fields not matching any rule are sent as "cumulative" if the
+  ## metric is a counter and as "gauge" otherwise.
+  # [[outputs.statsd.rule]]
+  #   ## Glob patterns of the measurements and fields matching the rule, by
+  #   ## default any.
+  #   measurements = ["nginx"]
+  #   fields = ["requests", "*_count"]
+  #
+  #   ## Type of the metric, one of "counter", "gauge", "untyped", "summary"
+  #   ## or "histogram", by default any.
+  #   # metric_type = "counter"
+  #
+  #   ## Statsd type of the fields, one of:
+  #   ##   gauge:      the value is sent as gauge
+  #   ##   counter:    the value is sent as counter increment
+  #   ##   cumulative: the increase since the previous value is sent as counter
+  #   ##   timing:     the value is sent as timing
+  #   type = "cumulative"
+`
+
+var statsdTypes = map[string]string{
+	"gauge":      "g",
+	"counter":    "c",
+	"cumulative": "c",
+	"timing":     "ms",
+}
+
+var valueTypes = map[string]telegraf.ValueType{
+	"counter":   telegraf.Counter,
+	"gauge":     telegraf.Gauge,
+	"untyped":   telegraf.Untyped,
+	"summary":   telegraf.Summary,
+	"histogram": telegraf.Histogram,
+}
+
+var (
+	nameReplacer = strings.NewReplacer(":", "_", "|", "_", "@", "_", ",", "_", "#", "_", " ", "_", "\n", "_")
+	tagReplacer  = strings.NewReplacer(":", "_", "|", "_", ",", "_", "#", "_", "=", "_", "\n", "_")
+	// Colons are allowed in the values of DogStatsD tags
+	datadogValueReplacer = strings.NewReplacer("|", "_", ",", "_", "#", "_", "\n", "_")
+)
+
+type Rule struct {
+	Measurements []string `toml:"measurements"`
+	Fields       []string `toml:"fields"`
+	MetricType   string   `toml:"metric_type"`
+	Type         string   `toml:"type"`
+
+	measurementFilter filter.Filter
+	fieldFilter       filter.Filter
+}
+
+type Statsd struct {
+	Address          string            `toml:"address"`
+	Timeout          internal.Duration `toml:"timeout"`
+	MTU              int               `toml:"mtu"`
+	Prefix           string            `toml:"prefix"`
+	MetricSeparator  string            `toml:"metric_separator"`
+	TagFormat        string            `toml:"tag_format"`
+	CumulativeExpiry int               `toml:"cumulative_expiry"`
+	Rules            []*Rule           `toml:"rule"`
+
+	Log telegraf.Logger `toml:"-"`
+
+	network string
+	host    string
+	conn    net.Conn
+
+	// Previous values of cumulative fields by series
+	previous map[uint64]*series
+	writes   int
+}
+
+// series holds the previous values of the cumulative fields of a series by
+// field name.
+type series struct {
+	values    map[string]float64
+	lastWrite int
+}
+
+func (s *Statsd) Description() string {
+	return "Send metrics to a statsd or DogStatsD server"
+}
+
+func (s *Statsd) SampleConfig() string {
+	return sampleConfig
+}
+
+func (s *Statsd) Init() error {
+	u, err := url.Parse(s.Address)
+	if err != nil {
+		return fmt.Errorf("invalid address %q: %v", s.Address, err)
+	}
+	switch u.Scheme {
+	case "udp", "udp4", "udp6", "tcp", "tcp4", "tcp6":
+	default:
+		return fmt.Errorf("unsupported protocol %q", u.Scheme)
+	}
+	s.network = u.Scheme
+	s.host = u.Host
+
+	switch s.TagFormat {
+	case "", "none", "datadog", "influx":
+	default:
+		return fmt.Errorf("unknown tag_format %q", s.TagFormat)
+	}
+
+	if s.MTU <= 0 {
+		s.MTU = defaultMTU
+	}
+
+	if s.CumulativeExpiry <= 0 {
+		s.CumulativeExpiry = defaultCumulativeExpiry
+	}
+
+	for _, r := range s.Rules {
+		if _, ok := statsdTypes[r.Type]; !ok {
+			return fmt.Errorf("unknown rule type %q", r.Type)
+		}
+		if _, ok := valueTypes[r.MetricType]; r.MetricType != "" && !ok {
+			return fmt.Errorf("unknown rule metric_type %q", r.MetricType)
+		}
+		if r.measurementFilter, err = filter.Compile(r.Measurements); err != nil {
+			return err
+		}
+		if r.fieldFilter, err = filter.Compile(r.Fields); err != nil {
+			return err
+		}
+	}
+
+	s.previous = make(map[uint64]*series)
+	return nil
+}
+
+func (s *Statsd) Connect() error {
+	conn, err := net.DialTimeout(s.network, s.host, s.Timeout.Duration)
+	if err != nil {
+		return err
+	}
+	s.conn = conn
+	return nil
+}
+
+func (s *Statsd) Close() error {
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+func (s *Statsd) Write(metrics []telegraf.Metric) error {
+	if s.conn == nil {
+		// previous write failed and the connection was closed
+		if err := s.Connect(); err != nil {
+			return err
+		}
+	}
+
+	// Cumulative values are only remembered once the lines are sent
+	var lines [][]byte
+	pending := make(map[uint64]map[string]float64)
+	for _, m := range metrics {
+		tags := s.tags(m)
+		for _, field := range m.FieldList() {
+			value, ok := toFloat(field.Value)
+			if !ok {
+				continue
+			}
+
+			typ := s.statsdType(m, field.Key)
+			if typ == "cumulative" {
+				id := m.HashID()
+				previous, ok := pending[id][field.Key]
+				if !ok && s.previous[id] != nil {
+					previous, ok = s.previous[id].values[field.Key]
+				}
+				if pending[id] == nil {
+					pending[id] = make(map[string]float64)
+				}
+				pending[id][field.Key] = value
+				if !ok {
+					continue
+				}
+				// A decrease means the counter was reset
+				if value >= previous {
+					value -= previous
+				}
+			}
+
+			name := s.name(m.Name(), field.Key)
+			lines = append(lines, s.line(name, value, statsdTypes[typ], tags))
+		}
+	}
+
+	if err := s.send(lines); err != nil {
+		s.Close()
+		return err
+	}
+
+	s.writes++
+	for id, fields := range pending {
+		if s.previous[id] == nil {
+			s.previous[id] = &series{values: make(map[string]float64)}
+		}
+		for field, value := range fields {
+			s.previous[id].values[field] = value
+		}
+		s.previous[id].lastWrite = s.writes
+	}
+
+	// Forget series which are no longer written, such as those of removed
+	// network interfaces.
+	for id, prev := range s.previous {
+		if s.writes-prev.lastWrite >= s.CumulativeExpiry {
+			delete(s.previous, id)
+		}
+	}
+	return nil
+}
+
+// send writes the lines to the server; over UDP as many lines as fit into
+// the MTU are packed into each datagram.
+func (s *Statsd) send(lines [][]byte) error {
+	if s.Timeout.Duration > 0 {
+		if err := s.conn.SetWriteDeadline(time.Now().Add(s.Timeout.Duration)); err != nil {
+			return err
+		}
+	}
+
+	if strings.HasPrefix(s.network, "tcp") {
+		var buf bytes.Buffer
+		for _, line := range lines {
+			buf.Write(line)
+			buf.WriteByte('\n')
+		}
+		_, err := s.conn.Write(buf.Bytes())
+		return err
+	}
+
+	buf := make([]byte, 0, s.MTU)
+	for _, line := range lines {
+		if len(buf) > 0 && len(buf)+1+len(line) > s.MTU {
+			if _, err := s.conn.Write(buf); err != nil {
+				return err
+			}
+			buf = buf[:0]
+		}
+		if len(line) > s.MTU {
+			s.Log.Debugf("Line of %d bytes exceeds the mtu", len(line))
+		}
+		if len(buf) > 0 {
+			buf = append(buf, '\n')
+		}
+		buf = append(buf, line...)
+	}
+	if len(buf) > 0 {
+		if _, err := s.conn.Write(buf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// statsdType returns the type of the first rule matching the field.
+func (s *Statsd) statsdType(m telegraf.Metric, field string) string {
+	for _, r := range s.Rules {
+		if r.measurementFilter != nil && !r.measurementFilter.Match(m.Name()) {
+			continue
+		}
+		if r.fieldFilter != nil && !r.fieldFilter.Match(field) {
+			continue
+		}
+		if r.MetricType != "" && valueTypes[r.MetricType] != m.Type() {
+			continue
+		}
+		return r.Type
+	}
+
+	if m.Type() == telegraf.Counter {
+		return "cumulative"
+	}
+	return "gauge"
+}
+
+func (s *Statsd) name(measurement, field string) string {
+	return nameReplacer.Replace(s.Prefix + measurement + s.MetricSeparator + field)
+}
+
+// tags returns the tags of the metric, formatted as suffix for the metric
+// name or for the line depending on the tag format.
+func (s *Statsd) tags(m telegraf.Metric) string {
+	if len(m.TagList()) == 0 {
+		return ""
+	}
+
+	var tags []string
+	switch s.TagFormat {
+	case "datadog":
+		for _, tag := range m.TagList() {
+			tags = append(tags, tagReplacer.Replace(tag.Key)+":"+datadogValueReplacer.Replace(tag.Value))
+		}
+		return "|#" + strings.Join(tags, ",")
+	case "influx":
+		for _, tag := range m.TagList() {
+			tags = append(tags, tagReplacer.Replace(tag.Key)+"="+tagReplacer.Replace(tag.Value))
+		}
+		return "," + strings.Join(tags, ",")
+	}
+	return ""
+}
+
+func (s *Statsd) line(name string, value float64, typ string, tags string) []byte {
+	var buf bytes.Buffer
+
+	write := func(v float64) {
+		buf.WriteString(name)
+		if s.TagFormat == "influx" {
+			buf.WriteString(tags)
+		}
+		buf.WriteByte(':')
+		buf.WriteString(strconv.FormatFloat(v, 'f', -1, 64))
+		buf.WriteByte('|')
+		buf.WriteString(typ)
+		if s.TagFormat == "datadog" {
+			buf.WriteString(tags)
+		}
+	}
+
+	// A signed gauge value changes the gauge by the value, so negative values
+	// are sent as difference to zero.
+	if typ == "g" && value < 0 {
+		write(0)
+		buf.WriteByte('\n')
+	}
+	write(value)
+	return buf.Bytes()
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	var f float64
+	switch v := v.(type) {
+	case int64:
+		f = float64(v)
+	case uint64:
+		f = float64(v)
+	case float64:
+		f = v
+	case bool:
+		if v {
+			f = 1
+		}
+	default:
+		return 0, false
+	}
+	return f, !math.IsNaN(f) && !math.IsInf(f, 0)
+}
+
+func init() {
+	outputs.Add("statsd", func() telegraf.Output {
+		return &Statsd{
+			Timeout:         internal.Duration{Duration: 5 * time.Second},
+			MTU:             defaultMTU,
+			MetricSeparator: ".",
+			TagFormat:       "none",
+		}
+	})
+}
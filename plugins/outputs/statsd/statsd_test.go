@@ -0,0 +1,250 @@
+package statsd
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func listenUDP(t *testing.T) (*net.UDPConn, string) {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn, "udp://" + conn.LocalAddr().String()
+}
+
+func readDatagrams(t *testing.T, conn *net.UDPConn) []string {
+	var datagrams []string
+	buf := make([]byte, 64*1024)
+	for {
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(200*time.Millisecond)))
+		n, err := conn.Read(buf)
+		if err != nil {
+			return datagrams
+		}
+		datagrams = append(datagrams, string(buf[:n]))
+	}
+}
+
+func newStatsd(address string) *Statsd {
+	s := &Statsd{
+		Address:         address,
+		MetricSeparator: ".",
+		Log:             testutil.Logger{},
+	}
+	return s
+}
+
+func start(t *testing.T, s *Statsd) {
+	require.NoError(t, s.Init())
+	require.NoError(t, s.Connect())
+	t.Cleanup(func() { s.Close() })
+}
+
+func TestWriteUDP(t *testing.T) {
+	conn, address := listenUDP(t)
+	s := newStatsd(address)
+	start(t, s)
+
+	var metrics []telegraf.Metric
+	for _, value := range []interface{}{99.5, int64(-1), uint64(1024), true, "ok"} {
+		metrics = append(metrics, testutil.MustMetric("cpu",
+			map[string]string{"host": "a"},
+			map[string]interface{}{"value": value},
+			time.Unix(0, 0)))
+	}
+	require.NoError(t, s.Write(metrics))
+	require.Equal(t, []string{
+		"cpu.value:99.5|g\ncpu.value:0|g\ncpu.value:-1|g\ncpu.value:1024|g\ncpu.value:1|g",
+	}, readDatagrams(t, conn))
+}
+
+func TestWriteMTU(t *testing.T) {
+	conn, address := listenUDP(t)
+	s := newStatsd(address)
+	s.MTU = 30
+	start(t, s)
+
+	var metrics []telegraf.Metric
+	for _, name := range []string{"a", "b", "c", "very_long_measurement_name"} {
+		metrics = append(metrics, testutil.MustMetric(name,
+			map[string]string{},
+			map[string]interface{}{"value": int64(1)},
+			time.Unix(0, 0)))
+	}
+	require.NoError(t, s.Write(metrics))
+	require.Equal(t, []string{
+		"a.value:1|g\nb.value:1|g",
+		"c.value:1|g",
+		"very_long_measurement_name.value:1|g",
+	}, readDatagrams(t, conn))
+}
+
+func TestWriteTCP(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	s := newStatsd("tcp://" + listener.Addr().String())
+	s.TagFormat = "datadog"
+	start(t, s)
+
+	conn, err := listener.Accept()
+	require.NoError(t, err)
+	defer conn.Close()
+
+	require.NoError(t, s.Write([]telegraf.Metric{
+		testutil.MustMetric("cpu",
+			map[string]string{"host": "a", "cpu": "cpu0"},
+			map[string]interface{}{"usage_idle": 99.5, "usage_user": 0.5},
+			time.Unix(0, 0)),
+	}))
+
+	var lines []string
+	reader := bufio.NewReader(conn)
+	for i := 0; i < 2; i++ {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		lines = append(lines, line)
+	}
+	require.ElementsMatch(t, []string{
+		"cpu.usage_idle:99.5|g|#cpu:cpu0,host:a\n",
+		"cpu.usage_user:0.5|g|#cpu:cpu0,host:a\n",
+	}, lines)
+}
+
+func TestCumulative(t *testing.T) {
+	conn, address := listenUDP(t)
+	s := newStatsd(address)
+	start(t, s)
+
+	counter := func(v int64) telegraf.Metric {
+		return testutil.MustMetric("net",
+			map[string]string{"interface": "eth0"},
+			map[string]interface{}{"bytes_recv": v},
+			time.Unix(0, 0), telegraf.Counter)
+	}
+
+	require.NoError(t, s.Write([]telegraf.Metric{counter(100)}))
+	require.Empty(t, readDatagrams(t, conn))
+
+	require.NoError(t, s.Write([]telegraf.Metric{counter(150), counter(170), counter(20)}))
+	require.Equal(t, []string{
+		"net.bytes_recv:50|c\nnet.bytes_recv:20|c\nnet.bytes_recv:20|c",
+	}, readDatagrams(t, conn))
+}
+
+func TestCumulativeExpiry(t *testing.T) {
+	conn, address := listenUDP(t)
+	s := newStatsd(address)
+	s.CumulativeExpiry = 2
+	start(t, s)
+
+	counter := func(name string, v int64) telegraf.Metric {
+		return testutil.MustMetric(name,
+			map[string]string{},
+			map[string]interface{}{"value": v},
+			time.Unix(0, 0), telegraf.Counter)
+	}
+
+	require.NoError(t, s.Write([]telegraf.Metric{counter("a", 100), counter("b", 100)}))
+	require.Empty(t, readDatagrams(t, conn))
+	require.Len(t, s.previous, 2)
+
+	require.NoError(t, s.Write([]telegraf.Metric{counter("a", 110)}))
+	require.Equal(t, []string{"a.value:10|c"}, readDatagrams(t, conn))
+	require.Len(t, s.previous, 2)
+
+	// b has not been written for two writes
+	require.NoError(t, s.Write([]telegraf.Metric{counter("a", 120)}))
+	require.Equal(t, []string{"a.value:10|c"}, readDatagrams(t, conn))
+	require.Len(t, s.previous, 1)
+
+	require.NoError(t, s.Write([]telegraf.Metric{counter("b", 150)}))
+	require.Empty(t, readDatagrams(t, conn))
+}
+
+func TestRules(t *testing.T) {
+	s := newStatsd("udp://127.0.0.1:8125")
+	s.Rules = []*Rule{
+		{Measurements: []string{"nginx"}, Fields: []string{"requests"}, Type: "counter"},
+		{Fields: []string{"*_time"}, Type: "timing"},
+		{MetricType: "counter", Type: "gauge"},
+	}
+	require.NoError(t, s.Init())
+
+	nginx := testutil.MustMetric("nginx", map[string]string{}, map[string]interface{}{}, time.Unix(0, 0))
+	apache := testutil.MustMetric("apache", map[string]string{}, map[string]interface{}{}, time.Unix(0, 0))
+	counter := testutil.MustMetric("apache", map[string]string{}, map[string]interface{}{}, time.Unix(0, 0), telegraf.Counter)
+
+	require.Equal(t, "counter", s.statsdType(nginx, "requests"))
+	require.Equal(t, "gauge", s.statsdType(apache, "requests"))
+	require.Equal(t, "timing", s.statsdType(apache, "response_time"))
+	require.Equal(t, "gauge", s.statsdType(counter, "requests"))
+
+	s.Rules = nil
+	require.Equal(t, "cumulative", s.statsdType(counter, "requests"))
+}
+
+func TestLine(t *testing.T) {
+	m := testutil.MustMetric("disk",
+		map[string]string{"path": "/var/lib", "fstype": "ext4,rw", "url": "http://a"},
+		map[string]interface{}{},
+		time.Unix(0, 0))
+
+	s := newStatsd("udp://127.0.0.1:8125")
+	s.Prefix = "telegraf."
+	name := s.name(m.Name(), "used percent")
+	require.Equal(t, "telegraf.disk.used_percent", name)
+
+	require.Equal(t, "telegraf.disk.used_percent:1.5|ms", string(s.line(name, 1.5, "ms", s.tags(m))))
+
+	s.TagFormat = "datadog"
+	require.Equal(t, "telegraf.disk.used_percent:1.5|ms|#fstype:ext4_rw,path:/var/lib,url:http://a",
+		string(s.line(name, 1.5, "ms", s.tags(m))))
+
+	s.TagFormat = "influx"
+	require.Equal(t, "telegraf.disk.used_percent,fstype=ext4_rw,path=/var/lib,url=http_//a:1.5|ms",
+		string(s.line(name, 1.5, "ms", s.tags(m))))
+}
+
+func TestInitErrors(t *testing.T) {
+	for _, s := range []*Statsd{
+		{Address: "127.0.0.1:8125"},
+		{Address: "unix:///tmp/statsd.sock"},
+		{Address: "udp://127.0.0.1:8125", TagFormat: "graphite"},
+		{Address: "udp://127.0.0.1:8125", Rules: []*Rule{{Type: "set"}}},
+		{Address: "udp://127.0.0.1:8125", Rules: []*Rule{{Type: "gauge", MetricType: "timing"}}},
+	} {
+		require.Error(t, s.Init(), s.Address)
+	}
+}
+
+func TestReconnect(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+
+	s := newStatsd("tcp://" + listener.Addr().String())
+	start(t, s)
+	conn, err := listener.Accept()
+	require.NoError(t, err)
+	conn.Close()
+	s.Close()
+
+	metric := testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 1}, time.Unix(0, 0))
+	require.NoError(t, s.Write([]telegraf.Metric{metric}))
+
+	conn, err = listener.Accept()
+	require.NoError(t, err)
+	defer conn.Close()
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(line, "cpu.value:1|g"))
+}
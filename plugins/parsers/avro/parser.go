@@ -0,0 +1,433 @@
+package avro
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/metric"
+	"github.com/linkedin/goavro/v2"
+)
+
+// Messages in the schema registry wire format start with a zero magic byte
+// followed by the schema ID as a big-endian uint32.
+const (
+	magicByte  = 0
+	headerSize = 5
+)
+
+var (
+	ErrNoMetric = errors.New("no metric in line")
+)
+
+type Config struct {
+	MetricName       string
+	SchemaRegistry   string
+	SchemaFile       string
+	MeasurementField string
+	Tags             []string
+	Fields           []string
+	Timestamp        string
+	TimestampFormat  string
+	FieldSeparator   string
+	DefaultTags      map[string]string
+}
+
+// Parser decodes Avro messages in the schema registry wire format.
+type Parser struct {
+	metricName       string
+	measurementField string
+	tags             []string
+	fields           []string
+	timestamp        string
+	timestampFormat  string
+	separator        string
+	defaultTags      map[string]string
+
+	registry *schemaRegistry
+	// schema is the schema loaded from the schema file, used for all messages
+	// regardless of their schema ID.
+	schema *schema
+
+	Now func() time.Time
+}
+
+func New(config *Config) (*Parser, error) {
+	if config.SchemaRegistry == "" && config.SchemaFile == "" {
+		return nil, errors.New("avro: one of schema registry or schema file is required")
+	}
+
+	p := &Parser{
+		metricName:       config.MetricName,
+		measurementField: config.MeasurementField,
+		tags:             config.Tags,
+		fields:           config.Fields,
+		timestamp:        config.Timestamp,
+		timestampFormat:  config.TimestampFormat,
+		separator:        config.FieldSeparator,
+		defaultTags:      config.DefaultTags,
+		Now:              time.Now,
+	}
+
+	if p.separator == "" {
+		p.separator = "_"
+	}
+	if p.timestampFormat == "" {
+		p.timestampFormat = "unix"
+	}
+
+	if config.SchemaFile != "" {
+		b, err := ioutil.ReadFile(config.SchemaFile)
+		if err != nil {
+			return nil, fmt.Errorf("avro: reading schema file: %v", err)
+		}
+
+		p.schema, err = newSchema(string(b))
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		p.registry = newSchemaRegistry(config.SchemaRegistry)
+	}
+
+	return p, nil
+}
+
+func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	if len(buf) < headerSize || buf[0] != magicByte {
+		return nil, errors.New("avro: message is not in the schema registry wire format")
+	}
+	schemaID := int(binary.BigEndian.Uint32(buf[1:headerSize]))
+
+	s := p.schema
+	if s == nil {
+		var err error
+		s, err = p.registry.get(schemaID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	native, rest, err := s.codec.NativeFromBinary(buf[headerSize:])
+	if err != nil {
+		return nil, fmt.Errorf("avro: decoding message with schema %d: %v", schemaID, err)
+	}
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("avro: %d trailing bytes after message", len(rest))
+	}
+
+	m, err := p.createMetric(s, native)
+	if err != nil {
+		return nil, err
+	}
+	return []telegraf.Metric{m}, nil
+}
+
+func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
+	metrics, err := p.Parse([]byte(line))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(metrics) < 1 {
+		return nil, ErrNoMetric
+	}
+	return metrics[0], nil
+}
+
+func (p *Parser) SetDefaultTags(tags map[string]string) {
+	p.defaultTags = tags
+}
+
+func (p *Parser) createMetric(s *schema, native interface{}) (telegraf.Metric, error) {
+	record, ok := native.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("avro: expected a record, got %T", native)
+	}
+
+	flat := make(map[string]interface{})
+	s.flatten("", s.root, record, p.separator, flat)
+
+	name := p.metricName
+	if p.measurementField != "" {
+		if v, ok := flat[p.measurementField]; ok {
+			name = toString(v)
+			delete(flat, p.measurementField)
+		}
+	}
+
+	tm := p.Now()
+	if p.timestamp != "" {
+		v, ok := flat[p.timestamp]
+		if !ok {
+			return nil, fmt.Errorf("avro: timestamp field %q not found", p.timestamp)
+		}
+
+		var err error
+		tm, err = p.parseTimestamp(v)
+		if err != nil {
+			return nil, fmt.Errorf("avro: parsing timestamp field %q: %v", p.timestamp, err)
+		}
+		delete(flat, p.timestamp)
+	}
+
+	tags := make(map[string]string, len(p.defaultTags)+len(p.tags))
+	for k, v := range p.defaultTags {
+		tags[k] = v
+	}
+	for _, key := range p.tags {
+		if v, ok := flat[key]; ok {
+			tags[key] = toString(v)
+			delete(flat, key)
+		}
+	}
+
+	fields := flat
+	if len(p.fields) != 0 {
+		fields = make(map[string]interface{}, len(p.fields))
+		for _, key := range p.fields {
+			if v, ok := flat[key]; ok {
+				fields[key] = v
+			}
+		}
+	}
+
+	// Logical timestamp types not used as the metric time are added as
+	// integer nanoseconds.
+	for k, v := range fields {
+		if t, ok := v.(time.Time); ok {
+			fields[k] = t.UnixNano()
+		}
+	}
+
+	return metric.New(name, tags, fields, tm)
+}
+
+func (p *Parser) parseTimestamp(v interface{}) (time.Time, error) {
+	switch v := v.(type) {
+	case time.Time:
+		return v, nil
+	case float64, int64, string:
+		return internal.ParseTimestamp(p.timestampFormat, v, "")
+	default:
+		return time.Time{}, fmt.Errorf("unsupported type %T", v)
+	}
+}
+
+func toString(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return v
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	case time.Time:
+		return v.UTC().Format(time.RFC3339Nano)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// schema is a compiled Avro schema along with its parsed JSON definition,
+// which is needed to tell unions apart from records when flattening decoded
+// values.
+type schema struct {
+	codec *goavro.Codec
+	root  interface{}
+	named map[string]interface{}
+}
+
+func newSchema(definition string) (*schema, error) {
+	codec, err := goavro.NewCodec(definition)
+	if err != nil {
+		return nil, fmt.Errorf("avro: invalid schema: %v", err)
+	}
+
+	var root interface{}
+	if err := json.Unmarshal([]byte(definition), &root); err != nil {
+		return nil, fmt.Errorf("avro: invalid schema: %v", err)
+	}
+
+	s := &schema{
+		codec: codec,
+		root:  root,
+		named: make(map[string]interface{}),
+	}
+	s.collectNamed(root, "")
+	return s, nil
+}
+
+// collectNamed records all named types so that references to them can be
+// resolved when flattening.
+func (s *schema) collectNamed(t interface{}, namespace string) {
+	switch t := t.(type) {
+	case []interface{}:
+		for _, member := range t {
+			s.collectNamed(member, namespace)
+		}
+	case map[string]interface{}:
+		if ns, ok := t["namespace"].(string); ok {
+			namespace = ns
+		}
+		if name, ok := t["name"].(string); ok {
+			s.named[name] = t
+			if namespace != "" && !strings.Contains(name, ".") {
+				s.named[namespace+"."+name] = t
+			}
+		}
+
+		switch t["type"] {
+		case "record", "error":
+			fields, _ := t["fields"].([]interface{})
+			for _, f := range fields {
+				if field, ok := f.(map[string]interface{}); ok {
+					s.collectNamed(field["type"], namespace)
+				}
+			}
+		case "array":
+			s.collectNamed(t["items"], namespace)
+		case "map":
+			s.collectNamed(t["values"], namespace)
+		}
+	}
+}
+
+func (s *schema) resolve(t interface{}) interface{} {
+	if name, ok := t.(string); ok {
+		if named, ok := s.named[name]; ok {
+			return named
+		}
+	}
+	return t
+}
+
+// flatten adds the decoded value v of schema type t to out.  Nested records,
+// maps and arrays are flattened into keys joined by the separator.
+func (s *schema) flatten(prefix string, t interface{}, v interface{}, sep string, out map[string]interface{}) {
+	join := func(key string) string {
+		if prefix == "" {
+			return key
+		}
+		return prefix + sep + key
+	}
+
+	t = s.resolve(t)
+	switch t := t.(type) {
+	case []interface{}:
+		// Unions decode to nil or a map with the name of the member type
+		// as key.
+		union, ok := v.(map[string]interface{})
+		if !ok {
+			return
+		}
+		for name, value := range union {
+			for _, member := range t {
+				tn := typeName(s.resolve(member))
+				if name == tn || strings.HasSuffix(name, "."+tn) {
+					s.flatten(prefix, member, value, sep, out)
+				}
+			}
+		}
+		return
+	case map[string]interface{}:
+		switch t["type"] {
+		case "record", "error":
+			record, ok := v.(map[string]interface{})
+			if !ok {
+				return
+			}
+			fields, _ := t["fields"].([]interface{})
+			for _, f := range fields {
+				field, ok := f.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				name, _ := field["name"].(string)
+				if value, ok := record[name]; ok {
+					s.flatten(join(name), field["type"], value, sep, out)
+				}
+			}
+			return
+		case "map":
+			values, ok := v.(map[string]interface{})
+			if !ok {
+				return
+			}
+			for key, value := range values {
+				s.flatten(join(key), t["values"], value, sep, out)
+			}
+			return
+		case "array":
+			items, ok := v.([]interface{})
+			if !ok {
+				return
+			}
+			for i, item := range items {
+				s.flatten(join(strconv.Itoa(i)), t["items"], item, sep, out)
+			}
+			return
+		}
+	}
+
+	if value := convert(v); value != nil && prefix != "" {
+		out[prefix] = value
+	}
+}
+
+// typeName returns the name used by goavro for a union member of type t.
+func typeName(t interface{}) string {
+	switch t := t.(type) {
+	case string:
+		return t
+	case map[string]interface{}:
+		if name, ok := t["name"].(string); ok {
+			if ns, ok := t["namespace"].(string); ok && !strings.Contains(name, ".") {
+				return ns + "." + name
+			}
+			return name
+		}
+		if logical, ok := t["logicalType"].(string); ok {
+			return fmt.Sprintf("%v.%s", t["type"], logical)
+		}
+		if name, ok := t["type"].(string); ok {
+			return name
+		}
+	}
+	return ""
+}
+
+// convert returns the decoded primitive value as a field value.
+func convert(v interface{}) interface{} {
+	switch v := v.(type) {
+	case int32:
+		return int64(v)
+	case int64:
+		return v
+	case float32:
+		return float64(v)
+	case float64:
+		return v
+	case bool:
+		return v
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v
+	case time.Duration:
+		return int64(v)
+	default:
+		return nil
+	}
+}
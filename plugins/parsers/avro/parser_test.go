@@ -0,0 +1,244 @@
+package avro
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/linkedin/goavro/v2"
+	"github.com/stretchr/testify/require"
+)
+
+const testSchema = `
+{
+  "type": "record",
+  "name": "Measurement",
+  "namespace": "com.example",
+  "fields": [
+    {"name": "measurement", "type": "string"},
+    {"name": "host", "type": "string"},
+    {"name": "timestamp", "type": {"type": "long", "logicalType": "timestamp-millis"}},
+    {"name": "value", "type": "double"},
+    {"name": "count", "type": "int"},
+    {"name": "status", "type": ["null", "string"], "default": null},
+    {"name": "cpu", "type": {
+      "type": "record",
+      "name": "Usage",
+      "fields": [
+        {"name": "user", "type": "float"},
+        {"name": "system", "type": "float"}
+      ]
+    }},
+    {"name": "labels", "type": {"type": "map", "values": "long"}}
+  ]
+}`
+
+func encode(t *testing.T, id uint32, native map[string]interface{}) []byte {
+	codec, err := goavro.NewCodec(testSchema)
+	require.NoError(t, err)
+
+	buf := make([]byte, headerSize)
+	binary.BigEndian.PutUint32(buf[1:], id)
+	buf, err = codec.BinaryFromNative(buf, native)
+	require.NoError(t, err)
+	return buf
+}
+
+func testRecord(status interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"measurement": "cpu",
+		"host":        "server01",
+		"timestamp":   time.Unix(1593604800, 0),
+		"value":       42.5,
+		"count":       int32(3),
+		"status":      status,
+		"cpu": map[string]interface{}{
+			"user":   float32(1.5),
+			"system": float32(0.25),
+		},
+		"labels": map[string]interface{}{"a": int64(1)},
+	}
+}
+
+func writeSchemaFile(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "avro")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	filename := filepath.Join(dir, "schema.avsc")
+	require.NoError(t, ioutil.WriteFile(filename, []byte(testSchema), 0644))
+	return filename
+}
+
+func TestParseSchemaFile(t *testing.T) {
+	parser, err := New(&Config{
+		MetricName:       "avro",
+		SchemaFile:       writeSchemaFile(t),
+		MeasurementField: "measurement",
+		Tags:             []string{"host"},
+		Timestamp:        "timestamp",
+		DefaultTags:      map[string]string{"dc": "west"},
+	})
+	require.NoError(t, err)
+
+	metrics, err := parser.Parse(encode(t, 1, testRecord(map[string]interface{}{"string": "ok"})))
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{"host": "server01", "dc": "west"},
+			map[string]interface{}{
+				"value":      42.5,
+				"count":      int64(3),
+				"status":     "ok",
+				"cpu_user":   1.5,
+				"cpu_system": 0.25,
+				"labels_a":   int64(1),
+			},
+			time.Unix(1593604800, 0),
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestParseNullUnionAndFieldSelection(t *testing.T) {
+	now := time.Unix(42, 0)
+	parser, err := New(&Config{
+		MetricName:     "avro",
+		SchemaFile:     writeSchemaFile(t),
+		Fields:         []string{"value", "status", "cpu.user"},
+		FieldSeparator: ".",
+	})
+	require.NoError(t, err)
+	parser.Now = func() time.Time { return now }
+
+	metrics, err := parser.Parse(encode(t, 1, testRecord(nil)))
+	require.NoError(t, err)
+
+	expected := []telegraf.Metric{
+		testutil.MustMetric(
+			"avro",
+			map[string]string{},
+			map[string]interface{}{
+				"value":    42.5,
+				"cpu.user": 1.5,
+			},
+			now,
+		),
+	}
+	testutil.RequireMetricsEqual(t, expected, metrics)
+}
+
+func TestParseSchemaRegistry(t *testing.T) {
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if r.URL.Path != "/schemas/ids/7" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"schema": testSchema})
+	}))
+	defer ts.Close()
+
+	parser, err := New(&Config{
+		MetricName:       "avro",
+		SchemaRegistry:   ts.URL + "/",
+		MeasurementField: "measurement",
+		Tags:             []string{"host"},
+		Timestamp:        "timestamp",
+	})
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		metrics, err := parser.Parse(encode(t, 7, testRecord(nil)))
+		require.NoError(t, err)
+		require.Len(t, metrics, 1)
+		require.Equal(t, "cpu", metrics[0].Name())
+		require.Equal(t, time.Unix(1593604800, 0).UnixNano(), metrics[0].Time().UnixNano())
+	}
+	require.Equal(t, int32(1), atomic.LoadInt32(&requests), "schema should be cached")
+
+	_, err = parser.Parse(encode(t, 8, testRecord(nil)))
+	require.Error(t, err)
+
+	// Failures are cached for a while
+	_, err = parser.Parse(encode(t, 8, testRecord(nil)))
+	require.Error(t, err)
+	require.Equal(t, int32(2), atomic.LoadInt32(&requests))
+}
+
+func TestSchemaRegistrySlowSchema(t *testing.T) {
+	release := make(chan struct{})
+	var requests int32
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if r.URL.Path == "/schemas/ids/8" {
+			<-release
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"schema": testSchema})
+	}))
+	defer ts.Close()
+	defer close(release)
+
+	registry := newSchemaRegistry(ts.URL)
+
+	// Lookups of the slow ID share one request
+	errs := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, err := registry.get(8)
+			errs <- err
+		}()
+	}
+
+	// Other IDs are not blocked by the pending request
+	require.Eventually(t, func() bool {
+		return atomic.LoadInt32(&requests) >= 1
+	}, time.Second, 10*time.Millisecond)
+	_, err := registry.get(7)
+	require.NoError(t, err)
+
+	release <- struct{}{}
+	require.Error(t, <-errs)
+	require.Error(t, <-errs)
+	require.Equal(t, int32(2), atomic.LoadInt32(&requests))
+}
+
+func TestParseInvalid(t *testing.T) {
+	parser, err := New(&Config{
+		MetricName: "avro",
+		SchemaFile: writeSchemaFile(t),
+	})
+	require.NoError(t, err)
+
+	tests := [][]byte{
+		nil,
+		[]byte("cpu value=42"),
+		{magicByte, 0, 0, 0, 1, 0xff},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("%d", i), func(t *testing.T) {
+			_, err := parser.Parse(tt)
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestNewRequiresSchema(t *testing.T) {
+	_, err := New(&Config{MetricName: "avro"})
+	require.Error(t, err)
+}
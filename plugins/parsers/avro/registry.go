@@ -0,0 +1,91 @@
+package avro
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const registryTimeout = 10 * time.Second
+
+// registryRetryInterval is how long a failure to fetch a schema is returned
+// for its ID before the schema is fetched again.
+const registryRetryInterval = 30 * time.Second
+
+// schemaRegistry fetches schemas by ID from a Confluent compatible schema
+// registry and caches them.  Schemas are immutable once registered, so cached
+// schemas are never refreshed.  Concurrent lookups of the same ID share one
+// request and do not block lookups of other IDs.
+type schemaRegistry struct {
+	url    string
+	client *http.Client
+
+	mu      sync.Mutex
+	entries map[int]*registryEntry
+}
+
+// registryEntry is a fetched schema or the failure to fetch it, done is
+// closed once the fetch completed.
+type registryEntry struct {
+	done    chan struct{}
+	schema  *schema
+	err     error
+	fetched time.Time
+}
+
+func newSchemaRegistry(url string) *schemaRegistry {
+	return &schemaRegistry{
+		url:     strings.TrimSuffix(url, "/"),
+		client:  &http.Client{Timeout: registryTimeout},
+		entries: make(map[int]*registryEntry),
+	}
+}
+
+func (r *schemaRegistry) get(id int) (*schema, error) {
+	r.mu.Lock()
+	e, ok := r.entries[id]
+	if ok && e.err != nil && time.Since(e.fetched) >= registryRetryInterval {
+		ok = false
+	}
+	if ok {
+		r.mu.Unlock()
+		<-e.done
+		return e.schema, e.err
+	}
+
+	e = &registryEntry{done: make(chan struct{})}
+	r.entries[id] = e
+	r.mu.Unlock()
+
+	s, err := r.fetch(id)
+
+	r.mu.Lock()
+	e.schema, e.err, e.fetched = s, err, time.Now()
+	r.mu.Unlock()
+	close(e.done)
+	return s, err
+}
+
+func (r *schemaRegistry) fetch(id int) (*schema, error) {
+	resp, err := r.client.Get(fmt.Sprintf("%s/schemas/ids/%d", r.url, id))
+	if err != nil {
+		return nil, fmt.Errorf("avro: fetching schema %d: %v", id, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("avro: fetching schema %d: received status code %d", id, resp.StatusCode)
+	}
+
+	var body struct {
+		Schema string `json:"schema"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("avro: decoding schema %d: %v", id, err)
+	}
+
+	return newSchema(body.Schema)
+}
@@ -0,0 +1,421 @@
+package msgpack
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/metric"
+)
+
+// headerSize is the size of the big-endian uint32 length prefix of each
+// encoded metric.
+const headerSize = 4
+
+// MaxFrameSize is the largest encoded metric accepted by the parser.
+const MaxFrameSize = 16 * 1024 * 1024
+
+var (
+	ErrShortBuffer = errors.New("msgpack: unexpected end of data")
+	ErrNoMetric    = errors.New("msgpack: no metric in data")
+)
+
+// Parser decodes metrics encoded by the msgpack serializer.
+type Parser struct {
+	DefaultTags map[string]string
+}
+
+// NewParser creates a parser.
+func NewParser(defaultTags map[string]string) *Parser {
+	return &Parser{
+		DefaultTags: defaultTags,
+	}
+}
+
+// Parse decodes one or more length-prefixed metrics.
+func (p *Parser) Parse(buf []byte) ([]telegraf.Metric, error) {
+	metrics := make([]telegraf.Metric, 0)
+	for len(buf) > 0 {
+		frame, rest, err := nextFrame(buf)
+		if err != nil {
+			return nil, err
+		}
+		buf = rest
+
+		m, err := p.decodeMetric(frame)
+		if err != nil {
+			return nil, err
+		}
+		metrics = append(metrics, m)
+	}
+	return metrics, nil
+}
+
+// ParseLine decodes a single length-prefixed metric.
+func (p *Parser) ParseLine(line string) (telegraf.Metric, error) {
+	metrics, err := p.Parse([]byte(line))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(metrics) < 1 {
+		return nil, ErrNoMetric
+	}
+	return metrics[0], nil
+}
+
+// SetDefaultTags adds tags to the metrics outputs of Parse and ParseLine.
+func (p *Parser) SetDefaultTags(tags map[string]string) {
+	p.DefaultTags = tags
+}
+
+// Split is a bufio.SplitFunc returning one length-prefixed metric, including
+// its prefix, per token.  It can be used to read metrics from a stream.
+func (p *Parser) Split(data []byte, atEOF bool) (int, []byte, error) {
+	if len(data) < headerSize {
+		if atEOF && len(data) > 0 {
+			return 0, nil, ErrShortBuffer
+		}
+		return 0, nil, nil
+	}
+
+	size := binary.BigEndian.Uint32(data)
+	if size > MaxFrameSize {
+		return 0, nil, fmt.Errorf("msgpack: frame size %d exceeds maximum of %d", size, MaxFrameSize)
+	}
+
+	n := headerSize + int(size)
+	if len(data) < n {
+		if atEOF {
+			return 0, nil, ErrShortBuffer
+		}
+		return 0, nil, nil
+	}
+	return n, data[:n], nil
+}
+
+// MaxTokenSize returns the size of the largest token returned by Split.
+func (p *Parser) MaxTokenSize() int {
+	return headerSize + MaxFrameSize
+}
+
+func nextFrame(buf []byte) ([]byte, []byte, error) {
+	if len(buf) < headerSize {
+		return nil, nil, ErrShortBuffer
+	}
+
+	size := binary.BigEndian.Uint32(buf)
+	if uint64(len(buf)-headerSize) < uint64(size) {
+		return nil, nil, ErrShortBuffer
+	}
+
+	end := headerSize + int(size)
+	return buf[headerSize:end], buf[end:], nil
+}
+
+func (p *Parser) decodeMetric(frame []byte) (telegraf.Metric, error) {
+	d := &decoder{buf: frame}
+
+	n, err := d.readArrayHeader()
+	if err != nil {
+		return nil, err
+	}
+	if n != 4 {
+		return nil, fmt.Errorf("msgpack: expected array of 4 elements, got %d", n)
+	}
+
+	name, err := d.readString()
+	if err != nil {
+		return nil, err
+	}
+
+	n, err = d.readMapHeader()
+	if err != nil {
+		return nil, err
+	}
+	tags := make(map[string]string, n+len(p.DefaultTags))
+	for k, v := range p.DefaultTags {
+		tags[k] = v
+	}
+	for i := 0; i < n; i++ {
+		key, err := d.readString()
+		if err != nil {
+			return nil, err
+		}
+		value, err := d.readString()
+		if err != nil {
+			return nil, err
+		}
+		tags[key] = value
+	}
+
+	n, err = d.readMapHeader()
+	if err != nil {
+		return nil, err
+	}
+	fields := make(map[string]interface{}, n)
+	for i := 0; i < n; i++ {
+		key, err := d.readString()
+		if err != nil {
+			return nil, err
+		}
+		value, err := d.readValue()
+		if err != nil {
+			return nil, err
+		}
+		if value != nil {
+			fields[key] = value
+		}
+	}
+
+	tm, err := d.readTimestamp()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(d.buf) != 0 {
+		return nil, fmt.Errorf("msgpack: %d trailing bytes after metric", len(d.buf))
+	}
+
+	return metric.New(name, tags, fields, tm)
+}
+
+// decoder reads MessagePack values from a buffer.
+type decoder struct {
+	buf []byte
+}
+
+func (d *decoder) next(n int) ([]byte, error) {
+	if len(d.buf) < n {
+		return nil, ErrShortBuffer
+	}
+	b := d.buf[:n]
+	d.buf = d.buf[n:]
+	return b, nil
+}
+
+func (d *decoder) readByte() (byte, error) {
+	b, err := d.next(1)
+	if err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+func (d *decoder) readUint(size int) (uint64, error) {
+	b, err := d.next(size)
+	if err != nil {
+		return 0, err
+	}
+
+	switch size {
+	case 1:
+		return uint64(b[0]), nil
+	case 2:
+		return uint64(binary.BigEndian.Uint16(b)), nil
+	case 4:
+		return uint64(binary.BigEndian.Uint32(b)), nil
+	default:
+		return binary.BigEndian.Uint64(b), nil
+	}
+}
+
+func (d *decoder) readArrayHeader() (int, error) {
+	c, err := d.readByte()
+	if err != nil {
+		return 0, err
+	}
+
+	switch {
+	case c&0xf0 == 0x90:
+		return d.checkCount(uint64(c&0x0f), 1)
+	case c == 0xdc:
+		n, err := d.readUint(2)
+		if err != nil {
+			return 0, err
+		}
+		return d.checkCount(n, 1)
+	case c == 0xdd:
+		n, err := d.readUint(4)
+		if err != nil {
+			return 0, err
+		}
+		return d.checkCount(n, 1)
+	}
+	return 0, fmt.Errorf("msgpack: expected array, got type 0x%02x", c)
+}
+
+func (d *decoder) readMapHeader() (int, error) {
+	c, err := d.readByte()
+	if err != nil {
+		return 0, err
+	}
+
+	switch {
+	case c&0xf0 == 0x80:
+		return d.checkCount(uint64(c&0x0f), 2)
+	case c == 0xde:
+		n, err := d.readUint(2)
+		if err != nil {
+			return 0, err
+		}
+		return d.checkCount(n, 2)
+	case c == 0xdf:
+		n, err := d.readUint(4)
+		if err != nil {
+			return 0, err
+		}
+		return d.checkCount(n, 2)
+	}
+	return 0, fmt.Errorf("msgpack: expected map, got type 0x%02x", c)
+}
+
+// checkCount returns the number of elements of an array or map header, if
+// the remaining data can hold them with each taking at least size bytes.
+// The count is used to size the decoded maps, so a corrupt header must not
+// cause a huge allocation.
+func (d *decoder) checkCount(n uint64, size int) (int, error) {
+	if n > uint64(len(d.buf)/size) {
+		return 0, ErrShortBuffer
+	}
+	return int(n), nil
+}
+
+func (d *decoder) readString() (string, error) {
+	c, err := d.readByte()
+	if err != nil {
+		return "", err
+	}
+	return d.readStringBody(c)
+}
+
+func (d *decoder) readStringBody(c byte) (string, error) {
+	var n uint64
+	var err error
+	switch {
+	case c&0xe0 == 0xa0:
+		n = uint64(c & 0x1f)
+	case c == 0xd9:
+		n, err = d.readUint(1)
+	case c == 0xda:
+		n, err = d.readUint(2)
+	case c == 0xdb:
+		n, err = d.readUint(4)
+	default:
+		return "", fmt.Errorf("msgpack: expected string, got type 0x%02x", c)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if uint64(len(d.buf)) < n {
+		return "", ErrShortBuffer
+	}
+	b, _ := d.next(int(n))
+	return string(b), nil
+}
+
+// readValue decodes a field value.  Positive fixints and the unsigned integer
+// formats decode to uint64, the signed formats to int64.
+func (d *decoder) readValue() (interface{}, error) {
+	c, err := d.readByte()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case c <= 0x7f:
+		return uint64(c), nil
+	case c >= 0xe0:
+		return int64(int8(c)), nil
+	case c&0xe0 == 0xa0, c == 0xd9, c == 0xda, c == 0xdb:
+		return d.readStringBody(c)
+	}
+
+	switch c {
+	case 0xc0:
+		return nil, nil
+	case 0xc2:
+		return false, nil
+	case 0xc3:
+		return true, nil
+	case 0xca:
+		v, err := d.readUint(4)
+		return float64(math.Float32frombits(uint32(v))), err
+	case 0xcb:
+		v, err := d.readUint(8)
+		return math.Float64frombits(v), err
+	case 0xcc:
+		return d.readUint(1)
+	case 0xcd:
+		return d.readUint(2)
+	case 0xce:
+		return d.readUint(4)
+	case 0xcf:
+		return d.readUint(8)
+	case 0xd0:
+		v, err := d.readUint(1)
+		return int64(int8(v)), err
+	case 0xd1:
+		v, err := d.readUint(2)
+		return int64(int16(v)), err
+	case 0xd2:
+		v, err := d.readUint(4)
+		return int64(int32(v)), err
+	case 0xd3:
+		v, err := d.readUint(8)
+		return int64(v), err
+	}
+	return nil, fmt.Errorf("msgpack: unsupported field type 0x%02x", c)
+}
+
+// readTimestamp decodes the timestamp extension type in any of its 32, 64 or
+// 96-bit forms.
+func (d *decoder) readTimestamp() (time.Time, error) {
+	c, err := d.readByte()
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var size uint64
+	switch c {
+	case 0xd6:
+		size = 4
+	case 0xd7:
+		size = 8
+	case 0xc7:
+		if size, err = d.readUint(1); err != nil {
+			return time.Time{}, err
+		}
+	default:
+		return time.Time{}, fmt.Errorf("msgpack: expected timestamp, got type 0x%02x", c)
+	}
+
+	extType, err := d.readByte()
+	if err != nil {
+		return time.Time{}, err
+	}
+	if int8(extType) != -1 {
+		return time.Time{}, fmt.Errorf("msgpack: expected timestamp, got extension type %d", int8(extType))
+	}
+
+	switch size {
+	case 4:
+		sec, err := d.readUint(4)
+		return time.Unix(int64(sec), 0), err
+	case 8:
+		v, err := d.readUint(8)
+		return time.Unix(int64(v&0x00000003ffffffff), int64(v>>34)), err
+	case 12:
+		nsec, err := d.readUint(4)
+		if err != nil {
+			return time.Time{}, err
+		}
+		sec, err := d.readUint(8)
+		return time.Unix(int64(sec), int64(nsec)), err
+	}
+	return time.Time{}, fmt.Errorf("msgpack: invalid timestamp size %d", size)
+}
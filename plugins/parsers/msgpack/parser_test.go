@@ -0,0 +1,168 @@
+package msgpack
+
+import (
+	"bufio"
+	"bytes"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/serializers/msgpack"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func testMetrics() []telegraf.Metric {
+	return []telegraf.Metric{
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{"host": "a", "cpu": "cpu0"},
+			map[string]interface{}{
+				"float":     91.5,
+				"int":       int64(-42),
+				"small_int": int64(1),
+				"max_int":   int64(math.MaxInt64),
+				"uint":      uint64(7),
+				"max_uint":  uint64(math.MaxUint64),
+				"bool":      true,
+				"string":    "a longer string value of more than thirty-two bytes",
+			},
+			time.Unix(1593604800, 123456789),
+		),
+		testutil.MustMetric(
+			"mem",
+			map[string]string{},
+			map[string]interface{}{"free": int64(42)},
+			time.Unix(-1, 999999999),
+		),
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	s, err := msgpack.NewSerializer()
+	require.NoError(t, err)
+
+	buf, err := s.SerializeBatch(testMetrics())
+	require.NoError(t, err)
+
+	parser := NewParser(nil)
+	actual, err := parser.Parse(buf)
+	require.NoError(t, err)
+
+	testutil.RequireMetricsEqual(t, testMetrics(), actual)
+}
+
+func TestParseLine(t *testing.T) {
+	s, err := msgpack.NewSerializer()
+	require.NoError(t, err)
+
+	buf, err := s.Serialize(testMetrics()[1])
+	require.NoError(t, err)
+
+	parser := NewParser(map[string]string{"region": "eu"})
+	actual, err := parser.ParseLine(string(buf))
+	require.NoError(t, err)
+
+	expected := testutil.MustMetric(
+		"mem",
+		map[string]string{"region": "eu"},
+		map[string]interface{}{"free": int64(42)},
+		time.Unix(-1, 999999999),
+	)
+	testutil.RequireMetricEqual(t, expected, actual)
+}
+
+func TestParseTimestampForms(t *testing.T) {
+	tests := []struct {
+		name      string
+		timestamp []byte
+		expected  time.Time
+	}{
+		{
+			name:      "32-bit",
+			timestamp: []byte{0xd6, 0xff, 0, 0, 0, 10},
+			expected:  time.Unix(10, 0),
+		},
+		{
+			name:      "64-bit",
+			timestamp: []byte{0xd7, 0xff, 0, 0, 0, 0x04, 0, 0, 0, 10},
+			expected:  time.Unix(10, 1),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := []byte{0x94, 0xa1, 'm', 0x80, 0x81, 0xa1, 'v', 0x01}
+			body = append(body, tt.timestamp...)
+			frame := append([]byte{0, 0, 0, byte(len(body))}, body...)
+
+			parser := NewParser(nil)
+			actual, err := parser.Parse(frame)
+			require.NoError(t, err)
+			require.Len(t, actual, 1)
+			require.True(t, tt.expected.Equal(actual[0].Time()))
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	s, err := msgpack.NewSerializer()
+	require.NoError(t, err)
+
+	buf, err := s.Serialize(testMetrics()[0])
+	require.NoError(t, err)
+
+	parser := NewParser(nil)
+
+	_, err = parser.Parse(buf[:len(buf)-1])
+	require.Error(t, err)
+
+	_, err = parser.Parse([]byte("cpu value=42\n"))
+	require.Error(t, err)
+}
+
+func TestParseHugeCount(t *testing.T) {
+	parser := NewParser(nil)
+
+	// A map header claiming 2^32-1 tags in a truncated frame
+	frame := []byte{0x94, 0xa3, 'c', 'p', 'u', 0xdf, 0xff, 0xff, 0xff, 0xff}
+	buf := append([]byte{0, 0, 0, byte(len(frame))}, frame...)
+	_, err := parser.Parse(buf)
+	require.Equal(t, ErrShortBuffer, err)
+
+	// The same for the array header of the metric
+	frame = []byte{0xdd, 0xff, 0xff, 0xff, 0xff}
+	buf = append([]byte{0, 0, 0, byte(len(frame))}, frame...)
+	_, err = parser.Parse(buf)
+	require.Equal(t, ErrShortBuffer, err)
+}
+
+func TestSplit(t *testing.T) {
+	s, err := msgpack.NewSerializer()
+	require.NoError(t, err)
+
+	buf, err := s.SerializeBatch(testMetrics())
+	require.NoError(t, err)
+
+	parser := NewParser(nil)
+	scanner := bufio.NewScanner(bytes.NewReader(buf))
+	scanner.Split(parser.Split)
+
+	var actual []telegraf.Metric
+	for scanner.Scan() {
+		metrics, err := parser.Parse(scanner.Bytes())
+		require.NoError(t, err)
+		require.Len(t, metrics, 1)
+		actual = append(actual, metrics...)
+	}
+	require.NoError(t, scanner.Err())
+	testutil.RequireMetricsEqual(t, testMetrics(), actual)
+
+	// A truncated stream is an error
+	scanner = bufio.NewScanner(bytes.NewReader(buf[:len(buf)-1]))
+	scanner.Split(parser.Split)
+	for scanner.Scan() {
+	}
+	require.Error(t, scanner.Err())
+}
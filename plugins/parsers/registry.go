@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/parsers/avro"
 	"github.com/influxdata/telegraf/plugins/parsers/collectd"
 	"github.com/influxdata/telegraf/plugins/parsers/csv"
 	"github.com/influxdata/telegraf/plugins/parsers/dropwizard"
@@ -13,6 +14,7 @@ import (
 	"github.com/influxdata/telegraf/plugins/parsers/influx"
 	"github.com/influxdata/telegraf/plugins/parsers/json"
 	"github.com/influxdata/telegraf/plugins/parsers/logfmt"
+	"github.com/influxdata/telegraf/plugins/parsers/msgpack"
 	"github.com/influxdata/telegraf/plugins/parsers/nagios"
 	"github.com/influxdata/telegraf/plugins/parsers/value"
 	"github.com/influxdata/telegraf/plugins/parsers/wavefront"
@@ -148,6 +150,16 @@ type Config struct {
 
 	// FormData configuration
 	FormUrlencodedTagKeys []string `toml:"form_urlencoded_tag_keys"`
+
+	// avro configuration
+	AvroSchemaRegistry   string   `toml:"avro_schema_registry"`
+	AvroSchemaFile       string   `toml:"avro_schema_file"`
+	AvroMeasurementField string   `toml:"avro_measurement_field"`
+	AvroTags             []string `toml:"avro_tags"`
+	AvroFields           []string `toml:"avro_fields"`
+	AvroTimestamp        string   `toml:"avro_timestamp"`
+	AvroTimestampFormat  string   `toml:"avro_timestamp_format"`
+	AvroFieldSeparator   string   `toml:"avro_field_separator"`
 }
 
 // NewParser returns a Parser interface based on the given config.
@@ -226,12 +238,27 @@ func NewParser(config *Config) (Parser, error) {
 		return csv.NewParser(config)
 	case "logfmt":
 		parser, err = NewLogFmtParser(config.MetricName, config.DefaultTags)
+	case "msgpack":
+		parser, err = NewMsgpackParser(config.DefaultTags)
 	case "form_urlencoded":
 		parser, err = NewFormUrlencodedParser(
 			config.MetricName,
 			config.DefaultTags,
 			config.FormUrlencodedTagKeys,
 		)
+	case "avro":
+		parser, err = avro.New(&avro.Config{
+			MetricName:       config.MetricName,
+			SchemaRegistry:   config.AvroSchemaRegistry,
+			SchemaFile:       config.AvroSchemaFile,
+			MeasurementField: config.AvroMeasurementField,
+			Tags:             config.AvroTags,
+			Fields:           config.AvroFields,
+			Timestamp:        config.AvroTimestamp,
+			TimestampFormat:  config.AvroTimestampFormat,
+			FieldSeparator:   config.AvroFieldSeparator,
+			DefaultTags:      config.DefaultTags,
+		})
 	default:
 		err = fmt.Errorf("Invalid data format: %s", config.DataFormat)
 	}
@@ -324,6 +351,11 @@ func NewLogFmtParser(metricName string, defaultTags map[string]string) (Parser,
 	return logfmt.NewParser(metricName, defaultTags), nil
 }
 
+// NewMsgpackParser returns a parser for the msgpack data format.
+func NewMsgpackParser(defaultTags map[string]string) (Parser, error) {
+	return msgpack.NewParser(defaultTags), nil
+}
+
 func NewWavefrontParser(defaultTags map[string]string) (Parser, error) {
 	return wavefront.NewWavefrontParser(defaultTags), nil
 }
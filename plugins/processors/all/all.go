@@ -9,6 +9,7 @@ import (
 	_ "github.com/influxdata/telegraf/plugins/processors/enum"
 	_ "github.com/influxdata/telegraf/plugins/processors/execd"
 	_ "github.com/influxdata/telegraf/plugins/processors/filepath"
+	_ "github.com/influxdata/telegraf/plugins/processors/grpc_plugin"
 	_ "github.com/influxdata/telegraf/plugins/processors/ifname"
 	_ "github.com/influxdata/telegraf/plugins/processors/override"
 	_ "github.com/influxdata/telegraf/plugins/processors/parser"
@@ -0,0 +1,155 @@
+package grpc_plugin
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/common/grpcplugin"
+	"github.com/influxdata/telegraf/plugins/common/grpcplugin/pluginv1"
+	"github.com/influxdata/telegraf/plugins/processors"
+)
+
+const sampleConfig = `
+  ## Program to run as plugin, it must serve the plugin on the socket passed
+  ## in the TELEGRAF_PLUGIN_SOCKET environment variable.
+  command = ["telegraf-rename-plugin"]
+
+  ## Delay before the process is restarted after an unexpected termination
+  # restart_delay = "10s"
+
+  ## Maximum time to wait for the plugin to serve after the process started
+  # start_timeout = "10s"
+
+  ## Interval of the health checks, the process is restarted when a check
+  ## fails.  Set to "0s" to disable the health checks.
+  # health_check_interval = "30s"
+
+  ## Settings of the plugin
+  # [processors.grpc_plugin.config]
+  #   prefix = "renamed_"
+`
+
+type GRPCPlugin struct {
+	grpcplugin.Config
+	Log telegraf.Logger `toml:"-"`
+
+	client *grpcplugin.Client
+	acc    telegraf.Accumulator
+
+	sync.Mutex
+	stream pluginv1.Plugin_ProcessClient
+	wg     sync.WaitGroup
+}
+
+func (g *GRPCPlugin) SampleConfig() string {
+	return sampleConfig
+}
+
+func (g *GRPCPlugin) Description() string {
+	return "Run an external processor plugin over gRPC"
+}
+
+func (g *GRPCPlugin) Init() error {
+	client, err := g.NewClient(pluginv1.PluginType_PLUGIN_TYPE_PROCESSOR, g.Log)
+	if err != nil {
+		return err
+	}
+	client.OnReady = g.onReady
+	g.client = client
+	return nil
+}
+
+func (g *GRPCPlugin) Start(acc telegraf.Accumulator) error {
+	g.acc = acc
+	return g.client.Start()
+}
+
+// onReady opens the stream of metrics to process.
+func (g *GRPCPlugin) onReady(ctx context.Context, plugin pluginv1.PluginClient, info *pluginv1.InitResponse) error {
+	stream, err := plugin.Process(ctx)
+	if err != nil {
+		return err
+	}
+
+	g.Lock()
+	g.stream = stream
+	g.Unlock()
+
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		g.readStream(ctx, stream)
+	}()
+	return nil
+}
+
+func (g *GRPCPlugin) readStream(ctx context.Context, stream pluginv1.Plugin_ProcessClient) {
+	for {
+		batch, err := stream.Recv()
+		if err != nil {
+			if err != io.EOF && ctx.Err() == nil {
+				g.Log.Errorf("Reading metrics: %v", err)
+			}
+			return
+		}
+
+		for _, pm := range batch.Metrics {
+			m, err := grpcplugin.FromProto(pm)
+			if err != nil {
+				g.acc.AddError(err)
+				continue
+			}
+			g.acc.AddMetric(m)
+		}
+	}
+}
+
+func (g *GRPCPlugin) Add(m telegraf.Metric, acc telegraf.Accumulator) error {
+	// Reconnects if the process was restarted
+	if _, _, err := g.client.Plugin(); err != nil {
+		return err
+	}
+
+	pm, err := grpcplugin.ToProto(m)
+	if err != nil {
+		return err
+	}
+
+	g.Lock()
+	stream := g.stream
+	g.Unlock()
+
+	if err := stream.Send(&pluginv1.Metrics{Metrics: []*pluginv1.Metric{pm}}); err != nil {
+		return fmt.Errorf("sending metric: %w", err)
+	}
+
+	// The plugin may return any number of metrics for each metric, so
+	// tracking cannot be maintained.
+	m.Drop()
+	return nil
+}
+
+// Stop closes the stream and waits for the plugin to return the remaining
+// metrics before stopping the process.
+func (g *GRPCPlugin) Stop() error {
+	g.Lock()
+	if g.stream != nil {
+		g.stream.CloseSend()
+	}
+	g.Unlock()
+
+	g.wg.Wait()
+	g.client.Stop()
+	return nil
+}
+
+func init() {
+	processors.AddStreaming("grpc_plugin", func() telegraf.StreamingProcessor {
+		return &GRPCPlugin{
+			Config: grpcplugin.DefaultConfig(),
+		}
+	})
+}
@@ -0,0 +1,83 @@
+package grpc_plugin
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/common/grpcplugin"
+	"github.com/influxdata/telegraf/plugins/common/grpcplugin/server"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProcess(t *testing.T) {
+	exe, err := os.Executable()
+	require.NoError(t, err)
+
+	g := &GRPCPlugin{Config: grpcplugin.DefaultConfig()}
+	g.Log = testutil.Logger{}
+	g.Command = []string{exe, "-rename"}
+	g.Config.Config = map[string]interface{}{"prefix": "renamed_"}
+	require.NoError(t, g.Init())
+
+	acc := &testutil.Accumulator{}
+	require.NoError(t, g.Start(acc))
+
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		m := testutil.MustMetric("cpu",
+			map[string]string{"host": "a"},
+			map[string]interface{}{"value": int64(i)},
+			now)
+		require.NoError(t, g.Add(m, acc))
+	}
+	require.NoError(t, g.Stop())
+
+	var expected []telegraf.Metric
+	for i := 0; i < 3; i++ {
+		expected = append(expected, testutil.MustMetric("renamed_cpu",
+			map[string]string{"host": "a"},
+			map[string]interface{}{"value": int64(i)},
+			now))
+	}
+	testutil.RequireMetricsEqual(t, expected, acc.GetTelegrafMetrics())
+}
+
+var rename = flag.Bool("rename", false,
+	"if true, act like a processor plugin prefixing the metric names")
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+	if *rename {
+		if err := server.Serve(&renameProcessor{}); err != nil {
+			fmt.Fprintf(os.Stderr, "ERR %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	code := m.Run()
+	os.Exit(code)
+}
+
+type renameProcessor struct {
+	Prefix string `toml:"prefix"`
+}
+
+func (r *renameProcessor) Apply(in ...telegraf.Metric) []telegraf.Metric {
+	for _, m := range in {
+		m.SetName(r.Prefix + m.Name())
+	}
+	return in
+}
+
+func (r *renameProcessor) SampleConfig() string {
+	return ""
+}
+
+func (r *renameProcessor) Description() string {
+	return ""
+}
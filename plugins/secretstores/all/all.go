@@ -0,0 +1,7 @@
+package all
+
+import (
+	_ "github.com/influxdata/telegraf/plugins/secretstores/docker"
+	_ "github.com/influxdata/telegraf/plugins/secretstores/encrypted_file"
+	_ "github.com/influxdata/telegraf/plugins/secretstores/vault"
+)
@@ -0,0 +1,73 @@
+package docker
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/secretstores"
+)
+
+const defaultPath = "/run/secrets"
+
+var sampleConfig = `
+  ## Unique identifier of the store, secrets are referenced as
+  ## "@{<id>:<secret name>}" e.g. "@{docker:influx_token}"
+  id = "docker"
+
+  ## Directory containing one file per secret with the secret name as file
+  ## name.  Docker mounts its secrets to "/run/secrets", for Kubernetes use
+  ## the mount path of the secret volume.
+  # path = "/run/secrets"
+`
+
+type Docker struct {
+	Path string `toml:"path"`
+}
+
+func (d *Docker) Description() string {
+	return "Read secrets from Docker or Kubernetes secret files"
+}
+
+func (d *Docker) SampleConfig() string {
+	return sampleConfig
+}
+
+func (d *Docker) Init() error {
+	if d.Path == "" {
+		d.Path = defaultPath
+	}
+
+	info, err := os.Stat(d.Path)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%q is not a directory", d.Path)
+	}
+	return nil
+}
+
+func (d *Docker) Get(key string) ([]byte, error) {
+	// Do not allow to escape the secrets directory
+	if key != filepath.Base(key) || key == "." || key == ".." {
+		return nil, fmt.Errorf("invalid secret name %q", key)
+	}
+
+	secret, err := ioutil.ReadFile(filepath.Join(d.Path, key))
+	if err != nil {
+		return nil, err
+	}
+
+	// Secret files created with echo or an editor end with a newline
+	return bytes.TrimRight(secret, "\r\n"), nil
+}
+
+func init() {
+	secretstores.Add("docker", func() telegraf.SecretStore {
+		return &Docker{}
+	})
+}
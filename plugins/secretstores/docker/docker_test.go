@@ -0,0 +1,37 @@
+package docker
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGet(t *testing.T) {
+	dir, err := ioutil.TempDir("", "secrets")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	err = ioutil.WriteFile(filepath.Join(dir, "influx_token"), []byte("s3cr3t\n"), 0600)
+	require.NoError(t, err)
+
+	d := &Docker{Path: dir}
+	require.NoError(t, d.Init())
+
+	secret, err := d.Get("influx_token")
+	require.NoError(t, err)
+	require.Equal(t, "s3cr3t", string(secret))
+
+	_, err = d.Get("missing")
+	require.Error(t, err)
+
+	_, err = d.Get("../" + filepath.Base(dir) + "/influx_token")
+	require.Error(t, err)
+}
+
+func TestInitMissingDirectory(t *testing.T) {
+	d := &Docker{Path: "/does/not/exist"}
+	require.Error(t, d.Init())
+}
@@ -0,0 +1,147 @@
+package encrypted_file
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/plugins/secretstores"
+	"github.com/influxdata/toml"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const (
+	// defaultIterations is the PBKDF2 iteration count used by openssl
+	defaultIterations = 10000
+
+	saltHeader = "Salted__"
+	saltSize   = 8
+)
+
+var errDecrypt = errors.New("could not decrypt, wrong password or corrupted file")
+
+var sampleConfig = `
+  ## Unique identifier of the store, secrets are referenced as
+  ## "@{<id>:<secret name>}" e.g. "@{secrets:mysql_password}"
+  id = "secrets"
+
+  ## File with the secrets as TOML key-value pairs, encrypted with
+  ##   openssl enc -aes-256-cbc -pbkdf2 -salt -in secrets.toml -out secrets.enc
+  ## The file may also be base64 encoded with the "-a" option of openssl.
+  path = "/etc/telegraf/secrets.enc"
+
+  ## Password for decrypting the file
+  password = "${TELEGRAF_SECRETS_PASSWORD}"
+
+  ## Number of PBKDF2 iterations, as passed to openssl with "-iter"
+  # iterations = 10000
+`
+
+type EncryptedFile struct {
+	Path       string `toml:"path"`
+	Password   string `toml:"password"`
+	Iterations int    `toml:"iterations"`
+
+	secrets map[string]string
+}
+
+func (e *EncryptedFile) Description() string {
+	return "Read secrets from a password-encrypted file"
+}
+
+func (e *EncryptedFile) SampleConfig() string {
+	return sampleConfig
+}
+
+func (e *EncryptedFile) Init() error {
+	if e.Path == "" {
+		return errors.New("path is required")
+	}
+	if e.Password == "" {
+		return errors.New("password is required")
+	}
+	if e.Iterations == 0 {
+		e.Iterations = defaultIterations
+	}
+
+	data, err := ioutil.ReadFile(e.Path)
+	if err != nil {
+		return err
+	}
+
+	plaintext, err := decrypt(data, []byte(e.Password), e.Iterations)
+	if err != nil {
+		return err
+	}
+
+	tbl, err := toml.Parse(plaintext)
+	if err != nil {
+		return fmt.Errorf("could not parse secrets: %v", err)
+	}
+	e.secrets = make(map[string]string)
+	if err := toml.UnmarshalTable(tbl, e.secrets); err != nil {
+		return fmt.Errorf("could not parse secrets: %v", err)
+	}
+	return nil
+}
+
+func (e *EncryptedFile) Get(key string) ([]byte, error) {
+	secret, ok := e.secrets[key]
+	if !ok {
+		return nil, fmt.Errorf("secret %q not found", key)
+	}
+	return []byte(secret), nil
+}
+
+// decrypt decrypts data in the format written by "openssl enc -aes-256-cbc
+// -pbkdf2 -salt".  The key and IV are derived from the password and the salt
+// following the header with PBKDF2-HMAC-SHA256.
+func decrypt(data, password []byte, iterations int) ([]byte, error) {
+	data = bytes.TrimSpace(data)
+	if decoded, err := base64.StdEncoding.DecodeString(
+		string(bytes.Join(bytes.Fields(data), nil))); err == nil {
+		data = decoded
+	}
+
+	if len(data) < len(saltHeader)+saltSize || string(data[:len(saltHeader)]) != saltHeader {
+		return nil, errors.New("not an encrypted file, missing salt header")
+	}
+	salt := data[len(saltHeader) : len(saltHeader)+saltSize]
+	ciphertext := data[len(saltHeader)+saltSize:]
+	if len(ciphertext) == 0 || len(ciphertext)%aes.BlockSize != 0 {
+		return nil, errDecrypt
+	}
+
+	keyIV := pbkdf2.Key(password, salt, iterations, 32+aes.BlockSize, sha256.New)
+	block, err := aes.NewCipher(keyIV[:32])
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext := make([]byte, len(ciphertext))
+	cipher.NewCBCDecrypter(block, keyIV[32:]).CryptBlocks(plaintext, ciphertext)
+
+	// Remove the PKCS#7 padding
+	padding := int(plaintext[len(plaintext)-1])
+	if padding == 0 || padding > aes.BlockSize {
+		return nil, errDecrypt
+	}
+	for _, b := range plaintext[len(plaintext)-padding:] {
+		if int(b) != padding {
+			return nil, errDecrypt
+		}
+	}
+	return plaintext[:len(plaintext)-padding], nil
+}
+
+func init() {
+	secretstores.Add("encrypted_file", func() telegraf.SecretStore {
+		return &EncryptedFile{}
+	})
+}
@@ -0,0 +1,59 @@
+package encrypted_file
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGet(t *testing.T) {
+	tests := []struct {
+		name       string
+		path       string
+		iterations int
+	}{
+		{
+			name: "binary",
+			path: "testdata/secrets.enc",
+		},
+		{
+			name:       "base64",
+			path:       "testdata/secrets.b64",
+			iterations: 1000,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &EncryptedFile{
+				Path:       tt.path,
+				Password:   "telegraf",
+				Iterations: tt.iterations,
+			}
+			require.NoError(t, e.Init())
+
+			secret, err := e.Get("mysql_password")
+			require.NoError(t, err)
+			require.Equal(t, "hunter2", string(secret))
+
+			secret, err = e.Get("influx_token")
+			require.NoError(t, err)
+			require.Equal(t, "tok3n", string(secret))
+
+			_, err = e.Get("missing")
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestWrongPassword(t *testing.T) {
+	e := &EncryptedFile{
+		Path:     "testdata/secrets.enc",
+		Password: "wrong",
+	}
+	require.Error(t, e.Init())
+}
+
+func TestNotEncrypted(t *testing.T) {
+	_, err := decrypt([]byte(`password = "plain"`), []byte("telegraf"), defaultIterations)
+	require.Error(t, err)
+}
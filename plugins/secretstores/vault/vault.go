@@ -0,0 +1,206 @@
+package vault
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/internal"
+	"github.com/influxdata/telegraf/plugins/common/tls"
+	"github.com/influxdata/telegraf/plugins/secretstores"
+)
+
+const (
+	defaultURL     = "http://127.0.0.1:8200"
+	defaultMount   = "secret"
+	defaultTimeout = 5 * time.Second
+)
+
+var sampleConfig = `
+  ## Unique identifier of the store, secrets are referenced as
+  ## "@{<id>:<field>}" e.g. "@{vault:influx_token}"
+  id = "vault"
+
+  ## Address of the Vault server
+  # url = "http://127.0.0.1:8200"
+
+  ## Token used to authenticate, defaults to the VAULT_TOKEN environment
+  ## variable
+  # token = ""
+
+  ## Mount point of the key-value secrets engine and the path of the secret
+  ## within it.  The fields of this secret are the keys of the store.
+  # mount = "secret"
+  path = "telegraf"
+
+  ## Version of the key-value secrets engine, either 1 or 2
+  # engine_version = 2
+
+  ## Timeout for HTTP requests
+  # timeout = "5s"
+
+  ## Optional TLS Config
+  # tls_ca = "/etc/telegraf/ca.pem"
+  # tls_cert = "/etc/telegraf/cert.pem"
+  # tls_key = "/etc/telegraf/key.pem"
+  ## Use TLS but skip chain & host verification
+  # insecure_skip_verify = false
+`
+
+type Vault struct {
+	URL           string            `toml:"url"`
+	Token         string            `toml:"token"`
+	Mount         string            `toml:"mount"`
+	Path          string            `toml:"path"`
+	EngineVersion int               `toml:"engine_version"`
+	Timeout       internal.Duration `toml:"timeout"`
+	tls.ClientConfig
+
+	client *http.Client
+}
+
+func (v *Vault) Description() string {
+	return "Read secrets from a HashiCorp Vault key-value secrets engine"
+}
+
+func (v *Vault) SampleConfig() string {
+	return sampleConfig
+}
+
+func (v *Vault) Init() error {
+	if v.Path == "" {
+		return errors.New("path is required")
+	}
+	if v.URL == "" {
+		v.URL = defaultURL
+	}
+	if v.Mount == "" {
+		v.Mount = defaultMount
+	}
+	if v.Token == "" {
+		v.Token = os.Getenv("VAULT_TOKEN")
+	}
+	if v.Timeout.Duration == 0 {
+		v.Timeout.Duration = defaultTimeout
+	}
+
+	switch v.EngineVersion {
+	case 0:
+		v.EngineVersion = 2
+	case 1, 2:
+	default:
+		return fmt.Errorf("unsupported engine_version %d", v.EngineVersion)
+	}
+
+	tlsCfg, err := v.ClientConfig.TLSConfig()
+	if err != nil {
+		return err
+	}
+
+	v.client = &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: tlsCfg,
+			Proxy:           http.ProxyFromEnvironment,
+		},
+		Timeout: v.Timeout.Duration,
+	}
+	return nil
+}
+
+func (v *Vault) Get(key string) ([]byte, error) {
+	fields, err := v.read()
+	if err != nil {
+		return nil, err
+	}
+
+	field, ok := fields[key]
+	if !ok {
+		return nil, fmt.Errorf("field %q not found in secret %q", key, v.Path)
+	}
+
+	var s string
+	if err := json.Unmarshal(field, &s); err == nil {
+		return []byte(s), nil
+	}
+	// Use the JSON of non-string values such as numbers as is
+	return field, nil
+}
+
+// read returns the fields of the secret.
+func (v *Vault) read() (map[string]json.RawMessage, error) {
+	mount := strings.Trim(v.Mount, "/")
+	path := strings.Trim(v.Path, "/")
+
+	u := strings.TrimRight(v.URL, "/") + "/v1/" + mount + "/" + path
+	if v.EngineVersion == 2 {
+		u = strings.TrimRight(v.URL, "/") + "/v1/" + mount + "/data/" + path
+	}
+
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, err
+	}
+	if v.Token != "" {
+		req.Header.Set("X-Vault-Token", v.Token)
+	}
+	req.Header.Set("User-Agent", internal.ProductToken())
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var errResp struct {
+			Errors []string `json:"errors"`
+		}
+		if json.Unmarshal(body, &errResp) == nil && len(errResp.Errors) > 0 {
+			return nil, fmt.Errorf("reading secret %q failed: %s: %s",
+				v.Path, resp.Status, strings.Join(errResp.Errors, "; "))
+		}
+		return nil, fmt.Errorf("reading secret %q failed: %s", v.Path, resp.Status)
+	}
+
+	var secret struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(body, &secret); err != nil {
+		return nil, err
+	}
+
+	data := secret.Data
+	if v.EngineVersion == 2 {
+		// Version 2 wraps the fields together with the secret metadata
+		var versioned struct {
+			Data json.RawMessage `json:"data"`
+		}
+		if err := json.Unmarshal(data, &versioned); err != nil {
+			return nil, err
+		}
+		data = versioned.Data
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+func init() {
+	secretstores.Add("vault", func() telegraf.SecretStore {
+		return &Vault{}
+	})
+}
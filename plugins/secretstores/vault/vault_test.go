@@ -0,0 +1,80 @@
+package vault
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGet(t *testing.T) {
+	tests := []struct {
+		name          string
+		engineVersion int
+		path          string
+		response      string
+	}{
+		{
+			name:          "version 1",
+			engineVersion: 1,
+			path:          "/v1/secret/telegraf",
+			response:      `{"data": {"influx_token": "tok3n", "port": 3306}}`,
+		},
+		{
+			name:     "version 2",
+			path:     "/v1/secret/data/telegraf",
+			response: `{"data": {"data": {"influx_token": "tok3n", "port": 3306}, "metadata": {"version": 3}}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != tt.path || r.Header.Get("X-Vault-Token") != "root" {
+					w.WriteHeader(http.StatusForbidden)
+					_, _ = w.Write([]byte(`{"errors": ["permission denied"]}`))
+					return
+				}
+				_, _ = w.Write([]byte(tt.response))
+			}))
+			defer ts.Close()
+
+			v := &Vault{
+				URL:           ts.URL,
+				Token:         "root",
+				Path:          "telegraf",
+				EngineVersion: tt.engineVersion,
+			}
+			require.NoError(t, v.Init())
+
+			secret, err := v.Get("influx_token")
+			require.NoError(t, err)
+			require.Equal(t, "tok3n", string(secret))
+
+			secret, err = v.Get("port")
+			require.NoError(t, err)
+			require.Equal(t, "3306", string(secret))
+
+			_, err = v.Get("missing")
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestPermissionDenied(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		_, _ = w.Write([]byte(`{"errors": ["permission denied"]}`))
+	}))
+	defer ts.Close()
+
+	v := &Vault{
+		URL:   ts.URL,
+		Token: "wrong",
+		Path:  "telegraf",
+	}
+	require.NoError(t, v.Init())
+
+	_, err := v.Get("influx_token")
+	require.EqualError(t, err, `reading secret "telegraf" failed: 403 Forbidden: permission denied`)
+}
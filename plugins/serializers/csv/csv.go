@@ -0,0 +1,310 @@
+package csv
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/influxdata/telegraf"
+)
+
+const (
+	// HeaderNone never writes a header row.
+	HeaderNone = "none"
+	// HeaderOnce writes a header row before the first row serialized.
+	HeaderOnce = "once"
+	// HeaderBatch writes a header row at the start of every serialized batch.
+	HeaderBatch = "batch"
+)
+
+const (
+	timestampColumn   = "timestamp"
+	measurementColumn = "measurement"
+	tagPrefix         = "tag."
+	fieldPrefix       = "field."
+)
+
+type columnKind int
+
+const (
+	kindTimestamp columnKind = iota
+	kindMeasurement
+	kindTag
+	kindField
+)
+
+type column struct {
+	kind columnKind
+	key  string
+}
+
+func (c column) header() string {
+	switch c.kind {
+	case kindTimestamp:
+		return timestampColumn
+	case kindMeasurement:
+		return measurementColumn
+	default:
+		return c.key
+	}
+}
+
+type serializer struct {
+	separator       rune
+	header          string
+	timestampFormat string
+	columns         []column
+
+	headerWritten bool
+
+	// frozen is true once the columns computed for the first batch are kept
+	// for all later batches, unknown holds the keys missing from them which
+	// were already warned about.
+	frozen  bool
+	unknown map[column]bool
+}
+
+// NewSerializer creates a CSV serializer.  When columns is empty one column
+// is written for every tag and field key found in the serialized metrics.
+func NewSerializer(separator string, header string, timestampFormat string, columns []string) (*serializer, error) {
+	s := &serializer{
+		separator:       ',',
+		header:          header,
+		timestampFormat: timestampFormat,
+	}
+
+	if separator != "" {
+		r, size := utf8.DecodeRuneInString(separator)
+		if size != len(separator) || r == '"' || r == '\r' || r == '\n' || r == utf8.RuneError {
+			return nil, fmt.Errorf("invalid csv separator %q", separator)
+		}
+		s.separator = r
+	}
+
+	switch s.header {
+	case "":
+		s.header = HeaderNone
+	case HeaderNone, HeaderOnce, HeaderBatch:
+	default:
+		return nil, fmt.Errorf("invalid csv header option %q", header)
+	}
+
+	if s.timestampFormat == "" {
+		s.timestampFormat = "unix"
+	}
+
+	for _, spec := range columns {
+		c, err := parseColumn(spec)
+		if err != nil {
+			return nil, err
+		}
+		s.columns = append(s.columns, c)
+	}
+
+	return s, nil
+}
+
+func parseColumn(spec string) (column, error) {
+	switch {
+	case spec == timestampColumn:
+		return column{kind: kindTimestamp}, nil
+	case spec == measurementColumn:
+		return column{kind: kindMeasurement}, nil
+	case strings.HasPrefix(spec, tagPrefix) && len(spec) > len(tagPrefix):
+		return column{kind: kindTag, key: strings.TrimPrefix(spec, tagPrefix)}, nil
+	case strings.HasPrefix(spec, fieldPrefix) && len(spec) > len(fieldPrefix):
+		return column{kind: kindField, key: strings.TrimPrefix(spec, fieldPrefix)}, nil
+	}
+	return column{}, fmt.Errorf("invalid csv column %q", spec)
+}
+
+func (s *serializer) Serialize(metric telegraf.Metric) ([]byte, error) {
+	return s.SerializeBatch([]telegraf.Metric{metric})
+}
+
+func (s *serializer) SerializeBatch(metrics []telegraf.Metric) ([]byte, error) {
+	columns := s.columns
+	if len(columns) == 0 {
+		columns = batchColumns(metrics)
+
+		// Once a header has been written the columns must not change,
+		// otherwise the rows would no longer match the header.
+		if s.header == HeaderOnce {
+			s.columns = columns
+			s.frozen = true
+		}
+	} else if s.frozen {
+		s.warnUnknown(metrics)
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Comma = s.separator
+
+	if s.header == HeaderBatch || (s.header == HeaderOnce && !s.headerWritten) {
+		if err := w.Write(headers(columns)); err != nil {
+			return nil, err
+		}
+		s.headerWritten = true
+	}
+
+	for _, metric := range metrics {
+		record := make([]string, 0, len(columns))
+		for _, c := range columns {
+			record = append(record, s.value(metric, c))
+		}
+		if err := w.Write(record); err != nil {
+			return nil, err
+		}
+	}
+
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// headers returns the header row for the columns.  Tag and field keys used
+// by more than one column are prefixed with "tag_" or "field_", so that the
+// column names are unique.
+func headers(columns []column) []string {
+	count := make(map[string]int, len(columns))
+	for _, c := range columns {
+		count[c.header()]++
+	}
+
+	record := make([]string, 0, len(columns))
+	for _, c := range columns {
+		name := c.header()
+		if count[name] > 1 {
+			switch c.kind {
+			case kindTag:
+				name = "tag_" + name
+			case kindField:
+				name = "field_" + name
+			}
+		}
+		record = append(record, name)
+	}
+	return record
+}
+
+// batchColumns returns the timestamp and measurement columns followed by one
+// column for every tag key and then every field key, each sorted by key.
+func batchColumns(metrics []telegraf.Metric) []column {
+	tagKeys := make(map[string]bool)
+	fieldKeys := make(map[string]bool)
+	for _, metric := range metrics {
+		for _, tag := range metric.TagList() {
+			tagKeys[tag.Key] = true
+		}
+		for _, field := range metric.FieldList() {
+			fieldKeys[field.Key] = true
+		}
+	}
+
+	columns := make([]column, 0, 2+len(tagKeys)+len(fieldKeys))
+	columns = append(columns, column{kind: kindTimestamp}, column{kind: kindMeasurement})
+	for _, key := range sortedKeys(tagKeys) {
+		columns = append(columns, column{kind: kindTag, key: key})
+	}
+	for _, key := range sortedKeys(fieldKeys) {
+		columns = append(columns, column{kind: kindField, key: key})
+	}
+	return columns
+}
+
+// warnUnknown logs a warning for each tag and field key of the metrics that
+// has no column, once per key.
+func (s *serializer) warnUnknown(metrics []telegraf.Metric) {
+	known := make(map[column]bool, len(s.columns))
+	for _, c := range s.columns {
+		known[c] = true
+	}
+
+	warn := func(c column, kind string) {
+		if known[c] || s.unknown[c] {
+			return
+		}
+		if s.unknown == nil {
+			s.unknown = make(map[column]bool)
+		}
+		s.unknown[c] = true
+		log.Printf("W! [serializers.csv] %s %q is not written, the columns were fixed by the header of the first batch",
+			kind, c.key)
+	}
+
+	for _, metric := range metrics {
+		for _, tag := range metric.TagList() {
+			warn(column{kind: kindTag, key: tag.Key}, "Tag")
+		}
+		for _, field := range metric.FieldList() {
+			warn(column{kind: kindField, key: field.Key}, "Field")
+		}
+	}
+}
+
+func sortedKeys(m map[string]bool) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (s *serializer) value(metric telegraf.Metric, c column) string {
+	switch c.kind {
+	case kindTimestamp:
+		return s.formatTimestamp(metric.Time())
+	case kindMeasurement:
+		return metric.Name()
+	case kindTag:
+		v, _ := metric.GetTag(c.key)
+		return v
+	case kindField:
+		if v, ok := metric.GetField(c.key); ok {
+			return formatValue(v)
+		}
+	}
+	return ""
+}
+
+func (s *serializer) formatTimestamp(t time.Time) string {
+	switch s.timestampFormat {
+	case "unix":
+		return strconv.FormatInt(t.Unix(), 10)
+	case "unix_ms":
+		return strconv.FormatInt(t.UnixNano()/int64(time.Millisecond), 10)
+	case "unix_us":
+		return strconv.FormatInt(t.UnixNano()/int64(time.Microsecond), 10)
+	case "unix_ns":
+		return strconv.FormatInt(t.UnixNano(), 10)
+	default:
+		return t.UTC().Format(s.timestampFormat)
+	}
+}
+
+func formatValue(v interface{}) string {
+	switch v := v.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case uint64:
+		return strconv.FormatUint(v, 10)
+	case bool:
+		return strconv.FormatBool(v)
+	case string:
+		return v
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
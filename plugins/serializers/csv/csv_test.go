@@ -0,0 +1,136 @@
+package csv
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func testMetrics() []telegraf.Metric {
+	return []telegraf.Metric{
+		testutil.MustMetric(
+			"cpu",
+			map[string]string{"host": "a", "cpu": "cpu0"},
+			map[string]interface{}{"usage_idle": 91.5, "usage_user": 2.25},
+			time.Unix(1593604800, 0),
+		),
+		testutil.MustMetric(
+			"mem",
+			map[string]string{"host": "b"},
+			map[string]interface{}{"free": int64(42), "active": true, "note": "a,b"},
+			time.Unix(1593604810, 0),
+		),
+	}
+}
+
+func TestSerializeBatch(t *testing.T) {
+	tests := []struct {
+		name            string
+		separator       string
+		header          string
+		timestampFormat string
+		columns         []string
+		expected        string
+	}{
+		{
+			name: "defaults",
+			expected: "1593604800,cpu,cpu0,a,,,,91.5,2.25\n" +
+				"1593604810,mem,,b,true,42,\"a,b\",,\n",
+		},
+		{
+			name:   "header per batch",
+			header: HeaderBatch,
+			expected: "timestamp,measurement,cpu,host,active,free,note,usage_idle,usage_user\n" +
+				"1593604800,cpu,cpu0,a,,,,91.5,2.25\n" +
+				"1593604810,mem,,b,true,42,\"a,b\",,\n",
+		},
+		{
+			name:            "columns and separator",
+			separator:       ";",
+			header:          HeaderBatch,
+			timestampFormat: time.RFC3339,
+			columns:         []string{"tag.host", "timestamp", "field.usage_idle", "field.free"},
+			expected: "host;timestamp;usage_idle;free\n" +
+				"a;2020-07-01T12:00:00Z;91.5;\n" +
+				"b;2020-07-01T12:00:10Z;;42\n",
+		},
+		{
+			name:            "timestamp in milliseconds",
+			timestampFormat: "unix_ms",
+			columns:         []string{"timestamp", "measurement"},
+			expected: "1593604800000,cpu\n" +
+				"1593604810000,mem\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewSerializer(tt.separator, tt.header, tt.timestampFormat, tt.columns)
+			require.NoError(t, err)
+
+			actual, err := s.SerializeBatch(testMetrics())
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, string(actual))
+		})
+	}
+}
+
+func TestHeaderOnce(t *testing.T) {
+	s, err := NewSerializer("", HeaderOnce, "", nil)
+	require.NoError(t, err)
+
+	metrics := testMetrics()
+
+	actual, err := s.Serialize(metrics[0])
+	require.NoError(t, err)
+	require.Equal(t, "timestamp,measurement,cpu,host,usage_idle,usage_user\n"+
+		"1593604800,cpu,cpu0,a,91.5,2.25\n", string(actual))
+
+	// The columns of the first header are kept for later metrics, keys
+	// without a column are warned about once
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	for i := 0; i < 2; i++ {
+		actual, err = s.Serialize(metrics[1])
+		require.NoError(t, err)
+		require.Equal(t, "1593604810,mem,,b,,\n", string(actual))
+	}
+	require.Equal(t, 3, strings.Count(buf.String(), "W! [serializers.csv]"))
+	require.Contains(t, buf.String(), `Field "free" is not written`)
+}
+
+func TestHeaderUniqueNames(t *testing.T) {
+	s, err := NewSerializer("", HeaderBatch, "", nil)
+	require.NoError(t, err)
+
+	m := testutil.MustMetric(
+		"cpu",
+		map[string]string{"host": "a", "measurement": "b"},
+		map[string]interface{}{"host": "c", "value": 1.0},
+		time.Unix(1593604800, 0),
+	)
+	actual, err := s.Serialize(m)
+	require.NoError(t, err)
+	require.Equal(t, "timestamp,measurement,tag_host,tag_measurement,field_host,value\n"+
+		"1593604800,cpu,a,b,c,1\n", string(actual))
+}
+
+func TestInvalidOptions(t *testing.T) {
+	_, err := NewSerializer("::", "", "", nil)
+	require.Error(t, err)
+
+	_, err = NewSerializer("", "always", "", nil)
+	require.Error(t, err)
+
+	_, err = NewSerializer("", "", "", []string{"host"})
+	require.Error(t, err)
+}
@@ -0,0 +1,173 @@
+package msgpack
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/influxdata/telegraf"
+)
+
+// Each metric is encoded as a MessagePack array of the metric name, a map of
+// tags, a map of fields and a timestamp extension, prefixed by the length of
+// the encoded metric as a big-endian uint32.
+const headerSize = 4
+
+type serializer struct {
+}
+
+func NewSerializer() (*serializer, error) {
+	s := &serializer{}
+	return s, nil
+}
+
+func (s *serializer) Serialize(metric telegraf.Metric) ([]byte, error) {
+	return s.appendMetric(nil, metric)
+}
+
+func (s *serializer) SerializeBatch(metrics []telegraf.Metric) ([]byte, error) {
+	var buf []byte
+	for _, metric := range metrics {
+		var err error
+		buf, err = s.appendMetric(buf, metric)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return buf, nil
+}
+
+func (s *serializer) appendMetric(buf []byte, metric telegraf.Metric) ([]byte, error) {
+	start := len(buf)
+	buf = append(buf, 0, 0, 0, 0)
+
+	buf = appendArrayHeader(buf, 4)
+	buf = appendString(buf, metric.Name())
+
+	tags := metric.TagList()
+	buf = appendMapHeader(buf, len(tags))
+	for _, tag := range tags {
+		buf = appendString(buf, tag.Key)
+		buf = appendString(buf, tag.Value)
+	}
+
+	fields := metric.FieldList()
+	buf = appendMapHeader(buf, len(fields))
+	for _, field := range fields {
+		buf = appendString(buf, field.Key)
+		var err error
+		buf, err = appendValue(buf, field.Value)
+		if err != nil {
+			return nil, fmt.Errorf("field %q: %v", field.Key, err)
+		}
+	}
+
+	buf = appendTimestamp(buf, metric.Time().Unix(), uint32(metric.Time().Nanosecond()))
+
+	size := len(buf) - start - headerSize
+	binary.BigEndian.PutUint32(buf[start:], uint32(size))
+	return buf, nil
+}
+
+func appendArrayHeader(buf []byte, n int) []byte {
+	switch {
+	case n < 16:
+		return append(buf, 0x90|byte(n))
+	case n <= math.MaxUint16:
+		return appendUint16(append(buf, 0xdc), uint16(n))
+	default:
+		return appendUint32(append(buf, 0xdd), uint32(n))
+	}
+}
+
+func appendMapHeader(buf []byte, n int) []byte {
+	switch {
+	case n < 16:
+		return append(buf, 0x80|byte(n))
+	case n <= math.MaxUint16:
+		return appendUint16(append(buf, 0xde), uint16(n))
+	default:
+		return appendUint32(append(buf, 0xdf), uint32(n))
+	}
+}
+
+func appendString(buf []byte, s string) []byte {
+	n := len(s)
+	switch {
+	case n < 32:
+		buf = append(buf, 0xa0|byte(n))
+	case n <= math.MaxUint8:
+		buf = append(buf, 0xd9, byte(n))
+	case n <= math.MaxUint16:
+		buf = appendUint16(append(buf, 0xda), uint16(n))
+	default:
+		buf = appendUint32(append(buf, 0xdb), uint32(n))
+	}
+	return append(buf, s...)
+}
+
+// appendValue encodes a field value.  Signed integers always use the signed
+// integer formats and unsigned integers the unsigned formats, so that the
+// field type is preserved when decoding.
+func appendValue(buf []byte, v interface{}) ([]byte, error) {
+	switch v := v.(type) {
+	case float64:
+		return appendUint64(append(buf, 0xcb), math.Float64bits(v)), nil
+	case int64:
+		switch {
+		case v < 0 && v >= -32:
+			return append(buf, byte(v)), nil
+		case v >= math.MinInt8 && v <= math.MaxInt8:
+			return append(buf, 0xd0, byte(v)), nil
+		case v >= math.MinInt16 && v <= math.MaxInt16:
+			return appendUint16(append(buf, 0xd1), uint16(v)), nil
+		case v >= math.MinInt32 && v <= math.MaxInt32:
+			return appendUint32(append(buf, 0xd2), uint32(v)), nil
+		default:
+			return appendUint64(append(buf, 0xd3), uint64(v)), nil
+		}
+	case uint64:
+		switch {
+		case v < 128:
+			return append(buf, byte(v)), nil
+		case v <= math.MaxUint8:
+			return append(buf, 0xcc, byte(v)), nil
+		case v <= math.MaxUint16:
+			return appendUint16(append(buf, 0xcd), uint16(v)), nil
+		case v <= math.MaxUint32:
+			return appendUint32(append(buf, 0xce), uint32(v)), nil
+		default:
+			return appendUint64(append(buf, 0xcf), v), nil
+		}
+	case bool:
+		if v {
+			return append(buf, 0xc3), nil
+		}
+		return append(buf, 0xc2), nil
+	case string:
+		return appendString(buf, v), nil
+	default:
+		return nil, fmt.Errorf("unsupported type %T", v)
+	}
+}
+
+// appendTimestamp encodes the time using the 96-bit form of the timestamp
+// extension type (-1), which holds any time with nanosecond precision.
+func appendTimestamp(buf []byte, sec int64, nsec uint32) []byte {
+	buf = append(buf, 0xc7, 12, 0xff)
+	buf = appendUint32(buf, nsec)
+	return appendUint64(buf, uint64(sec))
+}
+
+func appendUint16(buf []byte, v uint16) []byte {
+	return append(buf, byte(v>>8), byte(v))
+}
+
+func appendUint32(buf []byte, v uint32) []byte {
+	return append(buf, byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
+
+func appendUint64(buf []byte, v uint64) []byte {
+	return append(buf, byte(v>>56), byte(v>>48), byte(v>>40), byte(v>>32),
+		byte(v>>24), byte(v>>16), byte(v>>8), byte(v))
+}
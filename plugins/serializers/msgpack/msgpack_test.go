@@ -0,0 +1,87 @@
+package msgpack
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/influxdata/telegraf"
+	"github.com/influxdata/telegraf/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSerialize(t *testing.T) {
+	m := testutil.MustMetric(
+		"cpu",
+		map[string]string{"host": "a"},
+		map[string]interface{}{"value": int64(42)},
+		time.Unix(1, 5),
+	)
+
+	s, err := NewSerializer()
+	require.NoError(t, err)
+
+	actual, err := s.Serialize(m)
+	require.NoError(t, err)
+
+	expected := []byte{
+		0x94,                // array of 4
+		0xa3, 'c', 'p', 'u', // name
+		0x81, 0xa4, 'h', 'o', 's', 't', 0xa1, 'a', // tags
+		0x81, 0xa5, 'v', 'a', 'l', 'u', 'e', 0xd0, 42, // fields
+		0xc7, 12, 0xff, 0, 0, 0, 5, 0, 0, 0, 0, 0, 0, 0, 1, // timestamp
+	}
+	require.Equal(t, uint32(len(expected)), binary.BigEndian.Uint32(actual))
+	require.Equal(t, expected, actual[4:])
+}
+
+func TestSerializeValues(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    interface{}
+		expected []byte
+	}{
+		{"positive int", int64(1), []byte{0xd0, 1}},
+		{"negative fixint", int64(-1), []byte{0xff}},
+		{"int16", int64(-1000), []byte{0xd1, 0xfc, 0x18}},
+		{"int32", int64(100000), []byte{0xd2, 0x00, 0x01, 0x86, 0xa0}},
+		{"int64", int64(math.MinInt64), []byte{0xd3, 0x80, 0, 0, 0, 0, 0, 0, 0}},
+		{"uint fixint", uint64(1), []byte{0x01}},
+		{"uint8", uint64(200), []byte{0xcc, 200}},
+		{"uint64", uint64(math.MaxUint64), []byte{0xcf, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{"float", 1.5, []byte{0xcb, 0x3f, 0xf8, 0, 0, 0, 0, 0, 0}},
+		{"true", true, []byte{0xc3}},
+		{"false", false, []byte{0xc2}},
+		{"string", "ok", []byte{0xa2, 'o', 'k'}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			actual, err := appendValue(nil, tt.value)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, actual)
+		})
+	}
+}
+
+func TestSerializeBatch(t *testing.T) {
+	metrics := []telegraf.Metric{
+		testutil.MustMetric("cpu", map[string]string{}, map[string]interface{}{"value": 1.0}, time.Unix(0, 0)),
+		testutil.MustMetric("mem", map[string]string{}, map[string]interface{}{"value": 2.0}, time.Unix(0, 0)),
+	}
+
+	s, err := NewSerializer()
+	require.NoError(t, err)
+
+	batch, err := s.SerializeBatch(metrics)
+	require.NoError(t, err)
+
+	var expected []byte
+	for _, m := range metrics {
+		b, err := s.Serialize(m)
+		require.NoError(t, err)
+		expected = append(expected, b...)
+	}
+	require.Equal(t, expected, batch)
+}
@@ -2,6 +2,7 @@ package prometheus
 
 import (
 	"hash/fnv"
+	"math"
 	"sort"
 	"strconv"
 	"strings"
@@ -182,6 +183,14 @@ func (c *Collection) createLabels(metric telegraf.Metric) []LabelPair {
 
 func (c *Collection) Add(metric telegraf.Metric, now time.Time) {
 	labels := c.createLabels(metric)
+
+	switch metric.Type() {
+	case telegraf.Histogram, telegraf.Summary:
+		if c.addAggregate(metric, labels, now) {
+			return
+		}
+	}
+
 	for _, field := range metric.FieldList() {
 		metricName := MetricName(metric.Name(), field.Key, metric.Type())
 		metricName, ok := SanitizeMetricName(metricName)
@@ -333,6 +342,103 @@ func (c *Collection) Add(metric telegraf.Metric, now time.Time) {
 	}
 }
 
+// addAggregate adds a histogram or summary with all of its buckets or
+// quantiles as fields of a single metric, as created by the metric_version 1
+// of the prometheus input, such as fields "0.1", "+Inf", "sum" and "count".
+// It returns false if the metric is not in this layout.
+func (c *Collection) addAggregate(metric telegraf.Metric, labels []LabelPair, now time.Time) bool {
+	var hasBound bool
+	for _, field := range metric.FieldList() {
+		if _, ok := field.Value.(string); ok {
+			continue
+		}
+		switch field.Key {
+		case "sum", "count":
+		default:
+			if _, err := strconv.ParseFloat(field.Key, 64); err != nil {
+				return false
+			}
+			hasBound = true
+		}
+	}
+	if !hasBound {
+		return false
+	}
+
+	metricName, ok := SanitizeMetricName(metric.Name())
+	if !ok {
+		return true
+	}
+
+	family := MetricFamily{
+		Name: metricName,
+		Type: metric.Type(),
+	}
+
+	entry, ok := c.Entries[family]
+	if !ok {
+		entry = Entry{
+			Family:  family,
+			Metrics: make(map[MetricKey]*Metric),
+		}
+		c.Entries[family] = entry
+	}
+
+	metricKey := MakeMetricKey(labels)
+	if m, ok := entry.Metrics[metricKey]; ok && metric.Time().Before(m.Time) {
+		return true
+	}
+
+	m := &Metric{
+		Labels:  labels,
+		Time:    metric.Time(),
+		AddTime: now,
+	}
+	if metric.Type() == telegraf.Histogram {
+		m.Histogram = &Histogram{}
+	} else {
+		m.Summary = &Summary{}
+	}
+
+	for _, field := range metric.FieldList() {
+		switch field.Key {
+		case "sum":
+			if sum, ok := SampleSum(field.Value); ok {
+				if m.Histogram != nil {
+					m.Histogram.Sum = sum
+				} else {
+					m.Summary.Sum = sum
+				}
+			}
+		case "count":
+			if count, ok := SampleCount(field.Value); ok {
+				if m.Histogram != nil {
+					m.Histogram.Count = count
+				} else {
+					m.Summary.Count = count
+				}
+			}
+		default:
+			bound, err := strconv.ParseFloat(field.Key, 64)
+			if err != nil {
+				continue
+			}
+			if m.Histogram != nil {
+				if count, ok := SampleCount(field.Value); ok {
+					m.Histogram.merge(Bucket{Bound: bound, Count: count})
+				}
+			} else {
+				if value, ok := SampleValue(field.Value); ok {
+					m.Summary.merge(Quantile{Quantile: bound, Value: value})
+				}
+			}
+		}
+	}
+
+	entry.Metrics[metricKey] = m
+	return true
+}
+
 func (c *Collection) Expire(now time.Time, age time.Duration) {
 	expireTime := now.Add(-age)
 	for _, entry := range c.Entries {
@@ -438,8 +544,18 @@ func (c *Collection) GetProto() []*dto.MetricFamily {
 			case telegraf.Untyped:
 				m.Untyped = &dto.Untyped{Value: proto.Float64(metric.Scaler.Value)}
 			case telegraf.Histogram:
+				// The buckets are added in the order they are received
+				sort.Slice(metric.Histogram.Buckets, func(i, j int) bool {
+					return metric.Histogram.Buckets[i].Bound < metric.Histogram.Buckets[j].Bound
+				})
+
+				count := metric.Histogram.Count
 				buckets := make([]*dto.Bucket, 0, len(metric.Histogram.Buckets))
 				for _, bucket := range metric.Histogram.Buckets {
+					// Without a count field the +Inf bucket holds the count
+					if math.IsInf(bucket.Bound, 1) && count == 0 {
+						count = bucket.Count
+					}
 					buckets = append(buckets, &dto.Bucket{
 						UpperBound:      proto.Float64(bucket.Bound),
 						CumulativeCount: proto.Uint64(bucket.Count),
@@ -448,10 +564,14 @@ func (c *Collection) GetProto() []*dto.MetricFamily {
 
 				m.Histogram = &dto.Histogram{
 					Bucket:      buckets,
-					SampleCount: proto.Uint64(metric.Histogram.Count),
+					SampleCount: proto.Uint64(count),
 					SampleSum:   proto.Float64(metric.Histogram.Sum),
 				}
 			case telegraf.Summary:
+				sort.Slice(metric.Summary.Quantiles, func(i, j int) bool {
+					return metric.Summary.Quantiles[i].Quantile < metric.Summary.Quantiles[j].Quantile
+				})
+
 				quantiles := make([]*dto.Quantile, 0, len(metric.Summary.Quantiles))
 				for _, quantile := range metric.Summary.Quantiles {
 					quantiles = append(quantiles, &dto.Quantile{
@@ -379,6 +379,176 @@ func TestCollectionExpire(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "histogram buckets out of order without count",
+			now:  time.Unix(0, 0),
+			age:  10 * time.Second,
+			input: []Input{
+				{
+					metric: testutil.MustMetric(
+						"http_request",
+						map[string]string{"le": "+Inf"},
+						map[string]interface{}{
+							"duration_bucket": int64(3),
+						},
+						time.Unix(0, 0),
+						telegraf.Histogram,
+					),
+					addtime: time.Unix(0, 0),
+				}, {
+					metric: testutil.MustMetric(
+						"http_request",
+						map[string]string{"le": "0.5"},
+						map[string]interface{}{
+							"duration_bucket": int64(2),
+						},
+						time.Unix(0, 0),
+						telegraf.Histogram,
+					),
+					addtime: time.Unix(0, 0),
+				}, {
+					metric: testutil.MustMetric(
+						"http_request",
+						map[string]string{},
+						map[string]interface{}{
+							"duration_sum": 1.5,
+						},
+						time.Unix(0, 0),
+						telegraf.Histogram,
+					),
+					addtime: time.Unix(0, 0),
+				},
+			},
+			expected: []*dto.MetricFamily{
+				{
+					Name: proto.String("http_request_duration"),
+					Help: proto.String(helpString),
+					Type: dto.MetricType_HISTOGRAM.Enum(),
+					Metric: []*dto.Metric{
+						{
+							Label: []*dto.LabelPair{},
+							Histogram: &dto.Histogram{
+								SampleCount: proto.Uint64(3),
+								SampleSum:   proto.Float64(1.5),
+								Bucket: []*dto.Bucket{
+									{
+										UpperBound:      proto.Float64(0.5),
+										CumulativeCount: proto.Uint64(2),
+									},
+									{
+										UpperBound:      proto.Float64(math.Inf(1)),
+										CumulativeCount: proto.Uint64(3),
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "histogram with buckets as fields",
+			now:  time.Unix(0, 0),
+			age:  10 * time.Second,
+			input: []Input{
+				{
+					metric: testutil.MustMetric(
+						"http_request_duration_seconds",
+						map[string]string{"host": "a"},
+						map[string]interface{}{
+							"1":     int64(9),
+							"0.1":   int64(5),
+							"+Inf":  int64(10),
+							"sum":   2.7,
+							"count": int64(10),
+						},
+						time.Unix(0, 0),
+						telegraf.Histogram,
+					),
+					addtime: time.Unix(0, 0),
+				},
+			},
+			expected: []*dto.MetricFamily{
+				{
+					Name: proto.String("http_request_duration_seconds"),
+					Help: proto.String(helpString),
+					Type: dto.MetricType_HISTOGRAM.Enum(),
+					Metric: []*dto.Metric{
+						{
+							Label: []*dto.LabelPair{
+								{Name: proto.String("host"), Value: proto.String("a")},
+							},
+							Histogram: &dto.Histogram{
+								SampleCount: proto.Uint64(10),
+								SampleSum:   proto.Float64(2.7),
+								Bucket: []*dto.Bucket{
+									{
+										UpperBound:      proto.Float64(0.1),
+										CumulativeCount: proto.Uint64(5),
+									},
+									{
+										UpperBound:      proto.Float64(1),
+										CumulativeCount: proto.Uint64(9),
+									},
+									{
+										UpperBound:      proto.Float64(math.Inf(1)),
+										CumulativeCount: proto.Uint64(10),
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			name: "summary with quantiles as fields",
+			now:  time.Unix(0, 0),
+			age:  10 * time.Second,
+			input: []Input{
+				{
+					metric: testutil.MustMetric(
+						"rpc_duration_seconds",
+						map[string]string{},
+						map[string]interface{}{
+							"0.99":  0.8,
+							"0.5":   0.2,
+							"sum":   42.0,
+							"count": int64(100),
+						},
+						time.Unix(0, 0),
+						telegraf.Summary,
+					),
+					addtime: time.Unix(0, 0),
+				},
+			},
+			expected: []*dto.MetricFamily{
+				{
+					Name: proto.String("rpc_duration_seconds"),
+					Help: proto.String(helpString),
+					Type: dto.MetricType_SUMMARY.Enum(),
+					Metric: []*dto.Metric{
+						{
+							Label: []*dto.LabelPair{},
+							Summary: &dto.Summary{
+								SampleCount: proto.Uint64(100),
+								SampleSum:   proto.Float64(42.0),
+								Quantile: []*dto.Quantile{
+									{
+										Quantile: proto.Float64(0.5),
+										Value:    proto.Float64(0.2),
+									},
+									{
+										Quantile: proto.Float64(0.99),
+										Value:    proto.Float64(0.8),
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		},
 		{
 			name: "expire based on add time",
 			now:  time.Unix(20, 0),
@@ -6,9 +6,11 @@ import (
 
 	"github.com/influxdata/telegraf"
 	"github.com/influxdata/telegraf/plugins/serializers/carbon2"
+	"github.com/influxdata/telegraf/plugins/serializers/csv"
 	"github.com/influxdata/telegraf/plugins/serializers/graphite"
 	"github.com/influxdata/telegraf/plugins/serializers/influx"
 	"github.com/influxdata/telegraf/plugins/serializers/json"
+	"github.com/influxdata/telegraf/plugins/serializers/msgpack"
 	"github.com/influxdata/telegraf/plugins/serializers/nowmetric"
 	"github.com/influxdata/telegraf/plugins/serializers/prometheus"
 	"github.com/influxdata/telegraf/plugins/serializers/splunkmetric"
@@ -22,6 +24,25 @@ type SerializerOutput interface {
 	SetSerializer(serializer Serializer)
 }
 
+// SerializerFunc creates a new Serializer.
+type SerializerFunc func() (Serializer, error)
+
+// SerializerFuncOutput is an interface for output plugins writing to several
+// streams, which need a serializer for each of them.
+type SerializerFuncOutput interface {
+	// SetSerializerFunc sets the function creating a new serializer.
+	SetSerializerFunc(fn SerializerFunc)
+}
+
+// DestinationSerializerOutput is an interface for output plugins with
+// destination subtables, each having its own data format.
+type DestinationSerializerOutput interface {
+	// SetDestinationSerializerFuncs sets the functions creating the
+	// serializers of the destination subtables, in the order they appear in
+	// the config.
+	SetDestinationSerializerFuncs(fns []SerializerFunc)
+}
+
 // Serializer is an interface defining functions that a serializer plugin must
 // satisfy.
 //
@@ -100,6 +121,20 @@ type Config struct {
 	// Output string fields as metric labels; when false string fields are
 	// discarded.
 	PrometheusStringAsLabel bool `toml:"prometheus_string_as_label"`
+
+	// Column separator for CSV output.
+	CSVSeparator string `toml:"csv_separator"`
+
+	// When to write the CSV header row, one of "none", "once" or "batch".
+	CSVHeader string `toml:"csv_header"`
+
+	// Timestamp format for CSV output, "unix", "unix_ms", "unix_us",
+	// "unix_ns" or a Go time layout.
+	CSVTimestampFormat string `toml:"csv_timestamp_format"`
+
+	// Order of the CSV columns; when empty one column is written per tag and
+	// field key.
+	CSVColumns []string `toml:"csv_columns"`
 }
 
 // NewSerializer a Serializer interface based on the given config.
@@ -123,6 +158,10 @@ func NewSerializer(config *Config) (Serializer, error) {
 		serializer, err = NewWavefrontSerializer(config.Prefix, config.WavefrontUseStrict, config.WavefrontSourceOverride)
 	case "prometheus":
 		serializer, err = NewPrometheusSerializer(config)
+	case "csv":
+		serializer, err = NewCSVSerializer(config)
+	case "msgpack":
+		serializer, err = NewMsgpackSerializer()
 	default:
 		err = fmt.Errorf("Invalid data format: %s", config.DataFormat)
 	}
@@ -152,6 +191,10 @@ func NewPrometheusSerializer(config *Config) (Serializer, error) {
 	})
 }
 
+func NewCSVSerializer(config *Config) (Serializer, error) {
+	return csv.NewSerializer(config.CSVSeparator, config.CSVHeader, config.CSVTimestampFormat, config.CSVColumns)
+}
+
 func NewWavefrontSerializer(prefix string, useStrict bool, sourceOverride []string) (Serializer, error) {
 	return wavefront.NewSerializer(prefix, useStrict, sourceOverride)
 }
@@ -168,6 +211,10 @@ func NewSplunkmetricSerializer(splunkmetric_hec_routing bool, splunkmetric_multi
 	return splunkmetric.NewSerializer(splunkmetric_hec_routing, splunkmetric_multimetric)
 }
 
+func NewMsgpackSerializer() (Serializer, error) {
+	return msgpack.NewSerializer()
+}
+
 func NewNowSerializer() (Serializer, error) {
 	return nowmetric.NewSerializer()
 }
@@ -0,0 +1,10 @@
+package telegraf
+
+// SecretStore is a backend resolving the `@{store:key}` secret references
+// used in the configuration of other plugins.
+type SecretStore interface {
+	PluginDescriber
+
+	// Get returns the value of the secret stored under key.
+	Get(key string) ([]byte, error)
+}